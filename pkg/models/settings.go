@@ -11,6 +11,113 @@ type PluginSettings struct {
 	BaseUrl string                `json:"baseUrl"`
 	Path    string                `json:"path"` // Legacy field
 	Secrets *SecretPluginSettings `json:"-"`
+
+	// FallbackBaseUrl is a replica API endpoint. Requests that cannot reach
+	// the primary base URL (connection errors, not HTTP error responses) are
+	// retried against it.
+	FallbackBaseUrl string `json:"fallbackBaseUrl,omitempty"`
+
+	// ProxyPathPrefix is prepended to proxied resource paths, for deployments
+	// that serve the API under a sub-path (e.g. "nominal-api").
+	ProxyPathPrefix string `json:"proxyPathPrefix,omitempty"`
+
+	// ProxyAllowedHeaders names request headers the resource proxy forwards
+	// upstream in addition to Content-Type and Accept (e.g. "X-Request-Id").
+	// Credential and identity headers (Authorization, Cookie, User-Agent, the
+	// configured AuthHeaderName) are never forwarded; the plugin sets its own.
+	ProxyAllowedHeaders []string `json:"proxyAllowedHeaders,omitempty"`
+
+	// UIBaseUrl is the Nominal web app URL and UIChannelPath the route under it
+	// for an asset channel, e.g. "/assets/{assetRid}?channel={channel}&start=${__from}".
+	// {assetRid}, {channel}, and {dataScope} are filled in per query; Grafana
+	// variables such as ${__from} are left for Grafana. When both are set,
+	// value fields get a data link back to the queried asset channel.
+	UIBaseUrl     string `json:"uiBaseUrl,omitempty"`
+	UIChannelPath string `json:"uiChannelPath,omitempty"`
+
+	// AuthHeaderName and AuthHeaderScheme control how the API key is sent, for
+	// gateways that expect e.g. "Token <key>" or a custom header. Empty keeps
+	// "Authorization: Bearer <key>"; a custom header with no scheme carries the
+	// bare key.
+	AuthHeaderName   string `json:"authHeaderName,omitempty"`
+	AuthHeaderScheme string `json:"authHeaderScheme,omitempty"`
+
+	// SlowQueryThresholdMs is the batch chunk latency above which a slow-query
+	// warning is logged. Zero or negative uses the plugin default.
+	SlowQueryThresholdMs int `json:"slowQueryThresholdMs,omitempty"`
+
+	// AssetAccessCheck looks up each queried asset before batch compute, so a
+	// missing or forbidden asset fails only its own queries with a clear error.
+	AssetAccessCheck bool `json:"assetAccessCheck,omitempty"`
+
+	// DataScopeCheck looks up each queried asset before batch compute, so a
+	// dataScopeName the asset does not have fails its query with a clear error.
+	DataScopeCheck bool `json:"dataScopeCheck,omitempty"`
+
+	// RetryMissingBatchResults re-runs requests a batch compute response left
+	// without a result as individual compute calls instead of failing them.
+	RetryMissingBatchResults bool `json:"retryMissingBatchResults,omitempty"`
+
+	// DeepHealthCheck makes the health check also run a trivial computation,
+	// so a compute service that is unreachable or forbids the key from
+	// computing is reported as unhealthy. It does not prove the key can read
+	// any particular asset.
+	DeepHealthCheck bool `json:"deepHealthCheck,omitempty"`
+
+	// ClockSkewThresholdMs enables a health check warning when the local clock
+	// differs from the API server's Date header by more than this many
+	// milliseconds. Zero or negative disables the check.
+	ClockSkewThresholdMs int `json:"clockSkewThresholdMs,omitempty"`
+
+	// EmptyVariableNoContent makes the template variable endpoints answer an
+	// intentionally empty result (unresolved variables, unknown assets, no
+	// matches) with 204 No Content instead of 200 with [].
+	EmptyVariableNoContent bool `json:"emptyVariableNoContent,omitempty"`
+
+	// DebugEndpoints enables the debug/* resource paths, which echo query
+	// internals such as interpolated template variables.
+	DebugEndpoints bool `json:"debugEndpoints,omitempty"`
+
+	// DefaultTimeShift is the TimeShift applied to queries that leave theirs
+	// empty, for dashboards that always compare against a fixed baseline
+	// offset. It accepts the same expressions as a query's TimeShift.
+	DefaultTimeShift string `json:"defaultTimeShift,omitempty"`
+
+	// MaxExpandedQueries caps the compute subrequests one query request may
+	// expand into, so an "All" selection over a multi-value variable fails
+	// fast instead of issuing thousands of calls. Zero or negative uses the
+	// plugin default.
+	MaxExpandedQueries int `json:"maxExpandedQueries,omitempty"`
+
+	// MaxBuckets is the largest bucket count the backend accepts; queries
+	// requesting more are clamped to it with a notice instead of failing.
+	// Zero or negative uses the plugin default.
+	MaxBuckets int `json:"maxBuckets,omitempty"`
+
+	// MaxAssetSearchPages caps the pages one asset search (asset variables and
+	// recent assets) may request, independent of its result limit, so an API
+	// that keeps returning a next page token cannot hold the request open.
+	// Zero or negative uses the plugin default.
+	MaxAssetSearchPages int `json:"maxAssetSearchPages,omitempty"`
+
+	// DialLocalAddr binds outgoing API connections to a local IP (optionally
+	// with a port), for networks whose egress must leave through a specific
+	// interface. ForceIPv4 restricts those connections to IPv4.
+	DialLocalAddr string `json:"dialLocalAddr,omitempty"`
+	ForceIPv4     bool   `json:"forceIPv4,omitempty"`
+
+	// Connection pool limits for the resource and Conjure HTTP clients. Zero
+	// keeps each client's default for that limit.
+	MaxIdleConns        int `json:"maxIdleConns,omitempty"`
+	MaxIdleConnsPerHost int `json:"maxIdleConnsPerHost,omitempty"`
+	MaxConnsPerHost     int `json:"maxConnsPerHost,omitempty"`
+
+	// IdleConnTimeoutSeconds closes pooled API connections idle for longer,
+	// so a NAT that silently drops idle flows does not leave stale ones to
+	// fail with "unexpected EOF". Zero keeps the default. DisableKeepAlives
+	// opens a fresh connection for every request instead.
+	IdleConnTimeoutSeconds int  `json:"idleConnTimeoutSeconds,omitempty"`
+	DisableKeepAlives      bool `json:"disableKeepAlives,omitempty"`
 }
 
 // GetAPIBaseURL returns the API base URL, preferring baseUrl over legacy path
@@ -25,6 +25,20 @@ const (
 	AggLastPoint  = "LAST_POINT"
 )
 
+// EnumAggMode summarizes an enum bucket by its most frequent value. Enum queries
+// also accept AggFirstPoint and AggLastPoint; see enumBucketValueIndex.
+const EnumAggMode = "MODE"
+
+// validateEnumAggregation returns an error for an enum aggregation other than
+// EnumAggMode, AggFirstPoint, or AggLastPoint. Empty means EnumAggMode.
+func validateEnumAggregation(agg string) error {
+	switch agg {
+	case "", EnumAggMode, AggFirstPoint, AggLastPoint:
+		return nil
+	}
+	return fmt.Errorf("unsupported enumAggregation %q; valid options are MODE, FIRST_POINT, LAST_POINT", agg)
+}
+
 // AggregationSeries holds one aggregation's worth of data (e.g. "mean", "min").
 // Each series carries its own timestamps. Most aggregations share end_bucket_timestamp,
 // but FIRST_POINT/LAST_POINT use their own timestamp columns (first_timestamp, last_timestamp).
@@ -11,6 +11,7 @@ import (
 	"github.com/apache/arrow-go/v18/arrow/ipc"
 	"github.com/apache/arrow-go/v18/arrow/memory"
 
+	"github.com/nominal-io/nominal-api-go/io/nominal/api"
 	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
 )
 
@@ -1414,3 +1415,43 @@ func TestAggregationSeriesCarriesChannelUnitPropagation(t *testing.T) {
 		}
 	}
 }
+
+func TestCountQueryReturnsBucketCounts(t *testing.T) {
+	qm := NominalQueryModel{QueryType: queryTypeCount, AssetRid: "ri.nominal.asset.1", Channel: "speed", Aggregations: []string{AggMean}}
+	if resp := normalizeAggregations(&qm); resp != nil {
+		t.Fatalf("normalizeAggregations: %v", resp.Error)
+	}
+	if len(qm.Aggregations) != 1 || qm.Aggregations[0] != AggCount {
+		t.Fatalf("Aggregations = %v, want [COUNT]", qm.Aggregations)
+	}
+
+	bucketed := computeapi.BucketedNumericPlot{
+		Timestamps: []api.Timestamp{testTimestamp(1704067200), testTimestamp(1704067260), testTimestamp(1704067320)},
+		Buckets: []computeapi.NumericBucket{
+			{Mean: 1.5, Count: 12},
+			{Mean: 2.5, Count: 0},
+			{Mean: 3.5, Count: 7},
+		},
+	}
+	res := newTestQueryExecution(&Datasource{}, nil).transformBatchResult(computeapi.ComputeWithUnitsResult{
+		ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromBucketedNumeric(bucketed)),
+	}, qm)
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if len(res.Frames) != 1 {
+		t.Fatalf("got %d frames, want 1", len(res.Frames))
+	}
+	valueField, _ := res.Frames[0].FieldByName("value")
+	if valueField == nil {
+		t.Fatal("missing value field")
+	}
+	for i, want := range []float64{12, 0, 7} {
+		if got, _ := valueField.At(i).(*float64); got == nil || *got != want {
+			t.Errorf("value[%d] = %v, want %v", i, got, want)
+		}
+	}
+	if valueField.Config != nil && valueField.Config.Unit != "" {
+		t.Errorf("count field unit = %q, want none", valueField.Config.Unit)
+	}
+}
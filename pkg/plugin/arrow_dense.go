@@ -0,0 +1,108 @@
+package plugin
+
+import (
+	"bytes"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/apache/arrow-go/v18/arrow/array"
+	"github.com/apache/arrow-go/v18/arrow/ipc"
+	"github.com/apache/arrow-go/v18/arrow/memory"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+// ArrowDenseSeries holds the dense columns of an ArrowBucketedNumericPlot read
+// for a DenseArrowFrames query: one shared time axis and one non-nullable
+// value slice per aggregation.
+type ArrowDenseSeries struct {
+	TimePoints []time.Time
+	Series     []DenseAggregationSeries
+}
+
+// DenseAggregationSeries is one aggregation's values, bulk-copied from an
+// Arrow Float64 column with no nulls.
+type DenseAggregationSeries struct {
+	Name               string
+	Values             []float64
+	CarriesChannelUnit bool
+}
+
+// denseArrowFramesEligible reports whether a query's Arrow numeric response
+// can skip the AggregationSeries path: none of the options that rewrite
+// series values or points in transformNominalResponseFromClient are set.
+// Frame-level options (reduce, precision, value type, bucket edges) still apply.
+func denseArrowFramesEligible(qm NominalQueryModel) bool {
+	return qm.DenseArrowFrames &&
+		len(qm.StatFields) == 0 &&
+		!qm.IncludeStdDev &&
+		!qm.hasValueScale() &&
+		(qm.FillPolicy == "" || qm.FillPolicy == FillPolicyNone) &&
+		qm.GapValue == "" &&
+		qm.Stride <= 1
+}
+
+// extractArrowDenseSeries reads an ArrowBucketedNumericPlot as dense columns,
+// copying each Float64 value column with one bulk copy per record instead of
+// allocating a *float64 per value. data.NewField copies the slices again when
+// the frame is built, so a value is copied twice but never boxed.
+//
+// ok is false, with a nil error, when the response does not fit a dense frame:
+// a FIRST_POINT/LAST_POINT series with its own time axis, a non-Float64 value
+// column (COUNT), a null value, or more than maxReturnedPoints rows. Callers
+// then fall back to extractArrowBucketedNumericSeries.
+func extractArrowDenseSeries(arrowPlot computeapi.ArrowBucketedNumericPlot, specs []aggColumnSpec) (_ *ArrowDenseSeries, ok bool, _ error) {
+	reader, err := ipc.NewReader(bytes.NewReader(arrowPlot.ArrowBinary), ipc.WithAllocator(memory.DefaultAllocator))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to create Arrow IPC reader: %w", err)
+	}
+	defer reader.Release()
+
+	sharedTsIdx, resolved, err := resolveArrowSchema(reader.Schema(), specs)
+	if err != nil {
+		return nil, false, err
+	}
+	for _, rs := range resolved {
+		if rs.tsIdx >= 0 {
+			return nil, false, nil
+		}
+	}
+
+	dense := &ArrowDenseSeries{
+		TimePoints: []time.Time{},
+		Series:     make([]DenseAggregationSeries, len(specs)),
+	}
+	for i, spec := range specs {
+		dense.Series[i] = DenseAggregationSeries{Name: spec.Name, Values: []float64{}, CarriesChannelUnit: spec.CarriesChannelUnit}
+	}
+
+	for reader.Next() {
+		rec := reader.Record()
+		nRows := int(rec.NumRows())
+		if len(dense.TimePoints)+nRows > maxReturnedPoints {
+			return nil, false, nil
+		}
+		if err := validateRecordColumnLengths(rec, sharedTsIdx, resolved); err != nil {
+			return nil, false, err
+		}
+		tsCol, isInt64 := rec.Column(sharedTsIdx).(*array.Int64)
+		if !isInt64 {
+			return nil, false, fmt.Errorf("expected Int64 for end_bucket_timestamp, got %T", rec.Column(sharedTsIdx))
+		}
+		for si, rs := range resolved {
+			col, isFloat64 := rec.Column(rs.valueIdx).(*array.Float64)
+			if !isFloat64 || col.NullN() > 0 {
+				return nil, false, nil
+			}
+			dense.Series[si].Values = append(dense.Series[si].Values, col.Float64Values()...)
+		}
+		dense.TimePoints = slices.Grow(dense.TimePoints, nRows)
+		for _, nanos := range tsCol.Int64Values() {
+			dense.TimePoints = appendUnixNanos(dense.TimePoints, nanos)
+		}
+	}
+	if err := reader.Err(); err != nil {
+		return nil, false, fmt.Errorf("Arrow IPC read error: %w", err)
+	}
+	return dense, true, nil
+}
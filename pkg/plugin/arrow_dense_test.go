@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestDenseArrowFramesMatchStandardFrames(t *testing.T) {
+	result := createMockArrowComputeResult([]float64{1.5, -2, 3.25, 0})
+	qm := NominalQueryModel{Channel: "speed", ChannelUnit: "m/s", Aggregations: []string{AggMean}}
+	execution := newTestQueryExecution(&Datasource{}, nil)
+
+	standard := execution.transformBatchResult(result, qm)
+	qm.DenseArrowFrames = true
+	dense := execution.transformBatchResult(result, qm)
+	if standard.Error != nil || dense.Error != nil {
+		t.Fatalf("unexpected errors: standard %v, dense %v", standard.Error, dense.Error)
+	}
+	if len(standard.Frames) != 1 || len(dense.Frames) != 1 {
+		t.Fatalf("frames = %d standard, %d dense; want 1 each", len(standard.Frames), len(dense.Frames))
+	}
+
+	want, got := standard.Frames[0], dense.Frames[0]
+	if got.Name != want.Name {
+		t.Errorf("frame name = %q, want %q", got.Name, want.Name)
+	}
+	wantValues, _ := want.FieldByName("value")
+	gotValues, _ := got.FieldByName("value")
+	if gotValues.Type() != data.FieldTypeFloat64 {
+		t.Errorf("dense value type = %s, want non-nullable float64", gotValues.Type())
+	}
+	if gotValues.Config.Unit != wantValues.Config.Unit {
+		t.Errorf("unit = %q, want %q", gotValues.Config.Unit, wantValues.Config.Unit)
+	}
+	wantTimes, _ := want.FieldByName("time")
+	gotTimes, _ := got.FieldByName("time")
+	if gotValues.Len() != wantValues.Len() || gotTimes.Len() != wantTimes.Len() {
+		t.Fatalf("rows = %d dense, %d standard", gotValues.Len(), wantValues.Len())
+	}
+	for i := 0; i < wantValues.Len(); i++ {
+		if !gotTimes.At(i).(time.Time).Equal(wantTimes.At(i).(time.Time)) {
+			t.Errorf("time[%d] = %v, want %v", i, gotTimes.At(i), wantTimes.At(i))
+		}
+		if got, want := gotValues.At(i).(float64), *wantValues.At(i).(*float64); got != want {
+			t.Errorf("value[%d] = %v, want %v", i, got, want)
+		}
+	}
+}
+
+func TestDenseArrowFramesFallBackOnNulls(t *testing.T) {
+	timestamps := []int64{1704067200000000000, 1704067260000000000}
+	arrowPlot := computeapi.ArrowBucketedNumericPlot{
+		ArrowBinary: createTestArrowBucketedNumeric(timestamps, []float64{1, 0}, []bool{false, true}),
+	}
+	dense, ok, err := extractArrowDenseSeries(arrowPlot, []aggColumnSpec{aggColumnSpecFromEnum(AggMean)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ok || dense != nil {
+		t.Errorf("extractArrowDenseSeries = %v, %v; want fallback for a null value", dense, ok)
+	}
+}
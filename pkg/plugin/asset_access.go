@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// filterAccessibleAssets looks up every asset referenced by prepared and
+// splits off the queries whose asset is missing or forbidden, returning the
+// queries to run and a per-RefID error for the rest. Lookups that fail for any
+// other reason (e.g. a transport error) do not hold a query back; the batch
+// compute reports those on its own.
+func (e *NominalQueryExecution) filterAccessibleAssets(ctx context.Context, prepared []preparedQuery) ([]preparedQuery, map[string]backend.DataResponse) {
+	catalog := e.datasource.catalog()
+	denied := map[string]*backend.DataResponse{}
+	checked := map[string]bool{}
+	for _, query := range prepared {
+		assetRid := query.Model.AssetRid
+		// DataSourceRid queries bypass the asset entirely.
+		if assetRid == "" || query.Model.DataSourceRid != "" || checked[assetRid] {
+			continue
+		}
+		checked[assetRid] = true
+
+		asset, err := catalog.FetchAssetByRid(ctx, e.config, assetRid)
+		if response := assetAccessError(assetRid, asset, err); response != nil {
+			denied[assetRid] = response
+		}
+	}
+
+	if len(denied) == 0 {
+		return prepared, nil
+	}
+	accessible := make([]preparedQuery, 0, len(prepared))
+	failures := make(map[string]backend.DataResponse)
+	for _, query := range prepared {
+		if response, ok := denied[query.Model.AssetRid]; ok && query.Model.DataSourceRid == "" {
+			failures[query.Query.RefID] = *response
+			continue
+		}
+		accessible = append(accessible, query)
+	}
+	log.DefaultLogger.Warn("Skipping queries for inaccessible assets",
+		"assets", len(denied),
+		"queries", len(failures),
+	)
+	return accessible, failures
+}
+
+// assetAccessError returns the response for an asset lookup that shows the
+// asset is missing or forbidden, or nil when the query should still run.
+func assetAccessError(assetRid string, asset *SingleAssetResponse, err error) *backend.DataResponse {
+	var response backend.DataResponse
+	switch status := extractErrorDetails(err).Status; {
+	case err == nil && asset == nil, status == http.StatusNotFound:
+		response = backend.ErrDataResponse(backend.StatusNotFound,
+			fmt.Sprintf("asset not found: %s", assetRid))
+	case status == http.StatusForbidden || status == http.StatusUnauthorized:
+		response = backend.ErrDataResponse(backend.StatusForbidden,
+			appendInstanceID(fmt.Sprintf("access denied to asset %s", assetRid), err))
+	case err != nil:
+		logErrorWithConjureFields("Asset access check failed; running query anyway", err, "assetRid", assetRid)
+		return nil
+	default:
+		return nil
+	}
+	return &response
+}
@@ -0,0 +1,85 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestAssetAccessCheckSkipsInaccessibleAssets(t *testing.T) {
+	const (
+		accessibleRid = "ri.scout.main.asset.ok"
+		forbiddenRid  = "ri.scout.main.asset.forbidden"
+		missingRid    = "ri.scout.main.asset.missing"
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rids []string
+		body, _ := io.ReadAll(r.Body)
+		_ = json.Unmarshal(body, &rids)
+		w.Header().Set("Content-Type", "application/json")
+		if len(rids) == 1 && rids[0] == forbiddenRid {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"errorCode":"PERMISSION_DENIED","errorName":"Scout:AssetPermissionDenied","errorInstanceId":"denied-1"}`))
+			return
+		}
+		result := map[string]SingleAssetResponse{}
+		if len(rids) == 1 && rids[0] == accessibleRid {
+			result[accessibleRid] = SingleAssetResponse{Rid: accessibleRid, Title: "OK"}
+		}
+		_ = json.NewEncoder(w).Encode(result)
+	}))
+	defer server.Close()
+
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{1, 2})},
+		},
+	}
+	ds := &Datasource{computeService: mockService, resourceHTTPClient: server.Client()}
+	config := &models.PluginSettings{
+		BaseUrl:          server.URL,
+		AssetAccessCheck: true,
+		Secrets:          &models.SecretPluginSettings{ApiKey: "test-key"},
+	}
+	timeRange := backend.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+	query := func(refID, assetRid string) backend.DataQuery {
+		return backend.DataQuery{
+			RefID:     refID,
+			JSON:      mustMarshal(NominalQueryModel{AssetRid: assetRid, Channel: "temperature", DataScopeName: "default"}),
+			TimeRange: timeRange,
+		}
+	}
+
+	resp := newTestQueryExecution(ds, config).Execute(context.Background(), []backend.DataQuery{
+		query("A", accessibleRid),
+		query("B", forbiddenRid),
+		query("C", missingRid),
+	})
+
+	if res := resp.Responses["A"]; res.Error != nil || len(res.Frames) == 0 {
+		t.Errorf("accessible query: error = %v, frames = %d; want data", res.Error, len(res.Frames))
+	}
+	if res := resp.Responses["B"]; res.Status != backend.StatusForbidden || res.Error == nil ||
+		!strings.Contains(res.Error.Error(), "access denied to asset "+forbiddenRid) {
+		t.Errorf("forbidden query: status = %v, error = %v; want access denied", res.Status, res.Error)
+	}
+	if res := resp.Responses["C"]; res.Status != backend.StatusNotFound || res.Error == nil ||
+		!strings.Contains(res.Error.Error(), "asset not found: "+missingRid) {
+		t.Errorf("missing query: status = %v, error = %v; want asset not found", res.Status, res.Error)
+	}
+	if got := len(mockService.lastBatchRequest.Requests); got != 1 {
+		t.Errorf("batch compute requests = %d, want only the accessible query", got)
+	}
+}
@@ -0,0 +1,48 @@
+package plugin
+
+import (
+	"context"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// assetFrameMeta is the asset summary IncludeAssetMeta stores under the
+// "asset" key of each frame's Meta.Custom.
+type assetFrameMeta struct {
+	Rid         string `json:"rid"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+}
+
+// attachAssetMeta adds the queried asset's title and description to the
+// frames of each successful IncludeAssetMeta query. Asset lookups go through
+// the catalog's asset cache, so queries on the same asset share one fetch. A
+// failed or empty lookup leaves that query's frames without the metadata
+// rather than failing it. Channel RID queries name no asset and are skipped.
+func (e *NominalQueryExecution) attachAssetMeta(ctx context.Context, prepared []preparedQuery, results map[string]backend.DataResponse) {
+	if e.datasource == nil {
+		return
+	}
+	for _, query := range prepared {
+		if !query.Model.IncludeAssetMeta || query.Model.AssetRid == "" {
+			continue
+		}
+		res, ok := results[query.Query.RefID]
+		if !ok || res.Error != nil {
+			continue
+		}
+		asset, err := e.datasource.catalog().FetchAssetByRid(ctx, e.config, query.Model.AssetRid)
+		if err != nil {
+			log.DefaultLogger.Warn("Failed to look up asset metadata", "refId", query.Query.RefID, "assetRid", query.Model.AssetRid, "error", err)
+			continue
+		}
+		if asset == nil {
+			continue
+		}
+		meta := assetFrameMeta{Rid: asset.Rid, Title: asset.Title, Description: asset.Description}
+		for _, frame := range res.Frames {
+			setFrameCustomMeta(frame, "asset", meta)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestIncludeAssetMetaAddsFrameMeta(t *testing.T) {
+	const assetRid = "ri.scout.main.asset.1"
+	fetches := 0
+	server := newCountingAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {Rid: assetRid, Title: "Test Vehicle", Description: "Track car"},
+	}, &fetches)
+	defer server.Close()
+
+	mockCompute := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{1, 2}), createMockArrowComputeResult([]float64{3, 4})},
+		},
+	}
+	config := &models.PluginSettings{BaseUrl: server.URL, Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+	qe := newTestQueryExecution(&Datasource{computeService: mockCompute, resourceHTTPClient: &http.Client{}}, config)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	query := func(refID, channel string, includeMeta bool) backend.DataQuery {
+		return backend.DataQuery{
+			RefID: refID,
+			JSON: mustMarshal(NominalQueryModel{
+				AssetRid:         assetRid,
+				Channel:          channel,
+				DataScopeName:    "default",
+				IncludeAssetMeta: includeMeta,
+			}),
+			TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+		}
+	}
+	resp := qe.Execute(context.Background(), []backend.DataQuery{
+		query("A", "speed", true),
+		query("B", "rpm", false),
+	})
+
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	custom, _ := res.Frames[0].Meta.Custom.(map[string]any)
+	meta, ok := custom["asset"].(assetFrameMeta)
+	if !ok {
+		t.Fatalf("Meta.Custom = %+v, want asset metadata", res.Frames[0].Meta.Custom)
+	}
+	if meta.Title != "Test Vehicle" || meta.Description != "Track car" || meta.Rid != assetRid {
+		t.Errorf("asset meta = %+v, want the asset's title and description", meta)
+	}
+	if fetches != 1 {
+		t.Errorf("asset fetches = %d, want 1", fetches)
+	}
+
+	if frame := resp.Responses["B"].Frames[0]; frame.Meta != nil {
+		if custom, _ := frame.Meta.Custom.(map[string]any); custom["asset"] != nil {
+			t.Errorf("B Meta.Custom = %+v, want no asset metadata without includeAssetMeta", custom)
+		}
+	}
+}
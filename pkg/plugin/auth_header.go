@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+	conjurehttpclient "github.com/palantir/conjure-go-runtime/v2/conjure-go-client/httpclient"
+)
+
+const (
+	defaultAuthHeaderName   = "Authorization"
+	defaultAuthHeaderScheme = "Bearer"
+)
+
+// authHeader returns the header name and value that carry the API key on
+// requests to Nominal, honouring the authHeaderName and authHeaderScheme
+// settings. The scheme defaults to Bearer on the Authorization header; a custom
+// header with no scheme carries the bare key (e.g. X-API-Key: <key>).
+func authHeader(config *models.PluginSettings, apiKey string) (string, string) {
+	name := strings.TrimSpace(config.AuthHeaderName)
+	if name == "" {
+		name = defaultAuthHeaderName
+	}
+	scheme := strings.TrimSpace(config.AuthHeaderScheme)
+	if scheme == "" && http.CanonicalHeaderKey(name) == defaultAuthHeaderName {
+		scheme = defaultAuthHeaderScheme
+	}
+	if scheme == "" {
+		return name, apiKey
+	}
+	return name, scheme + " " + apiKey
+}
+
+// setAuthHeader sets the configured API key header on a direct HTTP request.
+func setAuthHeader(req *http.Request, config *models.PluginSettings, apiKey string) {
+	name, value := authHeader(config, apiKey)
+	req.Header.Set(name, value)
+}
+
+// isDefaultAuthHeader reports whether config keeps the standard
+// "Authorization: Bearer" header the generated Conjure clients already send.
+func isDefaultAuthHeader(config *models.PluginSettings) bool {
+	name, value := authHeader(config, "")
+	return http.CanonicalHeaderKey(name) == defaultAuthHeaderName && value == defaultAuthHeaderScheme+" "
+}
+
+// authHeaderMiddleware rewrites the Bearer Authorization header set by the
+// generated Conjure clients into the configured header and scheme, so service
+// calls authenticate the same way as direct HTTP and proxied requests.
+func authHeaderMiddleware(config *models.PluginSettings) conjurehttpclient.Middleware {
+	return conjurehttpclient.MiddlewareFunc(func(req *http.Request, next http.RoundTripper) (*http.Response, error) {
+		bearer := req.Header.Get(defaultAuthHeaderName)
+		apiKey, ok := strings.CutPrefix(bearer, defaultAuthHeaderScheme+" ")
+		if !ok {
+			return next.RoundTrip(req)
+		}
+		// Clone the request before mutating headers — RoundTripper contract.
+		r := req.Clone(req.Context())
+		r.Header.Del(defaultAuthHeaderName)
+		setAuthHeader(r, config, apiKey)
+		return next.RoundTrip(r)
+	})
+}
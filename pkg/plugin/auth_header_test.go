@@ -0,0 +1,88 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+	"github.com/palantir/pkg/bearertoken"
+)
+
+func TestAuthHeader(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    models.PluginSettings
+		wantName  string
+		wantValue string
+	}{
+		{name: "default bearer", wantName: "Authorization", wantValue: "Bearer key"},
+		{name: "custom scheme", config: models.PluginSettings{AuthHeaderScheme: "Token"}, wantName: "Authorization", wantValue: "Token key"},
+		{name: "custom header bare key", config: models.PluginSettings{AuthHeaderName: "X-API-Key"}, wantName: "X-API-Key", wantValue: "key"},
+		{name: "custom header and scheme", config: models.PluginSettings{AuthHeaderName: "X-Auth", AuthHeaderScheme: "Token"}, wantName: "X-Auth", wantValue: "Token key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			name, value := authHeader(&tt.config, "key")
+			if name != tt.wantName || value != tt.wantValue {
+				t.Errorf("authHeader = %q: %q, want %q: %q", name, value, tt.wantName, tt.wantValue)
+			}
+		})
+	}
+}
+
+func TestProxyUsesConfiguredAuthHeader(t *testing.T) {
+	var gotAuth, gotCustom string
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		gotCustom = r.Header.Get("X-Auth")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer proxyServer.Close()
+
+	ds := newTestDatasource(proxyServer.URL, &mockAuthService{}, &mockDatasourceService{})
+	ds.settings.JSONData = []byte(`{"baseUrl": "` + proxyServer.URL + `", "authHeaderName": "X-Auth", "authHeaderScheme": "Token"}`)
+	req := &backend.CallResourceRequest{Path: "nominal/scout/v1/search-assets", Method: "POST", Body: []byte(`{}`)}
+
+	resp := callResourceAndCapture(t, ds, req)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+	}
+	if gotCustom != "Token test-api-key" {
+		t.Errorf("X-Auth = %q, want %q", gotCustom, "Token test-api-key")
+	}
+	if gotAuth != "" {
+		t.Errorf("Authorization = %q, want it unset", gotAuth)
+	}
+}
+
+func TestConjureClientUsesConfiguredAuthHeader(t *testing.T) {
+	var gotAuth string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotAuth = r.Header.Get("Authorization")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"rid":"ri.authn.main.user.1","displayName":"Test","email":"test@example.com"}`))
+	}))
+	defer srv.Close()
+
+	instance, err := NewDatasource(context.Background(), backend.DataSourceInstanceSettings{
+		JSONData:                []byte(`{"baseUrl": "` + srv.URL + `", "authHeaderScheme": "Token"}`),
+		DecryptedSecureJSONData: map[string]string{"apiKey": "test-api-key"},
+	})
+	if err != nil {
+		t.Fatalf("NewDatasource: %v", err)
+	}
+	ds := instance.(*Datasource)
+	config, err := models.LoadPluginSettings(ds.settings)
+	if err != nil {
+		t.Fatalf("LoadPluginSettings: %v", err)
+	}
+
+	_, _ = ds.authService.GetMyProfile(context.Background(), bearertoken.Token(config.Secrets.ApiKey))
+	if gotAuth != "Token test-api-key" {
+		t.Errorf("Authorization = %q, want %q", gotAuth, "Token test-api-key")
+	}
+}
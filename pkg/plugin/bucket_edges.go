@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// validateBucketEdges rejects IncludeBucketEdges on queries whose rows are not
+// all bucket-end timestamps: raw (strided or dual-resolution detail) points,
+// and FIRST_POINT/LAST_POINT series, which carry each point's own time.
+func validateBucketEdges(qm NominalQueryModel) error {
+	if !qm.IncludeBucketEdges {
+		return nil
+	}
+	if qm.Stride > 0 || qm.DualResolution {
+		return fmt.Errorf("includeBucketEdges requires a bucketed query (no stride or dualResolution)")
+	}
+	if slices.Contains(qm.Aggregations, AggFirstPoint) || slices.Contains(qm.Aggregations, AggLastPoint) {
+		return fmt.Errorf("includeBucketEdges cannot be combined with FIRST_POINT or LAST_POINT aggregations")
+	}
+	return nil
+}
+
+// bucketInterval is the width of each of buckets equal buckets spanning
+// timeRange, or zero when there are no buckets.
+func bucketInterval(timeRange backend.TimeRange, buckets int) time.Duration {
+	if buckets <= 0 {
+		return 0
+	}
+	return timeRange.Duration() / time.Duration(buckets)
+}
+
+// addBucketEdgeFields inserts "bucketStart" and "bucketEnd" time fields after
+// each frame's "time" field. Bucketed timestamps are bucket ends (exclusive),
+// so each start is its end less interval.
+func addBucketEdgeFields(frames data.Frames, interval time.Duration) {
+	for _, frame := range frames {
+		timeField, idx := frame.FieldByName("time")
+		if timeField == nil || timeField.Type() != data.FieldTypeTime {
+			continue
+		}
+		n := timeField.Len()
+		starts := make([]time.Time, n)
+		ends := make([]time.Time, n)
+		for i := 0; i < n; i++ {
+			end := timeField.At(i).(time.Time)
+			starts[i] = end.Add(-interval)
+			ends[i] = end
+		}
+		edges := []*data.Field{data.NewField("bucketStart", nil, starts), data.NewField("bucketEnd", nil, ends)}
+		frame.Fields = slices.Insert(frame.Fields, idx+1, edges...)
+	}
+}
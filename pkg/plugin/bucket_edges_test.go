@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/nominal-io/nominal-api-go/io/nominal/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestBucketEdgeFieldsAreMonotonicAndSpaced(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	const buckets = 4
+	const width = 15 * time.Minute
+
+	timestamps := make([]api.Timestamp, buckets)
+	numericBuckets := make([]computeapi.NumericBucket, buckets)
+	for i := range timestamps {
+		timestamps[i] = testTimestamp(from.Add(time.Duration(i+1) * width).Unix())
+		numericBuckets[i] = computeapi.NumericBucket{Mean: float64(i)}
+	}
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{{
+				ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromBucketedNumeric(
+					computeapi.BucketedNumericPlot{Timestamps: timestamps, Buckets: numericBuckets},
+				)),
+			}},
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:           "ri.nominal.asset.1",
+			Channel:            "speed",
+			DataScopeName:      "default",
+			Buckets:            buckets,
+			IncludeBucketEdges: true,
+		}),
+		TimeRange: backend.TimeRange{From: from, To: from.Add(buckets * width)},
+	}})
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+
+	frame := res.Frames[0]
+	timeField, timeIdx := frame.FieldByName("time")
+	startField, startIdx := frame.FieldByName("bucketStart")
+	endField, endIdx := frame.FieldByName("bucketEnd")
+	if timeField == nil || startField == nil || endField == nil {
+		t.Fatalf("fields = %v, want time, bucketStart and bucketEnd", frame.Fields)
+	}
+	if startIdx != timeIdx+1 || endIdx != timeIdx+2 {
+		t.Errorf("edge field indexes = %d, %d, want %d, %d", startIdx, endIdx, timeIdx+1, timeIdx+2)
+	}
+	if startField.Len() != buckets || endField.Len() != buckets {
+		t.Fatalf("edge lengths = %d, %d, want %d", startField.Len(), endField.Len(), buckets)
+	}
+	for i := 0; i < buckets; i++ {
+		start := startField.At(i).(time.Time)
+		end := endField.At(i).(time.Time)
+		if !end.Equal(timeField.At(i).(time.Time)) {
+			t.Errorf("bucketEnd[%d] = %v, want the bucket timestamp %v", i, end, timeField.At(i))
+		}
+		if got := end.Sub(start); got != width {
+			t.Errorf("bucket %d width = %v, want %v", i, got, width)
+		}
+		if i > 0 {
+			prevEnd := endField.At(i - 1).(time.Time)
+			if !start.Equal(prevEnd) {
+				t.Errorf("bucketStart[%d] = %v, want previous bucketEnd %v", i, start, prevEnd)
+			}
+		}
+	}
+	if first := startField.At(0).(time.Time); !first.Equal(from) {
+		t.Errorf("bucketStart[0] = %v, want range start %v", first, from)
+	}
+}
+
+func TestValidateBucketEdges(t *testing.T) {
+	tests := []struct {
+		name      string
+		qm        NominalQueryModel
+		wantError string
+	}{
+		{name: "unset", qm: NominalQueryModel{Stride: 5}},
+		{name: "bucketed", qm: NominalQueryModel{IncludeBucketEdges: true, Aggregations: []string{AggMean, AggMax}}},
+		{name: "stride", qm: NominalQueryModel{IncludeBucketEdges: true, Stride: 5}, wantError: "requires a bucketed query"},
+		{name: "dual resolution", qm: NominalQueryModel{IncludeBucketEdges: true, DualResolution: true}, wantError: "requires a bucketed query"},
+		{name: "last point", qm: NominalQueryModel{IncludeBucketEdges: true, Aggregations: []string{AggLastPoint}}, wantError: "LAST_POINT"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateBucketEdges(tt.qm)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("err = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,206 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+// StatFields values select which per-bucket statistics a bucketed numeric
+// query emits as fields of a single frame. An empty StatFields keeps the
+// single "value" (mean) field.
+const (
+	StatMean   = "mean"
+	StatMin    = "min"
+	StatMax    = "max"
+	StatCount  = "count"
+	StatStdDev = "stddev"
+)
+
+// bucketStatSpec reads one statistic from a NumericBucket. CarriesChannelUnit
+// has the same meaning as aggColumnSpec.CarriesChannelUnit: COUNT is
+// dimensionless, so the channel unit is not attached to it.
+type bucketStatSpec struct {
+	value              func(computeapi.NumericBucket) float64
+	CarriesChannelUnit bool
+}
+
+var bucketStatSpecs = map[string]bucketStatSpec{
+	StatMean:  {value: func(b computeapi.NumericBucket) float64 { return b.Mean }, CarriesChannelUnit: true},
+	StatMin:   {value: func(b computeapi.NumericBucket) float64 { return b.Min }, CarriesChannelUnit: true},
+	StatMax:   {value: func(b computeapi.NumericBucket) float64 { return b.Max }, CarriesChannelUnit: true},
+	StatCount: {value: func(b computeapi.NumericBucket) float64 { return float64(b.Count) }},
+	// Standard deviation is in the channel's unit, like the mean.
+	StatStdDev: {value: func(b computeapi.NumericBucket) float64 { return math.Sqrt(b.Variance) }, CarriesChannelUnit: true},
+}
+
+// StatSeries is one bucket statistic's values, parallel to
+// TransformResult.TimePoints.
+type StatSeries struct {
+	Name               string
+	Values             []*float64
+	CarriesChannelUnit bool
+}
+
+// statDefaultAggregations is the server-side aggregation each stat field is
+// summarized with when StatConfig does not choose one. stddev is derived from
+// VARIANCE.
+var statDefaultAggregations = map[string]string{
+	StatMean:   AggMean,
+	StatMin:    AggMin,
+	StatMax:    AggMax,
+	StatCount:  AggCount,
+	StatStdDev: AggVariance,
+}
+
+// validateStatFields returns an error for the first unrecognised stat field.
+func validateStatFields(fields []string) error {
+	for _, field := range fields {
+		if _, ok := bucketStatSpecs[field]; !ok {
+			return fmt.Errorf("unsupported statFields entry %q; valid options are mean, min, max, count, stddev", field)
+		}
+	}
+	return nil
+}
+
+// bucketStatSeries extracts the requested statistics from a bucketed plot, in
+// the order requested, skipping duplicates. source maps each field to the
+// bucket statistic it is read from.
+func bucketStatSeries(bucketed computeapi.BucketedNumericPlot, fields []string, source func(string) string) []StatSeries {
+	n := min(len(bucketed.Timestamps), len(bucketed.Buckets))
+	seen := make(map[string]bool, len(fields))
+	series := make([]StatSeries, 0, len(fields))
+	for _, field := range fields {
+		spec, ok := bucketStatSpecs[source(field)]
+		if !ok || seen[field] {
+			continue
+		}
+		seen[field] = true
+		values := make([]*float64, n)
+		for i := 0; i < n; i++ {
+			v := spec.value(bucketed.Buckets[i])
+			values[i] = &v
+		}
+		series = append(series, StatSeries{Name: field, Values: values, CarriesChannelUnit: spec.CarriesChannelUnit})
+	}
+	return series
+}
+
+// validateStatConfig checks that every StatConfig entry names a requested
+// value stat (mean, min, or max) and a supported aggregation. count and
+// stddev keep their fixed summaries: remapping them would change their meaning.
+func validateStatConfig(fields []string, config map[string]string) error {
+	for field, agg := range config {
+		if !slices.Contains(fields, field) {
+			return fmt.Errorf("statConfig entry %q is not in statFields", field)
+		}
+		if field == StatCount || field == StatStdDev {
+			return fmt.Errorf("statConfig cannot change the summarization of %q", field)
+		}
+		if _, ok := aggSpecs[agg]; !ok {
+			return fmt.Errorf("statConfig %q: %s", field, unsupportedAggregationMessage(agg))
+		}
+	}
+	return nil
+}
+
+// statAggregation is the aggregation that summarizes field: its StatConfig
+// entry, or the field's default.
+func (qm NominalQueryModel) statAggregation(field string) string {
+	if agg, ok := qm.StatConfig[field]; ok {
+		return agg
+	}
+	return statDefaultAggregations[field]
+}
+
+// statAggregations lists the aggregations a StatFields query requests from
+// the compute API, in field order without duplicates.
+func (qm NominalQueryModel) statAggregations() []string {
+	aggs := make([]string, 0, len(qm.StatFields))
+	for _, field := range qm.StatFields {
+		if agg := qm.statAggregation(field); agg != "" && !slices.Contains(aggs, agg) {
+			aggs = append(aggs, agg)
+		}
+	}
+	return aggs
+}
+
+// stdDevFromAggregations turns the MEAN and VARIANCE series of an Arrow
+// IncludeStdDev query into the value and stddev columns the legacy bucketed
+// path reads, so both render the same frame.
+func stdDevFromAggregations(result *TransformResult) {
+	var mean, variance *AggregationSeries
+	for i := range result.AggSeries {
+		switch result.AggSeries[i].Name {
+		case aggSpecs[AggMean].Name:
+			mean = &result.AggSeries[i]
+		case aggSpecs[AggVariance].Name:
+			variance = &result.AggSeries[i]
+		}
+	}
+	if mean == nil || variance == nil || len(mean.Values) != len(variance.Values) {
+		return
+	}
+	stdDevs := make([]*float64, len(variance.Values))
+	for i, v := range variance.Values {
+		if v != nil {
+			stdDev := math.Sqrt(*v)
+			stdDevs[i] = &stdDev
+		}
+	}
+	result.TimePoints = mean.TimePoints
+	result.NumericValues = mean.Values
+	result.StdDevValues = stdDevs
+	result.AggSeries = nil
+}
+
+// statSeriesFromAggregations regroups the Arrow aggregation series of a
+// StatFields query into one StatSeries per requested field, so the result
+// renders as a single frame like the legacy bucketed path. All series share
+// one bucket index; the frame takes the bucket-end timestamps unless only
+// FIRST_POINT/LAST_POINT were requested.
+func statSeriesFromAggregations(result *TransformResult, qm NominalQueryModel) {
+	byName := make(map[string]AggregationSeries, len(result.AggSeries))
+	for _, series := range result.AggSeries {
+		byName[series.Name] = series
+	}
+
+	var timePoints []time.Time
+	stats := make([]StatSeries, 0, len(qm.StatFields))
+	for _, field := range qm.StatFields {
+		if slices.ContainsFunc(stats, func(s StatSeries) bool { return s.Name == field }) {
+			continue
+		}
+		agg := qm.statAggregation(field)
+		spec := aggSpecs[agg]
+		series, ok := byName[spec.Name]
+		if !ok {
+			continue
+		}
+		if timePoints == nil || spec.TimestampCol == "" {
+			timePoints = series.TimePoints
+		}
+		values, carriesUnit := series.Values, spec.CarriesChannelUnit
+		if field == StatStdDev && agg == AggVariance {
+			values = make([]*float64, len(series.Values))
+			for i, v := range series.Values {
+				if v != nil {
+					stdDev := math.Sqrt(*v)
+					values[i] = &stdDev
+				}
+			}
+			// Standard deviation is in the channel's unit, like the mean.
+			carriesUnit = true
+		}
+		stats = append(stats, StatSeries{Name: field, Values: values, CarriesChannelUnit: carriesUnit})
+	}
+	if len(stats) == 0 {
+		return
+	}
+	result.TimePoints = timePoints
+	result.StatSeries = stats
+	result.AggSeries = nil
+}
@@ -0,0 +1,75 @@
+package plugin
+
+import (
+	"context"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// attachChannelTags labels the frames of each successful IncludeChannelTags
+// query with its channel's tags. Lookups run at most
+// maxConcurrentChannelLookups at a time; a failed lookup leaves that query's
+// frames unlabelled rather than failing it.
+func (e *NominalQueryExecution) attachChannelTags(ctx context.Context, prepared []preparedQuery, results map[string]backend.DataResponse) {
+	if e.datasource == nil {
+		return
+	}
+	var lookups []preparedQuery
+	for _, query := range prepared {
+		if !query.Model.IncludeChannelTags {
+			continue
+		}
+		if res, ok := results[query.Query.RefID]; ok && res.Error == nil {
+			lookups = append(lookups, query)
+		}
+	}
+
+	labels := make([]data.Labels, len(lookups))
+	sem := make(chan struct{}, maxConcurrentChannelLookups)
+	var wg sync.WaitGroup
+	for i, query := range lookups {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, query preparedQuery) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			channelLabels, err := e.datasource.catalog().ChannelTags(ctx, e.config, query.Model, query.Query.TimeRange)
+			if err != nil {
+				log.DefaultLogger.Warn("Failed to look up channel tags", "refId", query.Query.RefID, "channel", query.Model.Channel, "error", err)
+				return
+			}
+			labels[i] = channelLabels
+		}(i, query)
+	}
+	wg.Wait()
+
+	for i, query := range lookups {
+		mergeFrameLabels(results[query.Query.RefID].Frames, labels[i])
+	}
+}
+
+// mergeFrameLabels adds labels to every non-time field, keeping any value a
+// field already carries for the same key (e.g. a GroupByTags group's).
+func mergeFrameLabels(frames data.Frames, labels data.Labels) {
+	if len(labels) == 0 {
+		return
+	}
+	for _, frame := range frames {
+		for _, field := range frame.Fields {
+			if fieldType := field.Type(); fieldType == data.FieldTypeTime || fieldType == data.FieldTypeNullableTime {
+				continue
+			}
+			if field.Labels == nil {
+				field.Labels = data.Labels{}
+			}
+			for key, value := range labels {
+				if _, exists := field.Labels[key]; !exists {
+					field.Labels[key] = value
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package plugin
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+	"github.com/nominal-io/nominal-api-go/api/rids"
+	datasourceapi "github.com/nominal-io/nominal-api-go/datasource/api"
+	"github.com/nominal-io/nominal-api-go/io/nominal/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+	"github.com/palantir/pkg/rid"
+)
+
+func TestIncludeChannelTagsAddsFrameLabels(t *testing.T) {
+	const dataSourceRid = "ri.scout.main.data-source.ds1"
+	run := func(t *testing.T, includeTags bool) (backend.DataResponse, *mockDatasourceService) {
+		t.Helper()
+		mockDS := &mockDatasourceService{
+			availableTagsResponse: datasourceapi.GetAvailableTagsForChannelResponse{
+				AvailableTags: datasourceapi.ChannelWithAvailableTags{
+					AvailableTags: map[api.TagName][]api.TagValue{
+						"vehicle": {"car-7"},
+						"lap":     {"1", "2"},
+					},
+				},
+			},
+		}
+		mockCompute := &mockComputeService{
+			batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+				Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{1, 2})},
+			},
+		}
+		qe := newTestQueryExecution(&Datasource{computeService: mockCompute, datasourceService: mockDS}, nil)
+
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		resp := qe.Execute(context.Background(), []backend.DataQuery{{
+			RefID: "A",
+			JSON: mustMarshal(NominalQueryModel{
+				DataSourceRid:      dataSourceRid,
+				Channel:            "speed",
+				IncludeChannelTags: includeTags,
+			}),
+			TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+		}})
+		res := resp.Responses["A"]
+		if res.Error != nil {
+			t.Fatalf("unexpected error: %v", res.Error)
+		}
+		return res, mockDS
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		res, mockDS := run(t, true)
+		valueField, _ := res.Frames[0].FieldByName("value")
+		if valueField == nil {
+			t.Fatalf("fields = %v, want a value field", res.Frames[0].Fields)
+		}
+		want := data.Labels{"vehicle": "car-7"}
+		if valueField.Labels.String() != want.String() {
+			t.Errorf("labels = %v, want %v (multi-valued tags are skipped)", valueField.Labels, want)
+		}
+		timeField, _ := res.Frames[0].FieldByName("time")
+		if len(timeField.Labels) != 0 {
+			t.Errorf("time field labels = %v, want none", timeField.Labels)
+		}
+		request := mockDS.availableTagsRequest.ChannelWithTagFilters
+		if request.DataSourceRid.String() != dataSourceRid || request.Channel != "speed" {
+			t.Errorf("tag lookup = %s/%s, want %s/speed", request.DataSourceRid, request.Channel, dataSourceRid)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		res, mockDS := run(t, false)
+		if mockDS.availableTagsCalls != 0 {
+			t.Errorf("tag lookups = %d, want 0", mockDS.availableTagsCalls)
+		}
+		valueField, _ := res.Frames[0].FieldByName("value")
+		if len(valueField.Labels) != 0 {
+			t.Errorf("labels = %v, want none", valueField.Labels)
+		}
+	})
+}
+
+func TestChannelTagsForAssetChannelUsesInferredDataSource(t *testing.T) {
+	const assetRid = "ri.scout.main.asset.1"
+	datasetRid := "ri.scout.main.data-source.ds1"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid: assetRid,
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "default", DataSource: AssetDataSource{Type: "dataset", Dataset: &datasetRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	doubleType := api.New_SeriesDataType(api.SeriesDataType_DOUBLE)
+	mockDS := &mockDatasourceService{
+		searchChannelsResponse: datasourceapi.SearchChannelsResponse{
+			Results: []datasourceapi.ChannelMetadata{{
+				Name:       api.Channel("speed"),
+				DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1")),
+				DataType:   &doubleType,
+			}},
+		},
+		availableTagsResponse: datasourceapi.GetAvailableTagsForChannelResponse{
+			AvailableTags: datasourceapi.ChannelWithAvailableTags{
+				AvailableTags: map[api.TagName][]api.TagValue{"vehicle": {"car-7"}},
+			},
+		},
+	}
+	catalog := newNominalCatalog(server.Client(), mockDS)
+	config := &models.PluginSettings{BaseUrl: server.URL, Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+	qm := NominalQueryModel{
+		AssetRid:          assetRid,
+		DataScopeName:     "default",
+		Channel:           "speed",
+		TagSelector:       map[string]string{"lap": "3"},
+		TimeShiftDuration: 24 * time.Hour,
+	}
+	catalog.InferChannelMetadata(context.Background(), config, &qm)
+
+	from := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	labels, err := catalog.ChannelTags(context.Background(), config, qm, backend.TimeRange{From: from, To: from.Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if want := (data.Labels{"vehicle": "car-7"}); labels.String() != want.String() {
+		t.Errorf("labels = %v, want %v", labels, want)
+	}
+
+	request := mockDS.availableTagsRequest
+	if request.ChannelWithTagFilters.DataSourceRid.String() != datasetRid {
+		t.Errorf("data source = %s, want the inferred %s", request.ChannelWithTagFilters.DataSourceRid, datasetRid)
+	}
+	if got := request.ChannelWithTagFilters.TagFilters; len(got) != 1 || got["lap"] != "3" {
+		t.Errorf("tag filters = %v, want the query's tag selector", got)
+	}
+	if want := utcTimestampFromTime(from.Add(-24 * time.Hour)); !reflect.DeepEqual(request.StartTime, want) {
+		t.Errorf("start = %v, want the range shifted back by the time shift (%v)", request.StartTime, want)
+	}
+}
+
+func TestMergeFrameLabelsKeepsGroupLabels(t *testing.T) {
+	field := data.NewField("value", data.Labels{"vehicle": "car-1"}, []float64{1})
+	frames := data.Frames{data.NewFrame("speed", field)}
+
+	mergeFrameLabels(frames, data.Labels{"vehicle": "car-7", "site": "north"})
+
+	want := data.Labels{"vehicle": "car-1", "site": "north"}
+	if field.Labels.String() != want.String() {
+		t.Errorf("labels = %v, want %v", field.Labels, want)
+	}
+}
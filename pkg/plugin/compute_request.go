@@ -1,9 +1,14 @@
 package plugin
 
 import (
+	"fmt"
+	"math"
+	"strings"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/nominal-io/nominal-api-go/io/nominal/api"
 	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
 	computeapi1 "github.com/nominal-io/nominal-api-go/scout/compute/api1"
@@ -13,15 +18,29 @@ import (
 
 const logPageSize = -250
 
+// defaultMaxBuckets is the backend's bucket limit when the datasource does not
+// configure maxBuckets.
+const defaultMaxBuckets = 10000
+
+// detailMaxPoints caps the raw detail series of a DualResolution query.
+const detailMaxPoints = 10000
+
 // assetRidVariableName is the compute-context variable that carries the asset RID.
 // AssetChannel binds the RID by this variable name; the value is supplied separately
 // in buildComputeContext, so the channel builders do not take the RID as a parameter.
 const assetRidVariableName computeapi.VariableName = "assetRid"
 
 // buildComputeRequest constructs a ComputeNodeRequest from query model and time range.
+// The range is sent as given. Compute responses do not report the range the
+// server used (they carry only points; bucketed plots stamp each bucket with
+// its exclusive end), so frames cannot be annotated with an effective range.
 func (e *NominalQueryExecution) buildComputeRequest(qm NominalQueryModel, timeRange backend.TimeRange, maxDataPoints int64) computeapi1.ComputeNodeRequest {
-	seriesPlan := e.buildSeriesPlan(qm, maxDataPoints)
-	node := computeapi1.NewComputableNodeFromSeries(seriesPlan)
+	var node computeapi1.ComputableNode
+	if qm.QueryType == queryTypeGaps {
+		node = e.buildGapsNode(qm)
+	} else {
+		node = computeapi1.NewComputableNodeFromSeries(e.buildSeriesPlan(qm, maxDataPoints))
+	}
 
 	return computeapi1.ComputeNodeRequest{
 		Start:   timestampFromTime(timeRange.From),
@@ -35,13 +54,13 @@ func (e *NominalQueryExecution) buildComputeRequest(qm NominalQueryModel, timeRa
 // series shape and its summarization strategy, so adding a new channel kind is a single
 // case here rather than coordinated edits across separate series/summarization helpers.
 func (e *NominalQueryExecution) buildSeriesPlan(qm NominalQueryModel, maxDataPoints int64) computeapi1.SummarizeSeries {
-	channelSeries := computeapi.NewChannelSeriesFromAsset(e.buildAssetChannel(qm.Channel, qm.DataScopeName))
+	channelSeries := e.buildChannelSeries(qm)
 
-	switch qm.ChannelDataType {
-	case ChannelDataTypeString:
+	switch {
+	case qm.isEnumQuery():
 		enumTimeShiftSeries := computeapi1.EnumTimeShiftSeries{
 			Input:    computeapi1.NewEnumSeriesFromChannel(channelSeries),
-			Duration: zeroDurationConstant(),
+			Duration: timeShiftDurationConstant(qm.TimeShiftDuration),
 		}
 		enumSeries := computeapi1.NewEnumSeriesFromTimeShift(enumTimeShiftSeries)
 		series := computeapi1.NewSeriesFromEnum(enumSeries)
@@ -52,7 +71,7 @@ func (e *NominalQueryExecution) buildSeriesPlan(qm NominalQueryModel, maxDataPoi
 			Buckets: &buckets,
 		}
 
-	case ChannelDataTypeLog:
+	case qm.ChannelDataType == ChannelDataTypeLog:
 		logSeries := computeapi1.NewLogSeriesFromChannel(channelSeries)
 		series := computeapi1.NewSeriesFromLog(logSeries)
 
@@ -65,16 +84,40 @@ func (e *NominalQueryExecution) buildSeriesPlan(qm NominalQueryModel, maxDataPoi
 		}
 
 	default:
+		numericInput := computeapi1.NewNumericSeriesFromChannel(channelSeries)
+		if qm.Expression != nil {
+			numericInput = e.buildArithmeticSeries(*qm.Expression, qm.DataScopeName)
+		}
 		numericTimeShiftSeries := computeapi1.NumericTimeShiftSeries{
-			Input:    computeapi1.NewNumericSeriesFromChannel(channelSeries),
-			Duration: zeroDurationConstant(),
+			Input:    numericInput,
+			Duration: timeShiftDurationConstant(qm.TimeShiftDuration),
 		}
 		numericSeries := computeapi1.NewNumericSeriesFromTimeShift(numericTimeShiftSeries)
 		series := computeapi1.NewSeriesFromNumeric(numericSeries)
 
+		if qm.ResolutionRole == ResolutionRoleDetail || qm.Stride > 0 {
+			// Raw points truncated at detailMaxPoints (maxReturnedPoints for a
+			// strided query); the API answers with a legacy NumericPlot rather
+			// than Arrow buckets.
+			maxPoints := detailMaxPoints
+			if qm.Stride > 0 {
+				maxPoints = maxReturnedPoints
+			}
+			truncateStrategy := computeapi.NewTruncateStrategyFromMaxPointsToReturn(maxPoints)
+			summarizationStrategy := computeapi.NewSummarizationStrategyFromTruncate(truncateStrategy)
+			return computeapi1.SummarizeSeries{
+				Input:                 series,
+				SummarizationStrategy: &summarizationStrategy,
+			}
+		}
+
 		buckets := effectiveBucketCount(qm, maxDataPoints)
 		arrowFormat := computeapi.New_OutputFormat(computeapi.OutputFormat_ARROW_V3)
-		outputFields := numericOutputFields(qm.Aggregations)
+		sources := make([]string, len(qm.Aggregations))
+		for i, agg := range qm.Aggregations {
+			sources[i] = qm.scaledSource(agg)
+		}
+		outputFields := numericOutputFields(sources)
 		return computeapi1.SummarizeSeries{
 			Input:               series,
 			Buckets:             &buckets,
@@ -84,6 +127,54 @@ func (e *NominalQueryExecution) buildSeriesPlan(qm NominalQueryModel, maxDataPoi
 	}
 }
 
+// buildChannelSeries picks the channel addressing for a query: a direct data-source
+// binding when DataSourceRid is set, otherwise the asset-bound channel.
+func (e *NominalQueryExecution) buildChannelSeries(qm NominalQueryModel) computeapi.ChannelSeries {
+	if qm.DataSourceRid != "" {
+		channel := e.buildDataSourceChannel(qm.DataSourceRid, qm.Channel)
+		channel.GroupByTags = groupByTagConstants(qm.GroupByTags)
+		return computeapi.NewChannelSeriesFromDataSource(channel)
+	}
+	channel := e.buildAssetChannel(qm.Channel, qm.DataScopeName)
+	channel.GroupByTags = groupByTagConstants(qm.GroupByTags)
+	channel.AdditionalTagFilters = tagSelectorFilters(qm.TagSelector)
+	return computeapi.NewChannelSeriesFromAsset(channel)
+}
+
+// groupByTagConstants converts the query's group-by tag keys to literal constants.
+func groupByTagConstants(tags []string) []computeapi.StringConstant {
+	constants := make([]computeapi.StringConstant, 0, len(tags))
+	for _, tag := range tags {
+		constants = append(constants, computeapi.NewStringConstantFromLiteral(tag))
+	}
+	return constants
+}
+
+// buildDataSourceChannel constructs a DataSourceChannel bound by literal RID, so no
+// compute-context variable is needed to resolve it.
+func (e *NominalQueryExecution) buildDataSourceChannel(dataSourceRid, channel string) computeapi.DataSourceChannel {
+	return computeapi.DataSourceChannel{
+		DataSourceRid: computeapi.NewStringConstantFromLiteral(dataSourceRid),
+		Channel:       computeapi.NewStringConstantFromLiteral(channel),
+		Tags:          map[string]computeapi.StringConstant{},
+		TagsToGroupBy: []string{},
+		GroupByTags:   []computeapi.StringConstant{},
+	}
+}
+
+// buildChannelUnitsRequest builds a ComputeUnitsRequest that asks the API which unit a
+// raw numeric data-source channel resolves to.
+func (e *NominalQueryExecution) buildChannelUnitsRequest(dataSourceRid, channel string) computeapi1.ComputeUnitsRequest {
+	channelSeries := computeapi.NewChannelSeriesFromDataSource(e.buildDataSourceChannel(dataSourceRid, channel))
+	series := computeapi1.NewSeriesFromNumeric(computeapi1.NewNumericSeriesFromChannel(channelSeries))
+	return computeapi1.ComputeUnitsRequest{
+		Node: computeapi1.NewComputableNodeFromSeries(computeapi1.SummarizeSeries{Input: series}),
+		Context: computeapi1.Context{
+			Variables: map[computeapi.VariableName]computeapi1.VariableValue{},
+		},
+	}
+}
+
 // buildAssetChannel constructs the asset-bound AssetChannel shared by every channel kind.
 // The asset RID is bound by variable name (see assetRidVariableName); its value is supplied in buildComputeContext.
 func (e *NominalQueryExecution) buildAssetChannel(channel, dataScopeName string) computeapi.AssetChannel {
@@ -111,20 +202,115 @@ func (e *NominalQueryExecution) buildComputeContext(qm NominalQueryModel) comput
 		}
 	}
 
+	log.DefaultLogger.Debug("Resolved compute context variables",
+		"assetRid", qm.AssetRid,
+		"channel", qm.Channel,
+		"variables", redactedContextVariables(qm),
+	)
+
 	return computeapi1.Context{
 		Variables:         variables,
 		FunctionVariables: nil,
 	}
 }
 
+// sensitiveVariableNameParts marks context variables whose values must never be
+// logged or echoed back in frame metadata.
+var sensitiveVariableNameParts = []string{"token", "secret", "password", "apikey", "api_key", "auth", "credential"}
+
+const redactedValue = "[REDACTED]"
+
+// redactedContextVariables mirrors the variables buildComputeContext sends, keyed
+// by name, with values of sensitive-looking names replaced by redactedValue.
+func redactedContextVariables(qm NominalQueryModel) map[string]string {
+	out := map[string]string{string(assetRidVariableName): qm.AssetRid}
+	for key, value := range qm.TemplateVariables {
+		strValue, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if isSensitiveVariableName(key) {
+			strValue = redactedValue
+		}
+		out[key] = strValue
+	}
+	return out
+}
+
+func isSensitiveVariableName(name string) bool {
+	lower := strings.ToLower(name)
+	for _, part := range sensitiveVariableNameParts {
+		if strings.Contains(lower, part) {
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveBucketCount is the bucket count a query requests: the count pinned
+// by IntervalMs when set, otherwise Buckets capped by the panel's maxDataPoints.
+// Either is clamped to MaxBuckets when set.
 func effectiveBucketCount(qm NominalQueryModel, maxDataPoints int64) int {
-	buckets := int(qm.Buckets)
-	if maxDataPoints > 0 && (buckets <= 0 || int(maxDataPoints) < buckets) {
-		buckets = int(maxDataPoints)
+	buckets := qm.IntervalBuckets
+	if buckets <= 0 {
+		buckets = int(qm.Buckets)
+		if maxDataPoints > 0 && (buckets <= 0 || int(maxDataPoints) < buckets) {
+			buckets = int(maxDataPoints)
+		}
+	}
+	if qm.MaxBuckets > 0 && buckets > qm.MaxBuckets {
+		buckets = qm.MaxBuckets
 	}
 	return buckets
 }
 
+// maxBuckets returns the configured backend bucket limit, falling back to
+// defaultMaxBuckets when unset.
+func (e *NominalQueryExecution) maxBuckets() int {
+	if e.config == nil || e.config.MaxBuckets <= 0 {
+		return defaultMaxBuckets
+	}
+	return e.config.MaxBuckets
+}
+
+// bucketClampNotice tells the user their bucket count was lowered to the
+// backend maximum.
+func bucketClampNotice(requested, limit int) data.Notice {
+	return data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text: fmt.Sprintf("Requested %d buckets, more than the backend maximum of %d; the query used %d buckets. Lower the bucket count or raise maxBuckets in the datasource settings.",
+			requested, limit, limit),
+	}
+}
+
+// maxDurationMs is the largest millisecond count that converts to a
+// time.Duration without overflowing; millisecond options above it are rejected.
+const maxDurationMs = math.MaxInt64 / int64(time.Millisecond)
+
+// intervalBucketCount returns how many intervalMs-wide buckets cover
+// timeRange, rounding up so no bucket is wider than requested. An interval
+// that is not positive or is longer than the range is rejected. Counts above
+// the datasource's bucket limit are not rejected here: prepareQuery clamps
+// them with a notice, as it does for an explicit Buckets value.
+func intervalBucketCount(timeRange backend.TimeRange, intervalMs int) (int, error) {
+	interval := time.Duration(intervalMs) * time.Millisecond
+	if intervalMs <= 0 || int64(intervalMs) > maxDurationMs || interval <= 0 {
+		return 0, fmt.Errorf("intervalMs must be between 1 and %d, got %d", maxDurationMs, intervalMs)
+	}
+	span := timeRange.Duration()
+	if span <= 0 {
+		return 1, nil
+	}
+	if interval > span {
+		return 0, fmt.Errorf("intervalMs %d is longer than the query time range (%s)", intervalMs, span)
+	}
+	buckets := span / interval
+	if span%interval != 0 {
+		buckets++
+	}
+	return int(buckets), nil
+}
+
 func numericOutputFields(aggregations []string) []computeapi.NumericOutputField {
 	var outputFields []computeapi.NumericOutputField
 	for _, agg := range aggregations {
@@ -135,12 +321,19 @@ func numericOutputFields(aggregations []string) []computeapi.NumericOutputField
 	return outputFields
 }
 
-func zeroDurationConstant() computeapi1.DurationConstant {
-	return computeapi1.NewDurationConstantFromLiteral(runapi.Duration{
-		Seconds: safelong.SafeLong(0),
-		Nanos:   safelong.SafeLong(0),
+// timeShiftDurationConstant is the TimeShiftSeries duration for a query; zero
+// leaves the series unshifted.
+func timeShiftDurationConstant(d time.Duration) computeapi1.DurationConstant {
+	return computeapi1.NewDurationConstantFromLiteral(durationFromTime(d))
+}
+
+// durationFromTime converts a Go duration into the API's run Duration.
+func durationFromTime(d time.Duration) runapi.Duration {
+	return runapi.Duration{
+		Seconds: safelong.SafeLong(d / time.Second),
+		Nanos:   safelong.SafeLong(d % time.Second),
 		Picos:   nil,
-	})
+	}
 }
 
 func timestampFromTime(value time.Time) api.Timestamp {
@@ -150,3 +343,12 @@ func timestampFromTime(value time.Time) api.Timestamp {
 		Picos:   nil,
 	}
 }
+
+// utcTimestampFromTime converts a time into the run API's UtcTimestamp.
+func utcTimestampFromTime(value time.Time) runapi.UtcTimestamp {
+	nanos := safelong.SafeLong(value.Nanosecond())
+	return runapi.UtcTimestamp{
+		SecondsSinceEpoch: safelong.SafeLong(value.Unix()),
+		OffsetNanoseconds: &nanos,
+	}
+}
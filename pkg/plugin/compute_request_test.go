@@ -1,11 +1,17 @@
 package plugin
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
 	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
 	computeapi1 "github.com/nominal-io/nominal-api-go/scout/compute/api1"
 )
@@ -202,6 +208,172 @@ func TestEffectiveBucketCount(t *testing.T) {
 	}
 }
 
+func TestIntervalMsOverridesBucketCount(t *testing.T) {
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{1})},
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      "ri.nominal.asset.1",
+			Channel:       "speed",
+			DataScopeName: "default",
+			Buckets:       1000,
+			IntervalMs:    int((10 * time.Second).Milliseconds()),
+		}),
+		// One hour in 10s buckets is 360, despite Buckets and MaxDataPoints.
+		TimeRange:     backend.TimeRange{From: from, To: from.Add(time.Hour)},
+		MaxDataPoints: 100,
+	}})
+
+	requests := mockService.lastBatchRequest.Requests
+	if len(requests) != 1 {
+		t.Fatalf("got %d batch requests, want 1", len(requests))
+	}
+	plan := summarizeSeriesFromNode(t, requests[0].Node)
+	if plan.Buckets == nil || *plan.Buckets != 360 {
+		t.Errorf("buckets = %v, want 360", plan.Buckets)
+	}
+}
+
+func TestIntervalMsOutOfRangeIsRejected(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		intervalMs int
+	}{
+		// Overflows time.Duration to zero if converted unchecked.
+		{name: "overflowing", intervalMs: 288230376151711744},
+		{name: "longer than the range", intervalMs: int((2 * time.Hour).Milliseconds())},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockComputeService{}
+			qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+			resp := qe.Execute(context.Background(), []backend.DataQuery{{
+				RefID: "A",
+				JSON: mustMarshal(NominalQueryModel{
+					AssetRid:      "ri.nominal.asset.1",
+					Channel:       "speed",
+					DataScopeName: "default",
+					IntervalMs:    tt.intervalMs,
+				}),
+				TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+			}})
+			res := resp.Responses["A"]
+			if res.Error == nil || res.Status != backend.StatusBadRequest {
+				t.Fatalf("got status %v, error %v; want a 400", res.Status, res.Error)
+			}
+			if mockService.batchComputeCalls != 0 {
+				t.Errorf("compute called %d times, want 0", mockService.batchComputeCalls)
+			}
+		})
+	}
+}
+
+func TestBucketsClampedToBackendMax(t *testing.T) {
+	tests := []struct {
+		name        string
+		maxBuckets  int
+		buckets     int
+		wantBuckets int
+		wantNotice  bool
+	}{
+		{name: "default limit", buckets: 20000, wantBuckets: defaultMaxBuckets, wantNotice: true},
+		{name: "configured limit", maxBuckets: 500, buckets: 800, wantBuckets: 500, wantNotice: true},
+		{name: "within limit", buckets: 800, wantBuckets: 800},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockService := &mockComputeService{
+				batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+					Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{1})},
+				},
+			}
+			config := &models.PluginSettings{
+				Secrets:    &models.SecretPluginSettings{ApiKey: "test-key"},
+				MaxBuckets: tt.maxBuckets,
+			}
+			qe := newTestQueryExecution(&Datasource{computeService: mockService}, config)
+
+			from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			resp := qe.Execute(context.Background(), []backend.DataQuery{{
+				RefID: "A",
+				JSON: mustMarshal(NominalQueryModel{
+					AssetRid:      "ri.nominal.asset.1",
+					Channel:       "speed",
+					DataScopeName: "default",
+					Buckets:       tt.buckets,
+				}),
+				TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+			}})
+			res := resp.Responses["A"]
+			if res.Error != nil {
+				t.Fatalf("unexpected error: %v", res.Error)
+			}
+
+			plan := summarizeSeriesFromNode(t, mockService.lastBatchRequest.Requests[0].Node)
+			if plan.Buckets == nil || *plan.Buckets != tt.wantBuckets {
+				t.Errorf("buckets = %v, want %d", plan.Buckets, tt.wantBuckets)
+			}
+			var notices []data.Notice
+			if meta := res.Frames[0].Meta; meta != nil {
+				notices = meta.Notices
+			}
+			if !tt.wantNotice {
+				if len(notices) != 0 {
+					t.Errorf("notices = %v, want none", notices)
+				}
+				return
+			}
+			want := fmt.Sprintf("Requested %d buckets, more than the backend maximum of %d", tt.buckets, tt.wantBuckets)
+			if len(notices) != 1 || !strings.Contains(notices[0].Text, want) {
+				t.Errorf("notices = %v, want one containing %q", notices, want)
+			}
+		})
+	}
+}
+
+func TestIntervalBucketCount(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		span       time.Duration
+		intervalMs int
+		want       int
+		wantError  bool
+	}{
+		{name: "exact", span: time.Hour, intervalMs: 60000, want: 60},
+		{name: "rounds up partial bucket", span: 90 * time.Second, intervalMs: 60000, want: 2},
+		{name: "many buckets are left to the clamp", span: 24 * time.Hour, intervalMs: 1, want: 86400000},
+		{name: "interval wider than range", span: time.Minute, intervalMs: 3600000, wantError: true},
+		{name: "zero interval", span: time.Minute, intervalMs: 0, wantError: true},
+		{name: "overflowing interval", span: time.Minute, intervalMs: 288230376151711744, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := intervalBucketCount(backend.TimeRange{From: from, To: from.Add(tt.span)}, tt.intervalMs)
+			if tt.wantError {
+				if err == nil {
+					t.Fatalf("expected an error, got %d buckets", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("buckets = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestNumericOutputFields(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -368,6 +540,31 @@ func TestBuildSeriesPlanBranching(t *testing.T) {
 		})
 	}
 
+	t.Run("explicit enumAggregation produces bucketed enum series", func(t *testing.T) {
+		qm := baseQM
+		qm.EnumAggregation = EnumAggMode
+		req := qe.buildComputeRequest(qm, backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(3600, 0)}, 0)
+		plan := summarizeSeriesFromNode(t, req.Node)
+		if got := seriesKind(t, plan.Input); got != "enum" {
+			t.Errorf("series kind = %q, want enum", got)
+		}
+		if plan.Buckets == nil || *plan.Buckets != 1000 {
+			t.Errorf("buckets = %v, want 1000", plan.Buckets)
+		}
+		if plan.OutputFormat != nil {
+			t.Errorf("enum plan should not request Arrow output, got %v", plan.OutputFormat)
+		}
+	})
+
+	t.Run("enumAggregation does not turn log channels into enums", func(t *testing.T) {
+		qm := baseQM
+		qm.ChannelDataType = ChannelDataTypeLog
+		qm.EnumAggregation = EnumAggMode
+		if got := seriesKind(t, qe.buildSeriesPlan(qm, 0).Input); got != "log" {
+			t.Errorf("series kind = %q, want log", got)
+		}
+	})
+
 	t.Run("string and numeric produce different series kinds", func(t *testing.T) {
 		stringQM := baseQM
 		stringQM.ChannelDataType = ChannelDataTypeString
@@ -476,6 +673,85 @@ func TestBuildSeriesPlanArrowFormat(t *testing.T) {
 	})
 }
 
+func TestBuildSeriesPlanDataSourceRid(t *testing.T) {
+	ds := &Datasource{}
+	qe := newTestQueryExecution(ds, nil)
+
+	qm := NominalQueryModel{
+		DataSourceRid: "ri.nominal.dataset.abc",
+		Channel:       "temperature",
+		Buckets:       100,
+	}
+	plan := qe.buildSeriesPlan(qm, 0)
+
+	if got := seriesKind(t, plan.Input); got != "numeric" {
+		t.Fatalf("series kind = %q, want numeric", got)
+	}
+
+	planJSON, err := json.Marshal(plan)
+	if err != nil {
+		t.Fatalf("marshal plan: %v", err)
+	}
+	got := string(planJSON)
+
+	wantFragments := []string{
+		`"type":"dataSource"`,
+		`"dataSourceRid":{"type":"literal","literal":"ri.nominal.dataset.abc"}`,
+		`"channel":{"type":"literal","literal":"temperature"}`,
+	}
+	for _, want := range wantFragments {
+		if !strings.Contains(got, want) {
+			t.Errorf("series JSON missing %s\ngot: %s", want, got)
+		}
+	}
+	if strings.Contains(got, `"type":"asset"`) {
+		t.Errorf("series JSON should not use the asset channel form\ngot: %s", got)
+	}
+}
+
+func TestBuildSeriesPlanDetailRole(t *testing.T) {
+	ds := &Datasource{}
+	qe := newTestQueryExecution(ds, nil)
+
+	qm := NominalQueryModel{
+		AssetRid:       "ri.nominal.asset.123",
+		Channel:        "temperature",
+		DataScopeName:  "default",
+		Buckets:        1000,
+		Aggregations:   []string{AggMean},
+		ResolutionRole: ResolutionRoleDetail,
+	}
+	plan := qe.buildSeriesPlan(qm, 0)
+
+	if plan.Buckets != nil {
+		t.Errorf("buckets = %d, want nil for raw detail plan", *plan.Buckets)
+	}
+	if plan.OutputFormat != nil {
+		t.Errorf("outputFormat = %v, want nil (legacy) for raw detail plan", plan.OutputFormat)
+	}
+	if plan.SummarizationStrategy == nil {
+		t.Fatal("expected a truncate summarization strategy")
+	}
+	var maxPoints int
+	err := plan.SummarizationStrategy.AcceptFuncs(
+		func(computeapi.DecimateStrategy) error { return fmt.Errorf("unexpected decimate strategy") },
+		func(computeapi.PageStrategy) error { return fmt.Errorf("unexpected page strategy") },
+		func(ts computeapi.TruncateStrategy) error {
+			return ts.AcceptFuncs(
+				func(n int) error { maxPoints = n; return nil },
+				func(string) error { return fmt.Errorf("unknown truncate strategy type") },
+			)
+		},
+		func(string) error { return fmt.Errorf("unknown summarization strategy type") },
+	)
+	if err != nil {
+		t.Fatalf("inspecting summarization strategy: %v", err)
+	}
+	if maxPoints != detailMaxPoints {
+		t.Errorf("maxPointsToReturn = %d, want %d", maxPoints, detailMaxPoints)
+	}
+}
+
 func TestBuildSeriesPlanLogPath(t *testing.T) {
 	ds := &Datasource{}
 	qe := newTestQueryExecution(ds, nil)
@@ -530,3 +806,106 @@ func BenchmarkBuildComputeContext(b *testing.B) {
 		newTestQueryExecution(ds, nil).buildComputeContext(qm)
 	}
 }
+
+func TestStatConfigSetsPerFieldSummarization(t *testing.T) {
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{1})},
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      "ri.nominal.asset.1",
+			Channel:       "speed",
+			DataScopeName: "default",
+			Buckets:       10,
+			StatFields:    []string{StatMean, StatMax, StatStdDev, StatCount},
+			StatConfig:    map[string]string{StatMax: AggLastPoint},
+		}),
+		TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+	}})
+
+	plan := summarizeSeriesFromNode(t, mockService.lastBatchRequest.Requests[0].Node)
+	if plan.NumericOutputFields == nil {
+		t.Fatal("NumericOutputFields not set")
+	}
+	var got []string
+	for _, field := range *plan.NumericOutputFields {
+		got = append(got, string(field.Value()))
+	}
+	if want := []string{AggMean, AggLastPoint, AggVariance, AggCount}; !reflect.DeepEqual(got, want) {
+		t.Errorf("output fields = %v, want %v", got, want)
+	}
+}
+
+func TestStatFieldsFromArrowAggregations(t *testing.T) {
+	arrowBytes := createTestArrowMultiAgg([]int64{1704067200000000000, 1704067260000000000}, map[string][]float64{
+		"mean":     {10, 12},
+		"variance": {4, 9},
+	})
+	result := computeapi.ComputeWithUnitsResult{
+		ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromArrowBucketedNumeric(
+			computeapi.ArrowBucketedNumericPlot{ArrowBinary: arrowBytes},
+		)),
+	}
+	qm := NominalQueryModel{
+		Channel:    "speed",
+		StatFields: []string{StatMin, StatStdDev},
+		StatConfig: map[string]string{StatMin: AggMean},
+	}
+	qm.Aggregations = qm.statAggregations()
+
+	resp := newTestQueryExecution(&Datasource{}, nil).transformBatchResult(result, qm)
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	if len(resp.Frames) != 1 {
+		t.Fatalf("frames = %d, want one frame with a field per stat", len(resp.Frames))
+	}
+	frame := resp.Frames[0]
+	want := map[string][]float64{"min": {10, 12}, "stddev": {2, 3}}
+	for name, values := range want {
+		field, _ := frame.FieldByName(name)
+		if field == nil {
+			t.Fatalf("missing %q field in %v", name, frame.Fields)
+		}
+		for i, w := range values {
+			if got, _ := field.At(i).(*float64); got == nil || *got != w {
+				t.Errorf("%s[%d] = %v, want %v", name, i, got, w)
+			}
+		}
+	}
+}
+
+func TestValidateStatConfig(t *testing.T) {
+	fields := []string{StatMean, StatMax, StatCount}
+	tests := []struct {
+		name      string
+		config    map[string]string
+		wantError string
+	}{
+		{name: "unset"},
+		{name: "remapped max", config: map[string]string{StatMax: AggLastPoint}},
+		{name: "field not requested", config: map[string]string{StatMin: AggMax}, wantError: "not in statFields"},
+		{name: "count", config: map[string]string{StatCount: AggMax}, wantError: "cannot change"},
+		{name: "unknown aggregation", config: map[string]string{StatMax: "P99"}, wantError: "unsupported aggregation"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateStatConfig(fields, tt.config)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("err = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/nominal-io/nominal-api-go/io/nominal/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+	unitsapi "github.com/nominal-io/nominal-api-go/scout/units/api"
+)
+
+// computeWarningNotices returns a warning notice for each non-fatal problem a
+// successful compute result reports. The compute API has no general warnings
+// field; the diagnostics it returns alongside data are the unit computation
+// errors of ComputeWithUnitsResult, such as an expression adding incompatible
+// units. UnitsMissing is left out: most channels have no unit, so it is
+// routine rather than worth a notice.
+func computeWarningNotices(result computeapi.ComputeUnitResult) []data.Notice {
+	var texts []string
+	collect := func(unitResult computeapi.UnitResult) error {
+		return unitResult.AcceptFuncs(
+			func(unitsapi.UnitSymbol) error { return nil },
+			func(unitErrors []computeapi.UnitComputationError) error {
+				for _, unitErr := range unitErrors {
+					if text := unitComputationWarning(unitErr); text != "" && !slices.Contains(texts, text) {
+						texts = append(texts, text)
+					}
+				}
+				return nil
+			},
+			func(string) error { return nil },
+		)
+	}
+	_ = result.AcceptFuncs(
+		collect,
+		func(cartesian computeapi.CartesianUnitResult) error {
+			_ = collect(cartesian.X)
+			return collect(cartesian.Y)
+		},
+		func(cartesian computeapi.Cartesian3dUnitResult) error {
+			_ = collect(cartesian.X)
+			_ = collect(cartesian.Y)
+			return collect(cartesian.Z)
+		},
+		func(string) error { return nil },
+	)
+
+	notices := make([]data.Notice, 0, len(texts))
+	for _, text := range texts {
+		notices = append(notices, data.Notice{Severity: data.NoticeSeverityWarning, Text: text})
+	}
+	return notices
+}
+
+// unitComputationWarning describes one unit computation error, or returns ""
+// for errors that are not worth surfacing.
+func unitComputationWarning(unitErr computeapi.UnitComputationError) string {
+	var text string
+	_ = unitErr.AcceptFuncs(
+		func(op computeapi.IncompatibleUnitOperation) error {
+			units := make([]string, 0, len(op.Units))
+			for _, unit := range op.Units {
+				units = append(units, string(unit))
+			}
+			text = fmt.Sprintf("Units could not be computed: %s is not defined for units [%s]; values are returned without a unit.",
+				op.Operation, strings.Join(units, ", "))
+			return nil
+		},
+		func(computeapi.UnitsMissing) error { return nil },
+		func(serializable api.SerializableError) error {
+			text = "Units could not be computed: " + serializable.Name
+			if serializable.Message != nil && *serializable.Message != "" {
+				text += ": " + *serializable.Message
+			}
+			return nil
+		},
+		func(string) error { return nil },
+	)
+	return text
+}
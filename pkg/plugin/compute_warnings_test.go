@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+	unitsapi "github.com/nominal-io/nominal-api-go/scout/units/api"
+)
+
+func TestComputeWarningsBecomeFrameNotices(t *testing.T) {
+	incompatible := computeapi.NewUnitComputationErrorFromIncompatibleUnitsOperation(computeapi.IncompatibleUnitOperation{
+		Operation: computeapi.New_UnitOperation(computeapi.UnitOperation_ADDITION),
+		Units:     []unitsapi.UnitSymbol{"m", "s"},
+	})
+	result := createMockArrowComputeResult([]float64{1, 2})
+	result.UnitResult = computeapi.NewComputeUnitResultFromSingle(computeapi.NewUnitResultFromNoUnitAvailable([]computeapi.UnitComputationError{
+		incompatible,
+		incompatible,
+		computeapi.NewUnitComputationErrorFromUnitsMissing(computeapi.UnitsMissing{}),
+	}))
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{Results: []computeapi.ComputeWithUnitsResult{result}},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      "ri.nominal.asset.1",
+			Channel:       "speed",
+			DataScopeName: "default",
+			Buckets:       2,
+		}),
+		TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+	}})
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if len(res.Frames) == 0 || res.Frames[0].Rows() != 2 {
+		t.Fatalf("frames = %v, want the data alongside the warning", res.Frames)
+	}
+
+	var warnings []data.Notice
+	for _, notice := range res.Frames[0].Meta.Notices {
+		if notice.Severity == data.NoticeSeverityWarning {
+			warnings = append(warnings, notice)
+		}
+	}
+	if len(warnings) != 1 {
+		t.Fatalf("warnings = %+v, want one deduplicated incompatible-units warning", warnings)
+	}
+	if !strings.Contains(warnings[0].Text, "ADDITION") || !strings.Contains(warnings[0].Text, "[m, s]") {
+		t.Errorf("warning = %q, want the operation and units", warnings[0].Text)
+	}
+}
+
+func TestComputeWarningNoticesIgnoresRoutineResults(t *testing.T) {
+	tests := []struct {
+		name   string
+		result computeapi.ComputeUnitResult
+	}{
+		{name: "unset"},
+		{name: "unit", result: computeapi.NewComputeUnitResultFromSingle(computeapi.NewUnitResultFromSuccess("m/s"))},
+		{name: "units missing", result: computeapi.NewComputeUnitResultFromSingle(computeapi.NewUnitResultFromNoUnitAvailable([]computeapi.UnitComputationError{
+			computeapi.NewUnitComputationErrorFromUnitsMissing(computeapi.UnitsMissing{}),
+		}))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if notices := computeWarningNotices(tt.result); len(notices) != 0 {
+				t.Errorf("notices = %+v, want none", notices)
+			}
+		})
+	}
+}
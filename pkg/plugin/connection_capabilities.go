@@ -0,0 +1,58 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/palantir/pkg/bearertoken"
+
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+)
+
+// connectionCapabilities reports what the configured API key was able to do
+// during a connection test. Nominal exposes no role or permission listing, so
+// each capability is probed with a cheap read: true when the call succeeded,
+// false when it was refused (401/403), and null when the probe could not tell
+// (timeouts or other errors). Compute reports whether the key may run a
+// computation, not whether it can read any particular asset; see
+// probeComputeService.
+type connectionCapabilities struct {
+	ReadProfile     bool  `json:"readProfile"`
+	ReadOrgSettings *bool `json:"readOrgSettings"`
+	ReadAssets      *bool `json:"readAssets"`
+	Compute         *bool `json:"compute"`
+}
+
+// probeConnectionCapabilities runs the capability probes after the profile
+// lookup has succeeded. It never fails: a probe that errors only leaves its
+// capability false or unknown.
+func (d *Datasource) probeConnectionCapabilities(ctx context.Context, config *models.PluginSettings, bearerToken bearertoken.Token) connectionCapabilities {
+	capabilities := connectionCapabilities{ReadProfile: true}
+
+	_, err := d.authService.GetMyOrgSettings(ctx, bearerToken)
+	capabilities.ReadOrgSettings = capabilityFromError("org settings", err)
+
+	_, err = d.catalog().FetchAssetsForVariable(ctx, config, "", assetSearchFilters{}, 1)
+	capabilities.ReadAssets = capabilityFromError("asset search", err)
+
+	if d.computeService != nil {
+		err = d.probeComputeService(ctx, bearerToken)
+		capabilities.Compute = capabilityFromError("compute", err)
+	}
+
+	return capabilities
+}
+
+// capabilityFromError maps a probe's outcome to a capability: true on
+// success, false when the API refused the key, and nil when it is unknown.
+func capabilityFromError(probe string, err error) *bool {
+	allowed := err == nil
+	if err != nil {
+		log.DefaultLogger.Debug("Connection test capability probe failed", "probe", probe, "error", err)
+		if status := extractErrorDetails(err).Status; status != http.StatusUnauthorized && status != http.StatusForbidden {
+			return nil
+		}
+	}
+	return &allowed
+}
@@ -0,0 +1,194 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// wideCSVRowsPerChunk is the number of CSV rows sent per response chunk.
+const wideCSVRowsPerChunk = 1000
+
+type wideCSVExportRequest struct {
+	AssetRid      string    `json:"assetRid"`
+	DataScopeName string    `json:"dataScopeName"`
+	Channels      []string  `json:"channels"`
+	From          time.Time `json:"from"`
+	To            time.Time `json:"to"`
+	// Buckets is the bucket count per channel; zero uses the query default.
+	Buckets int `json:"buckets,omitempty"`
+}
+
+// wideCSVColumn is one channel's points, keyed by Unix nanoseconds.
+type wideCSVColumn map[int64]string
+
+// handleWideCSVExport handles the export/csv/wide endpoint. It accepts
+// { assetRid, dataScopeName, channels: [...], from, to, buckets } and streams a
+// CSV with a time column and one column per channel.
+//
+// The channels are computed in one batch, the way QueryData would compute one
+// query per channel, so every channel is bucketed over the same range and
+// bucket count and shares the same time grid. Rows are joined on exact
+// timestamps: a row holds every timestamp any channel returned, and a channel
+// with no point at that timestamp (an empty bucket, or a raw point that does
+// not land on the grid) leaves its cell empty rather than being interpolated.
+func (h *NominalResourceHandler) handleWideCSVExport(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	if ok, err := requirePost(req, sender); !ok {
+		return err
+	}
+
+	var exportRequest wideCSVExportRequest
+	if ok, err := decodeResourceJSON(req.Body, sender, &exportRequest, "Failed to parse wide CSV export request body"); !ok {
+		return err
+	}
+	if exportRequest.AssetRid == "" || exportRequest.DataScopeName == "" || len(exportRequest.Channels) == 0 {
+		return jsonErrorResponse(sender, http.StatusBadRequest, "assetRid, dataScopeName, and channels are required")
+	}
+	if !exportRequest.From.Before(exportRequest.To) {
+		return jsonErrorResponse(sender, http.StatusBadRequest, "from must be before to")
+	}
+
+	config, ok, err := loadResourceSettings(h.datasource.settings, sender, "Failed to load settings for wide CSV export")
+	if !ok {
+		return err
+	}
+
+	columns, err := h.wideCSVColumns(ctx, exportRequest, newNominalQueryExecution(h.datasource, config))
+	if err != nil {
+		return jsonErrorResponse(sender, http.StatusBadGateway, err.Error())
+	}
+	log.DefaultLogger.Debug("Wide CSV export computed", "assetRid", exportRequest.AssetRid, "channels", len(columns))
+	return sendWideCSV(sender, exportRequest.Channels, columns)
+}
+
+// wideCSVColumns computes every requested channel and returns each one's
+// points in request order.
+func (h *NominalResourceHandler) wideCSVColumns(ctx context.Context, exportRequest wideCSVExportRequest, execution *NominalQueryExecution) ([]wideCSVColumn, error) {
+	timeRange := backend.TimeRange{From: exportRequest.From, To: exportRequest.To}
+	queries := make([]backend.DataQuery, len(exportRequest.Channels))
+	for i, channel := range exportRequest.Channels {
+		// Marshalling a plain query model cannot fail.
+		queryJSON, _ := json.Marshal(NominalQueryModel{
+			AssetRid:      exportRequest.AssetRid,
+			DataScopeName: exportRequest.DataScopeName,
+			Channel:       channel,
+			Buckets:       exportRequest.Buckets,
+		})
+		// Indexed RefIDs keep a channel listed twice from colliding.
+		queries[i] = backend.DataQuery{RefID: strconv.Itoa(i), JSON: queryJSON, TimeRange: timeRange}
+	}
+
+	response := execution.Execute(ctx, queries)
+	columns := make([]wideCSVColumn, len(exportRequest.Channels))
+	for i, channel := range exportRequest.Channels {
+		res := response.Responses[strconv.Itoa(i)]
+		if res.Error != nil {
+			return nil, fmt.Errorf("export failed for channel %s: %v", channel, res.Error)
+		}
+		columns[i] = wideCSVColumnFromFrames(res.Frames)
+	}
+	return columns, nil
+}
+
+// wideCSVColumnFromFrames reads the time and value fields of a channel's
+// first frame. A channel with no frames yields an empty column.
+func wideCSVColumnFromFrames(frames data.Frames) wideCSVColumn {
+	column := wideCSVColumn{}
+	if len(frames) == 0 {
+		return column
+	}
+	frame := frames[0]
+	var timeField, valueField *data.Field
+	for _, field := range frame.Fields {
+		switch fieldType := field.Type(); {
+		case timeField == nil && (fieldType == data.FieldTypeTime || fieldType == data.FieldTypeNullableTime):
+			timeField = field
+		case field.Name == "value" || (valueField == nil && fieldType != data.FieldTypeTime && fieldType != data.FieldTypeNullableTime):
+			valueField = field
+		}
+	}
+	if timeField == nil || valueField == nil {
+		return column
+	}
+	for row := 0; row < timeField.Len(); row++ {
+		ts, ok := timeField.ConcreteAt(row)
+		if !ok {
+			continue
+		}
+		value, ok := valueField.ConcreteAt(row)
+		if !ok {
+			continue
+		}
+		column[ts.(time.Time).UnixNano()] = formatCSVValue(value)
+	}
+	return column
+}
+
+func formatCSVValue(value any) string {
+	if v, ok := value.(float64); ok {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(value)
+}
+
+// sendWideCSV streams the joined columns as CSV: the header and first rows in
+// the response that carries the status and headers, then the remaining rows
+// in chunks of wideCSVRowsPerChunk.
+func sendWideCSV(sender backend.CallResourceResponseSender, channels []string, columns []wideCSVColumn) error {
+	var timestamps []int64
+	seen := map[int64]bool{}
+	for _, column := range columns {
+		for ts := range column {
+			if !seen[ts] {
+				seen[ts] = true
+				timestamps = append(timestamps, ts)
+			}
+		}
+	}
+	slices.Sort(timestamps)
+
+	var buf bytes.Buffer
+	writer := csv.NewWriter(&buf)
+	_ = writer.Write(append([]string{"time"}, channels...))
+
+	first := true
+	flush := func() error {
+		writer.Flush()
+		chunk := &backend.CallResourceResponse{Body: bytes.Clone(buf.Bytes())}
+		if first {
+			chunk.Status = http.StatusOK
+			chunk.Headers = map[string][]string{
+				"Content-Type":        {"text/csv; charset=utf-8"},
+				"Content-Disposition": {`attachment; filename="export.csv"`},
+			}
+			first = false
+		}
+		buf.Reset()
+		return sender.Send(chunk)
+	}
+
+	record := make([]string, len(columns)+1)
+	for i, ts := range timestamps {
+		record[0] = time.Unix(0, ts).UTC().Format(time.RFC3339Nano)
+		for j, column := range columns {
+			record[j+1] = column[ts]
+		}
+		_ = writer.Write(record)
+		if (i+1)%wideCSVRowsPerChunk == 0 && i+1 < len(timestamps) {
+			if err := flush(); err != nil {
+				return err
+			}
+		}
+	}
+	return flush()
+}
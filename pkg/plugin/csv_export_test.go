@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestWideCSVExportJoinsChannelsOnTime(t *testing.T) {
+	server := newTestAssetServer(t, nil, nil)
+	defer server.Close()
+
+	mockCompute := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{
+				createMockArrowComputeResult([]float64{1, 2, 3}),
+				createMockArrowComputeResult([]float64{4.5, 5}),
+			},
+		},
+	}
+	ds := newTestDatasource(server.URL, &mockAuthService{}, &mockDatasourceService{})
+	ds.computeService = mockCompute
+
+	var chunks []*backend.CallResourceResponse
+	sender := backend.CallResourceResponseSenderFunc(func(resp *backend.CallResourceResponse) error {
+		chunks = append(chunks, resp)
+		return nil
+	})
+	err := ds.CallResource(context.Background(), &backend.CallResourceRequest{
+		Path:   "export/csv/wide",
+		Method: http.MethodPost,
+		Body: []byte(`{"assetRid":"ri.scout.main.asset.1","dataScopeName":"default","channels":["speed","temp"],` +
+			`"from":"2024-01-01T00:00:00Z","to":"2024-01-01T01:00:00Z","buckets":100}`),
+	}, sender)
+	if err != nil {
+		t.Fatalf("CallResource returned error: %v", err)
+	}
+	if len(chunks) == 0 || chunks[0].Status != http.StatusOK {
+		t.Fatalf("responses = %+v, want a 200 first chunk", chunks)
+	}
+	if got := chunks[0].Headers["Content-Type"]; len(got) != 1 || !strings.HasPrefix(got[0], "text/csv") {
+		t.Errorf("Content-Type = %v, want text/csv", got)
+	}
+	if got := len(mockCompute.lastBatchRequest.Requests); got != 2 {
+		t.Errorf("batch subrequests = %d, want one per channel", got)
+	}
+
+	var body strings.Builder
+	for _, chunk := range chunks {
+		body.Write(chunk.Body)
+	}
+	lines := strings.Split(strings.TrimSpace(body.String()), "\n")
+	want := []string{
+		"time,speed,temp",
+		"2024-01-01T00:00:00Z,1,4.5",
+		"2024-01-01T00:01:00Z,2,5",
+		// temp has no point on this timestamp, so its cell is left empty.
+		"2024-01-01T00:02:00Z,3,",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("CSV =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,144 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// dataScopeLabel is the field label naming the data scope an AllDataScopes
+// query's frame was computed from.
+const dataScopeLabel = "dataScope"
+
+// validateAllDataScopes checks an AllDataScopes query: it computes one
+// asset channel in every data scope, so it needs both and replaces the other
+// ways of choosing what to compute.
+func validateAllDataScopes(qm NominalQueryModel) error {
+	if !qm.AllDataScopes {
+		return nil
+	}
+	if strings.TrimSpace(qm.AssetRid) == "" || strings.TrimSpace(qm.Channel) == "" {
+		return fmt.Errorf("allDataScopes queries require assetRid and channel")
+	}
+	if qm.DataSourceRid != "" || len(qm.TagSelector) > 0 {
+		return fmt.Errorf("allDataScopes cannot be combined with dataSourceRid or tagSelector")
+	}
+	return nil
+}
+
+// isChannelDataScope reports whether scope holds channels an asset channel
+// query can compute; log sets are queried through the log path instead.
+func isChannelDataScope(scope AssetDataScope) bool {
+	return scope.DataSource.Type == "dataset" || scope.DataSource.Type == "connection"
+}
+
+// expandAllDataScopes resolves an AllDataScopes query into one batchable query
+// per dataset or connection scope on the asset, all sharing the query's RefID
+// so the scopes' frames are returned together. An asset without such scopes
+// answers with no frames. A scope whose computation fails (e.g. it lacks the
+// channel) does not fail the query; see dataScopeFailureResponse.
+func (e *NominalQueryExecution) expandAllDataScopes(ctx context.Context, prepared preparedQuery) ([]preparedQuery, *backend.DataResponse) {
+	qm := prepared.Model
+	asset, err := e.datasource.catalog().FetchAssetByRid(ctx, e.config, qm.AssetRid)
+	if err != nil {
+		logErrorWithConjureFields("Failed to fetch asset for allDataScopes", err, "refId", prepared.Query.RefID, "assetRid", qm.AssetRid)
+		response := backend.ErrDataResponse(backend.StatusInternal, appendInstanceID("Failed to fetch asset for allDataScopes", err))
+		return nil, &response
+	}
+
+	var expanded []preparedQuery
+	if asset != nil {
+		for _, scope := range asset.DataScopes {
+			if !isChannelDataScope(scope) {
+				continue
+			}
+			scopeModel := qm
+			scopeModel.DataScopeName = scope.DataScopeName
+			e.inferChannelMetadata(ctx, &scopeModel)
+			if prepErr := resolveQueryModel(&scopeModel); prepErr != nil {
+				return nil, prepErr
+			}
+			expanded = append(expanded, preparedQuery{Query: prepared.Query, Model: scopeModel, Kind: preparedQueryBatchable})
+		}
+	}
+	log.DefaultLogger.Debug("Resolved allDataScopes", "refId", prepared.Query.RefID, "scopes", len(expanded))
+	if len(expanded) == 0 {
+		return nil, &backend.DataResponse{}
+	}
+	return expanded, nil
+}
+
+// labelDataScopeFrames labels an AllDataScopes query's frames with the data
+// scope they were computed from, so the same channel's scopes can be told apart.
+func labelDataScopeFrames(frames data.Frames, qm NominalQueryModel) {
+	if !qm.AllDataScopes {
+		return
+	}
+	mergeFrameLabels(frames, data.Labels{dataScopeLabel: qm.DataScopeName})
+}
+
+// dataScopeFailureResponse turns an AllDataScopes scope's failed response into
+// an empty frame carrying a warning notice, so the other scopes' frames are
+// still returned. A query whose every scope fails answers with only these.
+func dataScopeFailureResponse(res backend.DataResponse, qm NominalQueryModel) backend.DataResponse {
+	if !qm.AllDataScopes || res.Error == nil {
+		return res
+	}
+	log.DefaultLogger.Warn("allDataScopes scope failed; returning the other scopes", "dataScope", qm.DataScopeName, "error", res.Error)
+	frame := data.NewFrame(qm.Channel)
+	frame.Meta = &data.FrameMeta{Notices: []data.Notice{{
+		Severity: data.NoticeSeverityWarning,
+		Text:     fmt.Sprintf("Data scope %s was skipped: %v", qm.DataScopeName, res.Error),
+	}}}
+	return backend.DataResponse{Frames: data.Frames{frame}}
+}
+
+// filterKnownDataScopes splits off the queries whose dataScopeName is not a
+// data scope of their asset, returning the queries to run and a per-RefID
+// error for the rest. Asset lookups go through the catalog cache; a lookup
+// that fails or finds no asset does not hold a query back, since the asset
+// access check and the batch compute report those on their own.
+func (e *NominalQueryExecution) filterKnownDataScopes(ctx context.Context, prepared []preparedQuery) ([]preparedQuery, map[string]backend.DataResponse) {
+	catalog := e.datasource.catalog()
+	failures := make(map[string]backend.DataResponse)
+	for _, query := range prepared {
+		qm := query.Model
+		// DataSourceRid queries bypass the asset entirely.
+		if qm.AssetRid == "" || qm.DataSourceRid != "" || qm.DataScopeName == "" {
+			continue
+		}
+		asset, err := catalog.FetchAssetByRid(ctx, e.config, qm.AssetRid)
+		if err != nil || asset == nil || hasDataScope(asset, qm.DataScopeName) {
+			continue
+		}
+		failures[query.Query.RefID] = backend.ErrDataResponse(backend.StatusBadRequest,
+			fmt.Sprintf("data scope not found on asset %s: %s", qm.AssetRid, qm.DataScopeName))
+	}
+
+	if len(failures) == 0 {
+		return prepared, nil
+	}
+	// Expanded queries share a RefID, so one unknown scope fails them all.
+	known := make([]preparedQuery, 0, len(prepared))
+	for _, query := range prepared {
+		if _, failed := failures[query.Query.RefID]; !failed {
+			known = append(known, query)
+		}
+	}
+	log.DefaultLogger.Warn("Skipping queries for unknown data scopes", "queries", len(failures))
+	return known, failures
+}
+
+// hasDataScope reports whether asset has a data scope named dataScopeName.
+func hasDataScope(asset *SingleAssetResponse, dataScopeName string) bool {
+	for _, scope := range asset.DataScopes {
+		if scope.DataScopeName == dataScopeName {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package plugin
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestAllDataScopesReturnsFramePerScope(t *testing.T) {
+	const assetRid = "ri.scout.main.asset.1"
+	rawRid := "ri.scout.main.dataset.raw"
+	processedRid := "ri.scout.main.connection.processed"
+	logRid := "ri.scout.main.log-set.events"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid: assetRid,
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "raw", DataSource: AssetDataSource{Type: "dataset", Dataset: &rawRid}},
+				{DataScopeName: "processed", DataSource: AssetDataSource{Type: "connection", Connection: &processedRid}},
+				{DataScopeName: "events", DataSource: AssetDataSource{Type: "logSet", LogSet: &logRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	mockCompute := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{1, 2}), createMockArrowComputeResult([]float64{3, 4})},
+		},
+	}
+	config := &models.PluginSettings{BaseUrl: server.URL, Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+	qe := newTestQueryExecution(&Datasource{computeService: mockCompute, datasourceService: &mockDatasourceService{}, resourceHTTPClient: &http.Client{}}, config)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID:     "A",
+		JSON:      mustMarshal(NominalQueryModel{AssetRid: assetRid, Channel: "speed", AllDataScopes: true}),
+		TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+	}})
+
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if got := len(mockCompute.lastBatchRequest.Requests); got != 2 {
+		t.Fatalf("batch subrequests = %d, want one per dataset or connection scope", got)
+	}
+	if len(res.Frames) != 2 {
+		t.Fatalf("frames = %d, want one per scope", len(res.Frames))
+	}
+	for i, want := range []string{"raw", "processed"} {
+		field, idx := res.Frames[i].FieldByName("value")
+		if idx < 0 {
+			t.Fatalf("frame %d has no value field", i)
+		}
+		if got := field.Labels[dataScopeLabel]; got != want {
+			t.Errorf("frame %d %s label = %q, want %q", i, dataScopeLabel, got, want)
+		}
+	}
+}
+
+func TestAllDataScopesSkipsScopeMissingTheChannel(t *testing.T) {
+	const assetRid = "ri.scout.main.asset.1"
+	rawRid := "ri.scout.main.dataset.raw"
+	processedRid := "ri.scout.main.connection.processed"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid: assetRid,
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "raw", DataSource: AssetDataSource{Type: "dataset", Dataset: &rawRid}},
+				{DataScopeName: "processed", DataSource: AssetDataSource{Type: "connection", Connection: &processedRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	// "raw" has the channel; "processed" does not, so its subrequest fails.
+	mockCompute := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{1, 2}), createMockErrorResult(404, "CHANNEL_NOT_FOUND")},
+		},
+	}
+	config := &models.PluginSettings{BaseUrl: server.URL, Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+	qe := newTestQueryExecution(&Datasource{computeService: mockCompute, datasourceService: &mockDatasourceService{}, resourceHTTPClient: &http.Client{}}, config)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID:     "A",
+		JSON:      mustMarshal(NominalQueryModel{AssetRid: assetRid, Channel: "speed", AllDataScopes: true}),
+		TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+	}})
+
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if len(res.Frames) != 2 {
+		t.Fatalf("frames = %d, want the raw scope's frame and a notice frame", len(res.Frames))
+	}
+	field, idx := res.Frames[0].FieldByName("value")
+	if idx < 0 || field.Labels[dataScopeLabel] != "raw" {
+		t.Fatalf("first frame = %v, want the raw scope's values", res.Frames[0])
+	}
+	meta := res.Frames[1].Meta
+	if meta == nil || len(meta.Notices) != 1 || !strings.Contains(meta.Notices[0].Text, "processed") {
+		t.Errorf("notice frame meta = %+v, want a notice naming the processed scope", meta)
+	}
+}
+
+func TestAllDataScopesDryRunReturnsExpandedRequestWithoutComputing(t *testing.T) {
+	const assetRid = "ri.scout.main.asset.1"
+	rawRid := "ri.scout.main.dataset.raw"
+	processedRid := "ri.scout.main.connection.processed"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid: assetRid,
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "raw", DataSource: AssetDataSource{Type: "dataset", Dataset: &rawRid}},
+				{DataScopeName: "processed", DataSource: AssetDataSource{Type: "connection", Connection: &processedRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	mockCompute := &mockComputeService{}
+	config := &models.PluginSettings{BaseUrl: server.URL, Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+	qe := newTestQueryExecution(&Datasource{computeService: mockCompute, datasourceService: &mockDatasourceService{}, resourceHTTPClient: &http.Client{}}, config)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID:     "A",
+		JSON:      mustMarshal(NominalQueryModel{AssetRid: assetRid, Channel: "speed", AllDataScopes: true, DryRun: true}),
+		TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+	}})
+
+	if mockCompute.batchComputeCalls != 0 {
+		t.Fatalf("batch compute calls = %d, want 0 for a dry run", mockCompute.batchComputeCalls)
+	}
+	if got := dryRunSubrequests(t, resp.Responses["A"]); got != 2 {
+		t.Errorf("dry-run subrequests = %d, want one per scope", got)
+	}
+}
+
+func TestValidateAllDataScopes(t *testing.T) {
+	base := NominalQueryModel{AssetRid: "ri.scout.main.asset.1", Channel: "speed", AllDataScopes: true}
+	if err := validateAllDataScopes(base); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	noChannel := base
+	noChannel.Channel = ""
+	withDataSourceRid := base
+	withDataSourceRid.DataSourceRid = "ri.scout.main.channel.1"
+	for name, qm := range map[string]NominalQueryModel{
+		"no channel":           noChannel,
+		"with data source rid": withDataSourceRid,
+	} {
+		if err := validateAllDataScopes(qm); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestDataScopeCheckRejectsUnknownScope(t *testing.T) {
+	const assetRid = "ri.scout.main.asset.1"
+	datasetRid := "ri.scout.main.dataset.raw"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid:        assetRid,
+			DataScopes: []AssetDataScope{{DataScopeName: "default", DataSource: AssetDataSource{Type: "dataset", Dataset: &datasetRid}}},
+		},
+	}, nil)
+	defer server.Close()
+
+	mockCompute := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{1, 2})},
+		},
+	}
+	config := &models.PluginSettings{BaseUrl: server.URL, DataScopeCheck: true, Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+	qe := newTestQueryExecution(&Datasource{computeService: mockCompute, datasourceService: &mockDatasourceService{}, resourceHTTPClient: &http.Client{}}, config)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	query := func(refID, scope string) backend.DataQuery {
+		return backend.DataQuery{
+			RefID:     refID,
+			JSON:      mustMarshal(NominalQueryModel{AssetRid: assetRid, Channel: "speed", DataScopeName: scope}),
+			TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+		}
+	}
+	resp := qe.Execute(context.Background(), []backend.DataQuery{query("A", "default"), query("B", "typo")})
+
+	if res := resp.Responses["A"]; res.Error != nil || len(res.Frames) == 0 {
+		t.Errorf("known scope: error = %v, frames = %d; want data", res.Error, len(res.Frames))
+	}
+	res := resp.Responses["B"]
+	if res.Status != backend.StatusBadRequest || res.Error == nil ||
+		!strings.Contains(res.Error.Error(), "data scope not found on asset "+assetRid+": typo") {
+		t.Errorf("unknown scope: status = %v, error = %v; want data scope not found", res.Status, res.Error)
+	}
+	if got := len(mockCompute.lastBatchRequest.Requests); got != 1 {
+		t.Errorf("batch subrequests = %d, want only the known scope's", got)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 	"slices"
 	"strings"
@@ -16,6 +17,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/nominal-inc/nominal-ds/pkg/models"
 	authapi "github.com/nominal-io/nominal-api-go/authentication/api"
+	datasourceapi "github.com/nominal-io/nominal-api-go/datasource/api"
 	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
 	computeapi1 "github.com/nominal-io/nominal-api-go/scout/compute/api1"
 	datasourceservice "github.com/nominal-io/nominal-api-go/scout/datasource"
@@ -40,11 +42,46 @@ var (
 // See scout ComputeResource.SUBREQUEST_LIMIT.
 const maxBatchComputeSubrequests = 300
 
+// maxConcurrentSingleComputes bounds the single Compute calls that retry
+// results missing from a batch response.
+const maxConcurrentSingleComputes = 8
+
 // defaultAPIBaseURL is the fallback Nominal API base URL when none is configured.
 const defaultAPIBaseURL = "https://api.gov.nominal.io/api"
 
+// resourceRequestTimeout bounds each direct HTTP request to the Nominal API.
+const resourceRequestTimeout = 30 * time.Second
+
+// healthCheckTimeout bounds the health check and the connection test endpoint.
+const healthCheckTimeout = 10 * time.Second
+
+// datasourceOptions holds construction overrides used by tests.
+type datasourceOptions struct {
+	// transport, if set, carries every request of both the resource client
+	// and the Conjure client in place of their built-in transports.
+	transport http.RoundTripper
+}
+
+// datasourceOption customizes newDatasource.
+type datasourceOption func(*datasourceOptions)
+
+// withTransport routes all of the datasource's API traffic through transport,
+// so tests can exercise the HTTP layer without a live server.
+func withTransport(transport http.RoundTripper) datasourceOption {
+	return func(o *datasourceOptions) { o.transport = transport }
+}
+
 // NewDatasource creates a new datasource instance.
 func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSettings) (instancemgmt.Instance, error) {
+	return newDatasource(ctx, settings)
+}
+
+func newDatasource(ctx context.Context, settings backend.DataSourceInstanceSettings, opts ...datasourceOption) (*Datasource, error) {
+	var options datasourceOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+
 	config, err := models.LoadPluginSettings(settings)
 	if err != nil {
 		return nil, fmt.Errorf("failed to load plugin settings: %v", err)
@@ -62,20 +99,53 @@ func NewDatasource(ctx context.Context, settings backend.DataSourceInstanceSetti
 	if err != nil {
 		return nil, fmt.Errorf("failed to build HTTP client options: %v", err)
 	}
+	localAddr, err := parseDialLocalAddr(config)
+	if err != nil {
+		return nil, err
+	}
+	fallbackBaseURL, err := parseFallbackBaseURL(config)
+	if err != nil {
+		return nil, err
+	}
+	httpClientOpts.ConfigureTransport = chainConfigureTransport(httpClientOpts.ConfigureTransport, configureConnectionPool(config))
+	httpClientOpts.ConfigureTransport = chainConfigureTransport(httpClientOpts.ConfigureTransport, configureEgress(config, localAddr))
+	httpClientOpts.ConfigureTransport = chainConfigureTransport(httpClientOpts.ConfigureTransport, configureKeepAlive(config))
 
 	resourceHTTPClient, err := sdkhttpclient.New(httpClientOpts)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create resource HTTP client: %v", err)
 	}
-	resourceHTTPClient.Timeout = 30 * time.Second
+	resourceHTTPClient.Timeout = resourceRequestTimeout
+	if options.transport != nil {
+		resourceHTTPClient.Transport = options.transport
+	}
+	if fallbackBaseURL != "" {
+		resourceHTTPClient.Transport = newFallbackTransport(resourceHTTPClient.Transport, baseURL, fallbackBaseURL)
+	}
 	resourceHTTPClient.Transport = newUserAgentTransport(resourceHTTPClient.Transport)
 
 	// Generated Conjure clients still require their own client type, so keep this
 	// wrapper for those service integrations.
-	conjureClient, err := conjurehttpclient.NewClient(
+	conjureParams := []conjurehttpclient.ClientParam{
 		conjurehttpclient.WithBaseURLs([]string{baseURL}),
 		conjurehttpclient.WithMiddleware(userAgentMiddleware()),
-	)
+	}
+	conjureParams = append(conjureParams, keepAliveClientParams(config)...)
+	if !isDefaultAuthHeader(config) {
+		conjureParams = append(conjureParams, conjurehttpclient.WithMiddleware(authHeaderMiddleware(config)))
+	}
+	// Added before the transport middleware below, which WithInnerMiddleware
+	// then places inside it, so both attempts use the same transport.
+	if fallbackBaseURL != "" {
+		conjureParams = append(conjureParams, conjurehttpclient.WithInnerMiddleware(fallbackMiddleware(baseURL, fallbackBaseURL)))
+	}
+	switch {
+	case options.transport != nil:
+		conjureParams = append(conjureParams, conjurehttpclient.WithInnerMiddleware(transportMiddleware(options.transport)))
+	case hasEgressSettings(config) || hasConnectionPoolSettings(config):
+		conjureParams = append(conjureParams, conjurehttpclient.WithInnerMiddleware(transportMiddleware(newConjureTransport(config, localAddr))))
+	}
+	conjureClient, err := conjurehttpclient.NewClient(conjureParams...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create conjure HTTP client: %v", err)
 	}
@@ -104,6 +174,10 @@ type Datasource struct {
 
 	nominalCatalog          *NominalCatalog
 	templateVariableCatalog *TemplateVariableCatalog
+
+	// unhandledTypes counts compute result variants the plugin could not
+	// transform; the diagnostics endpoint reports it.
+	unhandledTypes unhandledTypeCounts
 }
 
 func (d *Datasource) getResourceHTTPClient() *http.Client {
@@ -185,16 +259,92 @@ func (e *NominalQueryExecution) handleConnectionTestQuery(ctx context.Context) b
 	return response
 }
 
-// handleLegacyQuery handles legacy queries that don't have asset/channel
+// handleChannelTableQuery lists the channels available on an asset (optionally
+// narrowed to one data scope) as a name/unit/type table frame.
+func (e *NominalQueryExecution) handleChannelTableQuery(ctx context.Context, qm NominalQueryModel) backend.DataResponse {
+	var response backend.DataResponse
+
+	catalog := e.datasource.catalog()
+	asset, err := catalog.FetchAssetByRid(ctx, e.config, qm.AssetRid)
+	if err != nil {
+		logErrorWithConjureFields("Channel table: failed to fetch asset", err, "assetRid", qm.AssetRid)
+		return backend.ErrDataResponse(backend.StatusInternal, formatUserError("Failed to fetch asset", err))
+	}
+	if asset == nil {
+		return backend.ErrDataResponse(backend.StatusNotFound, fmt.Sprintf("Asset not found: %s", qm.AssetRid))
+	}
+
+	var channels []datasourceapi.ChannelMetadata
+	if dataSourceRids := catalog.DataSourceRidsForScope(asset, qm.DataScopeName); len(dataSourceRids) > 0 {
+		bearerToken := bearertoken.Token(e.config.Secrets.ApiKey)
+		channels, err = catalog.SearchChannelsForVariables(ctx, bearerToken, dataSourceRids)
+		if err != nil {
+			logErrorWithConjureFields("Channel table: channels search failed", err, "assetRid", qm.AssetRid)
+			return backend.ErrDataResponse(backend.StatusInternal, formatUserError("Channels search failed", err))
+		}
+	}
+
+	seen := make(map[string]bool, len(channels))
+	names := make([]string, 0, len(channels))
+	units := make([]string, 0, len(channels))
+	types := make([]string, 0, len(channels))
+	for _, channel := range channels {
+		name := string(channel.Name)
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+		units = append(units, getChannelUnit(channel))
+		types = append(types, getChannelDataType(channel))
+	}
+
+	frame := data.NewFrame("channels",
+		data.NewField("name", nil, names),
+		data.NewField("unit", nil, units),
+		data.NewField("type", nil, types),
+	)
+	frame.Meta = &data.FrameMeta{
+		Type:                   data.FrameTypeTable,
+		PreferredVisualization: data.VisTypeTable,
+	}
+
+	log.DefaultLogger.Debug("Processed channelTable query", "assetRid", qm.AssetRid, "channels", len(names))
+	response.Frames = append(response.Frames, frame)
+	return response
+}
+
+// handleLegacyQuery handles legacy queries that don't have asset/channel: a
+// constant query returns qm.Constant at NumPoints evenly spaced times across
+// timeRange (two, one at each end, by default).
 func (e *NominalQueryExecution) handleLegacyQuery(qm NominalQueryModel, timeRange backend.TimeRange) backend.DataResponse {
 	var response backend.DataResponse
 
 	log.DefaultLogger.Debug("Using legacy query support")
 
+	n := qm.NumPoints
+	if n <= 0 {
+		n = 2
+	}
+	var step time.Duration
+	if n > 1 {
+		step = timeRange.Duration() / time.Duration(n-1)
+	}
+	times := make([]time.Time, n)
+	values := make([]float64, n)
+	for i := range times {
+		times[i] = timeRange.From.Add(step * time.Duration(i))
+		values[i] = qm.Constant
+	}
+	if n > 1 {
+		// The step is truncated to whole nanoseconds; pin the end exactly.
+		times[n-1] = timeRange.To
+	}
+
 	frame := data.NewFrame("response")
 	frame.Fields = append(frame.Fields,
-		data.NewField("time", nil, []time.Time{timeRange.From, timeRange.To}),
-		data.NewField("values", nil, []float64{qm.Constant, qm.Constant + 10}),
+		data.NewField("time", nil, times),
+		data.NewField("values", nil, values),
 	)
 
 	response.Frames = append(response.Frames, frame)
@@ -205,6 +355,7 @@ func (e *NominalQueryExecution) handleLegacyQuery(qm NominalQueryModel, timeRang
 // Handles both success and error cases from the ComputeNodeResult union type.
 func (e *NominalQueryExecution) transformBatchResult(result computeapi.ComputeWithUnitsResult, qm NominalQueryModel) backend.DataResponse {
 	var response backend.DataResponse
+	unitResult := result.UnitResult
 
 	// ComputeNodeResult is a union type - use AcceptFuncs to handle success/error
 	err := result.ComputeResult.AcceptFuncs(
@@ -219,135 +370,48 @@ func (e *NominalQueryExecution) transformBatchResult(result computeapi.ComputeWi
 				return nil
 			}
 
-			if result.IsLog {
-				// Sort descending (newest first) for Grafana's default log sort order.
-				// Grafana's infinite scroll uses the boundary row's timestamp to compute
-				// the next time-range query. Don't assume this sort is redundant: the
-				// compute API's PageInfo contract specifies selection direction (via sign
-				// of PageSize), not response order.
-				if !slices.IsSortedFunc(result.LogEntries, compareLogEntriesNewestFirst) {
-					slices.SortStableFunc(result.LogEntries, compareLogEntriesNewestFirst)
-				}
-
-				frame := data.NewFrame(qm.Channel)
-				frame.Meta = &data.FrameMeta{
-					Type: data.FrameTypeLogLines,
-					// log-lines dataplane contract is at v0.0 — don't confuse with time-series-wide's 0.1
-					TypeVersion:            data.FrameTypeVersion{0, 0},
-					PreferredVisualization: data.VisTypeLogs,
-				}
-
-				if len(result.LogEntries) > 0 {
-					times := make([]time.Time, len(result.LogEntries))
-					bodies := make([]string, len(result.LogEntries))
-					ids := make([]string, len(result.LogEntries))
-					labels := make([]json.RawMessage, len(result.LogEntries))
-					for i, e := range result.LogEntries {
-						times[i] = e.Time
-						bodies[i] = e.Body
-						ids[i] = e.ID
-						labels[i] = e.Labels
-					}
-					frame.Fields = append(frame.Fields,
-						data.NewField("timestamp", nil, times),
-						data.NewField("body", nil, bodies),
-						data.NewField("id", nil, ids),
-						data.NewField("labels", nil, labels),
-					)
-				} else {
-					frame.Fields = append(frame.Fields,
-						data.NewField("timestamp", nil, []time.Time{}),
-						data.NewField("body", nil, []string{}),
-						data.NewField("id", nil, []string{}),
-						data.NewField("labels", nil, []json.RawMessage{}),
-					)
-				}
+			// Frames are named after the channel; an expression query has none,
+			// so it is named after its expression instead.
+			frameModel := qm
+			if qm.Expression != nil {
+				frameModel.Channel = qm.QueryText
+			}
 
-				log.DefaultLogger.Debug("Successfully processed log query",
-					"entries", len(result.LogEntries))
-				response.Frames = append(response.Frames, frame)
-			} else if len(result.AggSeries) > 0 {
-				// Multi-aggregation Arrow path: one frame per series
-				for _, agg := range result.AggSeries {
-					frame := data.NewFrame("response")
-					displayName := qm.Channel
-					if qm.ExplicitAggregations {
-						displayName = fmt.Sprintf("%s (%s)", qm.Channel, agg.Name)
-					}
-					frame.Name = displayName
-					if len(agg.TimePoints) > 0 && len(agg.Values) > 0 {
-						valueField := data.NewField("value", nil, agg.Values)
-						valueField.Config = fieldConfigForNumeric(&qm, displayName, agg.CarriesChannelUnit)
-						frame.Fields = append(frame.Fields,
-							data.NewField("time", nil, agg.TimePoints),
-							valueField,
-						)
-					} else {
-						valueField := data.NewField("value", nil, []*float64{})
-						valueField.Config = fieldConfigForNumeric(&qm, displayName, agg.CarriesChannelUnit)
-						frame.Fields = append(frame.Fields,
-							data.NewField("time", nil, []time.Time{}),
-							valueField,
-						)
-					}
-					response.Frames = append(response.Frames, frame)
-				}
-				dataPoints := 0
-				if len(result.AggSeries) > 0 {
-					dataPoints = len(result.AggSeries[0].TimePoints)
+			// The API reports one unit per result (ComputeUnitResult has no
+			// per-group variant), so every group shares the query's unit.
+			if len(result.Groups) > 0 {
+				for _, group := range result.Groups {
+					groupFrames := e.buildResultFrames(group.Result, frameModel)
+					setFrameGroupLabels(groupFrames, group.Labels)
+					response.Frames = append(response.Frames, groupFrames...)
 				}
-				log.DefaultLogger.Debug("Successfully processed multi-agg query",
-					"series", len(result.AggSeries),
-					"dataPoints", dataPoints)
-			} else if result.IsEnum {
-				frame := data.NewFrame("response")
-				frame.Name = qm.Channel
-				// Mark enum frames as table type so panels like Stat can pick up string fields.
-				// Time series frames filter to numeric fields only by default.
-				frame.Meta = &data.FrameMeta{
-					Type:                   data.FrameTypeTable,
-					PreferredVisualization: data.VisTypeTable,
-				}
-				if len(result.TimePoints) > 0 && len(result.StringValues) > 0 {
-					valueField := data.NewField("value", nil, result.StringValues)
-					valueField.Config = fieldConfigForEnum(&qm)
-					frame.Fields = append(frame.Fields,
-						data.NewField("time", nil, result.TimePoints),
-						valueField,
-					)
-				} else {
-					valueField := data.NewField("value", nil, []string{})
-					valueField.Config = fieldConfigForEnum(&qm)
-					frame.Fields = append(frame.Fields,
-						data.NewField("time", nil, []time.Time{}),
-						valueField,
-					)
-				}
-				log.DefaultLogger.Debug("Successfully processed enum query", "dataPoints", len(result.TimePoints))
-				response.Frames = append(response.Frames, frame)
+				log.DefaultLogger.Debug("Successfully processed grouped query", "groups", len(result.Groups))
 			} else {
-				// Legacy numeric path (BucketedNumericPlot, NumericPlot)
-				frame := data.NewFrame("response")
-				frame.Name = qm.Channel
-				if len(result.TimePoints) > 0 && len(result.NumericValues) > 0 {
-					valueField := data.NewField("value", nil, result.NumericValues)
-					valueField.Config = fieldConfigForNumericWithChannelUnit(&qm, qm.Channel)
-					frame.Fields = append(frame.Fields,
-						data.NewField("time", nil, result.TimePoints),
-						valueField,
-					)
-				} else {
-					valueField := data.NewField("value", nil, []*float64{})
-					valueField.Config = fieldConfigForNumericWithChannelUnit(&qm, qm.Channel)
-					frame.Fields = append(frame.Fields,
-						data.NewField("time", nil, []time.Time{}),
-						valueField,
-					)
-				}
-				log.DefaultLogger.Debug("Successfully processed query", "dataPoints", len(result.TimePoints))
-				response.Frames = append(response.Frames, frame)
+				response.Frames = e.buildResultFrames(result, frameModel)
 			}
-
+			if qm.BucketInterval > 0 {
+				addBucketEdgeFields(response.Frames, qm.BucketInterval)
+			}
+			if qm.ReduceForAlerting {
+				response.Frames = reduceFramesForAlerting(response.Frames, alertingReducer(qm))
+			} else {
+				reduceFrames(response.Frames, qm.Reduce)
+			}
+			if qm.IncludeStats {
+				addFrameStats(response.Frames)
+			}
+			if qm.ClampedBuckets > 0 && qm.isBucketed() {
+				appendFrameNotice(response.Frames, bucketClampNotice(qm.ClampedBuckets, qm.MaxBuckets))
+			}
+			if qm.TimeZone != "" && qm.TimeShift == "" {
+				appendFrameNotice(response.Frames, timeZoneAlignmentNotice(qm.TimeZone))
+			}
+			for _, notice := range computeWarningNotices(unitResult) {
+				appendFrameNotice(response.Frames, notice)
+			}
+			applyValuePrecision(response.Frames, qm.ValuePrecision)
+			applyValueType(response.Frames, qm.ValueType)
+			e.attachNominalUILinks(response.Frames, qm)
 			return nil
 		},
 		// errorFunc - called when compute failed
@@ -371,6 +435,7 @@ func (e *NominalQueryExecution) transformBatchResult(result computeapi.ComputeWi
 		},
 		// unknownFunc - called for unknown union variants
 		func(typeName string) error {
+			e.recordUnhandledType("result", typeName)
 			response = backend.ErrDataResponse(
 				backend.StatusInternal,
 				fmt.Sprintf("Unknown result type: %s", typeName),
@@ -389,13 +454,227 @@ func (e *NominalQueryExecution) transformBatchResult(result computeapi.ComputeWi
 	return response
 }
 
+// buildResultFrames turns one transformed (ungrouped) compute response into
+// Grafana frames.
+func (e *NominalQueryExecution) buildResultFrames(result TransformResult, qm NominalQueryModel) data.Frames {
+	var frames data.Frames
+
+	if result.IsLog {
+		// Sort descending (newest first) for Grafana's default log sort order.
+		// Grafana's infinite scroll uses the boundary row's timestamp to compute
+		// the next time-range query. Don't assume this sort is redundant: the
+		// compute API's PageInfo contract specifies selection direction (via sign
+		// of PageSize), not response order.
+		if !slices.IsSortedFunc(result.LogEntries, compareLogEntriesNewestFirst) {
+			slices.SortStableFunc(result.LogEntries, compareLogEntriesNewestFirst)
+		}
+
+		frame := data.NewFrame(qm.Channel)
+		frame.Meta = &data.FrameMeta{
+			Type: data.FrameTypeLogLines,
+			// log-lines dataplane contract is at v0.0 — don't confuse with time-series-wide's 0.1
+			TypeVersion:            data.FrameTypeVersion{0, 0},
+			PreferredVisualization: data.VisTypeLogs,
+		}
+
+		if len(result.LogEntries) > 0 {
+			times := make([]time.Time, len(result.LogEntries))
+			bodies := make([]string, len(result.LogEntries))
+			ids := make([]string, len(result.LogEntries))
+			labels := make([]json.RawMessage, len(result.LogEntries))
+			for i, e := range result.LogEntries {
+				times[i] = e.Time
+				bodies[i] = e.Body
+				ids[i] = e.ID
+				labels[i] = e.Labels
+			}
+			frame.Fields = append(frame.Fields,
+				data.NewField("timestamp", nil, times),
+				data.NewField("body", nil, bodies),
+				data.NewField("id", nil, ids),
+				data.NewField("labels", nil, labels),
+			)
+		} else {
+			frame.Fields = append(frame.Fields,
+				data.NewField("timestamp", nil, []time.Time{}),
+				data.NewField("body", nil, []string{}),
+				data.NewField("id", nil, []string{}),
+				data.NewField("labels", nil, []json.RawMessage{}),
+			)
+		}
+
+		log.DefaultLogger.Debug("Successfully processed log query",
+			"entries", len(result.LogEntries))
+		frames = append(frames, frame)
+	} else if result.ArrowDense != nil {
+		// Dense Arrow path: same frames as AggSeries, with non-nullable values
+		for _, agg := range result.ArrowDense.Series {
+			frame := data.NewFrame("response")
+			displayName := aggSeriesDisplayName(qm, agg.Name)
+			frame.Name = displayName
+			valueField := data.NewField("value", nil, agg.Values)
+			valueField.Config = fieldConfigForNumeric(&qm, displayName, agg.CarriesChannelUnit)
+			frame.Fields = append(frame.Fields,
+				data.NewField("time", nil, result.ArrowDense.TimePoints),
+				valueField,
+			)
+			frames = append(frames, frame)
+		}
+		log.DefaultLogger.Debug("Successfully processed dense Arrow query",
+			"series", len(result.ArrowDense.Series),
+			"dataPoints", len(result.ArrowDense.TimePoints))
+	} else if len(result.AggSeries) > 0 {
+		// Multi-aggregation Arrow path: one frame per series
+		for _, agg := range result.AggSeries {
+			frame := data.NewFrame("response")
+			displayName := aggSeriesDisplayName(qm, agg.Name)
+			frame.Name = displayName
+			if len(agg.TimePoints) > 0 && len(agg.Values) > 0 {
+				valueField := data.NewField("value", nil, agg.Values)
+				valueField.Config = fieldConfigForNumeric(&qm, displayName, agg.CarriesChannelUnit)
+				frame.Fields = append(frame.Fields,
+					data.NewField("time", nil, agg.TimePoints),
+					valueField,
+				)
+			} else {
+				valueField := data.NewField("value", nil, []*float64{})
+				valueField.Config = fieldConfigForNumeric(&qm, displayName, agg.CarriesChannelUnit)
+				frame.Fields = append(frame.Fields,
+					data.NewField("time", nil, []time.Time{}),
+					valueField,
+				)
+			}
+			frames = append(frames, frame)
+		}
+		dataPoints := 0
+		if len(result.AggSeries) > 0 {
+			dataPoints = len(result.AggSeries[0].TimePoints)
+		}
+		log.DefaultLogger.Debug("Successfully processed multi-agg query",
+			"series", len(result.AggSeries),
+			"dataPoints", dataPoints)
+	} else if result.IsEnum {
+		frame := data.NewFrame("response")
+		frame.Name = qm.Channel
+		// Mark enum frames as table type so panels like Stat can pick up string fields.
+		// Time series frames filter to numeric fields only by default.
+		frame.Meta = &data.FrameMeta{
+			Type:                   data.FrameTypeTable,
+			PreferredVisualization: data.VisTypeTable,
+		}
+		if len(result.TimePoints) > 0 && len(result.StringValues) > 0 {
+			valueField := data.NewField("value", nil, result.StringValues)
+			valueField.Config = fieldConfigForEnum(&qm)
+			frame.Fields = append(frame.Fields,
+				data.NewField("time", nil, result.TimePoints),
+				valueField,
+			)
+		} else {
+			valueField := data.NewField("value", nil, []string{})
+			valueField.Config = fieldConfigForEnum(&qm)
+			frame.Fields = append(frame.Fields,
+				data.NewField("time", nil, []time.Time{}),
+				valueField,
+			)
+		}
+		log.DefaultLogger.Debug("Successfully processed enum query", "dataPoints", len(result.TimePoints))
+		frames = append(frames, frame)
+	} else if result.IsGaps {
+		log.DefaultLogger.Debug("Successfully processed gaps query", "gaps", len(result.GapStarts))
+		frames = append(frames, buildGapsFrame(result, qm))
+	} else {
+		// Legacy numeric path (BucketedNumericPlot, NumericPlot)
+		frame := data.NewFrame("response")
+		frame.Name = qm.Channel
+		if len(result.StatSeries) > 0 {
+			frame.Fields = append(frame.Fields, data.NewField("time", nil, result.TimePoints))
+			for _, stat := range result.StatSeries {
+				statField := data.NewField(stat.Name, nil, stat.Values)
+				statField.Config = fieldConfigForNumeric(&qm, fmt.Sprintf("%s (%s)", qm.Channel, stat.Name), stat.CarriesChannelUnit)
+				frame.Fields = append(frame.Fields, statField)
+			}
+		} else if len(result.TimePoints) > 0 && len(result.NumericValues) > 0 {
+			valueField := data.NewField("value", nil, result.NumericValues)
+			valueField.Config = fieldConfigForNumericWithChannelUnit(&qm, qm.Channel)
+			if result.IsStep {
+				setStepInterpolation(valueField.Config)
+			}
+			frame.Fields = append(frame.Fields,
+				data.NewField("time", nil, result.TimePoints),
+				valueField,
+			)
+			if len(result.StdDevValues) == len(result.NumericValues) {
+				// Standard deviation is in the channel's unit, like the mean.
+				stdDevField := data.NewField("stddev", nil, result.StdDevValues)
+				stdDevField.Config = fieldConfigForNumericWithChannelUnit(&qm, qm.Channel+" (stddev)")
+				frame.Fields = append(frame.Fields, stdDevField)
+			}
+		} else {
+			valueField := data.NewField("value", nil, []*float64{})
+			valueField.Config = fieldConfigForNumericWithChannelUnit(&qm, qm.Channel)
+			frame.Fields = append(frame.Fields,
+				data.NewField("time", nil, []time.Time{}),
+				valueField,
+			)
+		}
+		log.DefaultLogger.Debug("Successfully processed query", "dataPoints", len(result.TimePoints))
+		frames = append(frames, frame)
+	}
+
+	if result.DecimatedFrom > 0 {
+		appendFrameNotice(frames, decimationNotice(result.DecimatedFrom))
+	}
+	if result.StrideTruncated {
+		appendFrameNotice(frames, strideTruncationNotice())
+	}
+
+	if result.ScannedPoints != nil {
+		for _, frame := range frames {
+			setFrameCustomMeta(frame, scannedPointsMetaKey, *result.ScannedPoints)
+		}
+	}
+	return frames
+}
+
+// setFrameGroupLabels attaches a group's tag key/values as labels on every
+// non-time field, so legends like {{vehicle}} resolve per group.
+func setFrameGroupLabels(frames data.Frames, labels data.Labels) {
+	for _, frame := range frames {
+		for _, field := range frame.Fields {
+			if fieldType := field.Type(); fieldType == data.FieldTypeTime || fieldType == data.FieldTypeNullableTime {
+				continue
+			}
+			field.Labels = labels.Copy()
+		}
+	}
+}
+
 type TransformResult struct {
+	// Grouped path: one entry per group when the query sets GroupByTags. The
+	// other fields are unused when Groups is set.
+	Groups []GroupResult
+
 	// Numeric aggregation series (Arrow bucketed path, one entry per requested field)
 	AggSeries []AggregationSeries
+	// Dense Arrow path (DenseArrowFrames queries); replaces AggSeries when set
+	ArrowDense *ArrowDenseSeries
+
+	// ScannedPoints is the number of raw points the compute node summarized,
+	// when the response reports per-bucket counts; nil otherwise.
+	ScannedPoints *int64
 
 	// Legacy numeric path (non-Arrow) — single series only
 	TimePoints    []time.Time
 	NumericValues []*float64
+	// IsStep marks NumericValues as a value held over a range (rangeValue
+	// responses) rather than point samples; the value field is drawn as steps.
+	IsStep bool
+	// StdDevValues parallels NumericValues for bucketed results when the query
+	// sets IncludeStdDev; nil otherwise.
+	StdDevValues []*float64
+	// StatSeries parallels TimePoints for bucketed results when the query sets
+	// StatFields; it replaces the single "value" field.
+	StatSeries []StatSeries
 
 	// Enum path
 	StringValues []string
@@ -404,6 +683,26 @@ type TransformResult struct {
 	// Log path
 	IsLog      bool
 	LogEntries []LogEntry
+
+	// Gaps path: parallel start/end times of ranges with no data
+	IsGaps    bool
+	GapStarts []*time.Time
+	GapEnds   []*time.Time
+
+	// DecimatedFrom is the original point count when a numeric series exceeded
+	// maxReturnedPoints and was downsampled; zero otherwise.
+	DecimatedFrom int
+	// StrideTruncated is set when a strided query's raw fetch returned
+	// maxReturnedPoints points, the truncation limit, so the series may stop
+	// short of the end of the time range.
+	StrideTruncated bool
+}
+
+// GroupResult is one group of a grouped compute response: the group's tag
+// key/values and its transformed series.
+type GroupResult struct {
+	Labels data.Labels
+	Result TransformResult
 }
 
 // LogEntry represents a single log entry with its timestamp and metadata.
@@ -477,9 +776,26 @@ func (e *NominalQueryExecution) transformNominalResponseFromClient(response comp
 
 	// Use the conjure union visitor pattern to handle different response types
 	visitErr := response.AcceptFuncs(
-		nil, // rangeFunc
-		nil, // rangesSummaryFunc
-		nil, // rangeValueFunc
+		// rangeFunc - gap ranges answering a gaps query (see buildGapsNode)
+		func(ranges []computeapi.Range) error {
+			result.GapStarts, result.GapEnds = extractGapRanges(ranges)
+			result.IsGaps = true
+			return nil
+		},
+		// rangesSummaryFunc - returned instead of ranges above maxGapRanges
+		func(summary computeapi.RangesSummary) error {
+			total := 0
+			for _, s := range summary.RangesSummary {
+				total += s.SubRangeCount
+			}
+			return fmt.Errorf("found %d gaps, more than the %d that can be returned; raise gapThresholdMs or narrow the time range", total, maxGapRanges)
+		},
+		// rangeValueFunc - one value held over a range, rendered as a step
+		func(rangeValue *computeapi.Range) error {
+			result.TimePoints, result.NumericValues = extractRangeValue(rangeValue)
+			result.IsStep = true
+			return nil
+		},
 		func(numeric computeapi.NumericPlot) error {
 			timePoints, values, err := e.extractNumericDataFromConjure(numeric)
 			if err != nil {
@@ -491,12 +807,28 @@ func (e *NominalQueryExecution) transformNominalResponseFromClient(response comp
 			return nil
 		},
 		func(bucketed computeapi.BucketedNumericPlot) error {
-			timePoints, values, err := e.extractBucketedDataFromConjure(bucketed)
+			timePoints, values, stdDevs, err := e.extractBucketedDataFromConjure(bucketed)
 			if err != nil {
 				return err
 			}
+			result.ScannedPoints = bucketedScannedPoints(bucketed)
+			if qm.QueryType == queryTypeCount {
+				// Same series the Arrow path builds for the COUNT aggregation.
+				count := bucketStatSeries(bucketed, []string{StatCount}, qm.scaledSource)[0]
+				result.AggSeries = []AggregationSeries{{
+					Name:       aggSpecs[AggCount].Name,
+					TimePoints: timePoints,
+					Values:     count.Values,
+				}}
+				return nil
+			}
 			result.TimePoints = timePoints
 			result.NumericValues = values
+			if len(qm.StatFields) > 0 {
+				result.StatSeries = bucketStatSeries(bucketed, qm.StatFields, qm.scaledSource)
+			} else if qm.IncludeStdDev {
+				result.StdDevValues = stdDevs
+			}
 			result.IsEnum = false
 			return nil
 		},
@@ -513,16 +845,29 @@ func (e *NominalQueryExecution) transformNominalResponseFromClient(response comp
 		func(arrowBucketed computeapi.ArrowBucketedNumericPlot) error {
 			var specs []aggColumnSpec
 			for _, agg := range qm.Aggregations {
-				specs = append(specs, aggColumnSpecFromEnum(agg))
+				spec := aggColumnSpecFromEnum(agg)
+				spec.ValueCol = aggColumnSpecFromEnum(qm.scaledSource(agg)).ValueCol
+				specs = append(specs, spec)
 			}
 			if len(specs) == 0 {
 				return fmt.Errorf("no aggregation fields requested for ArrowBucketedNumericPlot response")
 			}
+			if denseArrowFramesEligible(qm) {
+				dense, ok, err := extractArrowDenseSeries(arrowBucketed, specs)
+				if err != nil {
+					return err
+				}
+				if ok {
+					result.ArrowDense = dense
+					return nil
+				}
+			}
 			series, err := extractArrowBucketedNumericSeries(arrowBucketed, specs)
 			if err != nil {
 				return err
 			}
 			result.AggSeries = series
+			result.ScannedPoints = aggSeriesScannedPoints(series)
 			result.IsEnum = false
 			return nil
 		},
@@ -552,7 +897,7 @@ func (e *NominalQueryExecution) transformNominalResponseFromClient(response comp
 		},
 		// bucketedEnumFunc - bucketed enum response (returned by SummarizeSeries with buckets)
 		func(bucketed computeapi.BucketedEnumPlot) error {
-			timePoints, values, err := e.extractBucketedEnumDataFromConjure(bucketed)
+			timePoints, values, err := e.extractBucketedEnumDataFromConjure(bucketed, qm.EnumAggregation)
 			if err != nil {
 				return err
 			}
@@ -561,8 +906,17 @@ func (e *NominalQueryExecution) transformNominalResponseFromClient(response comp
 			result.IsEnum = true
 			return nil
 		},
-		nil, // arrowEnumFunc
-		nil, // arrowBucketedEnumFunc
+		// arrowEnumFunc / arrowBucketedEnumFunc - Enum queries do not request an
+		// Arrow output format and the enum Arrow schema is undocumented, so these
+		// are rejected rather than decoded by guessing column names.
+		func(computeapi.ArrowEnumPlot) error {
+			return fmt.Errorf("received ArrowEnumPlot unexpectedly; " +
+				"this response type is not supported by the plugin")
+		},
+		func(computeapi.ArrowBucketedEnumPlot) error {
+			return fmt.Errorf("received ArrowBucketedEnumPlot unexpectedly; " +
+				"this response type is not supported by the plugin")
+		},
 		// pagedLogFunc — paginated log response
 		func(paged computeapi.PagedLogPlot) error {
 			n := min(len(paged.Timestamps), len(paged.Values))
@@ -612,12 +966,55 @@ func (e *NominalQueryExecution) transformNominalResponseFromClient(response comp
 		nil, // numericHistogramFunc
 		nil, // enumHistogramFunc
 		nil, // curveFitFunc
-		nil, // groupedFunc
-		nil, // arrowArrayFunc
+		// groupedFunc — one response per tag grouping; transformed recursively
+		func(grouped computeapi.GroupedComputeNodeResponses) error {
+			result.Groups = make([]GroupResult, 0, len(grouped.Responses))
+			for _, groupResponse := range grouped.Responses {
+				labels := data.Labels{}
+				if err := groupResponse.Grouping.AcceptFuncs(
+					func(tagsWithValues map[string]string) error {
+						for tag, value := range tagsWithValues {
+							labels[tag] = value
+						}
+						return nil
+					},
+					func(typeName string) error {
+						e.recordUnhandledType("grouping", typeName)
+						return nil
+					},
+				); err != nil {
+					return fmt.Errorf("grouping: %w", err)
+				}
+				groupResult, err := e.transformNominalResponseFromClient(groupResponse.Response, qm)
+				if err != nil {
+					return err
+				}
+				result.Groups = append(result.Groups, GroupResult{Labels: labels, Result: groupResult})
+			}
+			log.DefaultLogger.Debug("Extracted grouped data", "groups", len(result.Groups))
+			return nil
+		},
+		// arrowArrayFunc - array series are flattened into bucketed rows keyed
+		// by an array index column whose schema the API does not document, so
+		// they are rejected rather than decoded speculatively.
+		func(arrowArray computeapi.ArrowArrayPlot) error {
+			return arrowArray.AcceptFuncs(
+				func(computeapi.BucketedNumericArrayPlot) error {
+					return fmt.Errorf("array series are not supported")
+				},
+				func(computeapi.BucketedEnumArrayPlot) error {
+					return fmt.Errorf("enum array responses are not supported")
+				},
+				func(typeName string) error {
+					e.recordUnhandledType("array response", typeName)
+					return nil
+				},
+			)
+		},
 		nil, // arrowBucketedStructFunc
 		nil, // arrowFullResolutionFunc
 		func(typeName string) error {
-			log.DefaultLogger.Debug("Unhandled response type", "type", typeName)
+			e.recordUnhandledType("response", typeName)
 			return nil
 		},
 	)
@@ -626,6 +1023,36 @@ func (e *NominalQueryExecution) transformNominalResponseFromClient(response comp
 		return TransformResult{}, fmt.Errorf("failed to process response: %w", visitErr)
 	}
 
+	if len(qm.StatFields) > 0 && !qm.ExplicitAggregations && len(result.AggSeries) > 0 {
+		statSeriesFromAggregations(&result, qm)
+	} else if qm.IncludeStdDev && !qm.ExplicitAggregations && len(result.AggSeries) > 0 {
+		stdDevFromAggregations(&result)
+	}
+	if qm.hasValueScale() {
+		applyValueScale(&result, qm.ValueScale, qm.ValueOffset)
+	}
+	result.NumericValues = applyFillPolicy(result.NumericValues, result.TimePoints, qm.FillPolicy)
+	for i := range result.StatSeries {
+		result.StatSeries[i].Values = applyFillPolicy(result.StatSeries[i].Values, result.TimePoints, qm.FillPolicy)
+	}
+	for i := range result.AggSeries {
+		result.AggSeries[i].Values = applyFillPolicy(result.AggSeries[i].Values, result.AggSeries[i].TimePoints, qm.FillPolicy)
+	}
+	if qm.GapValue != "" && !result.IsStep {
+		applyGapValue(&result, qm.GapSentinel, qm.GapInterval)
+	}
+	if qm.QueryType == queryTypeStateTimeline && result.IsEnum {
+		result.TimePoints, result.StringValues = mergeStateRuns(result.TimePoints, result.StringValues)
+	}
+	if qm.Stride > 0 && len(result.TimePoints) >= maxReturnedPoints {
+		result.StrideTruncated = true
+		log.DefaultLogger.Warn("Strided query reached the raw point limit; the series may be truncated", "points", len(result.TimePoints))
+	}
+	if qm.Stride > 1 {
+		strideNumericPoints(&result, qm.Stride)
+	}
+	capNumericPoints(&result)
+
 	return result, nil
 }
 
@@ -651,9 +1078,12 @@ func (e *NominalQueryExecution) extractNumericDataFromConjure(numeric computeapi
 	return timePoints, values, nil
 }
 
-func (e *NominalQueryExecution) extractBucketedDataFromConjure(bucketed computeapi.BucketedNumericPlot) ([]time.Time, []*float64, error) {
+// extractBucketedDataFromConjure returns each bucket's mean, plus its standard
+// deviation (the square root of the bucket's population variance).
+func (e *NominalQueryExecution) extractBucketedDataFromConjure(bucketed computeapi.BucketedNumericPlot) ([]time.Time, []*float64, []*float64, error) {
 	var timePoints []time.Time
 	var values []*float64
+	var stdDevs []*float64
 
 	// Access the fields directly from the conjure struct
 	for i := 0; i < len(bucketed.Timestamps) && i < len(bucketed.Buckets); i++ {
@@ -669,10 +1099,12 @@ func (e *NominalQueryExecution) extractBucketedDataFromConjure(bucketed computea
 		// Use mean value from bucket (it's a direct float64, not pointer)
 		mean := bucket.Mean
 		values = append(values, &mean)
+		stdDev := math.Sqrt(bucket.Variance)
+		stdDevs = append(stdDevs, &stdDev)
 	}
 
 	log.DefaultLogger.Debug("Extracted bucketed data from conjure", "timePoints", len(timePoints), "values", len(values))
-	return timePoints, values, nil
+	return timePoints, values, stdDevs, nil
 }
 
 // extractEnumDataFromConjure converts an EnumPlot response to time/string slices.
@@ -708,9 +1140,9 @@ func (e *NominalQueryExecution) extractEnumDataFromConjure(enumPlot computeapi.E
 }
 
 // extractBucketedEnumDataFromConjure converts a BucketedEnumPlot response to time/string slices.
-// Uses the histogram mode (most frequent category) as the representative value for each bucket,
-// which is the categorical equivalent of the numeric path's Mean aggregate.
-func (e *NominalQueryExecution) extractBucketedEnumDataFromConjure(bucketed computeapi.BucketedEnumPlot) ([]time.Time, []string, error) {
+// aggregation picks each bucket's representative value (see enumBucketValueIndex); the
+// default histogram mode is the categorical equivalent of the numeric path's Mean aggregate.
+func (e *NominalQueryExecution) extractBucketedEnumDataFromConjure(bucketed computeapi.BucketedEnumPlot, aggregation string) ([]time.Time, []string, error) {
 	n := min(len(bucketed.Timestamps), len(bucketed.Buckets))
 	timePoints := make([]time.Time, 0, n)
 	values := make([]string, 0, n)
@@ -723,24 +1155,13 @@ func (e *NominalQueryExecution) extractBucketedEnumDataFromConjure(bucketed comp
 		nanos := int64(timestamp.Nanos)
 		timePoints = append(timePoints, time.Unix(seconds, nanos))
 
-		// Find the mode (most frequent value) from the histogram, breaking count
-		// ties by lowest category index so results are deterministic across Go's
-		// randomized map iteration. Falls back to FirstPoint if histogram is empty.
-		modeIndex := bucket.FirstPoint.Value
-		maxCount := safelong.SafeLong(0)
-		for idx, count := range bucket.Histogram {
-			if count > maxCount || (count == maxCount && count > 0 && idx < modeIndex) {
-				maxCount = count
-				modeIndex = idx
-			}
-		}
-
-		if modeIndex >= 0 && modeIndex < len(bucketed.Categories) {
-			values = append(values, bucketed.Categories[modeIndex])
+		valueIndex := enumBucketValueIndex(bucket, aggregation)
+		if valueIndex >= 0 && valueIndex < len(bucketed.Categories) {
+			values = append(values, bucketed.Categories[valueIndex])
 		} else {
-			values = append(values, fmt.Sprintf("unknown(%d)", modeIndex))
+			values = append(values, fmt.Sprintf("unknown(%d)", valueIndex))
 			log.DefaultLogger.Warn("Bucketed enum index out of bounds",
-				"index", modeIndex,
+				"index", valueIndex,
 				"categoriesLen", len(bucketed.Categories),
 			)
 		}
@@ -750,6 +1171,34 @@ func (e *NominalQueryExecution) extractBucketedEnumDataFromConjure(bucketed comp
 	return timePoints, values, nil
 }
 
+// enumBucketValueIndex returns the category index representing bucket for an enum
+// aggregation. AggFirstPoint and AggLastPoint take the bucket's boundary points (a
+// single-point bucket has no LastPoint, so its first point is also its last). Anything
+// else uses the histogram mode, breaking count ties by lowest category index so results
+// are deterministic across Go's randomized map iteration, and falling back to FirstPoint
+// if the histogram is empty.
+func enumBucketValueIndex(bucket computeapi.EnumBucket, aggregation string) int {
+	switch aggregation {
+	case AggFirstPoint:
+		return bucket.FirstPoint.Value
+	case AggLastPoint:
+		if bucket.LastPoint != nil {
+			return bucket.LastPoint.Value
+		}
+		return bucket.FirstPoint.Value
+	}
+
+	modeIndex := bucket.FirstPoint.Value
+	maxCount := safelong.SafeLong(0)
+	for idx, count := range bucket.Histogram {
+		if count > maxCount || (count == maxCount && count > 0 && idx < modeIndex) {
+			maxCount = count
+			modeIndex = idx
+		}
+	}
+	return modeIndex
+}
+
 // CheckHealth handles health checks sent from Grafana to the plugin.
 func (d *Datasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRequest) (*backend.CheckHealthResult, error) {
 	ctx = contextWithPluginRequestIdentity(ctx, req.PluginContext)
@@ -763,7 +1212,7 @@ func (d *Datasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRe
 	}
 
 	// Add timeout to prevent hanging
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, healthCheckTimeout)
 	defer cancel()
 
 	config, err := models.LoadPluginSettings(*req.PluginContext.DataSourceInstanceSettings)
@@ -784,35 +1233,120 @@ func (d *Datasource) CheckHealth(ctx context.Context, req *backend.CheckHealthRe
 		}, nil
 	}
 
+	// Failure messages name the base URL that was tried; the API key is never included.
+	baseURL := redactedBaseURL(config.GetAPIBaseURL())
+
 	if config.Secrets.ApiKey == "" {
-		log.DefaultLogger.Debug("Health check failed: missing API key")
+		log.DefaultLogger.Debug("Health check failed: missing API key", "baseUrl", baseURL)
 		return &backend.CheckHealthResult{
 			Status:  backend.HealthStatusError,
-			Message: "API key is required",
+			Message: withBaseURL("API key is required", baseURL),
 		}, nil
 	}
 
+	// Each phase is timed and the timings are reported on success and failure.
+	timings := &healthTimings{}
+
+	// Probe the base URL first so connection problems are told apart from auth.
+	if client := d.getResourceHTTPClient(); client != nil {
+		if err := probeBaseURL(ctxWithTimeout, client, strings.TrimSuffix(config.GetAPIBaseURL(), "/"), timings); err != nil {
+			message, _ := classifyConnectionError(err)
+			log.DefaultLogger.Debug("Health check failed", "baseUrl", baseURL, "message", message)
+			return &backend.CheckHealthResult{
+				Status:      backend.HealthStatusError,
+				Message:     withBaseURL(timings.annotate(message), baseURL),
+				JSONDetails: timings.jsonDetails(baseURL, ""),
+			}, nil
+		}
+	}
+
 	// Test connection using generated client with timeout
-	log.DefaultLogger.Debug("Testing connection using nominal-api-go client")
+	log.DefaultLogger.Debug("Testing connection using nominal-api-go client", "baseUrl", baseURL)
 
 	bearerToken := bearertoken.Token(config.Secrets.ApiKey)
+	authStart := time.Now()
 	profile, err := d.authService.GetMyProfile(ctxWithTimeout, bearerToken)
+	timings.record("auth", time.Since(authStart))
 	if err != nil {
 		logErrorWithConjureFields("Health check failed", err)
 		message, _ := classifyConnectionError(err)
+		log.DefaultLogger.Debug("Health check failed", "baseUrl", baseURL, "message", message)
 		return &backend.CheckHealthResult{
-			Status:  backend.HealthStatusError,
-			Message: message,
+			Status:      backend.HealthStatusError,
+			Message:     withBaseURL(timings.annotate(message), baseURL),
+			JSONDetails: timings.jsonDetails(baseURL, ""),
 		}, nil
 	}
 
+	if config.DeepHealthCheck {
+		if result := d.checkComputeAccess(ctxWithTimeout, bearerToken, baseURL, timings); result != nil {
+			result.JSONDetails = timings.jsonDetails(baseURL, profile.DisplayName)
+			return result, nil
+		}
+	}
+
 	log.DefaultLogger.Debug("Health check successful", "user", profile.DisplayName)
 	return &backend.CheckHealthResult{
-		Status:  backend.HealthStatusOk,
-		Message: "Successfully connected to Nominal API",
+		Status:      backend.HealthStatusOk,
+		Message:     timings.annotate("Successfully connected to Nominal API" + timings.clockSkewWarning(config)),
+		JSONDetails: timings.jsonDetails(baseURL, profile.DisplayName),
 	}, nil
 }
 
+// checkComputeAccess probes the compute service with probeComputeService. It
+// returns nil when the key may compute; a query can still fail on the data it
+// reads.
+func (d *Datasource) checkComputeAccess(ctx context.Context, bearerToken bearertoken.Token, baseURL string, timings *healthTimings) *backend.CheckHealthResult {
+	computeStart := time.Now()
+	err := d.probeComputeService(ctx, bearerToken)
+	timings.record("compute", time.Since(computeStart))
+	if err == nil {
+		return nil
+	}
+
+	logErrorWithConjureFields("Health check compute call failed", err)
+	var message string
+	if extractErrorDetails(err).Status == http.StatusForbidden {
+		message = appendInstanceID("API key is valid but lacks compute access - queries will fail", err)
+	} else {
+		connectionMessage, _ := classifyConnectionError(err)
+		message = "Authenticated, but compute check failed: " + connectionMessage
+	}
+	log.DefaultLogger.Debug("Health check failed", "baseUrl", baseURL, "message", message)
+	return &backend.CheckHealthResult{
+		Status:  backend.HealthStatusError,
+		Message: withBaseURL(timings.annotate(message), baseURL),
+	}
+}
+
+// probeComputeService runs one trivial computation: a literal 1 ms range,
+// summarized over that same range. It reads no data, so it needs no asset the
+// key may lack, but it is still a real compute call: a key that authenticates
+// yet may not compute is refused with a 403. Permission to read a given asset
+// is checked per query.
+func (d *Datasource) probeComputeService(ctx context.Context, bearerToken bearertoken.Token) error {
+	_, err := d.computeService.BatchComputeWithUnits(ctx, bearerToken, computeapi1.BatchComputeWithUnitsRequest{
+		Requests: []computeapi1.ComputeNodeRequest{computeProbeRequest(time.Now())},
+	})
+	return err
+}
+
+// computeProbeRequest is probeComputeService's subrequest, covering the 1 ms
+// starting at start.
+func computeProbeRequest(start time.Time) computeapi1.ComputeNodeRequest {
+	startTimestamp := computeapi.NewTimestampConstantFromLiteral(timestampFromTime(start))
+	endTimestamp := computeapi.NewTimestampConstantFromLiteral(timestampFromTime(start.Add(time.Millisecond)))
+	ranges := computeapi.LiteralRanges{LiteralRanges: []computeapi.LiteralRange{{StartTimestamp: &startTimestamp, EndTimestamp: &endTimestamp}}}
+	return computeapi1.ComputeNodeRequest{
+		Start: timestampFromTime(start),
+		End:   timestampFromTime(start.Add(time.Millisecond)),
+		Node:  computeapi1.NewComputableNodeFromRanges(computeapi1.SummarizeRanges{Input: computeapi1.NewRangeSeriesFromLiteralRanges(ranges)}),
+		Context: computeapi1.Context{
+			Variables: map[computeapi.VariableName]computeapi1.VariableValue{},
+		},
+	}
+}
+
 // CallResource handles HTTP requests sent to the plugin.
 func (d *Datasource) CallResource(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
 	ctx = contextWithPluginRequestIdentity(ctx, req.PluginContext)
@@ -821,6 +1355,15 @@ func (d *Datasource) CallResource(ctx context.Context, req *backend.CallResource
 	return newNominalResourceHandler(d).Handle(ctx, req, sender)
 }
 
+// aggSeriesDisplayName names an aggregation series' frame: the channel, with
+// the aggregation appended when the query chose its aggregations explicitly.
+func aggSeriesDisplayName(qm NominalQueryModel, aggName string) string {
+	if qm.ExplicitAggregations {
+		return fmt.Sprintf("%s (%s)", qm.Channel, aggName)
+	}
+	return qm.Channel
+}
+
 // carriesChannelUnit = false for COUNT (dimensionless) and VARIANCE (unit²),
 // suppressing the channel unit on the resulting frame. Multi-agg call sites
 // pass agg.CarriesChannelUnit directly; non-aggregated call sites should use
@@ -828,7 +1371,8 @@ func (d *Datasource) CallResource(ctx context.Context, req *backend.CallResource
 // call site rather than encoded as a literal true.
 func fieldConfigForNumeric(qm *NominalQueryModel, displayName string, carriesChannelUnit bool) *data.FieldConfig {
 	cfg := &data.FieldConfig{DisplayNameFromDS: displayName}
-	if !carriesChannelUnit {
+	// Scaled values are no longer in the channel's unit.
+	if !carriesChannelUnit || qm.hasValueScale() {
 		return cfg
 	}
 	cfg.Unit = mapToGrafanaUnit(qm.ChannelUnit)
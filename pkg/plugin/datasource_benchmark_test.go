@@ -148,7 +148,7 @@ func BenchmarkExtractBucketedEnumDataFromConjure(b *testing.B) {
 	for _, rows := range []int{250, 1000, 10000} {
 		plot := benchmarkBucketedEnumPlot(rows)
 		b.Run(fmt.Sprintf("rows_%d", rows), func(b *testing.B) {
-			gotTimes, gotValues, err := exec.extractBucketedEnumDataFromConjure(plot)
+			gotTimes, gotValues, err := exec.extractBucketedEnumDataFromConjure(plot, EnumAggMode)
 			if err != nil {
 				b.Fatalf("extract bucketed enum data: %v", err)
 			}
@@ -159,7 +159,7 @@ func BenchmarkExtractBucketedEnumDataFromConjure(b *testing.B) {
 			b.ReportAllocs()
 			b.ResetTimer()
 			for i := 0; i < b.N; i++ {
-				gotTimes, gotValues, err = exec.extractBucketedEnumDataFromConjure(plot)
+				gotTimes, gotValues, err = exec.extractBucketedEnumDataFromConjure(plot, EnumAggMode)
 				if err != nil {
 					b.Fatalf("extract bucketed enum data: %v", err)
 				}
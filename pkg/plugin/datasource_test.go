@@ -4,15 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+	"unicode/utf8"
 
 	"github.com/apache/arrow-go/v18/arrow"
 	"github.com/apache/arrow-go/v18/arrow/array"
@@ -22,6 +26,7 @@ import (
 	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/nominal-inc/nominal-ds/pkg/models"
 	"github.com/nominal-io/nominal-api-go/api/rids"
+	authapi "github.com/nominal-io/nominal-api-go/authentication/api"
 	datasourceapi "github.com/nominal-io/nominal-api-go/datasource/api"
 	"github.com/nominal-io/nominal-api-go/io/nominal/api"
 	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
@@ -84,6 +89,142 @@ func TestPrepareQueryAppliesTemplateVariablesAndDefaultsAggregations(t *testing.
 	}
 }
 
+func TestPrepareQueryInterpolatesBucketsTemplateVariable(t *testing.T) {
+	qe := newTestQueryExecution(&Datasource{}, nil)
+	prepare := func(t *testing.T, buckets string) (preparedQuery, *backend.DataResponse) {
+		t.Helper()
+		return qe.prepareQuery(context.Background(), backend.DataQuery{
+			RefID: "A",
+			JSON: []byte(`{"assetRid":"ri.nominal.asset.1","channel":"temperature","dataScopeName":"default",` +
+				`"buckets":` + buckets + `,"templateVariables":{"resolution":250}}`),
+		})
+	}
+
+	t.Run("template variable drives bucket count", func(t *testing.T) {
+		prepared, errResp := prepare(t, `"$resolution"`)
+		if errResp != nil {
+			t.Fatalf("unexpected preparation error: %v", errResp.Error)
+		}
+		if prepared.Model.Buckets != 250 {
+			t.Errorf("Buckets = %d, want 250", prepared.Model.Buckets)
+		}
+	})
+
+	t.Run("numeric buckets still decode", func(t *testing.T) {
+		prepared, errResp := prepare(t, `40`)
+		if errResp != nil {
+			t.Fatalf("unexpected preparation error: %v", errResp.Error)
+		}
+		if prepared.Model.Buckets != 40 {
+			t.Errorf("Buckets = %d, want 40", prepared.Model.Buckets)
+		}
+	})
+
+	t.Run("non-numeric interpolation is rejected", func(t *testing.T) {
+		_, errResp := prepare(t, `"$unknown"`)
+		if errResp == nil || errResp.Status != backend.StatusBadRequest {
+			t.Fatalf("expected bad request for non-numeric buckets, got %+v", errResp)
+		}
+		if !strings.Contains(errResp.Error.Error(), "buckets must be an integer") {
+			t.Errorf("error = %v, want it to mention buckets must be an integer", errResp.Error)
+		}
+	})
+}
+
+func TestPrepareQueryResolutionVariableSetsDefaultBuckets(t *testing.T) {
+	qe := newTestQueryExecution(&Datasource{}, nil)
+	prepare := func(t *testing.T, fields string) (preparedQuery, *backend.DataResponse) {
+		t.Helper()
+		return qe.prepareQuery(context.Background(), backend.DataQuery{
+			RefID: "A",
+			JSON: []byte(`{"assetRid":"ri.nominal.asset.1","channel":"temperature","dataScopeName":"default",` +
+				fields + `}`),
+		})
+	}
+
+	for name, fields := range map[string]string{
+		"number": `"templateVariables":{"__resolution":300}`,
+		"string": `"templateVariables":{"__resolution":" 300 "}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			prepared, errResp := prepare(t, fields)
+			if errResp != nil {
+				t.Fatalf("unexpected preparation error: %v", errResp.Error)
+			}
+			if prepared.Model.Buckets != 300 {
+				t.Errorf("Buckets = %d, want 300 from __resolution", prepared.Model.Buckets)
+			}
+		})
+	}
+
+	t.Run("query buckets override the variable", func(t *testing.T) {
+		prepared, errResp := prepare(t, `"buckets":40,"templateVariables":{"__resolution":300}`)
+		if errResp != nil {
+			t.Fatalf("unexpected preparation error: %v", errResp.Error)
+		}
+		if prepared.Model.Buckets != 40 {
+			t.Errorf("Buckets = %d, want the query's own 40", prepared.Model.Buckets)
+		}
+	})
+
+	t.Run("non-numeric value is rejected", func(t *testing.T) {
+		_, errResp := prepare(t, `"templateVariables":{"__resolution":"auto"}`)
+		if errResp == nil || errResp.Status != backend.StatusBadRequest {
+			t.Fatalf("expected bad request for non-numeric __resolution, got %+v", errResp)
+		}
+	})
+}
+
+func TestPrepareQueryRejectsUnknownFillPolicy(t *testing.T) {
+	qe := newTestQueryExecution(&Datasource{}, nil)
+	_, errResp := qe.prepareQuery(context.Background(), backend.DataQuery{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      "ri.nominal.asset.1",
+			Channel:       "temperature",
+			DataScopeName: "default",
+			FillPolicy:    "spline",
+		}),
+	})
+	if errResp == nil || errResp.Status != backend.StatusBadRequest {
+		t.Fatalf("expected bad request for unknown fill policy, got %+v", errResp)
+	}
+	if !strings.Contains(errResp.Error.Error(), "fillPolicy") {
+		t.Errorf("error = %v, want it to mention fillPolicy", errResp.Error)
+	}
+}
+
+func TestPrepareQueryAcceptsDataSourceRid(t *testing.T) {
+	ds := &Datasource{}
+	config := &models.PluginSettings{Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+	qe := newTestQueryExecution(ds, config)
+
+	t.Run("dataSourceRid with channel is batchable without asset or data scope", func(t *testing.T) {
+		query := backend.DataQuery{
+			RefID: "A",
+			JSON:  mustMarshal(NominalQueryModel{DataSourceRid: "ri.nominal.dataset.abc", Channel: "temperature", Buckets: 100}),
+		}
+		prepared, prepErr := qe.prepareQuery(context.Background(), query)
+		if prepErr != nil {
+			t.Fatalf("unexpected preparation error: %v", prepErr.Error)
+		}
+		if prepared.Kind != preparedQueryBatchable {
+			t.Fatalf("expected batchable query, got kind %d", prepared.Kind)
+		}
+	})
+
+	t.Run("dataSourceRid without channel is rejected", func(t *testing.T) {
+		query := backend.DataQuery{
+			RefID: "A",
+			JSON:  mustMarshal(NominalQueryModel{DataSourceRid: "ri.nominal.dataset.abc"}),
+		}
+		_, prepErr := qe.prepareQuery(context.Background(), query)
+		if prepErr == nil || prepErr.Error == nil {
+			t.Fatal("expected validation error for dataSourceRid without channel")
+		}
+	})
+}
+
 func TestPrepareQueryAggregationRules(t *testing.T) {
 	ds := &Datasource{}
 	config := &models.PluginSettings{Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
@@ -206,6 +347,59 @@ func TestPrepareQueryAggregationRules(t *testing.T) {
 	}
 }
 
+func TestLegacyConstantQueryReturnsNumPoints(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	timeRange := backend.TimeRange{From: from, To: from.Add(time.Hour)}
+	qe := newTestQueryExecution(&Datasource{}, nil)
+
+	tests := []struct {
+		numPoints int
+		want      int
+	}{
+		{numPoints: 0, want: 2},
+		{numPoints: 1, want: 1},
+		{numPoints: 5, want: 5},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.numPoints), func(t *testing.T) {
+			resp := qe.Execute(context.Background(), []backend.DataQuery{{
+				RefID:     "A",
+				JSON:      mustMarshal(NominalQueryModel{Constant: 42, NumPoints: tt.numPoints}),
+				TimeRange: timeRange,
+			}})
+			res := resp.Responses["A"]
+			if res.Error != nil {
+				t.Fatalf("unexpected error: %v", res.Error)
+			}
+			frame := res.Frames[0]
+			if rows, _ := frame.RowLen(); rows != tt.want {
+				t.Fatalf("rows = %d, want %d", rows, tt.want)
+			}
+			for i := 0; i < tt.want; i++ {
+				if got := frame.Fields[1].At(i).(float64); got != 42 {
+					t.Errorf("value[%d] = %v, want the constant 42", i, got)
+				}
+			}
+			if got := frame.Fields[0].At(0).(time.Time); !got.Equal(from) {
+				t.Errorf("first time = %v, want %v", got, from)
+			}
+			if tt.want > 1 {
+				if got := frame.Fields[0].At(tt.want - 1).(time.Time); !got.Equal(timeRange.To) {
+					t.Errorf("last time = %v, want %v", got, timeRange.To)
+				}
+			}
+		})
+	}
+
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON:  mustMarshal(NominalQueryModel{Constant: 42, NumPoints: maxReturnedPoints + 1}),
+	}})
+	if err := resp.Responses["A"].Error; err == nil || !strings.Contains(err.Error(), "numPoints") {
+		t.Errorf("error = %v, want numPoints rejected above the point cap", err)
+	}
+}
+
 func TestPrepareQueryInfersMissingChannelType(t *testing.T) {
 	assetRid := "ri.scout.main.asset.prepare1"
 	dataSourceRid := "ri.scout.main.data-source.ds1"
@@ -266,6 +460,57 @@ func TestPrepareQueryInfersMissingChannelType(t *testing.T) {
 	}
 }
 
+func TestPrepareQueryInfersChannelMetadataFromDataSourceRid(t *testing.T) {
+	const dataSourceRid = "ri.scout.main.data-source.ds1"
+	stringType := api.New_SeriesDataType(api.SeriesDataType_STRING)
+	mockDS := &mockDatasourceService{
+		searchChannelsResponse: datasourceapi.SearchChannelsResponse{
+			Results: []datasourceapi.ChannelMetadata{
+				{
+					Name:       api.Channel("state"),
+					DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1")),
+					DataType:   &stringType,
+				},
+			},
+		},
+	}
+	ds := &Datasource{datasourceService: mockDS, resourceHTTPClient: &http.Client{}}
+	config := &models.PluginSettings{
+		BaseUrl: "https://api.test.com",
+		Secrets: &models.SecretPluginSettings{
+			ApiKey: "test-key",
+		},
+	}
+	query := backend.DataQuery{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			DataSourceRid: dataSourceRid,
+			Channel:       "state",
+			Buckets:       100,
+		}),
+	}
+
+	qe := newTestQueryExecution(ds, config)
+	prepared, prepErr := qe.prepareQuery(context.Background(), query)
+	if prepErr != nil {
+		t.Fatalf("unexpected preparation error: %v", prepErr.Error)
+	}
+	if prepared.Model.ChannelDataType != "string" {
+		t.Fatalf("ChannelDataType = %q, want string", prepared.Model.ChannelDataType)
+	}
+	if got := mockDS.searchChannelsRequest.DataSources; len(got) != 1 || got[0].String() != dataSourceRid {
+		t.Errorf("search data sources = %v, want only %s", got, dataSourceRid)
+	}
+
+	// A second query for the same channel is served from the cache.
+	if _, prepErr := qe.prepareQuery(context.Background(), query); prepErr != nil {
+		t.Fatalf("unexpected preparation error: %v", prepErr.Error)
+	}
+	if mockDS.searchChannelsCalls != 1 {
+		t.Errorf("channel lookups = %d, want 1", mockDS.searchChannelsCalls)
+	}
+}
+
 func TestApplyChannelMetadataPreservesOmittedFields(t *testing.T) {
 	qm := NominalQueryModel{
 		ChannelDataType: "numeric",
@@ -584,6 +829,66 @@ func TestCheckHealthWithNilDataSourceInstanceSettings(t *testing.T) {
 	}
 }
 
+func TestCheckHealthDeepCheckReportsComputeForbidden(t *testing.T) {
+	compute := &mockComputeService{
+		batchComputeError: newAPIError(http.StatusForbidden, []byte(`{"errorCode":"PERMISSION_DENIED","errorName":"Default:PermissionDenied","errorInstanceId":"forbidden-1"}`)),
+	}
+	ds := &Datasource{
+		authService:    &mockAuthService{getMyProfileResponse: authapi.UserV2{DisplayName: "tester"}},
+		computeService: compute,
+	}
+	checkHealth := func(t *testing.T, jsonData string) *backend.CheckHealthResult {
+		t.Helper()
+		result, err := ds.CheckHealth(context.Background(), &backend.CheckHealthRequest{
+			PluginContext: backend.PluginContext{
+				DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{
+					JSONData:                []byte(jsonData),
+					DecryptedSecureJSONData: map[string]string{"apiKey": "test-key"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CheckHealth returned err: %v", err)
+		}
+		return result
+	}
+
+	t.Run("auth-only check stays healthy", func(t *testing.T) {
+		result := checkHealth(t, `{"baseUrl": "https://api.test.com"}`)
+		if result.Status != backend.HealthStatusOk {
+			t.Fatalf("Status = %v (%q), want HealthStatusOk", result.Status, result.Message)
+		}
+		if compute.batchComputeCalls != 0 {
+			t.Errorf("batch compute calls = %d, want 0 without deepHealthCheck", compute.batchComputeCalls)
+		}
+	})
+
+	t.Run("deep check reports forbidden compute", func(t *testing.T) {
+		result := checkHealth(t, `{"baseUrl": "https://api.test.com", "deepHealthCheck": true}`)
+		if result.Status != backend.HealthStatusError {
+			t.Fatalf("Status = %v, want HealthStatusError", result.Status)
+		}
+		if !strings.Contains(result.Message, "lacks compute access") {
+			t.Errorf("Message = %q, want compute-forbidden message", result.Message)
+		}
+		if !strings.Contains(result.Message, "errorInstanceId: forbidden-1") {
+			t.Errorf("Message = %q, want errorInstanceId", result.Message)
+		}
+		if compute.batchComputeCalls != 1 {
+			t.Errorf("batch compute calls = %d, want 1", compute.batchComputeCalls)
+		}
+		requests := compute.lastBatchRequest.Requests
+		if len(requests) != 1 {
+			t.Fatalf("probe subrequests = %d, want 1 real computation", len(requests))
+		}
+		start := time.Unix(int64(requests[0].Start.Seconds), int64(requests[0].Start.Nanos))
+		end := time.Unix(int64(requests[0].End.Seconds), int64(requests[0].End.Nanos))
+		if span := end.Sub(start); span != time.Millisecond {
+			t.Errorf("probe range = %v, want 1ms", span)
+		}
+	})
+}
+
 func TestQueryDataWithInvalidJSON(t *testing.T) {
 	ds := &Datasource{
 		settings: backend.DataSourceInstanceSettings{
@@ -622,6 +927,361 @@ func TestQueryDataWithInvalidJSON(t *testing.T) {
 	if response.Status != backend.StatusBadRequest {
 		t.Errorf("expected StatusBadRequest, got %v", response.Status)
 	}
+	if response.Error != nil {
+		msg := response.Error.Error()
+		if !strings.Contains(msg, "refId A") {
+			t.Errorf("error %q does not mention the RefID", msg)
+		}
+		if !strings.Contains(msg, "{invalid json") {
+			t.Errorf("error %q does not include the offending JSON", msg)
+		}
+	}
+}
+
+func TestComputeContextVariablesLoggedAndAttached(t *testing.T) {
+	logs := captureLogs(t)
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{1, 2})},
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	queries := []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      "ri.nominal.asset.1",
+			Channel:       "temperature",
+			DataScopeName: "default",
+			Buckets:       2,
+			DebugContext:  true,
+			TemplateVariables: map[string]interface{}{
+				"env":       "prod",
+				"authToken": "s3cr3t",
+			},
+		}),
+		TimeRange: backend.TimeRange{
+			From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+		},
+	}}
+
+	resp := qe.Execute(context.Background(), queries)
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+
+	want := map[string]string{
+		"assetRid":  "ri.nominal.asset.1",
+		"env":       "prod",
+		"authToken": redactedValue,
+	}
+
+	entries := logs.entriesWithMessage("debug", "Resolved compute context variables")
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 context debug log, got %d", len(entries))
+	}
+	if got, _ := entries[0].Args["variables"].(map[string]string); !reflect.DeepEqual(got, want) {
+		t.Errorf("logged variables = %v, want %v", got, want)
+	}
+
+	if len(res.Frames) == 0 || res.Frames[0].Meta == nil {
+		t.Fatal("expected a frame with metadata")
+	}
+	custom, _ := res.Frames[0].Meta.Custom.(map[string]any)
+	if got, _ := custom["computeContextVariables"].(map[string]string); !reflect.DeepEqual(got, want) {
+		t.Errorf("Meta.Custom variables = %v, want %v", got, want)
+	}
+}
+
+func TestExecuteAttachesRefIDToFrameMeta(t *testing.T) {
+	mockService := &mockComputeService{batchComputeResponse: makeBatchComputeWithUnitsResponse(2)}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, &models.PluginSettings{
+		Secrets: &models.SecretPluginSettings{ApiKey: "test-key"},
+	})
+	timeRange := backend.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+	query := func(refID, channel string) backend.DataQuery {
+		return backend.DataQuery{
+			RefID:     refID,
+			JSON:      mustMarshal(NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: channel, DataScopeName: "default", IncludeRefID: true}),
+			TimeRange: timeRange,
+		}
+	}
+
+	resp := qe.Execute(context.Background(), []backend.DataQuery{query("A", "temperature"), query("B", "pressure")})
+	for _, refID := range []string{"A", "B"} {
+		res := resp.Responses[refID]
+		if res.Error != nil || len(res.Frames) == 0 {
+			t.Fatalf("%s: error = %v, frames = %d; want data", refID, res.Error, len(res.Frames))
+		}
+		for _, frame := range res.Frames {
+			if frame.Meta == nil {
+				t.Fatalf("%s: frame %q has no metadata", refID, frame.Name)
+			}
+			custom, _ := frame.Meta.Custom.(map[string]any)
+			if got := custom["refId"]; got != refID {
+				t.Errorf("%s: frame %q Meta.Custom refId = %v", refID, frame.Name, got)
+			}
+		}
+	}
+}
+
+func TestQueryDataWithEmptyJSON(t *testing.T) {
+	ds := &Datasource{}
+
+	for name, raw := range map[string][]byte{"nil": nil, "blank": []byte("  "), "null": []byte("null")} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{
+				PluginContext: backend.PluginContext{
+					DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{
+						JSONData:                []byte(`{"baseUrl": "https://api.test.com"}`),
+						DecryptedSecureJSONData: map[string]string{"apiKey": "test-key"},
+					},
+				},
+				Queries: []backend.DataQuery{{RefID: "A", JSON: raw}},
+			})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			response := resp.Responses["A"]
+			if response.Status != backend.StatusBadRequest {
+				t.Errorf("expected StatusBadRequest, got %v", response.Status)
+			}
+			if response.Error == nil {
+				t.Fatal("expected an error for an empty query")
+			}
+			msg := response.Error.Error()
+			if !strings.Contains(msg, "empty query (refId A)") {
+				t.Errorf("error = %q, want the friendly empty-query message", msg)
+			}
+			if strings.Contains(msg, "unexpected end of JSON input") {
+				t.Errorf("error = %q, should not surface a JSON syntax error", msg)
+			}
+		})
+	}
+}
+
+func TestDuplicateRefIDsReturnErrors(t *testing.T) {
+	mockService := &mockComputeService{
+		batchComputeFunc: func(requestArg computeapi1.BatchComputeWithUnitsRequest) (computeapi.BatchComputeWithUnitsResponse, error) {
+			results := make([]computeapi.ComputeWithUnitsResult, len(requestArg.Requests))
+			for i := range results {
+				results[i] = createMockArrowComputeResult([]float64{1, 2})
+			}
+			return computeapi.BatchComputeWithUnitsResponse{Results: results}, nil
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	queries := makeBatchableQueries(3, backend.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+	})
+	queries[1].RefID = queries[0].RefID
+
+	resp := qe.Execute(context.Background(), queries)
+
+	dup := resp.Responses[queries[0].RefID]
+	if dup.Status != backend.StatusBadRequest || dup.Error == nil {
+		t.Fatalf("duplicate refId response = %+v, want bad request error", dup)
+	}
+	if !strings.Contains(dup.Error.Error(), "duplicate refId") {
+		t.Errorf("error = %v, want duplicate refId message", dup.Error)
+	}
+	if len(dup.Frames) != 0 {
+		t.Errorf("duplicate refId response has %d frames, want none", len(dup.Frames))
+	}
+
+	unique := resp.Responses[queries[2].RefID]
+	if unique.Error != nil || len(unique.Frames) == 0 {
+		t.Errorf("unique refId response = %+v, want frames and no error", unique)
+	}
+	if n := len(mockService.lastBatchRequest.Requests); n != 1 {
+		t.Errorf("batch subrequests = %d, want 1 (duplicates are not computed)", n)
+	}
+}
+
+func TestExpandedQueriesAboveLimitAreRejected(t *testing.T) {
+	timeRange := backend.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+
+	t.Run("default limit", func(t *testing.T) {
+		mockService := &mockComputeService{}
+		qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+		// An "All" selection over a large multi-value variable.
+		queries := makeBatchableQueries(defaultMaxExpandedQueries+1, timeRange)
+		resp := qe.Execute(context.Background(), queries)
+
+		if mockService.batchComputeCalls != 0 {
+			t.Errorf("batch compute calls = %d, want 0", mockService.batchComputeCalls)
+		}
+		for _, q := range queries {
+			res := resp.Responses[q.RefID]
+			if res.Status != backend.StatusBadRequest || res.Error == nil || !strings.Contains(res.Error.Error(), "maxExpandedQueries") {
+				t.Fatalf("response %s = %+v, want maxExpandedQueries error", q.RefID, res)
+			}
+		}
+	})
+
+	t.Run("dual resolution counts both subrequests", func(t *testing.T) {
+		mockService := &mockComputeService{}
+		config := &models.PluginSettings{
+			Secrets:            &models.SecretPluginSettings{ApiKey: "test-key"},
+			MaxExpandedQueries: 2,
+		}
+		qe := newTestQueryExecution(&Datasource{computeService: mockService}, config)
+
+		queries := makeBatchableQueries(2, timeRange)
+		queries[0].JSON = mustMarshal(NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temp1", DataScopeName: "ds1", DualResolution: true})
+		resp := qe.Execute(context.Background(), queries)
+
+		if mockService.batchComputeCalls != 0 {
+			t.Errorf("batch compute calls = %d, want 0", mockService.batchComputeCalls)
+		}
+		if res := resp.Responses[queries[1].RefID]; res.Error == nil || !strings.Contains(res.Error.Error(), "3 compute subrequests, more than the limit of 2") {
+			t.Errorf("error = %v, want 3 subrequests over a limit of 2", res.Error)
+		}
+	})
+
+	t.Run("at the limit", func(t *testing.T) {
+		mockService := &mockComputeService{batchComputeResponse: makeBatchComputeWithUnitsResponse(3)}
+		config := &models.PluginSettings{
+			Secrets:            &models.SecretPluginSettings{ApiKey: "test-key"},
+			MaxExpandedQueries: 3,
+		}
+		qe := newTestQueryExecution(&Datasource{computeService: mockService}, config)
+
+		resp := qe.Execute(context.Background(), makeBatchableQueries(3, timeRange))
+		for refID, res := range resp.Responses {
+			if res.Error != nil {
+				t.Errorf("response %s error = %v, want none", refID, res.Error)
+			}
+		}
+	})
+}
+
+func TestDryRunReturnsBatchRequestWithoutComputing(t *testing.T) {
+	mockService := &mockComputeService{}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	queries := []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      "ri.nominal.asset.1",
+			Channel:       "temperature",
+			DataScopeName: "default",
+			Buckets:       10,
+			DryRun:        true,
+		}),
+		TimeRange: backend.TimeRange{
+			From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+		},
+	}}
+
+	resp := qe.Execute(context.Background(), queries)
+
+	if mockService.batchComputeCalls != 0 {
+		t.Fatalf("batch compute calls = %d, want 0 for a dry run", mockService.batchComputeCalls)
+	}
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if len(res.Frames) != 1 || res.Frames[0].Meta == nil {
+		t.Fatalf("expected one frame with metadata, got %+v", res.Frames)
+	}
+	custom, _ := res.Frames[0].Meta.Custom.(map[string]any)
+	if custom["dryRun"] != true {
+		t.Errorf("dryRun meta = %v, want true", custom["dryRun"])
+	}
+	batchRequest, ok := custom["batchComputeRequest"].(computeapi1.BatchComputeWithUnitsRequest)
+	if !ok || len(batchRequest.Requests) != 1 {
+		t.Fatalf("batchComputeRequest meta = %#v, want one subrequest", custom["batchComputeRequest"])
+	}
+	plan := summarizeSeriesFromNode(t, batchRequest.Requests[0].Node)
+	if plan.Buckets == nil || *plan.Buckets != 10 {
+		t.Errorf("buckets = %v, want 10", plan.Buckets)
+	}
+
+	// Frame metadata must survive serialization to the frontend.
+	if _, err := json.Marshal(res.Frames[0].Meta); err != nil {
+		t.Errorf("marshal frame meta: %v", err)
+	}
+}
+
+// dryRunSubrequests returns the number of compute subrequests in a dry-run
+// response's batchComputeRequest metadata, failing t if res is not a dry run.
+func dryRunSubrequests(t *testing.T, res backend.DataResponse) int {
+	t.Helper()
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if len(res.Frames) != 1 || res.Frames[0].Meta == nil {
+		t.Fatalf("expected one dry-run frame with metadata, got %+v", res.Frames)
+	}
+	custom, _ := res.Frames[0].Meta.Custom.(map[string]any)
+	if custom["dryRun"] != true {
+		t.Fatalf("dryRun meta = %v, want true", custom["dryRun"])
+	}
+	batchRequest, ok := custom["batchComputeRequest"].(computeapi1.BatchComputeWithUnitsRequest)
+	if !ok {
+		t.Fatalf("batchComputeRequest meta = %#v, want a batch request", custom["batchComputeRequest"])
+	}
+	return len(batchRequest.Requests)
+}
+
+func TestTransformBatchResultRejectsArrowEnumPlots(t *testing.T) {
+	responses := map[string]computeapi.ComputeNodeResponse{
+		"ArrowEnumPlot":         computeapi.NewComputeNodeResponseFromArrowEnum(computeapi.ArrowEnumPlot{}),
+		"ArrowBucketedEnumPlot": computeapi.NewComputeNodeResponseFromArrowBucketedEnum(computeapi.ArrowBucketedEnumPlot{}),
+	}
+	for name, response := range responses {
+		result := computeapi.ComputeWithUnitsResult{ComputeResult: computeapi.NewComputeNodeResultFromSuccess(response)}
+		res := newTestQueryExecution(&Datasource{}, nil).transformBatchResult(result, NominalQueryModel{Channel: "state", ChannelDataType: ChannelDataTypeString})
+		if res.Error == nil || !strings.Contains(res.Error.Error(), "received "+name+" unexpectedly") {
+			t.Errorf("%s: error = %v, want it rejected as unsupported", name, res.Error)
+		}
+	}
+}
+
+func TestTransformBatchResultRejectsArraySeries(t *testing.T) {
+	result := computeapi.ComputeWithUnitsResult{
+		ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromArray(
+			computeapi.NewArrowArrayPlotFromBucketedNumeric(computeapi.BucketedNumericArrayPlot{ArrowBinary: []byte{}}),
+		)),
+	}
+	res := newTestQueryExecution(&Datasource{}, nil).transformBatchResult(result, NominalQueryModel{Channel: "speed"})
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "array series are not supported") {
+		t.Fatalf("error = %v, want array series are not supported", res.Error)
+	}
+}
+
+func TestQueryJSONSnippetTruncates(t *testing.T) {
+	short := []byte(`{"channel":"temp"}`)
+	if got := queryJSONSnippet(short); got != string(short) {
+		t.Errorf("short snippet = %q, want unchanged", got)
+	}
+
+	long := []byte(`{"channel":"` + strings.Repeat("é", maxQueryJSONSnippetBytes) + `"}`)
+	got := queryJSONSnippet(long)
+	if !strings.HasSuffix(got, "...") {
+		t.Errorf("long snippet %q missing truncation marker", got)
+	}
+	if len(got) > maxQueryJSONSnippetBytes+len("...") {
+		t.Errorf("long snippet length = %d, want <= %d", len(got), maxQueryJSONSnippetBytes+len("..."))
+	}
+	if !utf8.ValidString(got) {
+		t.Errorf("long snippet %q is not valid UTF-8", got)
+	}
 }
 
 func TestQueryDataRoutesQueriesByType(t *testing.T) {
@@ -781,15 +1441,26 @@ type mockComputeService struct {
 	batchComputeError     error
 	batchComputeErrors    []error
 	singleComputeCalls    int
+	// singleComputeFunc, if set, answers Compute calls.
+	singleComputeFunc func(requestArg computeapi1.ComputeNodeRequest) (computeapi.ComputeNodeResponse, error)
 	// batchComputeFunc, if set, is called instead of using the static responses.
 	// Useful for tests with nondeterministic call ordering (e.g. parallel batches).
 	batchComputeFunc func(requestArg computeapi1.BatchComputeWithUnitsRequest) (computeapi.BatchComputeWithUnitsResponse, error)
+	// batchComputeUnitsFunc, if set, answers BatchComputeUnits calls.
+	batchComputeUnitsFunc  func(requestArg computeapi1.BatchComputeUnitsRequest) (computeapi.BatchComputeUnitResult, error)
+	lastBatchUnitsRequest  computeapi1.BatchComputeUnitsRequest
+	batchComputeUnitsCalls int
 }
 
 func (m *mockComputeService) Compute(ctx context.Context, authHeader bearertoken.Token, requestArg computeapi1.ComputeNodeRequest) (computeapi.ComputeNodeResponse, error) {
 	m.mu.Lock()
-	defer m.mu.Unlock()
 	m.singleComputeCalls++
+	singleComputeFunc := m.singleComputeFunc
+	m.mu.Unlock()
+	// Called without the lock so concurrent retries can overlap.
+	if singleComputeFunc != nil {
+		return singleComputeFunc(requestArg)
+	}
 	return computeapi.ComputeNodeResponse{}, nil
 }
 
@@ -826,6 +1497,13 @@ func (m *mockComputeService) BatchComputeWithUnits(ctx context.Context, authHead
 }
 
 func (m *mockComputeService) BatchComputeUnits(ctx context.Context, authHeader bearertoken.Token, requestArg computeapi1.BatchComputeUnitsRequest) (computeapi.BatchComputeUnitResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.batchComputeUnitsCalls++
+	m.lastBatchUnitsRequest = requestArg
+	if m.batchComputeUnitsFunc != nil {
+		return m.batchComputeUnitsFunc(requestArg)
+	}
 	return computeapi.BatchComputeUnitResult{}, nil
 }
 
@@ -956,35 +1634,294 @@ func TestBatchQueryChunksAtSubrequestLimit(t *testing.T) {
 				DecryptedSecureJSONData: map[string]string{"apiKey": "test-key"},
 			},
 		},
-		Queries: queries,
+		Queries: queries,
+	}
+
+	resp, err := ds.QueryData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if mockService.batchComputeCalls != 2 {
+		t.Fatalf("expected 2 batch compute calls, got %d", mockService.batchComputeCalls)
+	}
+	if len(mockService.batchRequests) != 2 {
+		t.Fatalf("expected 2 recorded batch requests, got %d", len(mockService.batchRequests))
+	}
+	if len(mockService.batchRequests[0].Requests) != maxBatchComputeSubrequests {
+		t.Fatalf("expected first chunk size %d, got %d", maxBatchComputeSubrequests, len(mockService.batchRequests[0].Requests))
+	}
+	if len(mockService.batchRequests[1].Requests) != 1 {
+		t.Fatalf("expected second chunk size 1, got %d", len(mockService.batchRequests[1].Requests))
+	}
+	if len(resp.Responses) != len(queries) {
+		t.Fatalf("expected %d responses, got %d", len(queries), len(resp.Responses))
+	}
+
+	for _, q := range queries {
+		response := resp.Responses[q.RefID]
+		if response.Error != nil {
+			t.Fatalf("expected no error for %s, got %v", q.RefID, response.Error)
+		}
+	}
+}
+
+func TestBatchQueryGroupsByTimeRange(t *testing.T) {
+	mockService := &mockComputeService{
+		batchComputeFunc: func(request computeapi1.BatchComputeWithUnitsRequest) (computeapi.BatchComputeWithUnitsResponse, error) {
+			return makeBatchComputeWithUnitsResponse(len(request.Requests)), nil
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	lastHour := backend.TimeRange{From: from, To: from.Add(time.Hour)}
+	lastDay := backend.TimeRange{From: from.Add(-23 * time.Hour), To: from.Add(time.Hour)}
+	queries := makeBatchableQueries(3, lastHour)
+	queries[1].TimeRange = lastDay
+
+	resp := qe.Execute(context.Background(), queries)
+	for _, q := range queries {
+		if err := resp.Responses[q.RefID].Error; err != nil {
+			t.Fatalf("%s: unexpected error: %v", q.RefID, err)
+		}
+	}
+
+	if len(mockService.batchRequests) != 2 {
+		t.Fatalf("batch calls = %d, want one per distinct time range", len(mockService.batchRequests))
+	}
+	for i, want := range []struct {
+		count int
+		start time.Time
+	}{
+		{count: 2, start: lastHour.From},
+		{count: 1, start: lastDay.From},
+	} {
+		requests := mockService.batchRequests[i].Requests
+		if len(requests) != want.count {
+			t.Fatalf("batch %d has %d requests, want %d", i, len(requests), want.count)
+		}
+		for _, request := range requests {
+			if got := int64(request.Start.Seconds); got != want.start.Unix() {
+				t.Errorf("batch %d request start = %d, want %d", i, got, want.start.Unix())
+			}
+		}
+	}
+}
+
+func TestBatchQueryLogsSlowChunks(t *testing.T) {
+	timeRange := backend.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name        string
+		thresholdMs int
+		delay       time.Duration
+		wantWarning bool
+	}{
+		{name: "chunk slower than threshold warns", thresholdMs: 10, delay: 50 * time.Millisecond, wantWarning: true},
+		{name: "chunk faster than threshold is silent", thresholdMs: 10_000, delay: 0, wantWarning: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			mockService := &mockComputeService{
+				batchComputeFunc: func(requestArg computeapi1.BatchComputeWithUnitsRequest) (computeapi.BatchComputeWithUnitsResponse, error) {
+					time.Sleep(tt.delay)
+					return makeBatchComputeWithUnitsResponse(len(requestArg.Requests)), nil
+				},
+			}
+			config := &models.PluginSettings{
+				Secrets:              &models.SecretPluginSettings{ApiKey: "test-key"},
+				SlowQueryThresholdMs: tt.thresholdMs,
+			}
+			qe := newTestQueryExecution(&Datasource{computeService: mockService}, config)
+
+			resp := qe.Execute(context.Background(), makeBatchableQueries(2, timeRange))
+			for refID, res := range resp.Responses {
+				if res.Error != nil {
+					t.Fatalf("unexpected error for %s: %v", refID, res.Error)
+				}
+			}
+
+			warnings := logs.entriesWithMessage("warn", "Slow batch compute chunk")
+			if !tt.wantWarning {
+				if len(warnings) != 0 {
+					t.Fatalf("expected no slow-query warning, got %v", warnings)
+				}
+				return
+			}
+			if len(warnings) != 1 {
+				t.Fatalf("expected 1 slow-query warning, got %d", len(warnings))
+			}
+			args := warnings[0].Args
+			if got, ok := args["refIds"].([]string); !ok || !slices.Equal(got, []string{"Q000", "Q001"}) {
+				t.Errorf("refIds = %v, want [Q000 Q001]", args["refIds"])
+			}
+			if args["chunkSize"] != 2 {
+				t.Errorf("chunkSize = %v, want 2", args["chunkSize"])
+			}
+			if elapsed, ok := args["elapsedMs"].(int64); !ok || elapsed < int64(tt.thresholdMs) {
+				t.Errorf("elapsedMs = %v, want >= %d", args["elapsedMs"], tt.thresholdMs)
+			}
+		})
+	}
+}
+
+func TestDualResolutionQueryReturnsOverviewAndDetailFrames(t *testing.T) {
+	mockService := &mockComputeService{
+		batchComputeFunc: func(requestArg computeapi1.BatchComputeWithUnitsRequest) (computeapi.BatchComputeWithUnitsResponse, error) {
+			results := make([]computeapi.ComputeWithUnitsResult, len(requestArg.Requests))
+			for i, req := range requestArg.Requests {
+				plan := summarizeSeriesFromNode(t, req.Node)
+				if plan.Buckets != nil {
+					results[i] = createMockArrowComputeResult([]float64{1, 2, 3})
+				} else {
+					results[i] = createMockComputeResult([]float64{1, 1.5, 2, 2.5, 3, 3.5, 4, 4.5, 5, 5.5})
+				}
+			}
+			return computeapi.BatchComputeWithUnitsResponse{Results: results}, nil
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	queries := []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:       "ri.nominal.asset.1",
+			Channel:        "temperature",
+			DataScopeName:  "default",
+			Buckets:        3,
+			DualResolution: true,
+		}),
+		TimeRange: backend.TimeRange{
+			From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+		},
+	}}
+
+	resp := qe.Execute(context.Background(), queries)
+
+	if mockService.batchComputeCalls != 1 {
+		t.Fatalf("expected 1 batch compute call, got %d", mockService.batchComputeCalls)
+	}
+	if n := len(mockService.lastBatchRequest.Requests); n != 2 {
+		t.Fatalf("expected 2 subrequests for a dual-resolution query, got %d", n)
+	}
+
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if len(res.Frames) != 2 {
+		t.Fatalf("expected 2 frames, got %d", len(res.Frames))
+	}
+	overview, detail := res.Frames[0], res.Frames[1]
+	if overview.Name != ResolutionRoleOverview || detail.Name != ResolutionRoleDetail {
+		t.Fatalf("frame names = [%q, %q], want [overview, detail]", overview.Name, detail.Name)
+	}
+	if overview.Rows() != 3 {
+		t.Errorf("overview rows = %d, want 3", overview.Rows())
+	}
+	if detail.Rows() != 10 {
+		t.Errorf("detail rows = %d, want 10", detail.Rows())
+	}
+	if got := detail.Fields[1].Config.DisplayNameFromDS; got != "temperature (detail)" {
+		t.Errorf("detail display name = %q, want %q", got, "temperature (detail)")
+	}
+}
+
+func TestChannelTableQueryListsAssetChannels(t *testing.T) {
+	assetRid := "ri.scout.main.asset.abc123"
+	dataSourceRid := "ri.scout.main.data-source.ds1"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid:   assetRid,
+			Title: "Test Asset",
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "default", DataSource: AssetDataSource{Type: "dataset", Dataset: &dataSourceRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	numericType := api.New_SeriesDataType(api.SeriesDataType_DOUBLE)
+	stringType := api.New_SeriesDataType(api.SeriesDataType_STRING)
+	dsRid := rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))
+	mockDS := &mockDatasourceService{
+		searchChannelsResponse: datasourceapi.SearchChannelsResponse{
+			Results: []datasourceapi.ChannelMetadata{
+				{Name: "temperature", DataSource: dsRid, DataType: &numericType, Unit: &runapi.Unit{Symbol: "Cel"}},
+				{Name: "state", DataSource: dsRid, DataType: &stringType},
+				{Name: "temperature", DataSource: dsRid, DataType: &numericType, Unit: &runapi.Unit{Symbol: "Cel"}},
+			},
+		},
+	}
+	mockCompute := &mockComputeService{}
+	ds := &Datasource{
+		computeService:     mockCompute,
+		datasourceService:  mockDS,
+		resourceHTTPClient: server.Client(),
+	}
+	config := &models.PluginSettings{
+		BaseUrl: server.URL,
+		Secrets: &models.SecretPluginSettings{ApiKey: "test-key"},
 	}
 
-	resp, err := ds.QueryData(context.Background(), req)
-	if err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	resp := newTestQueryExecution(ds, config).Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON:  mustMarshal(NominalQueryModel{AssetRid: assetRid, QueryType: queryTypeChannelTable}),
+	}})
+
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if mockCompute.batchComputeCalls != 0 {
+		t.Errorf("channelTable must not call compute, got %d batch calls", mockCompute.batchComputeCalls)
+	}
+	if len(res.Frames) != 1 {
+		t.Fatalf("expected 1 frame, got %d", len(res.Frames))
+	}
+	frame := res.Frames[0]
+	if frame.Meta == nil || frame.Meta.Type != data.FrameTypeTable {
+		t.Errorf("frame meta = %+v, want table frame type", frame.Meta)
 	}
 
-	if mockService.batchComputeCalls != 2 {
-		t.Fatalf("expected 2 batch compute calls, got %d", mockService.batchComputeCalls)
+	wantColumns := []string{"name", "unit", "type"}
+	if len(frame.Fields) != len(wantColumns) {
+		t.Fatalf("expected %d columns, got %d", len(wantColumns), len(frame.Fields))
 	}
-	if len(mockService.batchRequests) != 2 {
-		t.Fatalf("expected 2 recorded batch requests, got %d", len(mockService.batchRequests))
+	for i, want := range wantColumns {
+		if frame.Fields[i].Name != want {
+			t.Errorf("column %d = %q, want %q", i, frame.Fields[i].Name, want)
+		}
 	}
-	if len(mockService.batchRequests[0].Requests) != maxBatchComputeSubrequests {
-		t.Fatalf("expected first chunk size %d, got %d", maxBatchComputeSubrequests, len(mockService.batchRequests[0].Requests))
+	if frame.Rows() != 2 {
+		t.Fatalf("rows = %d, want 2 (duplicate channel names collapse)", frame.Rows())
 	}
-	if len(mockService.batchRequests[1].Requests) != 1 {
-		t.Fatalf("expected second chunk size 1, got %d", len(mockService.batchRequests[1].Requests))
+	if got := frame.Fields[0].At(0); got != "temperature" {
+		t.Errorf("row 0 name = %v, want temperature", got)
 	}
-	if len(resp.Responses) != len(queries) {
-		t.Fatalf("expected %d responses, got %d", len(queries), len(resp.Responses))
+	if got := frame.Fields[1].At(0); got != "Cel" {
+		t.Errorf("row 0 unit = %v, want Cel", got)
+	}
+	if got := frame.Fields[2].At(1); got != ChannelDataTypeString {
+		t.Errorf("row 1 type = %v, want %s", got, ChannelDataTypeString)
 	}
+}
 
-	for _, q := range queries {
-		response := resp.Responses[q.RefID]
-		if response.Error != nil {
-			t.Fatalf("expected no error for %s, got %v", q.RefID, response.Error)
-		}
+func TestChannelTableQueryRequiresAssetRid(t *testing.T) {
+	resp := newTestQueryExecution(&Datasource{}, nil).Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON:  mustMarshal(NominalQueryModel{QueryType: queryTypeChannelTable}),
+	}})
+	res := resp.Responses["A"]
+	if res.Error == nil || res.Status != backend.StatusBadRequest {
+		t.Fatalf("expected bad request error, got status %v error %v", res.Status, res.Error)
 	}
 }
 
@@ -1539,6 +2476,47 @@ func TestBatchQueryWithPartialErrors(t *testing.T) {
 	}
 }
 
+func TestBatchQueryLogsFailedSubrequestsWithRefID(t *testing.T) {
+	logs := captureLogs(t)
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{
+				createMockArrowComputeResult([]float64{1, 2}),
+				createMockErrorResult(404, "CHANNEL_NOT_FOUND"),
+			},
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	timeRange := backend.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+	queries := []backend.DataQuery{
+		{RefID: "A", JSON: mustMarshal(NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temp1", DataScopeName: "ds1", Buckets: 100}), TimeRange: timeRange},
+		{RefID: "B", JSON: mustMarshal(NominalQueryModel{AssetRid: "ri.nominal.asset.2", Channel: "nonexistent", DataScopeName: "ds1", Buckets: 100}), TimeRange: timeRange},
+	}
+	qe.Execute(context.Background(), queries)
+
+	entries := logs.entriesWithMessage("warn", "Batch compute subrequest failed")
+	if len(entries) != 1 {
+		t.Fatalf("got %d subrequest failure logs, want 1", len(entries))
+	}
+	want := map[string]string{
+		"refId":     "B",
+		"index":     "1",
+		"assetRid":  "ri.nominal.asset.2",
+		"channel":   "nonexistent",
+		"errorType": "CHANNEL_NOT_FOUND",
+		"code":      "404",
+	}
+	for key, value := range want {
+		if got := fmt.Sprint(entries[0].Args[key]); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+}
+
 func TestBatchQueryWithMissingResults(t *testing.T) {
 	// Create mock compute service that returns fewer results than queries
 	mockService := &mockComputeService{}
@@ -1630,6 +2608,146 @@ func TestBatchQueryWithMissingResults(t *testing.T) {
 	}
 }
 
+func TestBatchQueryRetriesMissingResultsWithSingleCompute(t *testing.T) {
+	const settingsJSON = `{"baseUrl": "https://api.test.com", "retryMissingBatchResults": true}`
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{
+				createMockArrowComputeResult([]float64{1.0, 2.0, 3.0}),
+				// Missing second result
+			},
+		},
+	}
+	var retried []computeapi1.ComputeNodeRequest
+	mockService.singleComputeFunc = func(requestArg computeapi1.ComputeNodeRequest) (computeapi.ComputeNodeResponse, error) {
+		retried = append(retried, requestArg)
+		return computeapi.NewComputeNodeResponseFromNumeric(computeapi.NumericPlot{
+			Timestamps: []api.Timestamp{testTimestamp(1704067200), testTimestamp(1704067260)},
+			Values:     []float64{7, 8},
+		}), nil
+	}
+
+	ds := &Datasource{
+		settings:       backend.DataSourceInstanceSettings{JSONData: []byte(settingsJSON)},
+		computeService: mockService,
+	}
+	timeRange := backend.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+	req := &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{
+			DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{
+				JSONData:                []byte(settingsJSON),
+				DecryptedSecureJSONData: map[string]string{"apiKey": "test-key"},
+			},
+		},
+		Queries: []backend.DataQuery{
+			{RefID: "A", JSON: mustMarshal(NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temp1", DataScopeName: "ds1", Buckets: 100}), TimeRange: timeRange},
+			{RefID: "B", JSON: mustMarshal(NominalQueryModel{AssetRid: "ri.nominal.asset.2", Channel: "temp2", DataScopeName: "ds1", Buckets: 100}), TimeRange: timeRange},
+		},
+	}
+
+	resp, err := ds.QueryData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if mockService.singleComputeCalls != 1 {
+		t.Fatalf("single compute calls = %d, want 1 for the missing result", mockService.singleComputeCalls)
+	}
+	if want := mockService.lastBatchRequest.Requests[1]; !reflect.DeepEqual(retried[0], want) {
+		t.Errorf("retried request differs from the batch subrequest:\n%+v\nvs\n%+v", retried[0], want)
+	}
+	for _, refID := range []string{"A", "B"} {
+		if r := resp.Responses[refID]; r.Error != nil || len(r.Frames) != 1 {
+			t.Errorf("%s: error = %v, frames = %d; want one frame", refID, r.Error, len(r.Frames))
+		}
+	}
+	valueField, idx := resp.Responses["B"].Frames[0].FieldByName("value")
+	if idx < 0 || valueField.Len() != 2 {
+		t.Fatalf("B value field = %v, want the 2 retried points", valueField)
+	}
+	if got, _ := valueField.ConcreteAt(1); got != 8.0 {
+		t.Errorf("B value[1] = %v, want 8", got)
+	}
+}
+
+func TestBatchQueryRetriesMissingResultsConcurrently(t *testing.T) {
+	const settingsJSON = `{"baseUrl": "https://api.test.com", "retryMissingBatchResults": true}`
+	const queryCount = maxConcurrentSingleComputes + 4
+	mockService := &mockComputeService{
+		// The batch answers none of the queries, so every one is retried.
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{},
+	}
+	var inFlight, maxInFlight, calls atomic.Int32
+	mockService.singleComputeFunc = func(computeapi1.ComputeNodeRequest) (computeapi.ComputeNodeResponse, error) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			prev := maxInFlight.Load()
+			if n <= prev || maxInFlight.CompareAndSwap(prev, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		if calls.Add(1) == 1 {
+			return computeapi.ComputeNodeResponse{}, errors.New("compute unavailable")
+		}
+		return computeapi.NewComputeNodeResponseFromNumeric(computeapi.NumericPlot{
+			Timestamps: []api.Timestamp{testTimestamp(1704067200)},
+			Values:     []float64{1},
+		}), nil
+	}
+
+	ds := &Datasource{
+		settings:       backend.DataSourceInstanceSettings{JSONData: []byte(settingsJSON)},
+		computeService: mockService,
+	}
+	timeRange := backend.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+	queries := make([]backend.DataQuery, queryCount)
+	for i := range queries {
+		queries[i] = backend.DataQuery{
+			RefID:     fmt.Sprintf("Q%d", i),
+			JSON:      mustMarshal(NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: fmt.Sprintf("temp%d", i), DataScopeName: "ds1", Buckets: 100}),
+			TimeRange: timeRange,
+		}
+	}
+	req := &backend.QueryDataRequest{
+		PluginContext: backend.PluginContext{
+			DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{
+				JSONData:                []byte(settingsJSON),
+				DecryptedSecureJSONData: map[string]string{"apiKey": "test-key"},
+			},
+		},
+		Queries: queries,
+	}
+
+	resp, err := ds.QueryData(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := maxInFlight.Load(); got < 2 || got > maxConcurrentSingleComputes {
+		t.Errorf("max concurrent single computes = %d, want between 2 and %d", got, maxConcurrentSingleComputes)
+	}
+	failed := 0
+	for _, q := range queries {
+		r := resp.Responses[q.RefID]
+		if r.Error != nil {
+			failed++
+			continue
+		}
+		if len(r.Frames) != 1 {
+			t.Errorf("%s: frames = %d, want 1", q.RefID, len(r.Frames))
+		}
+	}
+	if failed != 1 {
+		t.Errorf("failed responses = %d, want only the one failed retry", failed)
+	}
+}
+
 func TestBatchQueryWithExtraResultsIgnoresExtras(t *testing.T) {
 	mockService := &mockComputeService{
 		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
@@ -2133,7 +3251,7 @@ func TestExtractionTruncatesToShortestInput(t *testing.T) {
 			Categories: []string{"idle"},
 		}
 
-		times, values, err := exec.extractBucketedEnumDataFromConjure(plot)
+		times, values, err := exec.extractBucketedEnumDataFromConjure(plot, EnumAggMode)
 		if err != nil {
 			t.Fatalf("extract bucketed enum data: %v", err)
 		}
@@ -2197,7 +3315,7 @@ func TestBucketedEnumModeBreaksTiesByLowestIndex(t *testing.T) {
 	// Run repeatedly: Go randomizes map iteration order, so a tie broken by
 	// iteration order would flip between "idle" and "running" across runs.
 	for i := 0; i < 50; i++ {
-		_, values, err := exec.extractBucketedEnumDataFromConjure(plot)
+		_, values, err := exec.extractBucketedEnumDataFromConjure(plot, EnumAggMode)
 		if err != nil {
 			t.Fatalf("extract bucketed enum data: %v", err)
 		}
@@ -2207,6 +3325,291 @@ func TestBucketedEnumModeBreaksTiesByLowestIndex(t *testing.T) {
 	}
 }
 
+func TestBucketedEnumAggregationSelectsBucketValue(t *testing.T) {
+	exec := newTestQueryExecution(&Datasource{}, nil)
+	plot := computeapi.BucketedEnumPlot{
+		Timestamps: []api.Timestamp{testTimestamp(1704067200), testTimestamp(1704067260)},
+		Buckets: []computeapi.EnumBucket{
+			{
+				Histogram:  map[int]safelong.SafeLong{0: 1, 1: 5, 2: 1},
+				FirstPoint: computeapi.CompactEnumPoint{Timestamp: testTimestamp(1704067200), Value: 0},
+				LastPoint:  &computeapi.CompactEnumPoint{Timestamp: testTimestamp(1704067259), Value: 2},
+			},
+			{
+				// Single-point bucket: no LastPoint.
+				Histogram:  map[int]safelong.SafeLong{1: 1},
+				FirstPoint: computeapi.CompactEnumPoint{Timestamp: testTimestamp(1704067260), Value: 1},
+			},
+		},
+		Categories: []string{"idle", "ready", "running"},
+	}
+
+	tests := []struct {
+		aggregation string
+		want        []string
+	}{
+		{aggregation: "", want: []string{"ready", "ready"}},
+		{aggregation: EnumAggMode, want: []string{"ready", "ready"}},
+		{aggregation: AggFirstPoint, want: []string{"idle", "ready"}},
+		{aggregation: AggLastPoint, want: []string{"running", "ready"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.aggregation, func(t *testing.T) {
+			_, values, err := exec.extractBucketedEnumDataFromConjure(plot, tt.aggregation)
+			if err != nil {
+				t.Fatalf("extract bucketed enum data: %v", err)
+			}
+			if !slices.Equal(values, tt.want) {
+				t.Errorf("values = %v, want %v", values, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateEnumAggregation(t *testing.T) {
+	qe := newTestQueryExecution(&Datasource{}, nil)
+	qm := NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "state", DataScopeName: "default", EnumAggregation: "MEDIAN"}
+	err := qe.validateQuery(qm)
+	if err == nil || !strings.Contains(err.Error(), "enumAggregation") {
+		t.Fatalf("validateQuery error = %v, want unsupported enumAggregation", err)
+	}
+	qm.EnumAggregation = AggLastPoint
+	if err := qe.validateQuery(qm); err != nil {
+		t.Fatalf("validateQuery(LAST_POINT) = %v, want nil", err)
+	}
+}
+
+func TestPrepareQueryRejectsEnumAggregationOnInferredNumericChannel(t *testing.T) {
+	const dataSourceRid = "ri.scout.main.data-source.ds1"
+	doubleType := api.New_SeriesDataType(api.SeriesDataType_DOUBLE)
+	mockDS := &mockDatasourceService{
+		searchChannelsResponse: datasourceapi.SearchChannelsResponse{
+			Results: []datasourceapi.ChannelMetadata{{
+				Name:       api.Channel("speed"),
+				DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1")),
+				DataType:   &doubleType,
+			}},
+		},
+	}
+	ds := &Datasource{datasourceService: mockDS, resourceHTTPClient: &http.Client{}}
+	config := &models.PluginSettings{BaseUrl: "https://api.test.com", Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+	query := backend.DataQuery{
+		RefID: "A",
+		JSON:  mustMarshal(NominalQueryModel{DataSourceRid: dataSourceRid, Channel: "speed", EnumAggregation: AggLastPoint}),
+	}
+
+	_, prepErr := newTestQueryExecution(ds, config).prepareQuery(context.Background(), query)
+	if prepErr == nil || prepErr.Status != backend.StatusBadRequest || !strings.Contains(prepErr.Error.Error(), "enumAggregation requires a string channel") {
+		t.Fatalf("prepareQuery error = %v, want enumAggregation rejected for a numeric channel", prepErr)
+	}
+
+	numeric := NominalQueryModel{ChannelDataType: ChannelDataTypeNumeric, EnumAggregation: AggLastPoint}
+	if numeric.isEnumQuery() {
+		t.Errorf("isEnumQuery() = true for a numeric channel with enumAggregation")
+	}
+	untyped := NominalQueryModel{EnumAggregation: AggLastPoint}
+	if !untyped.isEnumQuery() {
+		t.Errorf("isEnumQuery() = false for an untyped channel with enumAggregation")
+	}
+}
+
+func TestBucketedNumericStatFields(t *testing.T) {
+	plot := computeapi.BucketedNumericPlot{
+		Timestamps: []api.Timestamp{testTimestamp(1704067200), testTimestamp(1704067260)},
+		Buckets: []computeapi.NumericBucket{
+			{Mean: 10, Min: 8, Max: 12, Count: 5, Variance: 4},
+			{Mean: 12, Min: 11, Max: 14, Count: 3, Variance: 1},
+		},
+	}
+	result := computeapi.ComputeWithUnitsResult{
+		ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromBucketedNumeric(plot)),
+	}
+	exec := newTestQueryExecution(&Datasource{}, nil)
+
+	resp := exec.transformBatchResult(result, NominalQueryModel{
+		Channel:     "temperature",
+		ChannelUnit: "celsius",
+		StatFields:  []string{StatMin, StatMax, StatCount},
+	})
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	if len(resp.Frames) != 1 {
+		t.Fatalf("frames = %d, want 1", len(resp.Frames))
+	}
+	frame := resp.Frames[0]
+	var names []string
+	for _, field := range frame.Fields {
+		names = append(names, field.Name)
+	}
+	if want := []string{"time", "min", "max", "count"}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("fields = %v, want %v", names, want)
+	}
+
+	want := map[string][]float64{"min": {8, 11}, "max": {12, 14}, "count": {5, 3}}
+	for name, values := range want {
+		field, _ := frame.FieldByName(name)
+		for i, w := range values {
+			got, _ := field.At(i).(*float64)
+			if got == nil || *got != w {
+				t.Errorf("%s[%d] = %v, want %v", name, i, got, w)
+			}
+		}
+	}
+	if countField, _ := frame.FieldByName("count"); countField.Config.Unit != "" {
+		t.Errorf("count unit = %q, want none", countField.Config.Unit)
+	}
+	if maxField, _ := frame.FieldByName("max"); maxField.Config.DisplayNameFromDS != "temperature (max)" {
+		t.Errorf("max display name = %q, want %q", maxField.Config.DisplayNameFromDS, "temperature (max)")
+	}
+}
+
+func TestValidateStatFields(t *testing.T) {
+	qe := newTestQueryExecution(&Datasource{}, nil)
+	qm := NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temperature", DataScopeName: "default", StatFields: []string{"mean", "median"}}
+	if err := qe.validateQuery(qm); err == nil || !strings.Contains(err.Error(), "statFields") {
+		t.Fatalf("validateQuery error = %v, want unsupported statFields entry", err)
+	}
+	qm.StatFields = []string{StatMean, StatStdDev}
+	if err := qe.validateQuery(qm); err != nil {
+		t.Fatalf("validateQuery(mean, stddev) = %v, want nil", err)
+	}
+}
+
+func TestGroupedResultSetsPerGroupLabels(t *testing.T) {
+	groupResponse := func(vehicle string, values []float64) computeapi.GroupedComputeNodeResponse {
+		timestamps := make([]api.Timestamp, len(values))
+		for i := range values {
+			timestamps[i] = testTimestamp(int64(1704067200 + i*60))
+		}
+		return computeapi.GroupedComputeNodeResponse{
+			Grouping: computeapi.NewGroupingFromTagsWithValues(map[string]string{"vehicle": vehicle}),
+			Response: computeapi.NewComputeNodeResponseFromNumeric(computeapi.NumericPlot{Timestamps: timestamps, Values: values}),
+		}
+	}
+	result := computeapi.ComputeWithUnitsResult{
+		ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromGrouped(
+			computeapi.GroupedComputeNodeResponses{Responses: []computeapi.GroupedComputeNodeResponse{
+				groupResponse("rover-1", []float64{1, 2}),
+				groupResponse("rover-2", []float64{3}),
+			}},
+		)),
+	}
+	qm := NominalQueryModel{Channel: "speed", GroupByTags: []string{"vehicle"}}
+	exec := newTestQueryExecution(&Datasource{}, nil)
+
+	resp := exec.transformBatchResult(result, qm)
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	if len(resp.Frames) != 2 {
+		t.Fatalf("frames = %d, want one per group", len(resp.Frames))
+	}
+	for i, want := range []string{"rover-1", "rover-2"} {
+		frame := resp.Frames[i]
+		valueField, idx := frame.FieldByName("value")
+		if idx < 0 {
+			t.Fatalf("frame %d has no value field", i)
+		}
+		if got := valueField.Labels["vehicle"]; got != want {
+			t.Errorf("frame %d vehicle label = %q, want %q", i, got, want)
+		}
+		if timeField, _ := frame.FieldByName("time"); timeField.Labels != nil {
+			t.Errorf("frame %d time field labels = %v, want none", i, timeField.Labels)
+		}
+	}
+
+	requestJSON, err := json.Marshal(exec.buildComputeRequest(qm, backend.TimeRange{From: time.Unix(0, 0), To: time.Unix(60, 0)}, 0))
+	if err != nil {
+		t.Fatalf("marshal compute request: %v", err)
+	}
+	if !strings.Contains(string(requestJSON), `"groupByTags":[{"type":"literal","literal":"vehicle"}]`) {
+		t.Errorf("compute request does not group by vehicle: %s", requestJSON)
+	}
+}
+
+func TestBucketedNumericStdDevField(t *testing.T) {
+	plot := computeapi.BucketedNumericPlot{
+		Timestamps: []api.Timestamp{testTimestamp(1704067200), testTimestamp(1704067260)},
+		Buckets: []computeapi.NumericBucket{
+			{Mean: 10, Variance: 4},
+			{Mean: 12, Variance: 0},
+		},
+	}
+	result := computeapi.ComputeWithUnitsResult{
+		ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromBucketedNumeric(plot)),
+	}
+	exec := newTestQueryExecution(&Datasource{}, nil)
+
+	t.Run("includeStdDev adds stddev field", func(t *testing.T) {
+		resp := exec.transformBatchResult(result, NominalQueryModel{Channel: "temperature", IncludeStdDev: true})
+		if resp.Error != nil {
+			t.Fatalf("unexpected error: %v", resp.Error)
+		}
+		frame := resp.Frames[0]
+		field, idx := frame.FieldByName("stddev")
+		if idx < 0 {
+			t.Fatalf("expected stddev field, got fields %v", frame.Fields)
+		}
+		want := []float64{2, 0}
+		for i, w := range want {
+			got, _ := field.At(i).(*float64)
+			if got == nil || *got != w {
+				t.Errorf("stddev[%d] = %v, want %v", i, got, w)
+			}
+		}
+		if name := field.Config.DisplayNameFromDS; name != "temperature (stddev)" {
+			t.Errorf("stddev display name = %q, want %q", name, "temperature (stddev)")
+		}
+	})
+
+	t.Run("arrow path derives stddev from variance", func(t *testing.T) {
+		qm := NominalQueryModel{Channel: "temperature", IncludeStdDev: true}
+		if prepErr := normalizeAggregations(&qm); prepErr != nil {
+			t.Fatalf("unexpected error: %v", prepErr.Error)
+		}
+		if want := []string{AggMean, AggVariance}; !reflect.DeepEqual(qm.Aggregations, want) {
+			t.Fatalf("aggregations = %v, want %v", qm.Aggregations, want)
+		}
+		arrowPlot := computeapi.ArrowBucketedNumericPlot{ArrowBinary: createTestArrowMultiAgg(
+			[]int64{1704067200000000000, 1704067260000000000},
+			map[string][]float64{"mean": {10, 12}, "variance": {4, 0}},
+		)}
+		arrowResult := computeapi.ComputeWithUnitsResult{
+			ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromArrowBucketedNumeric(arrowPlot)),
+		}
+		resp := exec.transformBatchResult(arrowResult, qm)
+		if resp.Error != nil {
+			t.Fatalf("unexpected error: %v", resp.Error)
+		}
+		if len(resp.Frames) != 1 {
+			t.Fatalf("frames = %d, want one frame with value and stddev", len(resp.Frames))
+		}
+		frame := resp.Frames[0]
+		for name, want := range map[string][]float64{"value": {10, 12}, "stddev": {2, 0}} {
+			field, idx := frame.FieldByName(name)
+			if idx < 0 {
+				t.Fatalf("missing %s field, got fields %v", name, frame.Fields)
+			}
+			for i, w := range want {
+				if got, _ := field.At(i).(*float64); got == nil || *got != w {
+					t.Errorf("%s[%d] = %v, want %v", name, i, got, w)
+				}
+			}
+		}
+	})
+
+	t.Run("stddev omitted by default", func(t *testing.T) {
+		resp := exec.transformBatchResult(result, NominalQueryModel{Channel: "temperature"})
+		if resp.Error != nil {
+			t.Fatalf("unexpected error: %v", resp.Error)
+		}
+		if _, idx := resp.Frames[0].FieldByName("stddev"); idx >= 0 {
+			t.Error("stddev field present without includeStdDev")
+		}
+	})
+}
+
 func TestEnumPointTransformation(t *testing.T) {
 	ds := &Datasource{}
 
@@ -0,0 +1,64 @@
+package plugin
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+)
+
+// unhandledTypeCounts counts the union variants the plugin met but does not
+// handle, keyed by "<kind>:<type name>", so forward-compatibility gaps show up
+// in diagnostics instead of only in debug logs. The zero value is ready to use.
+type unhandledTypeCounts struct {
+	mu     sync.Mutex
+	counts map[string]int64
+}
+
+func (c *unhandledTypeCounts) record(kind, typeName string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.counts == nil {
+		c.counts = make(map[string]int64)
+	}
+	c.counts[kind+":"+typeName]++
+}
+
+// snapshot returns a copy of the counts; it is never nil.
+func (c *unhandledTypeCounts) snapshot() map[string]int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make(map[string]int64, len(c.counts))
+	for key, count := range c.counts {
+		out[key] = count
+	}
+	return out
+}
+
+// recordUnhandledType logs an unhandled union variant of kind and counts it on
+// the datasource.
+func (e *NominalQueryExecution) recordUnhandledType(kind, typeName string) {
+	log.DefaultLogger.Debug("Unhandled "+kind+" type", "type", typeName)
+	if e == nil || e.datasource == nil {
+		return
+	}
+	e.datasource.unhandledTypes.record(kind, typeName)
+}
+
+type diagnosticsResponse struct {
+	// UnhandledResponseTypes counts, since the datasource instance started,
+	// each compute result variant the plugin could not transform.
+	UnhandledResponseTypes map[string]int64 `json:"unhandledResponseTypes"`
+}
+
+// handleDiagnostics handles the diagnostics endpoint, returning the
+// datasource instance's runtime counters for support and telemetry.
+func (h *NominalResourceHandler) handleDiagnostics(req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	if ok, err := requireGet(req, sender); !ok {
+		return err
+	}
+	return jsonMarshalResponse(sender, http.StatusOK, diagnosticsResponse{
+		UnhandledResponseTypes: h.datasource.unhandledTypes.snapshot(),
+	})
+}
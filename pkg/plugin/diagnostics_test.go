@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestDiagnosticsCountsUnhandledResponseTypes(t *testing.T) {
+	var unknown computeapi.ComputeNodeResponse
+	if err := json.Unmarshal([]byte(`{"type":"futurePlot","futurePlot":{}}`), &unknown); err != nil {
+		t.Fatalf("unmarshal unknown response: %v", err)
+	}
+	mockCompute := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{
+				{ComputeResult: computeapi.NewComputeNodeResultFromSuccess(unknown)},
+			},
+		},
+	}
+	ds := newTestDatasource("https://api.test.com", &mockAuthService{}, &mockDatasourceService{})
+	ds.computeService = mockCompute
+
+	query := backend.DataQuery{
+		RefID: "A",
+		JSON:  mustMarshal(NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temperature", DataScopeName: "default"}),
+		TimeRange: backend.TimeRange{
+			From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+		},
+	}
+	for range 2 {
+		if _, err := ds.QueryData(context.Background(), &backend.QueryDataRequest{
+			PluginContext: backend.PluginContext{DataSourceInstanceSettings: &ds.settings},
+			Queries:       []backend.DataQuery{query},
+		}); err != nil {
+			t.Fatalf("QueryData: %v", err)
+		}
+	}
+
+	resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "diagnostics", Method: http.MethodGet})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", resp.Status, resp.Body)
+	}
+	var diagnostics diagnosticsResponse
+	if err := json.Unmarshal(resp.Body, &diagnostics); err != nil {
+		t.Fatalf("decode diagnostics: %v", err)
+	}
+	if got := diagnostics.UnhandledResponseTypes["response:futurePlot"]; got != 2 {
+		t.Errorf("unhandledResponseTypes = %v, want response:futurePlot counted twice", diagnostics.UnhandledResponseTypes)
+	}
+}
@@ -0,0 +1,139 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// maxReturnedPoints caps the points kept per numeric series. The backend
+// normally honours the requested bucket count, but raw responses over a long
+// range can be far larger than a browser can render.
+const maxReturnedPoints = 50000
+
+// decimationIndexes returns limit indexes spread evenly over [0, n), always
+// including the first and last, or nil when n is within limit.
+func decimationIndexes(n, limit int) []int {
+	if n <= limit || limit < 2 {
+		return nil
+	}
+	indexes := make([]int, limit)
+	for i := range indexes {
+		indexes[i] = int(int64(i) * int64(n-1) / int64(limit-1))
+	}
+	return indexes
+}
+
+// decimateSeries keeps only the points at indexes. Both slices must be the
+// same length as the series being decimated.
+func decimateSeries(timePoints []time.Time, values []*float64, indexes []int) ([]time.Time, []*float64) {
+	outTimes := make([]time.Time, len(indexes))
+	outValues := make([]*float64, len(indexes))
+	for i, idx := range indexes {
+		outTimes[i] = timePoints[idx]
+		outValues[i] = values[idx]
+	}
+	return outTimes, outValues
+}
+
+// strideIndexes returns every stride-th index of [0, n) plus the last, or nil
+// when stride keeps every point.
+func strideIndexes(n, stride int) []int {
+	if stride <= 1 || n == 0 {
+		return nil
+	}
+	indexes := make([]int, 0, (n-1)/stride+2)
+	for i := 0; i < n; i += stride {
+		indexes = append(indexes, i)
+	}
+	if indexes[len(indexes)-1] != n-1 {
+		indexes = append(indexes, n-1)
+	}
+	return indexes
+}
+
+// capNumericPoints decimates every numeric series in result that exceeds
+// maxReturnedPoints and records the largest original size in DecimatedFrom.
+func capNumericPoints(result *TransformResult) {
+	decimatedFrom := decimateNumericSeries(result, func(n int) []int {
+		return decimationIndexes(n, maxReturnedPoints)
+	})
+	result.DecimatedFrom = max(result.DecimatedFrom, decimatedFrom)
+	if result.DecimatedFrom > 0 {
+		log.DefaultLogger.Warn("Numeric result exceeded point cap; downsampled",
+			"points", result.DecimatedFrom,
+			"cap", maxReturnedPoints,
+		)
+	}
+}
+
+// strideNumericPoints keeps every stride-th point of each numeric series in
+// result, plus the last, for a query's explicit Stride. Unlike capNumericPoints
+// this is requested, so DecimatedFrom is left alone.
+func strideNumericPoints(result *TransformResult, stride int) {
+	decimateNumericSeries(result, func(n int) []int { return strideIndexes(n, stride) })
+}
+
+// decimateNumericSeries keeps the points selectIndexes picks from every numeric
+// series in result (nil keeps the series whole) and returns the largest
+// original size of a series it decimated.
+func decimateNumericSeries(result *TransformResult, selectIndexes func(n int) []int) int {
+	decimatedFrom := 0
+	if n := len(result.TimePoints); n == len(result.NumericValues) {
+		if indexes := selectIndexes(n); indexes != nil {
+			if len(result.StdDevValues) == n {
+				_, result.StdDevValues = decimateSeries(result.TimePoints, result.StdDevValues, indexes)
+			}
+			for i := range result.StatSeries {
+				if len(result.StatSeries[i].Values) == n {
+					_, result.StatSeries[i].Values = decimateSeries(result.TimePoints, result.StatSeries[i].Values, indexes)
+				}
+			}
+			result.TimePoints, result.NumericValues = decimateSeries(result.TimePoints, result.NumericValues, indexes)
+			decimatedFrom = n
+		}
+	}
+	for i := range result.AggSeries {
+		series := &result.AggSeries[i]
+		n := len(series.TimePoints)
+		if n != len(series.Values) {
+			continue
+		}
+		if indexes := selectIndexes(n); indexes != nil {
+			series.TimePoints, series.Values = decimateSeries(series.TimePoints, series.Values, indexes)
+			decimatedFrom = max(decimatedFrom, n)
+		}
+	}
+	return decimatedFrom
+}
+
+// decimationNotice is the frame warning shown when capNumericPoints downsampled a result.
+func decimationNotice(originalPoints int) data.Notice {
+	return data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text: fmt.Sprintf("Nominal returned %d points, more than the %d point limit; the series was downsampled evenly. Narrow the time range or lower the bucket count for full detail.",
+			originalPoints, maxReturnedPoints),
+	}
+}
+
+// strideTruncationNotice is the frame warning shown when a strided query's raw
+// fetch hit maxReturnedPoints, so later points in the range were not returned.
+func strideTruncationNotice() data.Notice {
+	return data.Notice{
+		Severity: data.NoticeSeverityWarning,
+		Text: fmt.Sprintf("Nominal returned the %d raw point limit before striding, so the series may end before the time range does. Narrow the time range or remove stride for full coverage.",
+			maxReturnedPoints),
+	}
+}
+
+// appendFrameNotice adds notice to every frame, creating Meta as needed.
+func appendFrameNotice(frames data.Frames, notice data.Notice) {
+	for _, frame := range frames {
+		if frame.Meta == nil {
+			frame.Meta = &data.FrameMeta{}
+		}
+		frame.Meta.Notices = append(frame.Meta.Notices, notice)
+	}
+}
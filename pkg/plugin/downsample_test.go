@@ -0,0 +1,125 @@
+package plugin
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestDecimationIndexesKeepsFirstAndLast(t *testing.T) {
+	if got := decimationIndexes(10, 10); got != nil {
+		t.Fatalf("decimationIndexes(10, 10) = %v, want nil", got)
+	}
+
+	got := decimationIndexes(101, 5)
+	want := []int{0, 25, 50, 75, 100}
+	if len(got) != len(want) {
+		t.Fatalf("decimationIndexes(101, 5) = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("decimationIndexes(101, 5) = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestTransformBatchResultCapsOversizedNumericResult(t *testing.T) {
+	n := maxReturnedPoints*2 + 1
+	values := make([]float64, n)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	qm := NominalQueryModel{Channel: "temperature"}
+
+	res := newTestQueryExecution(&Datasource{}, nil).transformBatchResult(createMockComputeResult(values), qm)
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	frame := res.Frames[0]
+	if frame.Rows() != maxReturnedPoints {
+		t.Fatalf("rows = %d, want %d", frame.Rows(), maxReturnedPoints)
+	}
+
+	valueField := frame.Fields[1]
+	first, _ := valueField.At(0).(*float64)
+	last, _ := valueField.At(valueField.Len() - 1).(*float64)
+	if first == nil || *first != 0 || last == nil || *last != float64(n-1) {
+		t.Errorf("first/last = %v/%v, want 0/%d", first, last, n-1)
+	}
+
+	if frame.Meta == nil || len(frame.Meta.Notices) != 1 {
+		t.Fatalf("expected one notice, got meta %+v", frame.Meta)
+	}
+	notice := frame.Meta.Notices[0]
+	if notice.Severity != data.NoticeSeverityWarning || !strings.Contains(notice.Text, "downsampled") {
+		t.Errorf("notice = %+v, want a downsampling warning", notice)
+	}
+}
+
+func TestTransformBatchResultLeavesSmallResultAlone(t *testing.T) {
+	res := newTestQueryExecution(&Datasource{}, nil).transformBatchResult(createMockComputeResult([]float64{1, 2, 3}), NominalQueryModel{Channel: "temperature"})
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if rows := res.Frames[0].Rows(); rows != 3 {
+		t.Errorf("rows = %d, want 3", rows)
+	}
+	if meta := res.Frames[0].Meta; meta != nil && len(meta.Notices) > 0 {
+		t.Errorf("unexpected notices: %+v", meta.Notices)
+	}
+}
+
+func TestStrideKeepsEveryNthPointAndEndpoints(t *testing.T) {
+	values := make([]float64, 10)
+	for i := range values {
+		values[i] = float64(i)
+	}
+	qm := NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temperature", DataScopeName: "default", Stride: 4}
+	exec := newTestQueryExecution(&Datasource{}, nil)
+
+	res := exec.transformBatchResult(createMockComputeResult(values), qm)
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	frame := res.Frames[0]
+	// Indexes 0, 4, 8 plus the last point, 9.
+	if frame.Rows() != 4 {
+		t.Fatalf("rows = %d, want 4", frame.Rows())
+	}
+	valueField := frame.Fields[1]
+	for i, want := range []float64{0, 4, 8, 9} {
+		if got, _ := valueField.At(i).(*float64); got == nil || *got != want {
+			t.Errorf("value[%d] = %v, want %v", i, got, want)
+		}
+	}
+	if meta := frame.Meta; meta != nil && len(meta.Notices) > 0 {
+		t.Errorf("requested stride should not add a downsampling notice: %+v", meta.Notices)
+	}
+
+	planJSON, err := json.Marshal(exec.buildSeriesPlan(qm, 100))
+	if err != nil {
+		t.Fatalf("marshal plan: %v", err)
+	}
+	if !strings.Contains(string(planJSON), `"truncate"`) || strings.Contains(string(planJSON), `"buckets"`) {
+		t.Errorf("strided query should request truncated raw points, got %s", planJSON)
+	}
+
+	t.Run("raw fetch at the point limit warns of truncation", func(t *testing.T) {
+		res := exec.transformBatchResult(createMockComputeResult(make([]float64, maxReturnedPoints)), qm)
+		if res.Error != nil {
+			t.Fatalf("unexpected error: %v", res.Error)
+		}
+		meta := res.Frames[0].Meta
+		if meta == nil || len(meta.Notices) != 1 || !strings.Contains(meta.Notices[0].Text, "remove stride") {
+			t.Errorf("notices = %+v, want one truncation warning", meta)
+		}
+	})
+
+	bad := qm
+	bad.Stride = -1
+	if err := exec.validateQuery(bad); err == nil || !strings.Contains(err.Error(), "stride must be >= 0") {
+		t.Errorf("err = %v, want stride validation error", err)
+	}
+}
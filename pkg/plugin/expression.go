@@ -0,0 +1,238 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+	computeapi1 "github.com/nominal-io/nominal-api-go/scout/compute/api1"
+)
+
+// channelExpression is a QueryText arithmetic expression over asset channels,
+// rewritten for an ArithmeticSeries: each distinct channel becomes a local
+// variable (ch0, ch1, ...) and Expression is fully parenthesized.
+//
+// Syntax: numbers, channel references, + - * /, unary minus, and parentheses.
+// A channel reference is a bare name ([A-Za-z_][A-Za-z0-9_.]*) or a
+// double-quoted name for channels containing other characters, e.g.
+// `("engine temp" - ambient.temp) * 1.8`.
+type channelExpression struct {
+	Expression string
+	Channels   []string
+}
+
+// expressionVariableName is the ArithmeticSeries input name for the i-th
+// distinct channel of an expression.
+func expressionVariableName(i int) computeapi.LocalVariableName {
+	return computeapi.LocalVariableName(fmt.Sprintf("ch%d", i))
+}
+
+// parseChannelExpression parses text into a channelExpression. It returns an
+// error for a syntax error or an expression that references no channel.
+func parseChannelExpression(text string) (channelExpression, error) {
+	tokens, err := tokenizeExpression(text)
+	if err != nil {
+		return channelExpression{}, err
+	}
+	p := &expressionParser{tokens: tokens, variables: map[string]computeapi.LocalVariableName{}}
+	expr, err := p.parseSum()
+	if err != nil {
+		return channelExpression{}, err
+	}
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return channelExpression{}, fmt.Errorf("queryText: unexpected %q at position %d", tok.text, tok.pos)
+	}
+	if len(p.channels) == 0 {
+		return channelExpression{}, fmt.Errorf("queryText: expression must reference at least one channel")
+	}
+	return channelExpression{Expression: expr, Channels: p.channels}, nil
+}
+
+// buildArithmeticSeries binds each channel of expr to its local variable on
+// the query's asset and data scope.
+func (e *NominalQueryExecution) buildArithmeticSeries(expr channelExpression, dataScopeName string) computeapi1.NumericSeries {
+	inputs := make(map[computeapi.LocalVariableName]computeapi1.NumericSeries, len(expr.Channels))
+	for i, channel := range expr.Channels {
+		channelSeries := computeapi.NewChannelSeriesFromAsset(e.buildAssetChannel(channel, dataScopeName))
+		inputs[expressionVariableName(i)] = computeapi1.NewNumericSeriesFromChannel(channelSeries)
+	}
+	return computeapi1.NewNumericSeriesFromArithmetic(computeapi1.ArithmeticSeries{
+		Inputs:     inputs,
+		Expression: expr.Expression,
+	})
+}
+
+type expressionTokenKind int
+
+const (
+	tokenEOF expressionTokenKind = iota
+	tokenNumber
+	tokenChannel
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+)
+
+type expressionToken struct {
+	kind expressionTokenKind
+	text string
+	pos  int
+}
+
+func isChannelNameStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isChannelNamePart(r rune) bool {
+	return r == '_' || r == '.' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+func tokenizeExpression(text string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case strings.ContainsRune("+-*/", r):
+			tokens = append(tokens, expressionToken{kind: tokenOperator, text: string(r), pos: i})
+			i++
+		case r == '(':
+			tokens = append(tokens, expressionToken{kind: tokenLeftParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, expressionToken{kind: tokenRightParen, text: ")", pos: i})
+			i++
+		case r == '"':
+			end := i + 1
+			for end < len(runes) && runes[end] != '"' {
+				end++
+			}
+			if end == len(runes) {
+				return nil, fmt.Errorf("queryText: unterminated quoted channel name at position %d", i)
+			}
+			name := string(runes[i+1 : end])
+			if strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("queryText: empty quoted channel name at position %d", i)
+			}
+			tokens = append(tokens, expressionToken{kind: tokenChannel, text: name, pos: i})
+			i = end + 1
+		case unicode.IsDigit(r) || r == '.':
+			end := i
+			for end < len(runes) && (unicode.IsDigit(runes[end]) || runes[end] == '.') {
+				end++
+			}
+			number := string(runes[i:end])
+			if _, err := strconv.ParseFloat(number, 64); err != nil {
+				return nil, fmt.Errorf("queryText: invalid number %q at position %d", number, i)
+			}
+			tokens = append(tokens, expressionToken{kind: tokenNumber, text: number, pos: i})
+			i = end
+		case isChannelNameStart(r):
+			end := i
+			for end < len(runes) && isChannelNamePart(runes[end]) {
+				end++
+			}
+			tokens = append(tokens, expressionToken{kind: tokenChannel, text: string(runes[i:end]), pos: i})
+			i = end
+		default:
+			return nil, fmt.Errorf("queryText: unexpected character %q at position %d", r, i)
+		}
+	}
+	return append(tokens, expressionToken{kind: tokenEOF, text: "end of expression", pos: len(runes)}), nil
+}
+
+// expressionParser is a recursive-descent parser over the standard
+// precedence: sum := product (('+'|'-') product)*; product := unary
+// (('*'|'/') unary)*; unary := '-' unary | primary.
+type expressionParser struct {
+	tokens    []expressionToken
+	next      int
+	variables map[string]computeapi.LocalVariableName
+	channels  []string
+}
+
+func (p *expressionParser) peek() expressionToken {
+	return p.tokens[p.next]
+}
+
+func (p *expressionParser) advance() expressionToken {
+	tok := p.tokens[p.next]
+	if tok.kind != tokenEOF {
+		p.next++
+	}
+	return tok
+}
+
+func (p *expressionParser) parseSum() (string, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return "", err
+	}
+	for tok := p.peek(); tok.kind == tokenOperator && (tok.text == "+" || tok.text == "-"); tok = p.peek() {
+		p.advance()
+		right, err := p.parseProduct()
+		if err != nil {
+			return "", err
+		}
+		left = "(" + left + " " + tok.text + " " + right + ")"
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseProduct() (string, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return "", err
+	}
+	for tok := p.peek(); tok.kind == tokenOperator && (tok.text == "*" || tok.text == "/"); tok = p.peek() {
+		p.advance()
+		right, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		left = "(" + left + " " + tok.text + " " + right + ")"
+	}
+	return left, nil
+}
+
+func (p *expressionParser) parseUnary() (string, error) {
+	if tok := p.peek(); tok.kind == tokenOperator && tok.text == "-" {
+		p.advance()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return "", err
+		}
+		return "(-" + operand + ")", nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *expressionParser) parsePrimary() (string, error) {
+	tok := p.advance()
+	switch tok.kind {
+	case tokenNumber:
+		return tok.text, nil
+	case tokenChannel:
+		variable, ok := p.variables[tok.text]
+		if !ok {
+			variable = expressionVariableName(len(p.channels))
+			p.variables[tok.text] = variable
+			p.channels = append(p.channels, tok.text)
+		}
+		return string(variable), nil
+	case tokenLeftParen:
+		inner, err := p.parseSum()
+		if err != nil {
+			return "", err
+		}
+		if closing := p.advance(); closing.kind != tokenRightParen {
+			return "", fmt.Errorf("queryText: expected \")\" at position %d, got %q", closing.pos, closing.text)
+		}
+		return inner, nil
+	}
+	return "", fmt.Errorf("queryText: unexpected %q at position %d", tok.text, tok.pos)
+}
@@ -0,0 +1,146 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestParseChannelExpression(t *testing.T) {
+	tests := []struct {
+		name         string
+		text         string
+		wantExpr     string
+		wantChannels []string
+		wantErr      string
+	}{
+		{
+			name:         "sum of two channels",
+			text:         "left.current + right.current",
+			wantExpr:     "(ch0 + ch1)",
+			wantChannels: []string{"left.current", "right.current"},
+		},
+		{
+			name:         "precedence and repeated channel",
+			text:         `("engine temp" - ambient) * 1.8 / ambient`,
+			wantExpr:     "(((ch0 - ch1) * 1.8) / ch1)",
+			wantChannels: []string{"engine temp", "ambient"},
+		},
+		{
+			name:         "unary minus",
+			text:         "-speed + 2",
+			wantExpr:     "((-ch0) + 2)",
+			wantChannels: []string{"speed"},
+		},
+		{name: "constant only", text: "1 + 2", wantErr: "at least one channel"},
+		{name: "unbalanced parenthesis", text: "(a + b", wantErr: `expected ")"`},
+		{name: "dangling operator", text: "a +", wantErr: "unexpected"},
+		{name: "unknown character", text: "a % b", wantErr: "unexpected character"},
+		{name: "unterminated quote", text: `"a + b`, wantErr: "unterminated"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseChannelExpression(tt.text)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Expression != tt.wantExpr {
+				t.Errorf("Expression = %q, want %q", got.Expression, tt.wantExpr)
+			}
+			if !reflect.DeepEqual(got.Channels, tt.wantChannels) {
+				t.Errorf("Channels = %v, want %v", got.Channels, tt.wantChannels)
+			}
+		})
+	}
+}
+
+func TestExpressionQueryComputesArithmeticOverTwoChannels(t *testing.T) {
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{3, 5})},
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      "ri.scout.main.asset.1",
+			DataScopeName: "default",
+			QueryText:     "voltage * current",
+		}),
+		TimeRange: backend.TimeRange{
+			From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+		},
+	}})
+
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if mockService.batchComputeCalls != 1 || len(mockService.lastBatchRequest.Requests) != 1 {
+		t.Fatalf("expected one batch compute call with one request, got %d calls", mockService.batchComputeCalls)
+	}
+	requestJSON, err := json.Marshal(mockService.lastBatchRequest.Requests[0].Node)
+	if err != nil {
+		t.Fatalf("marshal node: %v", err)
+	}
+	for _, want := range []string{
+		`"expression":"(ch0 * ch1)"`,
+		`"ch0":{"type":"channel"`,
+		`"literal":"voltage"`,
+		`"literal":"current"`,
+	} {
+		if !strings.Contains(string(requestJSON), want) {
+			t.Errorf("compute node missing %s: %s", want, requestJSON)
+		}
+	}
+
+	if len(res.Frames) != 1 || res.Frames[0].Name != "voltage * current" {
+		t.Fatalf("frames = %v, want one frame named after the expression", res.Frames)
+	}
+	if res.Frames[0].Rows() != 2 {
+		t.Errorf("rows = %d, want 2", res.Frames[0].Rows())
+	}
+}
+
+func TestExpressionQueryValidation(t *testing.T) {
+	qe := newTestQueryExecution(&Datasource{}, nil)
+	for _, tt := range []struct {
+		name    string
+		model   NominalQueryModel
+		wantErr string
+	}{
+		{
+			name:    "requires data scope",
+			model:   NominalQueryModel{AssetRid: "ri.scout.main.asset.1", QueryText: "a + b"},
+			wantErr: "dataScopeName is required for queryText expressions",
+		},
+		{
+			name:    "rejects syntax errors",
+			model:   NominalQueryModel{AssetRid: "ri.scout.main.asset.1", DataScopeName: "default", QueryText: "a + * b"},
+			wantErr: "queryText",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			_, errResp := qe.prepareQuery(context.Background(), backend.DataQuery{RefID: "A", JSON: mustMarshal(tt.model)})
+			if errResp == nil || !strings.Contains(errResp.Error.Error(), tt.wantErr) {
+				t.Fatalf("prepareQuery error = %+v, want containing %q", errResp, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+)
+
+// FillPolicy values control how null values in a numeric series (buckets with
+// no data) are rendered. An empty FillPolicy behaves like FillPolicyNone.
+const (
+	FillPolicyNone     = "none"
+	FillPolicyPrevious = "previous"
+	FillPolicyZero     = "zero"
+	FillPolicyLinear   = "linear"
+)
+
+// validateFillPolicy returns an error for an unrecognised fill policy.
+func validateFillPolicy(policy string) error {
+	switch policy {
+	case "", FillPolicyNone, FillPolicyPrevious, FillPolicyZero, FillPolicyLinear:
+		return nil
+	}
+	return fmt.Errorf("unsupported fillPolicy %q; valid options are none, previous, zero, linear", policy)
+}
+
+// applyFillPolicy replaces nil values in place according to policy and returns
+// values. FillPolicyPrevious carries the last non-nil value forward; leading
+// nils stay nil because there is nothing to carry. FillPolicyLinear
+// interpolates each run of nils between two values (see fillLinear); runs at
+// either end stay nil. Filled entries get their own pointers so no two entries
+// alias the same value.
+func applyFillPolicy(values []*float64, times []time.Time, policy string) []*float64 {
+	switch policy {
+	case FillPolicyPrevious:
+		var last *float64
+		for i, v := range values {
+			if v != nil {
+				last = v
+				continue
+			}
+			if last != nil {
+				filled := *last
+				values[i] = &filled
+			}
+		}
+	case FillPolicyZero:
+		for i, v := range values {
+			if v == nil {
+				zero := 0.0
+				values[i] = &zero
+			}
+		}
+	case FillPolicyLinear:
+		fillLinear(values, times)
+	}
+	return values
+}
+
+// fillLinear fills each run of nils bounded by values on both sides with the
+// straight line between them, placed by timestamp when times parallels values
+// and by index otherwise.
+func fillLinear(values []*float64, times []time.Time) {
+	byTime := len(times) == len(values)
+	prev := -1
+	for i, v := range values {
+		if v == nil {
+			continue
+		}
+		if prev >= 0 && i-prev > 1 {
+			from, to := *values[prev], *v
+			for j := prev + 1; j < i; j++ {
+				frac := float64(j-prev) / float64(i-prev)
+				if byTime && times[i].After(times[prev]) {
+					frac = float64(times[j].Sub(times[prev])) / float64(times[i].Sub(times[prev]))
+				}
+				filled := from + (to-from)*frac
+				values[j] = &filled
+			}
+		}
+		prev = i
+	}
+}
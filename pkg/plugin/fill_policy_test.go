@@ -0,0 +1,124 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func floatPtrs(values ...any) []*float64 {
+	out := make([]*float64, len(values))
+	for i, v := range values {
+		if f, ok := v.(float64); ok {
+			out[i] = &f
+		}
+	}
+	return out
+}
+
+func derefValues(values []*float64) []any {
+	out := make([]any, len(values))
+	for i, v := range values {
+		if v != nil {
+			out[i] = *v
+		}
+	}
+	return out
+}
+
+func TestApplyFillPolicy(t *testing.T) {
+	tests := []struct {
+		policy string
+		in     []*float64
+		want   []any
+	}{
+		{policy: "", in: floatPtrs(1.0, nil, 3.0), want: []any{1.0, nil, 3.0}},
+		{policy: FillPolicyNone, in: floatPtrs(1.0, nil, 3.0), want: []any{1.0, nil, 3.0}},
+		{policy: FillPolicyPrevious, in: floatPtrs(1.0, nil, nil, 4.0), want: []any{1.0, 1.0, 1.0, 4.0}},
+		{policy: FillPolicyPrevious, in: floatPtrs(nil, 2.0, nil), want: []any{nil, 2.0, 2.0}},
+		{policy: FillPolicyZero, in: floatPtrs(nil, 2.0, nil), want: []any{0.0, 2.0, 0.0}},
+		{policy: FillPolicyLinear, in: floatPtrs(nil, 1.0, nil, nil, 4.0, nil), want: []any{nil, 1.0, 2.0, 3.0, 4.0, nil}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			got := derefValues(applyFillPolicy(tt.in, nil, tt.policy))
+			if len(got) != len(tt.want) {
+				t.Fatalf("len = %d, want %d", len(got), len(tt.want))
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("values = %v, want %v", got, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
+
+func TestApplyFillPolicyPreviousDoesNotAlias(t *testing.T) {
+	values := applyFillPolicy(floatPtrs(1.0, nil), nil, FillPolicyPrevious)
+	*values[1] = 99
+	if *values[0] != 1 {
+		t.Fatalf("mutating a filled value changed its source to %v", *values[0])
+	}
+}
+
+func TestApplyFillPolicyLinearInterpolatesByTime(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	// Uneven spacing: the gap sits a quarter of the way from 0s to 40s.
+	times := []time.Time{base, base.Add(10 * time.Second), base.Add(40 * time.Second)}
+	got := derefValues(applyFillPolicy(floatPtrs(0.0, nil, 8.0), times, FillPolicyLinear))
+	if got[1] != 2.0 {
+		t.Errorf("values = %v, want the interior gap interpolated to 2", got)
+	}
+}
+
+func TestValidateFillPolicy(t *testing.T) {
+	for _, policy := range []string{"", FillPolicyNone, FillPolicyPrevious, FillPolicyZero, FillPolicyLinear} {
+		if err := validateFillPolicy(policy); err != nil {
+			t.Errorf("validateFillPolicy(%q) = %v, want nil", policy, err)
+		}
+	}
+	if err := validateFillPolicy("spline"); err == nil {
+		t.Error("validateFillPolicy(\"spline\") = nil, want error")
+	}
+}
+
+func TestTransformBatchResultAppliesFillPolicy(t *testing.T) {
+	timestamps := []int64{1704067200000000000, 1704067260000000000, 1704067320000000000}
+	arrowBytes := createTestArrowBucketedNumeric(timestamps, []float64{5, 0, 7}, []bool{false, true, false})
+	computeResponse := computeapi.NewComputeNodeResponseFromArrowBucketedNumeric(computeapi.ArrowBucketedNumericPlot{ArrowBinary: arrowBytes})
+	result := computeapi.ComputeWithUnitsResult{ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeResponse)}
+
+	tests := []struct {
+		policy string
+		want   []any
+	}{
+		{policy: FillPolicyNone, want: []any{5.0, nil, 7.0}},
+		{policy: FillPolicyPrevious, want: []any{5.0, 5.0, 7.0}},
+		{policy: FillPolicyZero, want: []any{5.0, 0.0, 7.0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			qm := NominalQueryModel{Channel: "temperature", Aggregations: []string{AggMean}, FillPolicy: tt.policy}
+			res := newTestQueryExecution(&Datasource{}, nil).transformBatchResult(result, qm)
+			if res.Error != nil {
+				t.Fatalf("unexpected error: %v", res.Error)
+			}
+			field := res.Frames[0].Fields[1]
+			got := make([]*float64, field.Len())
+			for i := range got {
+				got[i], _ = field.At(i).(*float64)
+			}
+			gotValues := derefValues(got)
+			for i := range tt.want {
+				if gotValues[i] != tt.want[i] {
+					t.Fatalf("values = %v, want %v", gotValues, tt.want)
+				}
+			}
+		})
+	}
+}
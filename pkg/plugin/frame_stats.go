@@ -0,0 +1,67 @@
+package plugin
+
+import (
+	"math"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// addFrameStats appends min, max, avg, and count of each numeric field to its
+// frame's Meta.Stats, for panels that show summary footers. Null and NaN
+// values (e.g. gapValue sentinels) are skipped; count is the number of values
+// that remain, and a field with none gets only its count. In a frame with
+// several numeric fields each stat's display name is prefixed with the field
+// name, and every stat carries its field's unit.
+func addFrameStats(frames data.Frames) {
+	for _, frame := range frames {
+		var numeric []*data.Field
+		for _, field := range frame.Fields {
+			if field.Type().Numeric() {
+				numeric = append(numeric, field)
+			}
+		}
+		for _, field := range numeric {
+			prefix := ""
+			if len(numeric) > 1 {
+				prefix = field.Name + " "
+			}
+			unit := ""
+			if field.Config != nil {
+				unit = field.Config.Unit
+			}
+			stat := func(name string, value float64) data.QueryStat {
+				return data.QueryStat{FieldConfig: data.FieldConfig{DisplayName: prefix + name, Unit: unit}, Value: value}
+			}
+
+			var sum, lo, hi float64
+			count := 0
+			for row := 0; row < field.Len(); row++ {
+				v, err := field.NullableFloatAt(row)
+				if err != nil || v == nil || math.IsNaN(*v) {
+					continue
+				}
+				if count == 0 {
+					lo, hi = *v, *v
+				}
+				lo, hi = min(lo, *v), max(hi, *v)
+				sum += *v
+				count++
+			}
+
+			if frame.Meta == nil {
+				frame.Meta = &data.FrameMeta{}
+			}
+			if count > 0 {
+				frame.Meta.Stats = append(frame.Meta.Stats,
+					stat("min", lo),
+					stat("max", hi),
+					stat("avg", sum/float64(count)),
+				)
+			}
+			// Count is dimensionless.
+			countStat := stat("count", float64(count))
+			countStat.Unit = ""
+			frame.Meta.Stats = append(frame.Meta.Stats, countStat)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package plugin
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func statsByName(stats []data.QueryStat) map[string]data.QueryStat {
+	out := make(map[string]data.QueryStat, len(stats))
+	for _, stat := range stats {
+		out[stat.DisplayName] = stat
+	}
+	return out
+}
+
+func TestTransformBatchResultIncludeStats(t *testing.T) {
+	exec := newTestQueryExecution(&Datasource{}, nil)
+	qm := NominalQueryModel{Channel: "speed", ChannelUnit: "m/s", IncludeStats: true}
+	res := exec.transformBatchResult(createMockComputeResult([]float64{1, 7, 4}), qm)
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+
+	meta := res.Frames[0].Meta
+	if meta == nil || len(meta.Stats) != 4 {
+		t.Fatalf("meta = %+v, want four stats", meta)
+	}
+	stats := statsByName(meta.Stats)
+	for name, want := range map[string]float64{"min": 1, "max": 7, "avg": 4, "count": 3} {
+		if got, ok := stats[name]; !ok || got.Value != want {
+			t.Errorf("%s = %+v, want %v", name, got, want)
+		}
+	}
+	if stats["max"].Unit == "" {
+		t.Errorf("max unit is empty, want the channel unit")
+	}
+	if stats["count"].Unit != "" {
+		t.Errorf("count unit = %q, want none", stats["count"].Unit)
+	}
+
+	qm.IncludeStats = false
+	if meta := exec.transformBatchResult(createMockComputeResult([]float64{1, 7, 4}), qm).Frames[0].Meta; meta != nil && len(meta.Stats) > 0 {
+		t.Errorf("stats = %+v, want none without includeStats", meta.Stats)
+	}
+}
+
+func TestAddFrameStatsSkipsNullsAndPrefixesFields(t *testing.T) {
+	one, three, nan := 1.0, 3.0, math.NaN()
+	frame := data.NewFrame("speed",
+		data.NewField("time", nil, []time.Time{time.Unix(0, 0), time.Unix(60, 0), time.Unix(120, 0)}),
+		data.NewField("min", nil, []*float64{&one, nil, &three}),
+		data.NewField("max", nil, []*float64{nil, &nan, nil}),
+	)
+	addFrameStats(data.Frames{frame})
+
+	stats := statsByName(frame.Meta.Stats)
+	if got := stats["min avg"].Value; got != 2 {
+		t.Errorf("min avg = %v, want 2 over the non-null values", got)
+	}
+	if got := stats["min count"].Value; got != 2 {
+		t.Errorf("min count = %v, want 2", got)
+	}
+	if _, ok := stats["max avg"]; ok {
+		t.Errorf("max avg = %+v, want no value stats for a field with only nulls and NaN", stats["max avg"])
+	}
+	if got, ok := stats["max count"]; !ok || got.Value != 0 {
+		t.Errorf("max count = %+v, want 0", got)
+	}
+}
@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// validateGapValue returns an error for a GapValue that is not a number (NaN
+// and ±Inf included) or is set on a query without evenly spaced buckets to
+// detect gaps against.
+func validateGapValue(qm NominalQueryModel) error {
+	if qm.GapValue == "" {
+		return nil
+	}
+	if _, err := strconv.ParseFloat(qm.GapValue, 64); err != nil {
+		return fmt.Errorf("gapValue must be a number or NaN, got %q", qm.GapValue)
+	}
+	if qm.isEnumQuery() || qm.ChannelDataType == ChannelDataTypeLog || qm.QueryType == queryTypeGaps {
+		return fmt.Errorf("gapValue requires a numeric channel")
+	}
+	if qm.Stride > 0 || qm.DualResolution {
+		return fmt.Errorf("gapValue requires a bucketed query (no stride or dualResolution)")
+	}
+	if qm.Reduce != "" {
+		return fmt.Errorf("gapValue cannot be combined with reduce")
+	}
+	return nil
+}
+
+// applyGapValue inserts a sentinel point at every bucket missing from each
+// numeric series in result: wherever consecutive timestamps are at least one
+// and a half intervals apart, one point per skipped bucket, stepped by
+// interval from the earlier timestamp. It runs after applyFillPolicy, so the
+// fill policy only touches buckets the API returned as null and never the
+// inserted sentinels.
+//
+// FIRST_POINT/LAST_POINT series are left alone: their timestamps are each
+// point's own time rather than a bucket end, so their spacing says nothing
+// about empty buckets.
+func applyGapValue(result *TransformResult, sentinel float64, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	if n := len(result.TimePoints); n == len(result.NumericValues) {
+		if times, sources := gapInsertions(result.TimePoints, interval); sources != nil {
+			result.NumericValues = insertSentinels(result.NumericValues, sources, sentinel)
+			if len(result.StdDevValues) == n {
+				result.StdDevValues = insertSentinels(result.StdDevValues, sources, sentinel)
+			}
+			for i := range result.StatSeries {
+				if len(result.StatSeries[i].Values) == n {
+					result.StatSeries[i].Values = insertSentinels(result.StatSeries[i].Values, sources, sentinel)
+				}
+			}
+			result.TimePoints = times
+		}
+	}
+	for i := range result.AggSeries {
+		series := &result.AggSeries[i]
+		if series.Name == aggSpecs[AggFirstPoint].Name || series.Name == aggSpecs[AggLastPoint].Name ||
+			len(series.TimePoints) != len(series.Values) {
+			continue
+		}
+		if times, sources := gapInsertions(series.TimePoints, interval); sources != nil {
+			series.Values = insertSentinels(series.Values, sources, sentinel)
+			series.TimePoints = times
+		}
+	}
+}
+
+// gapInsertions returns times with a timestamp added for each bucket missing
+// between consecutive entries, and for each returned row the index of its
+// source row in times, or -1 for an inserted one. Both are nil when nothing
+// is missing. A gap counts only when it is at least one and a half intervals
+// wide, so bucket timestamps that are not an exact multiple apart do not
+// produce spurious points.
+func gapInsertions(times []time.Time, interval time.Duration) ([]time.Time, []int) {
+	out := make([]time.Time, 0, len(times))
+	sources := make([]int, 0, len(times))
+	inserted := false
+	for i, t := range times {
+		if i > 0 && t.Sub(times[i-1]) >= interval+interval/2 {
+			for missing := times[i-1].Add(interval); t.Sub(missing) >= interval/2; missing = missing.Add(interval) {
+				out = append(out, missing)
+				sources = append(sources, -1)
+				inserted = true
+			}
+		}
+		out = append(out, t)
+		sources = append(sources, i)
+	}
+	if !inserted {
+		return nil, nil
+	}
+	return out, sources
+}
+
+// insertSentinels lays values out along sources (see gapInsertions), giving
+// each inserted row its own pointer to sentinel.
+func insertSentinels(values []*float64, sources []int, sentinel float64) []*float64 {
+	out := make([]*float64, len(sources))
+	for i, source := range sources {
+		if source >= 0 {
+			out[i] = values[source]
+			continue
+		}
+		v := sentinel
+		out[i] = &v
+	}
+	return out
+}
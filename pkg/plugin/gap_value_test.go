@@ -0,0 +1,120 @@
+package plugin
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/nominal-io/nominal-api-go/io/nominal/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestGapValueInsertsSentinelAtGaps(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{{
+				ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromNumeric(computeapi.NumericPlot{
+					// Buckets at 1m and 4m are present; 2m and 3m are missing.
+					Timestamps: []api.Timestamp{testTimestamp(from.Add(time.Minute).Unix()), testTimestamp(from.Add(4 * time.Minute).Unix())},
+					Values:     []float64{1, 4},
+				})),
+			}},
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      "ri.nominal.asset.1",
+			Channel:       "speed",
+			DataScopeName: "default",
+			Buckets:       60,
+			GapValue:      "NaN",
+		}),
+		TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+	}})
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+
+	frame := res.Frames[0]
+	if rows, _ := frame.RowLen(); rows != 4 {
+		t.Fatalf("rows = %d, want 4 (two points and two sentinels)", rows)
+	}
+	for i, minute := range []time.Duration{1, 2, 3, 4} {
+		if got := frame.Fields[0].At(i).(time.Time); !got.Equal(from.Add(minute * time.Minute)) {
+			t.Errorf("time[%d] = %v, want %v", i, got, from.Add(minute*time.Minute))
+		}
+	}
+	values := make([]*float64, 4)
+	for i := range values {
+		values[i] = frame.Fields[1].At(i).(*float64)
+	}
+	if values[0] == nil || *values[0] != 1 || values[3] == nil || *values[3] != 4 {
+		t.Errorf("values = %v, want the returned points kept", derefValues(values))
+	}
+	for _, i := range []int{1, 2} {
+		if values[i] == nil || !math.IsNaN(*values[i]) {
+			t.Errorf("value[%d] = %v, want NaN sentinel", i, values[i])
+		}
+	}
+}
+
+func TestApplyGapValueRunsAfterFillPolicy(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	result := TransformResult{
+		TimePoints:    []time.Time{from, from.Add(time.Minute), from.Add(3 * time.Minute)},
+		NumericValues: applyFillPolicy(floatPtrs(1.0, nil, 3.0), nil, FillPolicyPrevious),
+		AggSeries: []AggregationSeries{
+			{Name: "max", TimePoints: []time.Time{from, from.Add(2 * time.Minute)}, Values: floatPtrs(1.0, 2.0)},
+			{Name: "first", TimePoints: []time.Time{from, from.Add(2 * time.Minute)}, Values: floatPtrs(1.0, 2.0)},
+		},
+	}
+	applyGapValue(&result, -1, time.Minute)
+
+	if got := derefValues(result.NumericValues); len(got) != 4 || got[1] != 1.0 || got[2] != -1.0 {
+		t.Errorf("values = %v, want the null filled and the missing bucket set to the sentinel", got)
+	}
+	if got := derefValues(result.AggSeries[0].Values); len(got) != 3 || got[1] != -1.0 {
+		t.Errorf("max = %v, want a sentinel at the missing bucket", got)
+	}
+	if got := len(result.AggSeries[1].Values); got != 2 {
+		t.Errorf("first has %d points, want its own timestamps left alone", got)
+	}
+}
+
+func TestValidateGapValue(t *testing.T) {
+	tests := []struct {
+		name      string
+		qm        NominalQueryModel
+		wantError string
+	}{
+		{name: "unset", qm: NominalQueryModel{}},
+		{name: "number", qm: NominalQueryModel{GapValue: "-1"}},
+		{name: "NaN", qm: NominalQueryModel{GapValue: "NaN"}},
+		{name: "not a number", qm: NominalQueryModel{GapValue: "none"}, wantError: "number or NaN"},
+		{name: "enum", qm: NominalQueryModel{GapValue: "0", ChannelDataType: ChannelDataTypeString}, wantError: "numeric channel"},
+		{name: "stride", qm: NominalQueryModel{GapValue: "0", Stride: 2}, wantError: "bucketed query"},
+		{name: "reduce", qm: NominalQueryModel{GapValue: "0", Reduce: ReduceAvg}, wantError: "reduce"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGapValue(tt.qm)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("err = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
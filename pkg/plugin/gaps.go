@@ -0,0 +1,112 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+	computeapi1 "github.com/nominal-io/nominal-api-go/scout/compute/api1"
+)
+
+// defaultGapThreshold is the minimum gap length when neither GapThresholdMs
+// nor a bucket width is available to derive one from.
+const defaultGapThreshold = time.Second
+
+// maxGapRanges caps the gaps returned for one query. Above it the API answers
+// with a RangesSummary, which is reported as an error.
+const maxGapRanges = 2000
+
+// validateGapQuery checks the options that do not apply to a gaps query: it
+// reads one channel's raw data availability, so there is nothing to stride,
+// shift, or bucket.
+func validateGapQuery(qm NominalQueryModel) error {
+	if qm.GapThresholdMs < 0 || int64(qm.GapThresholdMs) > maxDurationMs {
+		return fmt.Errorf("gapThresholdMs must be between 0 and %d, got %d", maxDurationMs, qm.GapThresholdMs)
+	}
+	if qm.QueryType != queryTypeGaps {
+		return nil
+	}
+	if !qm.hasChannelQuery() {
+		return fmt.Errorf("gaps queries require a channel")
+	}
+	if qm.Stride > 0 || qm.DualResolution || qm.IncludeBucketEdges {
+		return fmt.Errorf("gaps queries cannot be combined with stride, dualResolution, or includeBucketEdges")
+	}
+	if qm.TimeShift != "" {
+		return fmt.Errorf("gaps queries cannot be combined with timeShift")
+	}
+	return nil
+}
+
+// gapThreshold is the minimum gap length for a gaps query: GapThresholdMs when
+// set, otherwise the width of one bucket, so a gap is reported wherever a
+// bucketed panel over the same range would show an empty bucket.
+func gapThreshold(qm NominalQueryModel, timeRange backend.TimeRange, maxDataPoints int64) time.Duration {
+	if qm.GapThresholdMs > 0 {
+		return time.Duration(qm.GapThresholdMs) * time.Millisecond
+	}
+	if interval := bucketInterval(timeRange, effectiveBucketCount(qm, maxDataPoints)); interval > 0 {
+		return interval
+	}
+	return defaultGapThreshold
+}
+
+// buildGapsNode asks for the ranges in which the channel has no data for at
+// least the query's GapThreshold.
+func (e *NominalQueryExecution) buildGapsNode(qm NominalQueryModel) computeapi1.ComputableNode {
+	channelSeries := e.buildChannelSeries(qm)
+
+	var series computeapi1.Series
+	switch {
+	case qm.ChannelDataType == ChannelDataTypeLog:
+		series = computeapi1.NewSeriesFromLog(computeapi1.NewLogSeriesFromChannel(channelSeries))
+	case qm.isEnumQuery():
+		series = computeapi1.NewSeriesFromEnum(computeapi1.NewEnumSeriesFromChannel(channelSeries))
+	default:
+		series = computeapi1.NewSeriesFromNumeric(computeapi1.NewNumericSeriesFromChannel(channelSeries))
+	}
+
+	stale := computeapi1.StaleRanges{
+		Input:     series,
+		Threshold: computeapi1.NewDurationConstantFromLiteral(durationFromTime(qm.GapThreshold)),
+	}
+	maxRanges := maxGapRanges
+	return computeapi1.NewComputableNodeFromRanges(computeapi1.SummarizeRanges{
+		Input:     computeapi1.NewRangeSeriesFromStaleRange(stale),
+		MaxRanges: &maxRanges,
+	})
+}
+
+// extractGapRanges converts the API's stale ranges into parallel start/end
+// slices. A missing bound (a gap still open at either end) decodes as nil.
+func extractGapRanges(ranges []computeapi.Range) ([]*time.Time, []*time.Time) {
+	starts := make([]*time.Time, len(ranges))
+	ends := make([]*time.Time, len(ranges))
+	for i, r := range ranges {
+		if r.Start != nil {
+			start := time.Unix(int64(r.Start.Seconds), int64(r.Start.Nanos))
+			starts[i] = &start
+		}
+		if r.End != nil {
+			end := time.Unix(int64(r.End.Seconds), int64(r.End.Nanos))
+			ends[i] = &end
+		}
+	}
+	return starts, ends
+}
+
+// buildGapsFrame renders gaps as a table frame named after the channel, one
+// row per gap: "start" and "end" (nullable times) bound the range with no data.
+func buildGapsFrame(result TransformResult, qm NominalQueryModel) *data.Frame {
+	frame := data.NewFrame(qm.Channel,
+		data.NewField("start", nil, result.GapStarts),
+		data.NewField("end", nil, result.GapEnds),
+	)
+	frame.Meta = &data.FrameMeta{
+		Type:                   data.FrameTypeTable,
+		PreferredVisualization: data.VisTypeTable,
+	}
+	return frame
+}
@@ -0,0 +1,162 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/nominal-io/nominal-api-go/io/nominal/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestGapsQueryReturnsGapRanges(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	ts := func(offset time.Duration) *api.Timestamp {
+		stamp := testTimestamp(from.Add(offset).Unix())
+		return &stamp
+	}
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{{
+				ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromRange([]computeapi.Range{
+					{Start: ts(10 * time.Minute), End: ts(25 * time.Minute)},
+					{Start: ts(50 * time.Minute)},
+				})),
+			}},
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			QueryType:     queryTypeGaps,
+			AssetRid:      "ri.nominal.asset.1",
+			Channel:       "speed",
+			DataScopeName: "default",
+			Buckets:       4,
+		}),
+		TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+	}})
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+
+	frame := res.Frames[0]
+	if frame.Name != "speed" || frame.Meta == nil || frame.Meta.Type != data.FrameTypeTable {
+		t.Fatalf("frame = %q %+v, want table frame named speed", frame.Name, frame.Meta)
+	}
+	startField, _ := frame.FieldByName("start")
+	endField, _ := frame.FieldByName("end")
+	if startField == nil || endField == nil || startField.Len() != 2 {
+		t.Fatalf("fields = %v, want start and end with 2 rows", frame.Fields)
+	}
+	if got := startField.At(0).(*time.Time); got == nil || !got.Equal(from.Add(10*time.Minute)) {
+		t.Errorf("start[0] = %v, want %v", got, from.Add(10*time.Minute))
+	}
+	if got := endField.At(0).(*time.Time); got == nil || !got.Equal(from.Add(25*time.Minute)) {
+		t.Errorf("end[0] = %v, want %v", got, from.Add(25*time.Minute))
+	}
+	if got := endField.At(1).(*time.Time); got != nil {
+		t.Errorf("end[1] = %v, want nil for a gap still open", got)
+	}
+
+	// The request asks for stale ranges at least one bucket (15m) long.
+	raw, err := json.Marshal(mockService.lastBatchRequest.Requests[0].Node)
+	if err != nil {
+		t.Fatalf("marshal node: %v", err)
+	}
+	var node struct {
+		Ranges struct {
+			Input struct {
+				Type       string `json:"type"`
+				StaleRange struct {
+					Threshold struct {
+						Literal struct {
+							Seconds int64 `json:"seconds"`
+						} `json:"literal"`
+					} `json:"threshold"`
+				} `json:"staleRange"`
+			} `json:"input"`
+		} `json:"ranges"`
+	}
+	if err := json.Unmarshal(raw, &node); err != nil {
+		t.Fatalf("unmarshal node: %v", err)
+	}
+	if node.Ranges.Input.Type != "staleRange" {
+		t.Fatalf("node = %s, want a staleRange ranges node", raw)
+	}
+	if got := node.Ranges.Input.StaleRange.Threshold.Literal.Seconds; got != 15*60 {
+		t.Errorf("threshold = %ds, want %ds", got, 15*60)
+	}
+}
+
+func TestGapsQueryTooManyGaps(t *testing.T) {
+	mockService := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{{
+				ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromRangesSummary(computeapi.RangesSummary{
+					RangesSummary: []computeapi.RangeSummary{{SubRangeCount: 1500}, {SubRangeCount: 1500}},
+				})),
+			}},
+		},
+	}
+	qe := newTestQueryExecution(&Datasource{computeService: mockService}, nil)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			QueryType:      queryTypeGaps,
+			AssetRid:       "ri.nominal.asset.1",
+			Channel:        "speed",
+			DataScopeName:  "default",
+			GapThresholdMs: 1000,
+		}),
+		TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+	}})
+	res := resp.Responses["A"]
+	if res.Error == nil || !strings.Contains(res.Error.Error(), "found 3000 gaps") {
+		t.Fatalf("error = %v, want too many gaps", res.Error)
+	}
+}
+
+func TestValidateGapQuery(t *testing.T) {
+	gaps := NominalQueryModel{QueryType: queryTypeGaps, AssetRid: "ri.nominal.asset.1", Channel: "speed"}
+	with := func(edit func(*NominalQueryModel)) NominalQueryModel {
+		qm := gaps
+		edit(&qm)
+		return qm
+	}
+	tests := []struct {
+		name      string
+		qm        NominalQueryModel
+		wantError string
+	}{
+		{name: "gaps", qm: gaps},
+		{name: "not gaps", qm: NominalQueryModel{Stride: 5}},
+		{name: "negative threshold", qm: with(func(qm *NominalQueryModel) { qm.GapThresholdMs = -1 }), wantError: "gapThresholdMs must be between 0 and"},
+		{name: "no channel", qm: with(func(qm *NominalQueryModel) { qm.Channel = "" }), wantError: "require a channel"},
+		{name: "stride", qm: with(func(qm *NominalQueryModel) { qm.Stride = 2 }), wantError: "cannot be combined with stride"},
+		{name: "time shift", qm: with(func(qm *NominalQueryModel) { qm.TimeShift = "1h" }), wantError: "timeShift"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateGapQuery(tt.qm)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("err = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -0,0 +1,174 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+)
+
+// healthTimings records how long each health check phase took, in order, so
+// a slow or failing check shows where the time went.
+type healthTimings struct {
+	mu     sync.Mutex
+	phases []healthPhase
+	// serverDate is the Date header of the base-URL probe response, and
+	// probedAt the local time it is compared against; zero when absent.
+	serverDate time.Time
+	probedAt   time.Time
+}
+
+type healthPhase struct {
+	name     string
+	duration time.Duration
+}
+
+func (t *healthTimings) record(phase string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.phases = append(t.phases, healthPhase{name: phase, duration: d})
+}
+
+// annotate appends the recorded timings to a health check message.
+func (t *healthTimings) annotate(message string) string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.phases) == 0 {
+		return message
+	}
+	formatted := make([]string, len(t.phases))
+	for i, phase := range t.phases {
+		formatted[i] = fmt.Sprintf("%s=%.1fms", phase.name, durationMs(phase.duration))
+	}
+	return fmt.Sprintf("%s (timings: %s)", message, strings.Join(formatted, ", "))
+}
+
+// healthDetails is the CheckHealthResult JSONDetails payload, for automation
+// that checks health without parsing the message. LatencyMs is the round trip
+// of the authenticated API call, omitted when the check failed before making
+// it; User is omitted unless authentication succeeded.
+type healthDetails struct {
+	BaseURL     string             `json:"baseUrl"`
+	User        string             `json:"user,omitempty"`
+	LatencyMs   *float64           `json:"latencyMs,omitempty"`
+	TimingsMs   map[string]float64 `json:"timingsMs"`
+	ClockSkewMs *float64           `json:"clockSkewMs,omitempty"`
+}
+
+// jsonDetails encodes the recorded timings as health check JSONDetails. A
+// phase recorded more than once reports its last duration.
+func (t *healthTimings) jsonDetails(baseURL, user string) []byte {
+	t.mu.Lock()
+	details := healthDetails{BaseURL: baseURL, User: user, TimingsMs: make(map[string]float64, len(t.phases))}
+	for _, phase := range t.phases {
+		ms := durationMs(phase.duration)
+		details.TimingsMs[phase.name] = ms
+		if phase.name == "auth" {
+			details.LatencyMs = &ms
+		}
+	}
+	t.mu.Unlock()
+	if skew, ok := t.clockSkew(); ok {
+		ms := durationMs(skew)
+		details.ClockSkewMs = &ms
+	}
+
+	encoded, err := json.Marshal(details)
+	if err != nil {
+		return nil
+	}
+	return encoded
+}
+
+func durationMs(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+// probeBaseURL issues an unauthenticated GET to baseURL to time name
+// resolution and connection setup apart from authentication. Any HTTP response
+// means the API is reachable; only transport errors are returned. It records a
+// "dns" phase when a lookup happened and a "connect" phase for the whole probe,
+// and keeps the response's Date header for the clock skew check.
+func probeBaseURL(ctx context.Context, client *http.Client, baseURL string, timings *healthTimings) error {
+	var dnsStart time.Time
+	trace := &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			if !dnsStart.IsZero() {
+				timings.record("dns", time.Since(dnsStart))
+			}
+		},
+	}
+	req, err := http.NewRequestWithContext(httptrace.WithClientTrace(ctx, trace), http.MethodGet, baseURL, nil)
+	if err != nil {
+		return fmt.Errorf("invalid base URL: %w", err)
+	}
+
+	start := time.Now()
+	resp, err := client.Do(req)
+	elapsed := time.Since(start)
+	timings.record("connect", elapsed)
+	if err != nil {
+		return err
+	}
+	if date, err := http.ParseTime(resp.Header.Get("Date")); err == nil {
+		timings.mu.Lock()
+		// The server stamped Date somewhere within the round trip.
+		timings.serverDate, timings.probedAt = date, start.Add(elapsed/2)
+		timings.mu.Unlock()
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	resp.Body.Close()
+	return nil
+}
+
+// clockSkew returns how far the local clock is ahead of the API server's
+// (negative when behind), and false when the probe saw no Date header.
+func (t *healthTimings) clockSkew() (time.Duration, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.serverDate.IsZero() {
+		return 0, false
+	}
+	return t.probedAt.Sub(t.serverDate), true
+}
+
+// clockSkewThreshold is the configured clock skew warning threshold, zero
+// when the check is disabled.
+func clockSkewThreshold(config *models.PluginSettings) time.Duration {
+	if config == nil || config.ClockSkewThresholdMs <= 0 {
+		return 0
+	}
+	return time.Duration(config.ClockSkewThresholdMs) * time.Millisecond
+}
+
+// clockSkewWarning returns a health message suffix when the clock skew check
+// is enabled and the local clock differs from the server's by more than the
+// threshold, and "" otherwise. The Date header has one-second resolution, so
+// small thresholds can warn on skew under a second.
+func (t *healthTimings) clockSkewWarning(config *models.PluginSettings) string {
+	threshold := clockSkewThreshold(config)
+	skew, ok := t.clockSkew()
+	if threshold <= 0 || !ok {
+		return ""
+	}
+	direction := "ahead of"
+	magnitude := skew
+	if skew < 0 {
+		direction, magnitude = "behind", -skew
+	}
+	if magnitude <= threshold {
+		return ""
+	}
+	log.DefaultLogger.Warn("Local clock differs from the Nominal API server", "skew", skew, "threshold", threshold)
+	return fmt.Sprintf("; warning: the local clock is %s %s the Nominal API server, so relative time ranges such as \"now\" may return no data",
+		magnitude.Round(time.Second), direction)
+}
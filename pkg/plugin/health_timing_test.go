@@ -0,0 +1,174 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	authapi "github.com/nominal-io/nominal-api-go/authentication/api"
+	conjurehttpclient "github.com/palantir/conjure-go-runtime/v2/conjure-go-client/httpclient"
+)
+
+func TestCheckHealthReportsPhaseTimings(t *testing.T) {
+	checkHealth := func(t *testing.T, profileStatus int) *backend.CheckHealthResult {
+		t.Helper()
+		var probed bool
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				// The unauthenticated base-URL probe.
+				probed = true
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(profileStatus)
+			_, _ = w.Write([]byte(`{"rid":"ri.authn.main.user.1","displayName":"tester","email":"t@example.com"}`))
+		}))
+		t.Cleanup(srv.Close)
+
+		conjureClient, err := conjurehttpclient.NewClient(conjurehttpclient.WithBaseURLs([]string{srv.URL}))
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		ds := &Datasource{
+			authService:        authapi.NewAuthenticationServiceV2Client(conjureClient),
+			resourceHTTPClient: srv.Client(),
+		}
+		result, err := ds.CheckHealth(context.Background(), &backend.CheckHealthRequest{
+			PluginContext: backend.PluginContext{
+				DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{
+					JSONData:                []byte(`{"baseUrl": "` + srv.URL + `"}`),
+					DecryptedSecureJSONData: map[string]string{"apiKey": "test-key"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CheckHealth returned err: %v", err)
+		}
+		if !probed {
+			t.Error("expected an unauthenticated base-URL probe")
+		}
+		return result
+	}
+
+	t.Run("success", func(t *testing.T) {
+		result := checkHealth(t, http.StatusOK)
+		if result.Status != backend.HealthStatusOk {
+			t.Fatalf("Status = %v (%q), want HealthStatusOk", result.Status, result.Message)
+		}
+		for _, phase := range []string{"timings: ", "connect=", "auth="} {
+			if !strings.Contains(result.Message, phase) {
+				t.Errorf("Message = %q, missing %q", result.Message, phase)
+			}
+		}
+
+		var details healthDetails
+		if err := json.Unmarshal(result.JSONDetails, &details); err != nil {
+			t.Fatalf("JSONDetails = %s: %v", result.JSONDetails, err)
+		}
+		if details.User != "tester" {
+			t.Errorf("user = %q, want %q", details.User, "tester")
+		}
+		if details.LatencyMs == nil || *details.LatencyMs != details.TimingsMs["auth"] {
+			t.Errorf("latencyMs = %v, want the auth timing %v", details.LatencyMs, details.TimingsMs["auth"])
+		}
+		if _, ok := details.TimingsMs["connect"]; !ok || details.BaseURL == "" {
+			t.Errorf("details = %+v, want baseUrl and a connect timing", details)
+		}
+	})
+
+	t.Run("auth failure", func(t *testing.T) {
+		result := checkHealth(t, http.StatusUnauthorized)
+		if result.Status != backend.HealthStatusError {
+			t.Fatalf("Status = %v, want HealthStatusError", result.Status)
+		}
+		for _, phase := range []string{"connect=", "auth="} {
+			if !strings.Contains(result.Message, phase) {
+				t.Errorf("Message = %q, missing %q", result.Message, phase)
+			}
+		}
+
+		var details healthDetails
+		if err := json.Unmarshal(result.JSONDetails, &details); err != nil {
+			t.Fatalf("JSONDetails = %s: %v", result.JSONDetails, err)
+		}
+		if details.User != "" {
+			t.Errorf("user = %q, want none for a failed authentication", details.User)
+		}
+		if details.LatencyMs == nil {
+			t.Error("latencyMs missing; the rejected auth call still made a round trip")
+		}
+	})
+}
+
+func TestCheckHealthWarnsOnClockSkew(t *testing.T) {
+	checkHealth := func(t *testing.T, serverOffset time.Duration, jsonData string) *backend.CheckHealthResult {
+		t.Helper()
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Date", time.Now().Add(serverOffset).UTC().Format(http.TimeFormat))
+			if r.Header.Get("Authorization") == "" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"rid":"ri.authn.main.user.1","displayName":"tester","email":"t@example.com"}`))
+		}))
+		t.Cleanup(srv.Close)
+
+		conjureClient, err := conjurehttpclient.NewClient(conjurehttpclient.WithBaseURLs([]string{srv.URL}))
+		if err != nil {
+			t.Fatalf("NewClient: %v", err)
+		}
+		ds := &Datasource{
+			authService:        authapi.NewAuthenticationServiceV2Client(conjureClient),
+			resourceHTTPClient: srv.Client(),
+		}
+		result, err := ds.CheckHealth(context.Background(), &backend.CheckHealthRequest{
+			PluginContext: backend.PluginContext{
+				DataSourceInstanceSettings: &backend.DataSourceInstanceSettings{
+					JSONData:                []byte(`{"baseUrl": "` + srv.URL + `"` + jsonData + `}`),
+					DecryptedSecureJSONData: map[string]string{"apiKey": "test-key"},
+				},
+			},
+		})
+		if err != nil {
+			t.Fatalf("CheckHealth returned err: %v", err)
+		}
+		if result.Status != backend.HealthStatusOk {
+			t.Fatalf("Status = %v (%q), want HealthStatusOk", result.Status, result.Message)
+		}
+		return result
+	}
+
+	t.Run("skew over the threshold warns", func(t *testing.T) {
+		// The server is ten minutes behind, so the plugin's clock is ahead.
+		result := checkHealth(t, -10*time.Minute, `, "clockSkewThresholdMs": 30000`)
+		if !strings.Contains(result.Message, "ahead of the Nominal API server") {
+			t.Errorf("Message = %q, want a clock skew warning", result.Message)
+		}
+		var details healthDetails
+		if err := json.Unmarshal(result.JSONDetails, &details); err != nil {
+			t.Fatalf("JSONDetails = %s: %v", result.JSONDetails, err)
+		}
+		if details.ClockSkewMs == nil || *details.ClockSkewMs < 9*60*1000 {
+			t.Errorf("clockSkewMs = %v, want about ten minutes", details.ClockSkewMs)
+		}
+	})
+
+	t.Run("skew within the threshold does not warn", func(t *testing.T) {
+		if result := checkHealth(t, 0, `, "clockSkewThresholdMs": 30000`); strings.Contains(result.Message, "warning") {
+			t.Errorf("Message = %q, want no warning", result.Message)
+		}
+	})
+
+	t.Run("check is off by default", func(t *testing.T) {
+		if result := checkHealth(t, -10*time.Minute, ""); strings.Contains(result.Message, "warning") {
+			t.Errorf("Message = %q, want no warning without clockSkewThresholdMs", result.Message)
+		}
+	})
+}
@@ -0,0 +1,274 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+	conjurehttpclient "github.com/palantir/conjure-go-runtime/v2/conjure-go-client/httpclient"
+)
+
+// configureConnectionPool applies the datasource's connection pool limits to a
+// transport: the resource client's, and the Conjure client's (see
+// newConjureTransport). Unset (zero) limits keep the transport's defaults.
+func configureConnectionPool(config *models.PluginSettings) sdkhttpclient.ConfigureTransportFunc {
+	return func(_ sdkhttpclient.Options, transport *http.Transport) {
+		if config.MaxIdleConns > 0 {
+			transport.MaxIdleConns = config.MaxIdleConns
+		}
+		if config.MaxIdleConnsPerHost > 0 {
+			transport.MaxIdleConnsPerHost = config.MaxIdleConnsPerHost
+		}
+		if config.MaxConnsPerHost > 0 {
+			transport.MaxConnsPerHost = config.MaxConnsPerHost
+		}
+	}
+}
+
+// configureKeepAlive applies the datasource's idle-connection timeout and
+// keep-alive settings to a transport. Unset settings keep the transport's own.
+func configureKeepAlive(config *models.PluginSettings) sdkhttpclient.ConfigureTransportFunc {
+	return func(_ sdkhttpclient.Options, transport *http.Transport) {
+		if config.IdleConnTimeoutSeconds > 0 {
+			transport.IdleConnTimeout = time.Duration(config.IdleConnTimeoutSeconds) * time.Second
+		}
+		if config.DisableKeepAlives {
+			transport.DisableKeepAlives = true
+		}
+	}
+}
+
+// hasConnectionPoolSettings reports whether the datasource sets any connection
+// pool limit.
+func hasConnectionPoolSettings(config *models.PluginSettings) bool {
+	return config.MaxIdleConns > 0 || config.MaxIdleConnsPerHost > 0 || config.MaxConnsPerHost > 0
+}
+
+// keepAliveClientParams carries the keep-alive settings to the Conjure client,
+// which builds its own transport.
+func keepAliveClientParams(config *models.PluginSettings) []conjurehttpclient.ClientParam {
+	var params []conjurehttpclient.ClientParam
+	if config.IdleConnTimeoutSeconds > 0 {
+		params = append(params, conjurehttpclient.WithIdleConnTimeout(time.Duration(config.IdleConnTimeoutSeconds)*time.Second))
+	}
+	if config.DisableKeepAlives {
+		params = append(params, conjurehttpclient.WithDisableKeepAlives())
+	}
+	return params
+}
+
+// chainConfigureTransport runs next after any hook already present on the SDK
+// options, so plugin-level transport tweaks never drop SDK-provided ones.
+func chainConfigureTransport(prev, next sdkhttpclient.ConfigureTransportFunc) sdkhttpclient.ConfigureTransportFunc {
+	if prev == nil {
+		return next
+	}
+	return func(opts sdkhttpclient.Options, transport *http.Transport) {
+		prev(opts, transport)
+		next(opts, transport)
+	}
+}
+
+// Dial timeouts for the Conjure client's own transport, matching
+// http.DefaultTransport.
+const (
+	egressDialTimeout = 30 * time.Second
+	egressKeepAlive   = 30 * time.Second
+)
+
+// hasEgressSettings reports whether the datasource customizes how connections
+// to the API are dialed.
+func hasEgressSettings(config *models.PluginSettings) bool {
+	return config.DialLocalAddr != "" || config.ForceIPv4
+}
+
+// parseDialLocalAddr parses the dialLocalAddr setting, an IP optionally with a
+// port. It returns nil when the setting is empty.
+func parseDialLocalAddr(config *models.PluginSettings) (*net.TCPAddr, error) {
+	if config.DialLocalAddr == "" {
+		return nil, nil
+	}
+	host, port := config.DialLocalAddr, 0
+	if h, p, err := net.SplitHostPort(config.DialLocalAddr); err == nil {
+		if port, err = strconv.Atoi(p); err != nil {
+			return nil, fmt.Errorf("invalid dialLocalAddr %q: bad port", config.DialLocalAddr)
+		}
+		host = h
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, fmt.Errorf("invalid dialLocalAddr %q: must be an IP address", config.DialLocalAddr)
+	}
+	if config.ForceIPv4 && ip.To4() == nil {
+		return nil, fmt.Errorf("dialLocalAddr %q is not an IPv4 address but forceIPv4 is set", config.DialLocalAddr)
+	}
+	return &net.TCPAddr{IP: ip, Port: port}, nil
+}
+
+// egressDialContext returns a dial function bound to localAddr (when non-nil)
+// that restricts TCP dials to IPv4 when forceIPv4 is set.
+func egressDialContext(localAddr *net.TCPAddr, forceIPv4 bool, timeout, keepAlive time.Duration) func(context.Context, string, string) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: timeout, KeepAlive: keepAlive}
+	if localAddr != nil {
+		dialer.LocalAddr = localAddr
+	}
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		if forceIPv4 && network == "tcp" {
+			network = "tcp4"
+		}
+		return dialer.DialContext(ctx, network, address)
+	}
+}
+
+// configureEgress replaces the resource client's dialer with one honoring the
+// datasource's egress settings, keeping the SDK's dial timeouts.
+func configureEgress(config *models.PluginSettings, localAddr *net.TCPAddr) sdkhttpclient.ConfigureTransportFunc {
+	return func(opts sdkhttpclient.Options, transport *http.Transport) {
+		if !hasEgressSettings(config) {
+			return
+		}
+		var timeout, keepAlive time.Duration
+		if opts.Timeouts != nil {
+			timeout, keepAlive = opts.Timeouts.DialTimeout, opts.Timeouts.KeepAlive
+		}
+		transport.DialContext = egressDialContext(localAddr, config.ForceIPv4, timeout, keepAlive)
+	}
+}
+
+// transportMiddleware sends Conjure client requests through transport instead
+// of the client's built-in one, which does not expose its dialer. It carries
+// the transport from newConjureTransport and transports injected by tests.
+func transportMiddleware(transport http.RoundTripper) conjurehttpclient.Middleware {
+	return conjurehttpclient.MiddlewareFunc(func(req *http.Request, _ http.RoundTripper) (*http.Response, error) {
+		return transport.RoundTrip(req)
+	})
+}
+
+// newConjureTransport returns a transport for the Conjure client carrying the
+// datasource's egress settings and connection pool limits, which the client's
+// built-in transport cannot take (it has no dialer or MaxConnsPerHost option).
+func newConjureTransport(config *models.PluginSettings, localAddr *net.TCPAddr) *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if hasEgressSettings(config) {
+		transport.DialContext = egressDialContext(localAddr, config.ForceIPv4, egressDialTimeout, egressKeepAlive)
+	}
+	configureConnectionPool(config)(sdkhttpclient.Options{}, transport)
+	configureKeepAlive(config)(sdkhttpclient.Options{}, transport)
+	return transport
+}
+
+// parseFallbackBaseURL parses the fallbackBaseUrl setting. It returns "" when
+// the setting is empty.
+func parseFallbackBaseURL(config *models.PluginSettings) (string, error) {
+	if config.FallbackBaseUrl == "" {
+		return "", nil
+	}
+	fallback := strings.TrimSuffix(config.FallbackBaseUrl, "/")
+	parsed, err := url.Parse(fallback)
+	if err != nil || parsed.Scheme == "" || parsed.Host == "" {
+		return "", fmt.Errorf("invalid fallbackBaseUrl %q: must be an absolute URL", redactedBaseURL(config.FallbackBaseUrl))
+	}
+	return fallback, nil
+}
+
+// fallbackTransport retries a request against the fallback base URL when the
+// primary cannot be reached. Only transport errors fail over: any HTTP
+// response, including an auth rejection, is the primary's answer.
+type fallbackTransport struct {
+	next     http.RoundTripper
+	primary  string
+	fallback string
+}
+
+func newFallbackTransport(next http.RoundTripper, primary, fallback string) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &fallbackTransport{next: next, primary: primary, fallback: fallback}
+}
+
+func (t *fallbackTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.roundTrip(req, t.next)
+}
+
+// roundTrip sends req through next, then once more to the fallback when the
+// primary was unreachable and the caller is still waiting.
+func (t *fallbackTransport) roundTrip(req *http.Request, next http.RoundTripper) (*http.Response, error) {
+	resp, err := next.RoundTrip(req)
+	if err == nil || req.Context().Err() != nil {
+		return resp, err
+	}
+	retry, ok := t.fallbackRequest(req)
+	if !ok {
+		return resp, err
+	}
+	log.DefaultLogger.Warn("Primary API unreachable; retrying against fallback base URL",
+		"fallbackBaseUrl", redactedBaseURL(t.fallback), "error", err)
+	return next.RoundTrip(retry)
+}
+
+// fallbackRequest rewrites req from the primary base URL onto the fallback.
+// It reports false for requests to other hosts, requests outside the
+// primary's base path, and bodies that cannot be replayed.
+func (t *fallbackTransport) fallbackRequest(req *http.Request) (*http.Request, bool) {
+	rest, ok := pathUnderBaseURL(req.URL, t.primary)
+	if !ok {
+		return nil, false
+	}
+	target := t.fallback + rest
+	if req.URL.RawQuery != "" {
+		target += "?" + req.URL.RawQuery
+	}
+	fallbackURL, err := url.Parse(target)
+	if err != nil {
+		return nil, false
+	}
+	retry := req.Clone(req.Context())
+	if req.Body != nil && req.Body != http.NoBody {
+		if req.GetBody == nil {
+			return nil, false
+		}
+		if retry.Body, err = req.GetBody(); err != nil {
+			return nil, false
+		}
+	}
+	retry.URL = fallbackURL
+	retry.Host = ""
+	return retry, true
+}
+
+// pathUnderBaseURL returns the escaped path of u below baseURL, starting with
+// "/" or empty. It reports false unless u has baseURL's scheme and host and
+// its path is baseURL's path or continues it at a "/" boundary.
+func pathUnderBaseURL(u *url.URL, baseURL string) (string, bool) {
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return "", false
+	}
+	if !strings.EqualFold(u.Scheme, base.Scheme) || !strings.EqualFold(u.Host, base.Host) {
+		return "", false
+	}
+	basePath := strings.TrimSuffix(base.EscapedPath(), "/")
+	path := u.EscapedPath()
+	if path != basePath && !strings.HasPrefix(path, basePath+"/") {
+		return "", false
+	}
+	return strings.TrimPrefix(path, basePath), true
+}
+
+// fallbackMiddleware applies fallbackTransport to the Conjure client, whose
+// own multi-URL support balances load across URLs rather than preferring one.
+func fallbackMiddleware(primary, fallback string) conjurehttpclient.Middleware {
+	transport := &fallbackTransport{primary: primary, fallback: fallback}
+	return conjurehttpclient.MiddlewareFunc(func(req *http.Request, next http.RoundTripper) (*http.Response, error) {
+		return transport.roundTrip(req, next)
+	})
+}
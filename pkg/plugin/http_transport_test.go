@@ -0,0 +1,422 @@
+package plugin
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	sdkhttpclient "github.com/grafana/grafana-plugin-sdk-go/backend/httpclient"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+	"github.com/palantir/pkg/bearertoken"
+)
+
+func TestConfigureConnectionPool(t *testing.T) {
+	t.Run("configured limits are applied", func(t *testing.T) {
+		config, err := models.LoadPluginSettings(backend.DataSourceInstanceSettings{
+			JSONData: []byte(`{"maxIdleConns": 250, "maxIdleConnsPerHost": 50, "maxConnsPerHost": 75}`),
+		})
+		if err != nil {
+			t.Fatalf("LoadPluginSettings: %v", err)
+		}
+
+		transport := &http.Transport{MaxIdleConns: 100, MaxIdleConnsPerHost: 2}
+		configureConnectionPool(config)(sdkhttpclient.Options{}, transport)
+
+		if transport.MaxIdleConns != 250 {
+			t.Errorf("MaxIdleConns = %d, want 250", transport.MaxIdleConns)
+		}
+		if transport.MaxIdleConnsPerHost != 50 {
+			t.Errorf("MaxIdleConnsPerHost = %d, want 50", transport.MaxIdleConnsPerHost)
+		}
+		if transport.MaxConnsPerHost != 75 {
+			t.Errorf("MaxConnsPerHost = %d, want 75", transport.MaxConnsPerHost)
+		}
+	})
+
+	t.Run("the Conjure transport gets the limits too", func(t *testing.T) {
+		for name, config := range map[string]*models.PluginSettings{
+			"pool only":   {MaxIdleConns: 250, MaxIdleConnsPerHost: 50, MaxConnsPerHost: 75},
+			"with egress": {MaxIdleConns: 250, MaxIdleConnsPerHost: 50, MaxConnsPerHost: 75, ForceIPv4: true},
+		} {
+			if !hasConnectionPoolSettings(config) {
+				t.Fatalf("%s: hasConnectionPoolSettings = false, want true", name)
+			}
+			transport := newConjureTransport(config, nil)
+			if transport.MaxIdleConns != 250 || transport.MaxIdleConnsPerHost != 50 || transport.MaxConnsPerHost != 75 {
+				t.Errorf("%s: limits idle=%d idlePerHost=%d perHost=%d, want 250/50/75", name,
+					transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+			}
+			if hasEgressSettings(config) && transport.DialContext == nil {
+				t.Errorf("%s: expected the egress DialContext", name)
+			}
+		}
+	})
+
+	t.Run("unset limits keep the existing transport values", func(t *testing.T) {
+		transport := &http.Transport{MaxIdleConns: 100, MaxIdleConnsPerHost: 2, MaxConnsPerHost: 0}
+		configureConnectionPool(&models.PluginSettings{})(sdkhttpclient.Options{}, transport)
+
+		if transport.MaxIdleConns != 100 || transport.MaxIdleConnsPerHost != 2 || transport.MaxConnsPerHost != 0 {
+			t.Errorf("transport limits changed: idle=%d idlePerHost=%d perHost=%d",
+				transport.MaxIdleConns, transport.MaxIdleConnsPerHost, transport.MaxConnsPerHost)
+		}
+	})
+}
+
+func TestConfigureKeepAlive(t *testing.T) {
+	t.Run("configured settings are applied", func(t *testing.T) {
+		config, err := models.LoadPluginSettings(backend.DataSourceInstanceSettings{
+			JSONData: []byte(`{"idleConnTimeoutSeconds": 45, "disableKeepAlives": true}`),
+		})
+		if err != nil {
+			t.Fatalf("LoadPluginSettings: %v", err)
+		}
+
+		transport := &http.Transport{IdleConnTimeout: 90 * time.Second}
+		configureKeepAlive(config)(sdkhttpclient.Options{}, transport)
+
+		if transport.IdleConnTimeout != 45*time.Second {
+			t.Errorf("IdleConnTimeout = %v, want 45s", transport.IdleConnTimeout)
+		}
+		if !transport.DisableKeepAlives {
+			t.Error("DisableKeepAlives = false, want true")
+		}
+		if conjure := newConjureTransport(config, nil); conjure.IdleConnTimeout != 45*time.Second || !conjure.DisableKeepAlives {
+			t.Errorf("Conjure transport: IdleConnTimeout = %v, DisableKeepAlives = %v; want 45s, true",
+				conjure.IdleConnTimeout, conjure.DisableKeepAlives)
+		}
+	})
+
+	t.Run("unset settings keep the existing transport values", func(t *testing.T) {
+		transport := &http.Transport{IdleConnTimeout: 90 * time.Second}
+		configureKeepAlive(&models.PluginSettings{})(sdkhttpclient.Options{}, transport)
+
+		if transport.IdleConnTimeout != 90*time.Second || transport.DisableKeepAlives {
+			t.Errorf("transport changed: IdleConnTimeout = %v, DisableKeepAlives = %v",
+				transport.IdleConnTimeout, transport.DisableKeepAlives)
+		}
+	})
+}
+
+func TestChainConfigureTransportRunsBothHooks(t *testing.T) {
+	var order []string
+	prev := func(sdkhttpclient.Options, *http.Transport) { order = append(order, "prev") }
+	next := func(sdkhttpclient.Options, *http.Transport) { order = append(order, "next") }
+
+	chainConfigureTransport(prev, next)(sdkhttpclient.Options{}, &http.Transport{})
+
+	if len(order) != 2 || order[0] != "prev" || order[1] != "next" {
+		t.Errorf("hook order = %v, want [prev next]", order)
+	}
+}
+
+func TestConfigureEgressBindsLocalAddr(t *testing.T) {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	// Reserve a free local port to bind the outgoing connection to.
+	reserved, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Listen: %v", err)
+	}
+	wantLocal := reserved.Addr().(*net.TCPAddr)
+	reserved.Close()
+
+	config := &models.PluginSettings{DialLocalAddr: wantLocal.String(), ForceIPv4: true}
+	localAddr, err := parseDialLocalAddr(config)
+	if err != nil {
+		t.Fatalf("parseDialLocalAddr: %v", err)
+	}
+	transport := &http.Transport{}
+	configureEgress(config, localAddr)(sdkhttpclient.Options{}, transport)
+	if transport.DialContext == nil {
+		t.Fatal("expected a custom DialContext")
+	}
+
+	conn, err := transport.DialContext(context.Background(), "tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("DialContext: %v", err)
+	}
+	defer conn.Close()
+	gotLocal := conn.LocalAddr().(*net.TCPAddr)
+	if !gotLocal.IP.Equal(wantLocal.IP) || gotLocal.Port != wantLocal.Port {
+		t.Errorf("local addr = %v, want %v", gotLocal, wantLocal)
+	}
+}
+
+func TestParseDialLocalAddr(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    models.PluginSettings
+		want      string
+		wantError string
+	}{
+		{name: "unset", want: "<nil>"},
+		{name: "ip only", config: models.PluginSettings{DialLocalAddr: "10.0.0.5"}, want: "10.0.0.5:0"},
+		{name: "ip and port", config: models.PluginSettings{DialLocalAddr: "10.0.0.5:4000"}, want: "10.0.0.5:4000"},
+		{name: "ipv6", config: models.PluginSettings{DialLocalAddr: "[fd00::1]:0"}, want: "[fd00::1]:0"},
+		{name: "hostname", config: models.PluginSettings{DialLocalAddr: "eth0"}, wantError: "must be an IP address"},
+		{name: "ipv6 with forceIPv4", config: models.PluginSettings{DialLocalAddr: "fd00::1", ForceIPv4: true}, wantError: "not an IPv4 address"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseDialLocalAddr(&tt.config)
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("err = %v, want %q", err, tt.wantError)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.String() != tt.want {
+				t.Errorf("addr = %v, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+// roundTripperFunc adapts a function to http.RoundTripper.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// jsonResponse returns a 200 response with body as JSON.
+func jsonResponse(req *http.Request, body string) *http.Response {
+	return &http.Response{
+		StatusCode:    http.StatusOK,
+		ContentLength: int64(len(body)),
+		Header:        http.Header{"Content-Type": []string{"application/json"}},
+		Body:          io.NopCloser(strings.NewReader(body)),
+		Request:       req,
+	}
+}
+
+func TestNewDatasourceWithTransport(t *testing.T) {
+	var mu sync.Mutex
+	var paths []string
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		paths = append(paths, req.URL.Path)
+		mu.Unlock()
+		if req.Header.Get("Authorization") != "Bearer test-api-key" {
+			t.Errorf("%s: Authorization = %q", req.URL.Path, req.Header.Get("Authorization"))
+		}
+		switch req.URL.Path {
+		case "/api/scout/v1/asset/multiple":
+			return jsonResponse(req, `{"ri.nominal.asset.1": {"rid": "ri.nominal.asset.1", "title": "Rover"}}`), nil
+		default:
+			return jsonResponse(req, `{"rid":"ri.authn.main.user.1","displayName":"Test","email":"test@example.com"}`), nil
+		}
+	})
+
+	ds, err := newDatasource(context.Background(), backend.DataSourceInstanceSettings{
+		JSONData:                []byte(`{"baseUrl": "https://nominal.invalid/api"}`),
+		DecryptedSecureJSONData: map[string]string{"apiKey": "test-api-key"},
+	}, withTransport(transport))
+	if err != nil {
+		t.Fatalf("newDatasource: %v", err)
+	}
+	config, err := models.LoadPluginSettings(ds.settings)
+	if err != nil {
+		t.Fatalf("LoadPluginSettings: %v", err)
+	}
+
+	t.Run("resource client", func(t *testing.T) {
+		asset, err := ds.nominalCatalog.FetchAssetByRid(context.Background(), config, "ri.nominal.asset.1")
+		if err != nil {
+			t.Fatalf("FetchAssetByRid: %v", err)
+		}
+		if asset == nil || asset.Title != "Rover" {
+			t.Errorf("asset = %+v, want Rover", asset)
+		}
+	})
+
+	t.Run("conjure client", func(t *testing.T) {
+		profile, err := ds.authService.GetMyProfile(context.Background(), bearertoken.Token(config.Secrets.ApiKey))
+		if err != nil {
+			t.Fatalf("GetMyProfile: %v", err)
+		}
+		if profile.DisplayName != "Test" {
+			t.Errorf("DisplayName = %q, want Test", profile.DisplayName)
+		}
+	})
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(paths) != 2 {
+		t.Errorf("transport saw %v, want the asset fetch and the profile call", paths)
+	}
+}
+
+func TestFallbackBaseURL(t *testing.T) {
+	var mu sync.Mutex
+	var hosts []string
+	primaryStatus := 0
+	transport := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		mu.Lock()
+		hosts = append(hosts, req.URL.Host)
+		status := primaryStatus
+		mu.Unlock()
+		if req.URL.Host == "primary.invalid" {
+			if status == 0 {
+				return nil, &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+			}
+			resp := jsonResponse(req, `{}`)
+			resp.StatusCode = status
+			return resp, nil
+		}
+		if !strings.HasPrefix(req.URL.Path, "/replica/") {
+			t.Errorf("fallback path = %q, want it under /replica", req.URL.Path)
+		}
+		if req.Body != nil {
+			if body, _ := io.ReadAll(req.Body); req.Method == http.MethodPost && len(body) == 0 {
+				t.Errorf("%s: fallback request body is empty", req.URL.Path)
+			}
+		}
+		return jsonResponse(req, `{"rid":"ri.authn.main.user.1","displayName":"Replica","email":"test@example.com"}`), nil
+	})
+
+	ds, err := newDatasource(context.Background(), backend.DataSourceInstanceSettings{
+		JSONData:                []byte(`{"baseUrl": "https://primary.invalid/api", "fallbackBaseUrl": "https://replica.invalid/replica/"}`),
+		DecryptedSecureJSONData: map[string]string{"apiKey": "test-api-key"},
+	}, withTransport(transport))
+	if err != nil {
+		t.Fatalf("newDatasource: %v", err)
+	}
+	config, err := models.LoadPluginSettings(ds.settings)
+	if err != nil {
+		t.Fatalf("LoadPluginSettings: %v", err)
+	}
+	reset := func(status int) {
+		mu.Lock()
+		defer mu.Unlock()
+		hosts = nil
+		primaryStatus = status
+	}
+	assertHosts := func(t *testing.T, want ...string) {
+		t.Helper()
+		mu.Lock()
+		defer mu.Unlock()
+		if strings.Join(hosts, ",") != strings.Join(want, ",") {
+			t.Errorf("hosts = %v, want %v", hosts, want)
+		}
+	}
+
+	t.Run("conjure client fails over when the primary is down", func(t *testing.T) {
+		reset(0)
+		profile, err := ds.authService.GetMyProfile(context.Background(), bearertoken.Token(config.Secrets.ApiKey))
+		if err != nil {
+			t.Fatalf("GetMyProfile: %v", err)
+		}
+		if profile.DisplayName != "Replica" {
+			t.Errorf("DisplayName = %q, want Replica", profile.DisplayName)
+		}
+		assertHosts(t, "primary.invalid", "replica.invalid")
+	})
+
+	t.Run("proxy fails over when the primary is down", func(t *testing.T) {
+		reset(0)
+		resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{
+			Path:   "nominal/scout/v1/search-assets",
+			Method: http.MethodPost,
+			Body:   []byte(`{"query":{}}`),
+		})
+		if resp.Status != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+		}
+		assertHosts(t, "primary.invalid", "replica.invalid")
+	})
+
+	t.Run("auth errors from the primary do not fail over", func(t *testing.T) {
+		reset(http.StatusUnauthorized)
+		resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{
+			Path:   "nominal/scout/v1/search-assets",
+			Method: http.MethodPost,
+			Body:   []byte(`{}`),
+		})
+		if resp.Status != http.StatusUnauthorized {
+			t.Errorf("status = %d, want 401", resp.Status)
+		}
+		assertHosts(t, "primary.invalid")
+	})
+}
+
+func TestFallbackRequestOnlyRewritesPrimaryURLs(t *testing.T) {
+	transport := &fallbackTransport{primary: "https://api.example.com/api", fallback: "https://replica.example.com/replica"}
+	tests := []struct {
+		target string
+		want   string
+	}{
+		{target: "https://api.example.com/api/scout/v1/asset?x=1", want: "https://replica.example.com/replica/scout/v1/asset?x=1"},
+		{target: "https://api.example.com/api", want: "https://replica.example.com/replica"},
+		{target: "https://API.example.com/api/a", want: "https://replica.example.com/replica/a"},
+		{target: "https://api.example.com.evil.net/api/a"},
+		{target: "https://api.example.com2/api/a"},
+		{target: "https://api.example.com/apix/a"},
+		{target: "http://api.example.com/api/a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			retry, ok := transport.fallbackRequest(req)
+			if tt.want == "" {
+				if ok {
+					t.Fatalf("rewrote %s to %s, want no failover", tt.target, retry.URL)
+				}
+				return
+			}
+			if !ok {
+				t.Fatalf("did not rewrite %s", tt.target)
+			}
+			if got := retry.URL.String(); got != tt.want {
+				t.Errorf("fallback URL = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseFallbackBaseURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		setting   string
+		want      string
+		wantError bool
+	}{
+		{name: "unset"},
+		{name: "trailing slash", setting: "https://replica.example.com/api/", want: "https://replica.example.com/api"},
+		{name: "relative", setting: "replica.example.com", wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseFallbackBaseURL(&models.PluginSettings{FallbackBaseUrl: tt.setting})
+			if (err != nil) != tt.wantError {
+				t.Fatalf("err = %v, wantError %v", err, tt.wantError)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// isoDurationPattern matches ISO 8601 durations ("PT1H", "P1DT12H", "PT0.5S").
+// Years and months are matched only so they can be rejected with a clear error.
+var isoDurationPattern = regexp.MustCompile(`^P(?:(\d+)Y)?(?:(\d+)M)?(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+(?:\.\d+)?)S)?)?$`)
+
+// isISO8601Duration reports whether expr is written as an ISO 8601 duration
+// rather than Go duration syntax.
+func isISO8601Duration(expr string) bool {
+	return strings.HasPrefix(expr, "P")
+}
+
+// parseISO8601Duration converts an ISO 8601 duration to a fixed duration. Weeks
+// and days are fixed 7×24h and 24h, so "P1D" is always 86400s (unlike the
+// calendar time shift "1d", which follows DST). Years and months have no fixed
+// length and are rejected.
+func parseISO8601Duration(expr string) (time.Duration, error) {
+	m := isoDurationPattern.FindStringSubmatch(expr)
+	if m == nil || expr == "P" || strings.HasSuffix(expr, "T") {
+		return 0, fmt.Errorf("invalid ISO 8601 duration %q", expr)
+	}
+	if m[1] != "" || m[2] != "" {
+		return 0, fmt.Errorf("invalid ISO 8601 duration %q: years and months have no fixed length; use weeks or days", expr)
+	}
+
+	var d time.Duration
+	for i, unit := range []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute} {
+		if m[i+3] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(m[i+3], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q: %w", expr, err)
+		}
+		if n > math.MaxInt64/int64(unit) || time.Duration(n)*unit > math.MaxInt64-d {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q: overflows the maximum duration", expr)
+		}
+		d += time.Duration(n) * unit
+	}
+	if m[7] != "" {
+		seconds, err := strconv.ParseFloat(m[7], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q: %w", expr, err)
+		}
+		// float64(math.MaxInt64) rounds up to 2^63, so >= rejects it too.
+		nanos := seconds * float64(time.Second)
+		if nanos >= float64(math.MaxInt64-d) {
+			return 0, fmt.Errorf("invalid ISO 8601 duration %q: overflows the maximum duration", expr)
+		}
+		d += time.Duration(nanos)
+	}
+	return d, nil
+}
+
+// parseIntervalMs parses a string intervalMs value: a whole number of
+// milliseconds, a Go duration ("1m"), or an ISO 8601 duration ("PT1M").
+func parseIntervalMs(value string) (int, error) {
+	if ms, err := strconv.Atoi(value); err == nil {
+		return ms, nil
+	}
+	var d time.Duration
+	var err error
+	if isISO8601Duration(value) {
+		if d, err = parseISO8601Duration(value); err != nil {
+			return 0, err
+		}
+	} else if d, err = time.ParseDuration(value); err != nil {
+		return 0, fmt.Errorf("must be milliseconds or a duration like \"1m\" or \"PT1M\"")
+	}
+	if d > 0 && d < time.Millisecond {
+		return 0, fmt.Errorf("must be at least 1ms")
+	}
+	return int(d.Milliseconds()), nil
+}
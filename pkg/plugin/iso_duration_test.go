@@ -0,0 +1,99 @@
+package plugin
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+)
+
+func TestParseISO8601Duration(t *testing.T) {
+	tests := map[string]float64{
+		"PT1H":      3600,
+		"P1D":       86400,
+		"P1W":       604800,
+		"PT90M":     5400,
+		"P1DT12H":   129600,
+		"PT1M30S":   90,
+		"PT0.5S":    0.5,
+		"P2DT3H4M5": -1, // missing unit designator
+		"P":         -1,
+		"PT":        -1,
+		"P1DT":      -1,
+		"P1Y":       -1,
+		"P1M":       -1,
+		// Overflow of a single component, of the running sum, and of seconds.
+		"P9999999999W":     -1,
+		"P15000WT1000000H": -1,
+		"PT9999999999S":    -1,
+	}
+	for expr, wantSeconds := range tests {
+		got, err := parseISO8601Duration(expr)
+		if wantSeconds < 0 {
+			if err == nil {
+				t.Errorf("parseISO8601Duration(%q) = %v, want an error", expr, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseISO8601Duration(%q): %v", expr, err)
+			continue
+		}
+		if got.Seconds() != wantSeconds {
+			t.Errorf("parseISO8601Duration(%q) = %vs, want %vs", expr, got.Seconds(), wantSeconds)
+		}
+	}
+}
+
+func TestResolveTimeShiftAcceptsISO8601(t *testing.T) {
+	from := time.Date(2024, 3, 10, 12, 0, 0, 0, time.UTC)
+	got, err := resolveTimeShift("PT1H30M", "", from)
+	if err != nil || got != 90*time.Minute {
+		t.Errorf("resolveTimeShift(PT1H30M) = %v, %v; want 1h30m", got, err)
+	}
+	// ISO days are fixed 24h, even across a DST change.
+	got, err = resolveTimeShift("P1D", "America/New_York", from)
+	if err != nil || got != 24*time.Hour {
+		t.Errorf("resolveTimeShift(P1D) = %v, %v; want 24h", got, err)
+	}
+}
+
+func TestPrepareQueryParsesIntervalMsDurations(t *testing.T) {
+	qe := newTestQueryExecution(&Datasource{}, nil)
+	timeRange := backend.TimeRange{
+		From: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		To:   time.Date(2024, 1, 1, 1, 0, 0, 0, time.UTC),
+	}
+	for raw, wantMs := range map[string]int{
+		`"PT1M"`:  60000,
+		`"5m"`:    300000,
+		`"1500"`:  1500,
+		`"$step"`: 30000,
+		`60000`:   60000,
+	} {
+		prepared, errResp := qe.prepareQuery(context.Background(), backend.DataQuery{
+			RefID: "A",
+			JSON: []byte(`{"assetRid":"ri.nominal.asset.1","channel":"temperature","dataScopeName":"default",` +
+				`"templateVariables":{"step":"PT30S"},"intervalMs":` + raw + `}`),
+			TimeRange: timeRange,
+		})
+		if errResp != nil {
+			t.Errorf("intervalMs %s: %v", raw, errResp.Error)
+			continue
+		}
+		if prepared.Model.IntervalMs != wantMs {
+			t.Errorf("intervalMs %s = %d, want %d", raw, prepared.Model.IntervalMs, wantMs)
+		}
+	}
+
+	_, errResp := qe.prepareQuery(context.Background(), backend.DataQuery{
+		RefID:     "A",
+		JSON:      []byte(`{"assetRid":"ri.nominal.asset.1","channel":"temperature","dataScopeName":"default","intervalMs":"P1M"}`),
+		TimeRange: timeRange,
+	})
+	if errResp == nil || !strings.Contains(errResp.Error.Error(), "years and months") {
+		t.Errorf("intervalMs P1M: error = %v, want the months rejection", errResp)
+	}
+}
@@ -4,14 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
+	"mime"
 	"net/http"
+	"slices"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/nominal-inc/nominal-ds/pkg/models"
 	"github.com/nominal-io/nominal-api-go/api/rids"
 	datasourceapi "github.com/nominal-io/nominal-api-go/datasource/api"
@@ -26,6 +32,14 @@ const assetCacheTTL = 5 * time.Minute
 
 const maxChannelVariables = 5000
 
+// maxConcurrentChannelLookups bounds the per-channel SearchChannels calls a
+// single catalog request fans out.
+const maxConcurrentChannelLookups = 8
+
+// defaultMaxAssetSearchPages caps the pages FetchAssetsForVariable requests
+// when the datasource does not configure maxAssetSearchPages.
+const defaultMaxAssetSearchPages = 20
+
 // assetCacheEntry holds a cached asset response with its fetch time.
 type assetCacheEntry struct {
 	asset     *SingleAssetResponse
@@ -36,6 +50,7 @@ type assetCacheEntry struct {
 type channelMetadataCacheEntry struct {
 	channelDataType string // "string", "log", "numeric", or "" for searched-but-not-found / DataType nil
 	unit            string // raw Nominal canonical unit symbol; "" if Unit was nil or missing
+	dataSourceRid   string // data source that owns the channel; "" for searched-but-not-found
 	fetchedAt       time.Time
 }
 
@@ -75,9 +90,10 @@ type AssetDataScope struct {
 
 // SingleAssetResponse represents a single asset from the batch lookup API.
 type SingleAssetResponse struct {
-	Rid        string           `json:"rid"`
-	Title      string           `json:"title"`
-	DataScopes []AssetDataScope `json:"dataScopes"`
+	Rid         string           `json:"rid"`
+	Title       string           `json:"title"`
+	Description string           `json:"description,omitempty"`
+	DataScopes  []AssetDataScope `json:"dataScopes"`
 }
 
 // clone returns a deep copy so cached entries can never be mutated through a
@@ -146,6 +162,9 @@ func dataSourceRidFor(ds AssetDataSource) (string, bool) {
 	return "", false
 }
 
+// HasSupportedDataSource reports whether the asset has a data scope that
+// supports channel queries. An asset without data scopes (nil or empty) has
+// nothing to query, so asset variables leave it out rather than offering it.
 func (c *NominalCatalog) HasSupportedDataSource(asset AssetSearchResult) bool {
 	for _, scope := range asset.DataScopes {
 		if isSupportedDataSourceType(scope.DataSource.Type) {
@@ -182,9 +201,13 @@ func (c *NominalCatalog) FetchAssetByRid(ctx context.Context, config *models.Plu
 }
 
 // postNominalJSON marshals body as JSON and POSTs it to {config baseURL}+path
-// with the standard Authorization and Content-Type headers. On non-200 the
+// with the configured API key header and Content-Type. On non-200 the
 // response body is read, closed, and returned as a typed *apiError. On 200
 // the caller owns closing resp.Body.
+//
+// Every caller is a read-only lookup, so transport errors and 429/5xx
+// responses are retried up to nominalRequestMaxAttempts times with
+// exponential backoff.
 func (c *NominalCatalog) postNominalJSON(ctx context.Context, config *models.PluginSettings, path string, body any) (*http.Response, error) {
 	baseURL := config.GetAPIBaseURL()
 	if baseURL == "" {
@@ -197,17 +220,48 @@ func (c *NominalCatalog) postNominalJSON(ctx context.Context, config *models.Plu
 		return nil, fmt.Errorf("failed to marshal request: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, baseURL+path, bytes.NewReader(bodyBytes))
+	if c.resourceHTTPClient == nil {
+		return nil, fmt.Errorf("resource HTTP client is not configured")
+	}
+
+	backoff := nominalRequestInitialBackoff
+	for attempt := 1; ; attempt++ {
+		resp, err := c.doNominalPost(ctx, config, baseURL+path, bodyBytes)
+		if err == nil || attempt >= nominalRequestMaxAttempts || !isRetryableNominalError(ctx, err) {
+			return resp, err
+		}
+
+		log.DefaultLogger.Warn("Retrying Nominal API request",
+			"path", path,
+			"attempt", attempt,
+			"backoffMs", backoff.Milliseconds(),
+			"error", err.Error(),
+		)
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("request failed: %w", ctx.Err())
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Retry bounds for postNominalJSON.
+const (
+	nominalRequestMaxAttempts    = 3
+	nominalRequestInitialBackoff = 100 * time.Millisecond
+)
+
+// doNominalPost performs a single POST attempt for postNominalJSON.
+func (c *NominalCatalog) doNominalPost(ctx context.Context, config *models.PluginSettings, url string, bodyBytes []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(bodyBytes))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
-	req.Header.Set("Authorization", "Bearer "+config.Secrets.ApiKey)
+	setAuthHeader(req, config, config.Secrets.ApiKey)
 	req.Header.Set("Content-Type", "application/json")
 
-	if c.resourceHTTPClient == nil {
-		return nil, fmt.Errorf("resource HTTP client is not configured")
-	}
 	resp, err := c.resourceHTTPClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("request failed: %w", err)
@@ -219,9 +273,45 @@ func (c *NominalCatalog) postNominalJSON(ctx context.Context, config *models.Plu
 		return nil, newAPIError(resp.StatusCode, errBody)
 	}
 
+	if contentType := resp.Header.Get("Content-Type"); !isJSONContentType(contentType) {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w (got Content-Type %q)", errBaseURLNotAPI, contentType)
+	}
+
 	return resp, nil
 }
 
+// errBaseURLNotAPI reports a successful response that is not JSON, which
+// usually means the base URL points at the web app rather than the API.
+var errBaseURLNotAPI = errors.New("base URL appears to point at a web page, not the API; check the data source base URL")
+
+// isJSONContentType reports whether contentType is JSON (application/json or
+// a +json suffix). A missing Content-Type is accepted.
+func isJSONContentType(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// isRetryableNominalError reports whether a postNominalJSON attempt failed
+// transiently: a 429 or 5xx response, or a transport error that was not
+// caused by the caller's context ending.
+func isRetryableNominalError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil || errors.Is(err, errBaseURLNotAPI) {
+		return false
+	}
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr.Status == http.StatusTooManyRequests || apiErr.Status >= 500
+	}
+	return true
+}
+
 func (c *NominalCatalog) fetchAssetByRidUncached(ctx context.Context, config *models.PluginSettings, assetRid string) (*SingleAssetResponse, error) {
 	resp, err := c.postNominalJSON(ctx, config, "/scout/v1/asset/multiple", []string{assetRid})
 	if err != nil {
@@ -229,36 +319,108 @@ func (c *NominalCatalog) fetchAssetByRidUncached(ctx context.Context, config *mo
 	}
 	defer resp.Body.Close()
 
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	return findAssetInResponse(body, assetRid)
+}
+
+// findAssetInResponse returns the asset with assetRid from a batch asset
+// lookup response, or nil when it is absent. Most API versions answer with a
+// map keyed by RID; some return an array of assets, which is matched on Rid.
+func findAssetInResponse(body []byte, assetRid string) (*SingleAssetResponse, error) {
 	var assetMap map[string]SingleAssetResponse
-	if err := json.NewDecoder(resp.Body).Decode(&assetMap); err != nil {
-		return nil, fmt.Errorf("failed to decode response: %w", err)
+	mapErr := json.Unmarshal(body, &assetMap)
+	if mapErr == nil {
+		if asset, ok := assetMap[assetRid]; ok {
+			return &asset, nil
+		}
+		return nil, nil
 	}
 
-	if asset, ok := assetMap[assetRid]; ok {
-		return &asset, nil
+	var assetList []SingleAssetResponse
+	if err := json.Unmarshal(body, &assetList); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", mapErr)
+	}
+	for i := range assetList {
+		if assetList[i].Rid == assetRid {
+			return &assetList[i], nil
+		}
 	}
 	return nil, nil
 }
 
+// assetSearchFilters narrows an asset search beyond its search text.
+type assetSearchFilters struct {
+	// Labels restricts results to assets carrying every listed label.
+	Labels []string
+	// IncludeArchived adds archived assets, which the API excludes by default.
+	IncludeArchived bool
+	// NewestFirst sorts by creation time descending instead of ascending.
+	NewestFirst bool
+}
+
+// assetSearchQuery builds the search-assets query union: a plain searchText
+// query, AND-ed with a labels filter when filters.Labels is set.
+func assetSearchQuery(searchText string, filters assetSearchFilters) map[string]interface{} {
+	textQuery := map[string]interface{}{
+		"searchText": searchText,
+		"type":       "searchText",
+	}
+	if len(filters.Labels) == 0 {
+		return textQuery
+	}
+	labelsQuery := map[string]interface{}{
+		"type": "labels",
+		"labels": map[string]interface{}{
+			"operator": "AND",
+			"labels":   filters.Labels,
+		},
+	}
+	return map[string]interface{}{
+		"type": "and",
+		"and":  []interface{}{textQuery, labelsQuery},
+	}
+}
+
+// maxAssetSearchPages returns the configured asset search page cap, falling
+// back to defaultMaxAssetSearchPages when unset.
+func maxAssetSearchPages(config *models.PluginSettings) int {
+	if config == nil || config.MaxAssetSearchPages <= 0 {
+		return defaultMaxAssetSearchPages
+	}
+	return config.MaxAssetSearchPages
+}
+
 // FetchAssetsForVariable fetches assets from the Nominal API using direct HTTP calls.
-func (c *NominalCatalog) FetchAssetsForVariable(ctx context.Context, config *models.PluginSettings, searchText string, maxResults int) ([]AssetResponse, error) {
+// It stops at maxResults, the last page, or maxAssetSearchPages pages,
+// whichever comes first; hitting the page cap is logged and returns the pages
+// fetched so far.
+func (c *NominalCatalog) FetchAssetsForVariable(ctx context.Context, config *models.PluginSettings, searchText string, filters assetSearchFilters, maxResults int) ([]AssetResponse, error) {
 	var allResults []AssetResponse
 	pageToken := ""
-	pageSize := 50
+	pageSize := min(50, maxResults)
 	totalFetched := 0
+	maxPages := maxAssetSearchPages(config)
 
-	for totalFetched < maxResults {
+	for page := 0; totalFetched < maxResults; page++ {
+		if page == maxPages {
+			log.DefaultLogger.Warn("Asset search reached the page limit; returning partial results",
+				"maxPages", maxPages, "assets", totalFetched)
+			break
+		}
 		requestBody := map[string]interface{}{
-			"query": map[string]interface{}{
-				"searchText": searchText,
-				"type":       "searchText",
-			},
+			"query": assetSearchQuery(searchText, filters),
 			"sort": map[string]interface{}{
 				"field":        "CREATED_AT",
-				"isDescending": false,
+				"isDescending": filters.NewestFirst,
 			},
 			"pageSize": pageSize,
 		}
+		if filters.IncludeArchived {
+			requestBody["archivedStatuses"] = []string{"NOT_ARCHIVED", "ARCHIVED"}
+		}
 		if pageToken != "" {
 			requestBody["nextPageToken"] = pageToken
 		}
@@ -298,31 +460,27 @@ func (d *Datasource) catalog() *NominalCatalog {
 
 // InferChannelMetadata verifies (or backfills) channel metadata — both data type
 // and unit symbol — against the actual ChannelMetadata returned by SearchChannels.
+// The search covers the query's DataSourceRid when set, otherwise the data
+// sources of its asset's data scope.
 func (c *NominalCatalog) InferChannelMetadata(ctx context.Context, config *models.PluginSettings, qm *NominalQueryModel) {
 	if qm == nil || c == nil || c.datasourceService == nil {
 		return
 	}
-	if strings.TrimSpace(qm.AssetRid) == "" || strings.TrimSpace(qm.Channel) == "" || strings.TrimSpace(qm.DataScopeName) == "" {
+	if strings.TrimSpace(qm.Channel) == "" {
+		return
+	}
+	if strings.TrimSpace(qm.DataSourceRid) == "" && (strings.TrimSpace(qm.AssetRid) == "" || strings.TrimSpace(qm.DataScopeName) == "") {
 		return
 	}
 
-	cacheKey := qm.AssetRid + "|" + qm.DataScopeName + "|" + qm.Channel
+	cacheKey := channelMetadataCacheKey(*qm)
 
 	if entry, hit := c.lookupChannelMetadata(cacheKey); hit {
 		applyChannelMetadata(qm, entry)
 		return
 	}
 
-	asset, err := c.FetchAssetByRid(ctx, config, qm.AssetRid)
-	if err != nil {
-		log.DefaultLogger.Warn("Failed to fetch asset for channel metadata inference", "assetRid", qm.AssetRid, "error", err)
-		return
-	}
-	if asset == nil {
-		return
-	}
-
-	dataSourceRids := c.DataSourceRidsForScope(asset, qm.DataScopeName)
+	dataSourceRids := c.channelMetadataDataSources(ctx, config, *qm)
 	if len(dataSourceRids) == 0 {
 		return
 	}
@@ -334,7 +492,7 @@ func (c *NominalCatalog) InferChannelMetadata(ctx context.Context, config *model
 	}
 	channelsResponse, err := c.datasourceService.SearchChannels(ctx, bearerToken, searchRequest)
 	if err != nil {
-		log.DefaultLogger.Warn("Failed to search channels for channel metadata inference", "assetRid", qm.AssetRid, "error", err)
+		log.DefaultLogger.Warn("Failed to search channels for channel metadata inference", "assetRid", qm.AssetRid, "dataSourceRid", qm.DataSourceRid, "error", err)
 		return
 	}
 
@@ -348,14 +506,61 @@ func (c *NominalCatalog) InferChannelMetadata(ctx context.Context, config *model
 	c.storeChannelMetadata(cacheKey, channelMetadataCacheEntry{fetchedAt: time.Now()})
 }
 
+// channelMetadataDataSources returns the data sources InferChannelMetadata
+// searches for qm's channel: its DataSourceRid, or the data sources of its
+// asset's data scope. It returns nil, after logging, when they cannot be resolved.
+func (c *NominalCatalog) channelMetadataDataSources(ctx context.Context, config *models.PluginSettings, qm NominalQueryModel) []rids.DataSourceRid {
+	if qm.DataSourceRid != "" {
+		parsedRid, err := rid.ParseRID(qm.DataSourceRid)
+		if err != nil {
+			log.DefaultLogger.Warn("Invalid data source RID for channel metadata inference", "dataSourceRid", qm.DataSourceRid, "error", err)
+			return nil
+		}
+		return []rids.DataSourceRid{rids.DataSourceRid(parsedRid)}
+	}
+
+	asset, err := c.FetchAssetByRid(ctx, config, qm.AssetRid)
+	if err != nil {
+		log.DefaultLogger.Warn("Failed to fetch asset for channel metadata inference", "assetRid", qm.AssetRid, "error", err)
+		return nil
+	}
+	if asset == nil {
+		return nil
+	}
+	return c.DataSourceRidsForScope(asset, qm.DataScopeName)
+}
+
 func (c *NominalCatalog) SearchChannelsForVariables(ctx context.Context, bearerToken bearertoken.Token, dataSourceRids []rids.DataSourceRid) ([]datasourceapi.ChannelMetadata, error) {
+	channels, _, err := c.SearchChannelsForVariablesWithin(ctx, bearerToken, dataSourceRids, 0)
+	return channels, err
+}
+
+// SearchChannelsForVariablesWithin is SearchChannelsForVariables under a soft
+// time budget (none when budget is not positive). Once the budget is spent,
+// including while a page is in flight, it stops paging and returns the
+// channels gathered so far with partial set instead of failing.
+func (c *NominalCatalog) SearchChannelsForVariablesWithin(ctx context.Context, bearerToken bearertoken.Token, dataSourceRids []rids.DataSourceRid, budget time.Duration) ([]datasourceapi.ChannelMetadata, bool, error) {
 	if c == nil || c.datasourceService == nil || len(dataSourceRids) == 0 {
-		return nil, nil
+		return nil, false, nil
+	}
+
+	searchCtx := ctx
+	if budget > 0 {
+		var cancel context.CancelFunc
+		searchCtx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+	partial := false
+
+	requested := make(map[string]bool, len(dataSourceRids))
+	for _, dataSourceRid := range dataSourceRids {
+		requested[dataSourceRid.String()] = true
 	}
 
 	pageSize := 1000
 	var allChannelResults []datasourceapi.ChannelMetadata
 	var nextPageToken *api.Token
+	dropped := 0
 
 	for {
 		searchChannelsRequest := datasourceapi.SearchChannelsRequest{
@@ -365,23 +570,194 @@ func (c *NominalCatalog) SearchChannelsForVariables(ctx context.Context, bearerT
 			NextPageToken:   nextPageToken,
 		}
 
-		channelsResponse, err := c.datasourceService.SearchChannels(ctx, bearerToken, searchChannelsRequest)
+		channelsResponse, err := c.datasourceService.SearchChannels(searchCtx, bearerToken, searchChannelsRequest)
 		if err != nil {
-			return nil, err
+			if budget > 0 && ctx.Err() == nil && searchCtx.Err() != nil {
+				partial = true
+				break
+			}
+			return nil, false, err
 		}
 
-		allChannelResults = append(allChannelResults, channelsResponse.Results...)
+		// The search is scoped to dataSourceRids, but a channel from any other
+		// source would not belong to the asset, so drop it rather than list it.
+		for _, channel := range channelsResponse.Results {
+			if !requested[channel.DataSource.String()] {
+				dropped++
+				continue
+			}
+			allChannelResults = append(allChannelResults, channel)
+		}
 
 		if channelsResponse.NextPageToken == nil || len(allChannelResults) >= maxChannelVariables || len(channelsResponse.Results) == 0 {
 			break
 		}
+		if budget > 0 && searchCtx.Err() != nil {
+			partial = true
+			break
+		}
 		nextPageToken = channelsResponse.NextPageToken
 	}
 
+	if partial {
+		log.DefaultLogger.Debug("Channel variable search hit its time budget; returning partial results",
+			"budget", budget,
+			"channels", len(allChannelResults),
+		)
+	}
+	if dropped > 0 {
+		log.DefaultLogger.Warn("SearchChannels returned channels outside the requested data sources; ignoring them",
+			"dropped", dropped,
+			"dataSources", len(dataSourceRids),
+		)
+	}
 	if len(allChannelResults) > maxChannelVariables {
 		allChannelResults = allChannelResults[:maxChannelVariables]
 	}
-	return allChannelResults, nil
+	return allChannelResults, partial, nil
+}
+
+// ChannelsWithExactNames reports which of names are channels in
+// dataSourceRids. Each name is looked up with its own exact-match
+// SearchChannels call, as InferChannelMetadata does, at most
+// maxConcurrentChannelLookups at a time, so the cost grows with the names
+// checked rather than with the data sources' channel count. The search is
+// case-insensitive; a name only counts when a result matches it exactly.
+func (c *NominalCatalog) ChannelsWithExactNames(ctx context.Context, bearerToken bearertoken.Token, dataSourceRids []rids.DataSourceRid, names []string) (map[string]bool, error) {
+	found := make(map[string]bool, len(names))
+	for _, name := range names {
+		found[name] = false
+	}
+	if c == nil || c.datasourceService == nil || len(dataSourceRids) == 0 || len(names) == 0 {
+		return found, nil
+	}
+
+	requested := make(map[string]bool, len(dataSourceRids))
+	for _, dataSourceRid := range dataSourceRids {
+		requested[dataSourceRid.String()] = true
+	}
+
+	var (
+		mu       sync.Mutex
+		firstErr error
+		wg       sync.WaitGroup
+	)
+	sem := make(chan struct{}, maxConcurrentChannelLookups)
+	// found is written as lookups finish, so range over its keys collected up front.
+	for _, name := range slices.Collect(maps.Keys(found)) {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(name string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			response, err := c.datasourceService.SearchChannels(ctx, bearerToken, datasourceapi.SearchChannelsRequest{
+				ExactMatch:  []string{name},
+				DataSources: dataSourceRids,
+			})
+			exists := slices.ContainsFunc(response.Results, func(channel datasourceapi.ChannelMetadata) bool {
+				return string(channel.Name) == name && requested[channel.DataSource.String()]
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+				}
+				return
+			}
+			found[name] = exists
+		}(name)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	return found, nil
+}
+
+// SampleChannels returns up to limit channels from dataSourceRids in a single
+// SearchChannels page, for previews that do not need the full listing.
+func (c *NominalCatalog) SampleChannels(ctx context.Context, bearerToken bearertoken.Token, dataSourceRids []rids.DataSourceRid, limit int) ([]datasourceapi.ChannelMetadata, error) {
+	if c == nil || c.datasourceService == nil || len(dataSourceRids) == 0 || limit <= 0 {
+		return nil, nil
+	}
+
+	requested := make(map[string]bool, len(dataSourceRids))
+	for _, dataSourceRid := range dataSourceRids {
+		requested[dataSourceRid.String()] = true
+	}
+
+	channelsResponse, err := c.datasourceService.SearchChannels(ctx, bearerToken, datasourceapi.SearchChannelsRequest{
+		DataSources: dataSourceRids,
+		PageSize:    &limit,
+	})
+	if err != nil {
+		return nil, err
+	}
+	sample := make([]datasourceapi.ChannelMetadata, 0, min(limit, len(channelsResponse.Results)))
+	for _, channel := range channelsResponse.Results {
+		if len(sample) == limit {
+			break
+		}
+		if requested[channel.DataSource.String()] {
+			sample = append(sample, channel)
+		}
+	}
+	return sample, nil
+}
+
+// maxTagSelectorChannels is the most channels one tag selector can match.
+const maxTagSelectorChannels = 200
+
+// SearchChannelsByTags returns the channels in dataSourceRids carrying every
+// tag in tags, deduplicated by name, for tag-selector queries. One result past
+// maxTagSelectorChannels is requested: when it arrives, truncated is set and
+// no channels are returned, since the full match is unknown.
+func (c *NominalCatalog) SearchChannelsByTags(ctx context.Context, bearerToken bearertoken.Token, dataSourceRids []rids.DataSourceRid, tags map[string]string) (_ []datasourceapi.ChannelMetadata, truncated bool, _ error) {
+	if c == nil || c.datasourceService == nil || len(dataSourceRids) == 0 {
+		return nil, false, nil
+	}
+
+	tagFilter := tagValues(tags)
+	// A data source missing from Tags is searched unfiltered, so every one
+	// must carry the selector.
+	filters := make(map[rids.DataSourceRid]map[api.TagName]api.TagValue, len(dataSourceRids))
+	for _, dataSourceRid := range dataSourceRids {
+		filters[dataSourceRid] = tagFilter
+	}
+
+	resultSize := maxTagSelectorChannels + 1
+	response, err := c.datasourceService.SearchFilteredChannels(ctx, bearerToken, datasourceapi.SearchFilteredChannelsRequest{
+		DataSources: dataSourceRids,
+		Tags:        filters,
+		ResultSize:  &resultSize,
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	if len(response.Results) > maxTagSelectorChannels {
+		return nil, true, nil
+	}
+
+	seen := make(map[api.Channel]bool, len(response.Results))
+	channels := make([]datasourceapi.ChannelMetadata, 0, len(response.Results))
+	for _, channel := range response.Results {
+		if seen[channel.Name] {
+			continue
+		}
+		seen[channel.Name] = true
+		channels = append(channels, channel)
+	}
+	return channels, false, nil
+}
+
+// tagValues converts a tag selector into the API's tag filter map.
+func tagValues(tags map[string]string) map[api.TagName]api.TagValue {
+	values := make(map[api.TagName]api.TagValue, len(tags))
+	for key, value := range tags {
+		values[api.TagName(key)] = api.TagValue(value)
+	}
+	return values
 }
 
 func channelMetadataEntryForExactMatch(channels []datasourceapi.ChannelMetadata, channelName string) (channelMetadataCacheEntry, bool) {
@@ -395,6 +771,7 @@ func channelMetadataEntryForExactMatch(channels []datasourceapi.ChannelMetadata,
 		entry := channelMetadataCacheEntry{
 			channelDataType: getChannelDataType(channel), // "" if ChannelMetadata.DataType is nil
 			unit:            getChannelUnit(channel),     // "" if Unit is nil
+			dataSourceRid:   channel.DataSource.String(),
 		}
 		if entry.channelDataType == "" && entry.unit == "" {
 			continue
@@ -404,6 +781,63 @@ func channelMetadataEntryForExactMatch(channels []datasourceapi.ChannelMetadata,
 	return channelMetadataCacheEntry{}, false
 }
 
+// channelMetadataCacheKey identifies a channel in the channel metadata cache:
+// by data source for DataSourceRid queries, otherwise by asset and data scope.
+func channelMetadataCacheKey(qm NominalQueryModel) string {
+	if qm.DataSourceRid != "" {
+		return "dataSource|" + qm.DataSourceRid + "|" + qm.Channel
+	}
+	return qm.AssetRid + "|" + qm.DataScopeName + "|" + qm.Channel
+}
+
+// ChannelTags returns the tags of qm's channel that hold a single value over
+// timeRange. A tag with several values varies within the series, so it does
+// not describe it as a whole (GroupByTags splits on those instead). The lookup
+// covers the series the query computes: restricted to its TagSelector, and
+// over the earlier range a TimeShiftDuration reads from.
+//
+// The channel's data source is the query's DataSourceRid or, for an asset-bound
+// channel, the one InferChannelMetadata cached; a channel whose data source is
+// not known yields no tags.
+func (c *NominalCatalog) ChannelTags(ctx context.Context, config *models.PluginSettings, qm NominalQueryModel, timeRange backend.TimeRange) (data.Labels, error) {
+	if c == nil || c.datasourceService == nil {
+		return nil, nil
+	}
+	dataSourceRid := qm.DataSourceRid
+	if dataSourceRid == "" {
+		entry, ok := c.lookupChannelMetadata(channelMetadataCacheKey(qm))
+		if !ok || entry.dataSourceRid == "" {
+			return nil, nil
+		}
+		dataSourceRid = entry.dataSourceRid
+	}
+	parsedRid, err := rid.ParseRID(dataSourceRid)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data source RID %q: %w", dataSourceRid, err)
+	}
+
+	response, err := c.datasourceService.GetAvailableTagsForChannel(ctx, bearertoken.Token(config.Secrets.ApiKey), datasourceapi.GetAvailableTagsForChannelRequest{
+		ChannelWithTagFilters: datasourceapi.ChannelWithTagFilters{
+			DataSourceRid: rids.DataSourceRid(parsedRid),
+			Channel:       api.Channel(qm.Channel),
+			TagFilters:    tagValues(qm.TagSelector),
+		},
+		StartTime: utcTimestampFromTime(timeRange.From.Add(-qm.TimeShiftDuration)),
+		EndTime:   utcTimestampFromTime(timeRange.To.Add(-qm.TimeShiftDuration)),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	labels := data.Labels{}
+	for name, values := range response.AvailableTags.AvailableTags {
+		if len(values) == 1 {
+			labels[string(name)] = string(values[0])
+		}
+	}
+	return labels, nil
+}
+
 // lookupChannelMetadata returns a cached channel metadata entry if present and
 // not yet expired. Caller must apply the entry to its query model on hit.
 func (c *NominalCatalog) lookupChannelMetadata(cacheKey string) (channelMetadataCacheEntry, bool) {
@@ -2,6 +2,8 @@ package plugin
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
@@ -246,6 +248,54 @@ func TestNominalCatalogFetchAssetByRidUsesOwnCache(t *testing.T) {
 	}
 }
 
+func TestNominalCatalogFetchAssetByRidAcceptsMapAndArrayResponses(t *testing.T) {
+	assetRid := "ri.scout.main.asset.wanted"
+	tests := []struct {
+		name      string
+		body      string
+		wantTitle string
+		wantError bool
+	}{
+		{name: "map", body: `{"ri.scout.main.asset.other": {"rid": "ri.scout.main.asset.other", "title": "Other"}, "ri.scout.main.asset.wanted": {"rid": "ri.scout.main.asset.wanted", "title": "Wanted"}}`, wantTitle: "Wanted"},
+		{name: "array", body: `[{"rid": "ri.scout.main.asset.other", "title": "Other"}, {"rid": "ri.scout.main.asset.wanted", "title": "Wanted"}]`, wantTitle: "Wanted"},
+		{name: "map without asset", body: `{}`},
+		{name: "array without asset", body: `[{"rid": "ri.scout.main.asset.other", "title": "Other"}]`},
+		{name: "neither shape", body: `"unexpected"`, wantError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			config := &models.PluginSettings{BaseUrl: server.URL, Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+			catalog := newNominalCatalog(server.Client(), &mockDatasourceService{})
+
+			asset, err := catalog.FetchAssetByRid(context.Background(), config, assetRid)
+			if tt.wantError {
+				if err == nil || !strings.Contains(err.Error(), "failed to decode response") {
+					t.Fatalf("err = %v, want a decode error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchAssetByRid returned error: %v", err)
+			}
+			if tt.wantTitle == "" {
+				if asset != nil {
+					t.Errorf("asset = %+v, want nil", asset)
+				}
+				return
+			}
+			if asset == nil || asset.Rid != assetRid || asset.Title != tt.wantTitle {
+				t.Errorf("asset = %+v, want %s", asset, tt.wantTitle)
+			}
+		})
+	}
+}
+
 func TestNominalCatalogFetchAssetByRidReturnsCopy(t *testing.T) {
 	assetRid := "ri.scout.main.asset.copied"
 	dataSourceRid := "ri.scout.main.data-source.dataset1"
@@ -304,7 +354,9 @@ func TestNominalCatalogFetchAssetByRidReturnsCopy(t *testing.T) {
 }
 
 func TestNominalCatalogFetchAssetByRidSurfacesHTTPError(t *testing.T) {
+	var calls int
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
 		http.Error(w, fmt.Sprintf(`{"error":"bad path %s"}`, r.URL.Path), http.StatusTeapot)
 	}))
 	defer server.Close()
@@ -320,6 +372,144 @@ func TestNominalCatalogFetchAssetByRidSurfacesHTTPError(t *testing.T) {
 	if _, err := catalog.FetchAssetByRid(context.Background(), config, "ri.scout.main.asset.missing"); err == nil {
 		t.Fatal("FetchAssetByRid error = nil, want non-nil")
 	}
+	if calls != 1 {
+		t.Fatalf("request count = %d, want 1 (4xx responses are not retried)", calls)
+	}
+}
+
+// newFlakyAssetServer wraps newTestAssetServer so the first failures requests
+// return 503 before the real handler takes over. *calls counts every request.
+func newFlakyAssetServer(t *testing.T, failures int, calls *int, assets map[string]SingleAssetResponse, searchResults []AssetResponse) *httptest.Server {
+	t.Helper()
+	backing := newTestAssetServer(t, assets, searchResults)
+	t.Cleanup(backing.Close)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*calls++
+		if *calls <= failures {
+			http.Error(w, `{"errorCode":"INTERNAL","errorName":"Default:Internal"}`, http.StatusServiceUnavailable)
+			return
+		}
+		backing.Config.Handler.ServeHTTP(w, r)
+	}))
+}
+
+func TestNominalCatalogFetchAssetByRidRetriesTransientFailure(t *testing.T) {
+	assetRid := "ri.scout.main.asset.flaky"
+	var calls int
+	server := newFlakyAssetServer(t, 1, &calls, map[string]SingleAssetResponse{
+		assetRid: {Rid: assetRid, Title: "Flaky Asset"},
+	}, nil)
+	defer server.Close()
+
+	config := &models.PluginSettings{
+		BaseUrl: server.URL,
+		Secrets: &models.SecretPluginSettings{
+			ApiKey: "test-key",
+		},
+	}
+	catalog := newNominalCatalog(server.Client(), &mockDatasourceService{})
+
+	asset, err := catalog.FetchAssetByRid(context.Background(), config, assetRid)
+	if err != nil {
+		t.Fatalf("FetchAssetByRid returned error: %v", err)
+	}
+	if asset == nil || asset.Title != "Flaky Asset" {
+		t.Fatalf("asset = %+v, want Flaky Asset", asset)
+	}
+	if calls != 2 {
+		t.Fatalf("request count = %d, want 2 (one failure, one retry)", calls)
+	}
+}
+
+func TestNominalCatalogFetchAssetsForVariableRetriesTransientFailure(t *testing.T) {
+	var calls int
+	server := newFlakyAssetServer(t, 1, &calls, nil, []AssetResponse{{
+		Results: []AssetSearchResult{{Rid: "ri.scout.main.asset.1", Title: "Asset 1"}},
+	}})
+	defer server.Close()
+
+	config := &models.PluginSettings{
+		BaseUrl: server.URL,
+		Secrets: &models.SecretPluginSettings{
+			ApiKey: "test-key",
+		},
+	}
+	catalog := newNominalCatalog(server.Client(), &mockDatasourceService{})
+
+	pages, err := catalog.FetchAssetsForVariable(context.Background(), config, "", assetSearchFilters{}, 10)
+	if err != nil {
+		t.Fatalf("FetchAssetsForVariable returned error: %v", err)
+	}
+	if len(pages) != 1 || len(pages[0].Results) != 1 {
+		t.Fatalf("pages = %+v, want one page with one asset", pages)
+	}
+	if calls != 2 {
+		t.Fatalf("request count = %d, want 2 (one failure, one retry)", calls)
+	}
+}
+
+func TestNominalCatalogFetchAssetsForVariableStopsAtMaxPages(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxPages  int
+		wantPages int
+	}{
+		{name: "configured", maxPages: 3, wantPages: 3},
+		{name: "default", wantPages: defaultMaxAssetSearchPages},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var calls int
+			// Every page is full and carries a next token, so only the page
+			// cap ends the search.
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				calls++
+				page := AssetResponse{NextPageToken: fmt.Sprintf("page-%d", calls)}
+				for i := range 50 {
+					page.Results = append(page.Results, AssetSearchResult{Rid: fmt.Sprintf("ri.scout.main.asset.%d-%d", calls, i)})
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(page)
+			}))
+			defer server.Close()
+
+			config := &models.PluginSettings{
+				BaseUrl:             server.URL,
+				MaxAssetSearchPages: tt.maxPages,
+				Secrets:             &models.SecretPluginSettings{ApiKey: "test-key"},
+			}
+			catalog := newNominalCatalog(server.Client(), &mockDatasourceService{})
+
+			pages, err := catalog.FetchAssetsForVariable(context.Background(), config, "", assetSearchFilters{}, 1_000_000)
+			if err != nil {
+				t.Fatalf("FetchAssetsForVariable returned error: %v", err)
+			}
+			if calls != tt.wantPages || len(pages) != tt.wantPages {
+				t.Errorf("requests = %d, pages = %d; want %d of each", calls, len(pages), tt.wantPages)
+			}
+		})
+	}
+}
+
+func TestNominalCatalogFetchAssetByRidGivesUpAfterMaxAttempts(t *testing.T) {
+	var calls int
+	server := newFlakyAssetServer(t, nominalRequestMaxAttempts+1, &calls, nil, nil)
+	defer server.Close()
+
+	config := &models.PluginSettings{
+		BaseUrl: server.URL,
+		Secrets: &models.SecretPluginSettings{
+			ApiKey: "test-key",
+		},
+	}
+	catalog := newNominalCatalog(server.Client(), &mockDatasourceService{})
+
+	if _, err := catalog.FetchAssetByRid(context.Background(), config, "ri.scout.main.asset.down"); err == nil {
+		t.Fatal("FetchAssetByRid error = nil, want non-nil")
+	}
+	if calls != nominalRequestMaxAttempts {
+		t.Fatalf("request count = %d, want %d", calls, nominalRequestMaxAttempts)
+	}
 }
 
 func TestNominalCatalogFetchAssetByRidRequiresResourceHTTPClient(t *testing.T) {
@@ -406,3 +596,43 @@ func TestNominalCatalogInferChannelMetadataUsesOwnCache(t *testing.T) {
 		t.Fatalf("SearchChannels calls = %d, want 1", mockDS.searchChannelsCalls)
 	}
 }
+
+func TestNominalCatalogDetectsHTMLResponse(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = w.Write([]byte("<!doctype html><html><body>Nominal</body></html>"))
+	}))
+	defer server.Close()
+
+	config := &models.PluginSettings{
+		BaseUrl: server.URL,
+		Secrets: &models.SecretPluginSettings{
+			ApiKey: "test-key",
+		},
+	}
+	catalog := newNominalCatalog(server.Client(), &mockDatasourceService{})
+
+	t.Run("FetchAssetByRid", func(t *testing.T) {
+		calls = 0
+		_, err := catalog.FetchAssetByRid(context.Background(), config, "ri.scout.main.asset.html")
+		if !errors.Is(err, errBaseURLNotAPI) {
+			t.Fatalf("error = %v, want errBaseURLNotAPI", err)
+		}
+		if strings.Contains(err.Error(), "invalid character") {
+			t.Errorf("error = %v, want no JSON decode error", err)
+		}
+		if calls != 1 {
+			t.Errorf("request count = %d, want 1 (HTML responses are not retried)", calls)
+		}
+	})
+
+	t.Run("FetchAssetsForVariable", func(t *testing.T) {
+		calls = 0
+		_, err := catalog.FetchAssetsForVariable(context.Background(), config, "", assetSearchFilters{}, 10)
+		if err == nil || !strings.Contains(err.Error(), "base URL appears to point at a web page, not the API") {
+			t.Fatalf("error = %v, want base URL message", err)
+		}
+	})
+}
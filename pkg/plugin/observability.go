@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"net/url"
 	"runtime"
 	"strings"
 
@@ -273,6 +274,21 @@ func classifyConnectionError(err error) (message string, httpStatus int) {
 	return appendInstanceID("Failed to connect to Nominal API", err), http.StatusServiceUnavailable
 }
 
+// redactedBaseURL returns rawURL with any userinfo password masked, so a base
+// URL can be echoed in health-check messages and logs.
+func redactedBaseURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Redacted()
+}
+
+// withBaseURL appends the base URL a health check tried to message.
+func withBaseURL(message, baseURL string) string {
+	return fmt.Sprintf("%s (base URL: %s)", message, baseURL)
+}
+
 // formatUserError builds a "<prefix>: <details>" message with a labeled
 // trace ID for any error carrying the Conjure classification triple (typed
 // Conjure errors or raw-HTTP *apiError), avoiding the duplicate ID that "%v"
@@ -2,15 +2,27 @@ package plugin
 
 import (
 	"context"
+	"fmt"
 	"sync"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/grafana/grafana-plugin-sdk-go/data"
 	"github.com/nominal-inc/nominal-ds/pkg/models"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
 	computeapi1 "github.com/nominal-io/nominal-api-go/scout/compute/api1"
 	"github.com/palantir/pkg/bearertoken"
 )
 
+// defaultSlowQueryThreshold is the batch chunk latency that triggers a slow-query
+// warning when the datasource does not configure slowQueryThresholdMs.
+const defaultSlowQueryThreshold = 5 * time.Second
+
+// defaultMaxExpandedQueries caps the compute subrequests of one QueryData call
+// when the datasource does not configure maxExpandedQueries.
+const defaultMaxExpandedQueries = 1000
+
 type NominalQueryExecution struct {
 	datasource *Datasource
 	config     *models.PluginSettings
@@ -28,8 +40,20 @@ func newNominalQueryExecution(datasource *Datasource, config *models.PluginSetti
 func (e *NominalQueryExecution) Execute(ctx context.Context, queries []backend.DataQuery) *backend.QueryDataResponse {
 	response := backend.NewQueryDataResponse()
 
+	duplicates := duplicateRefIDs(queries)
+	for refID, count := range duplicates {
+		log.DefaultLogger.Warn("Duplicate query refId", "refId", refID, "count", count)
+		response.Responses[refID] = backend.ErrDataResponse(
+			backend.StatusBadRequest,
+			fmt.Sprintf("duplicate refId %q: %d queries share this refId; give each query a unique refId", refID, count),
+		)
+	}
+
 	var batchable []preparedQuery
 	for _, q := range queries {
+		if _, dup := duplicates[q.RefID]; dup {
+			continue
+		}
 		prepared, prepErr := e.prepareQuery(ctx, q)
 		if prepErr != nil {
 			response.Responses[q.RefID] = *prepErr
@@ -39,28 +63,181 @@ func (e *NominalQueryExecution) Execute(ctx context.Context, queries []backend.D
 		switch prepared.Kind {
 		case preparedQueryConnectionTest:
 			response.Responses[q.RefID] = e.handleConnectionTestQuery(ctx)
+		case preparedQueryChannelTable:
+			response.Responses[q.RefID] = e.handleChannelTableQuery(ctx, prepared.Model)
 		case preparedQueryBatchable:
+			if prepared.Model.DryRun {
+				response.Responses[q.RefID] = e.handleDryRunQuery(q.RefID, prepared)
+				continue
+			}
 			batchable = append(batchable, prepared)
+		case preparedQueryTagSelector:
+			expanded, expandErr := e.expandTagSelector(ctx, prepared)
+			if expandErr != nil {
+				response.Responses[q.RefID] = *expandErr
+				continue
+			}
+			if prepared.Model.DryRun {
+				response.Responses[q.RefID] = e.handleDryRunQuery(q.RefID, expanded...)
+				continue
+			}
+			batchable = append(batchable, expanded...)
+		case preparedQueryAllDataScopes:
+			expanded, expandErr := e.expandAllDataScopes(ctx, prepared)
+			if expandErr != nil {
+				response.Responses[q.RefID] = *expandErr
+				continue
+			}
+			if prepared.Model.DryRun {
+				response.Responses[q.RefID] = e.handleDryRunQuery(q.RefID, expanded...)
+				continue
+			}
+			batchable = append(batchable, expanded...)
 		case preparedQueryLegacy:
 			response.Responses[q.RefID] = e.handleLegacyQuery(prepared.Model, q.TimeRange)
 		}
 	}
 
-	for refID, res := range e.executePreparedBatches(ctx, batchable) {
+	if e.config != nil && e.config.AssetAccessCheck {
+		var denied map[string]backend.DataResponse
+		batchable, denied = e.filterAccessibleAssets(ctx, batchable)
+		for refID, res := range denied {
+			response.Responses[refID] = res
+		}
+	}
+
+	if e.config != nil && e.config.DataScopeCheck {
+		var unknown map[string]backend.DataResponse
+		batchable, unknown = e.filterKnownDataScopes(ctx, batchable)
+		for refID, res := range unknown {
+			response.Responses[refID] = res
+		}
+	}
+
+	if limitErr := e.checkExpandedQueries(batchable); limitErr != nil {
+		for _, prepared := range batchable {
+			response.Responses[prepared.Query.RefID] = *limitErr
+		}
+		return response
+	}
+
+	results := e.executePreparedBatches(ctx, batchable)
+	e.attachChannelTags(ctx, batchable, results)
+	e.attachAssetMeta(ctx, batchable, results)
+	for refID, res := range results {
 		response.Responses[refID] = res
 	}
 
 	return response
 }
 
+// duplicateRefIDs returns each RefID shared by more than one query, with its
+// count. Responses are keyed by RefID, so none of those queries can be answered
+// without overwriting another.
+func duplicateRefIDs(queries []backend.DataQuery) map[string]int {
+	counts := make(map[string]int, len(queries))
+	for _, q := range queries {
+		counts[q.RefID]++
+	}
+	duplicates := map[string]int{}
+	for refID, count := range counts {
+		if count > 1 {
+			duplicates[refID] = count
+		}
+	}
+	return duplicates
+}
+
 type queryBatch struct {
 	queries []backend.DataQuery
 	models  []NominalQueryModel
 }
 
+// byTimeRange splits b into batches of queries with identical time ranges,
+// keeping the queries' order within each batch and ordering the batches by
+// their first query. A batch whose queries all share one range is returned
+// whole.
+func (b queryBatch) byTimeRange() []queryBatch {
+	type rangeKey struct{ from, to int64 }
+	var groups []queryBatch
+	index := make(map[rangeKey]int)
+	for i, q := range b.queries {
+		key := rangeKey{q.TimeRange.From.UnixNano(), q.TimeRange.To.UnixNano()}
+		g, ok := index[key]
+		if !ok {
+			g = len(groups)
+			index[key] = g
+			groups = append(groups, queryBatch{})
+		}
+		groups[g].queries = append(groups[g].queries, q)
+		groups[g].models = append(groups[g].models, b.models[i])
+	}
+	return groups
+}
+
+// add appends one batch entry per compute subrequest the query needs. A numeric
+// DualResolution query expands into an overview and a detail entry sharing the
+// same RefID; executeBatchQuery merges their frames back into one response.
 func (b *queryBatch) add(prepared preparedQuery) {
-	b.queries = append(b.queries, prepared.Query)
-	b.models = append(b.models, prepared.Model)
+	qm := prepared.Model
+	if !qm.DualResolution || qm.isEnumQuery() || qm.ChannelDataType == ChannelDataTypeLog {
+		b.queries = append(b.queries, prepared.Query)
+		b.models = append(b.models, qm)
+		return
+	}
+	for _, role := range []string{ResolutionRoleOverview, ResolutionRoleDetail} {
+		roleModel := qm
+		roleModel.ResolutionRole = role
+		b.queries = append(b.queries, prepared.Query)
+		b.models = append(b.models, roleModel)
+	}
+}
+
+// mergeBatchResponse records res for refID. Entries expanded from one query
+// append their frames in batch order; the first error wins.
+func mergeBatchResponse(results map[string]backend.DataResponse, refID string, res backend.DataResponse) {
+	existing, ok := results[refID]
+	switch {
+	case !ok:
+		results[refID] = res
+	case existing.Error != nil:
+		// First error wins.
+	case res.Error != nil:
+		results[refID] = res
+	default:
+		existing.Frames = append(existing.Frames, res.Frames...)
+		results[refID] = existing
+	}
+}
+
+// applyResolutionRole names a DualResolution entry's frames after its role and
+// suffixes the field display names so overview and detail stay distinct in legends.
+func applyResolutionRole(res backend.DataResponse, role string) {
+	for _, frame := range res.Frames {
+		frame.Name = role
+		for _, field := range frame.Fields {
+			if field.Config != nil && field.Config.DisplayNameFromDS != "" {
+				field.Config.DisplayNameFromDS = fmt.Sprintf("%s (%s)", field.Config.DisplayNameFromDS, role)
+			}
+		}
+	}
+}
+
+// setFrameCustomMeta stores value under key in the frame's Meta.Custom map,
+// creating Meta and the map as needed. A non-map Custom value is left alone.
+func setFrameCustomMeta(frame *data.Frame, key string, value any) {
+	if frame.Meta == nil {
+		frame.Meta = &data.FrameMeta{}
+	}
+	if frame.Meta.Custom == nil {
+		frame.Meta.Custom = map[string]any{}
+	}
+	custom, ok := frame.Meta.Custom.(map[string]any)
+	if !ok {
+		log.DefaultLogger.Warn("Frame Meta.Custom is not a map; skipping custom metadata", "key", key)
+		return
+	}
+	custom[key] = value
 }
 
 func (e *NominalQueryExecution) executePreparedBatches(ctx context.Context, prepared []preparedQuery) map[string]backend.DataResponse {
@@ -127,6 +304,19 @@ func (e *NominalQueryExecution) executeBatchQuery(ctx context.Context, batch que
 		return results
 	}
 
+	// Some backends accept a single time range per batch request, so queries
+	// with different ranges (e.g. a panel's relative time override) are sent
+	// as separate batches.
+	for _, group := range batch.byTimeRange() {
+		e.executeBatchChunks(ctx, bearerToken, group, results)
+	}
+	return results
+}
+
+// executeBatchChunks runs batch, whose queries share one time range, in
+// chunks of at most maxBatchComputeSubrequests and merges each query's
+// response into results.
+func (e *NominalQueryExecution) executeBatchChunks(ctx context.Context, bearerToken bearertoken.Token, batch queryBatch, results map[string]backend.DataResponse) {
 	for chunkStart := 0; chunkStart < len(batch.queries); chunkStart += maxBatchComputeSubrequests {
 		chunkEnd := chunkStart + maxBatchComputeSubrequests
 		if chunkEnd > len(batch.queries) {
@@ -151,13 +341,15 @@ func (e *NominalQueryExecution) executeBatchQuery(ctx context.Context, batch que
 			"queryCount", len(computeRequests),
 		)
 
+		chunkStartedAt := time.Now()
 		batchResponse, err := e.datasource.computeService.BatchComputeWithUnits(ctx, bearerToken, batchRequest)
+		e.logSlowChunk(chunkQueries, time.Since(chunkStartedAt))
 		if err != nil {
 			logErrorWithConjureFields("Batch compute API call failed", err,
 				"chunkStart", chunkStart, "chunkEnd", chunkEnd)
 			errMsg := formatUserError("Batch compute failed", err)
 			for _, q := range chunkQueries {
-				results[q.RefID] = backend.ErrDataResponse(backend.StatusInternal, errMsg)
+				mergeBatchResponse(results, q.RefID, backend.ErrDataResponse(backend.StatusInternal, errMsg))
 			}
 			continue
 		}
@@ -169,18 +361,191 @@ func (e *NominalQueryExecution) executeBatchQuery(ctx context.Context, batch que
 			"resultCount", len(batchResponse.Results),
 		)
 
+		if missing := len(chunkQueries) - len(batchResponse.Results); missing > 0 {
+			log.DefaultLogger.Warn("Batch compute returned fewer results than requests",
+				"chunkStart", chunkStart,
+				"missing", missing,
+				"retryIndividually", e.config.RetryMissingBatchResults,
+			)
+		}
+
+		returned := len(batchResponse.Results)
+		var retried []backend.DataResponse
+		if e.config.RetryMissingBatchResults && returned < len(chunkQueries) {
+			retried = e.computeMissingResults(ctx, bearerToken, computeRequests[returned:], chunkModels[returned:])
+		}
+
 		for i, q := range chunkQueries {
-			if i >= len(batchResponse.Results) {
-				results[q.RefID] = backend.ErrDataResponse(
+			var res backend.DataResponse
+			switch {
+			case i < returned:
+				logBatchSubrequestError(q.RefID, chunkStart+i, batchResponse.Results[i], chunkModels[i])
+				res = dataScopeFailureResponse(e.transformBatchResult(batchResponse.Results[i], chunkModels[i]), chunkModels[i])
+			case e.config.RetryMissingBatchResults:
+				res = dataScopeFailureResponse(retried[i-returned], chunkModels[i])
+			default:
+				mergeBatchResponse(results, q.RefID, backend.ErrDataResponse(
 					backend.StatusInternal,
 					"Missing result in batch response",
-				)
+				))
 				continue
 			}
 
-			results[q.RefID] = e.transformBatchResult(batchResponse.Results[i], chunkModels[i])
+			labelDataScopeFrames(res.Frames, chunkModels[i])
+			if role := chunkModels[i].ResolutionRole; role != "" {
+				applyResolutionRole(res, role)
+			}
+			if chunkModels[i].DebugContext {
+				for _, frame := range res.Frames {
+					setFrameCustomMeta(frame, "computeContextVariables", redactedContextVariables(chunkModels[i]))
+				}
+			}
+			if chunkModels[i].IncludeRefID {
+				for _, frame := range res.Frames {
+					setFrameCustomMeta(frame, "refId", q.RefID)
+				}
+			}
+			mergeBatchResponse(results, q.RefID, res)
 		}
 	}
 
-	return results
+}
+
+// logBatchSubrequestError logs a failed batch result with the query it
+// answers, so a partial batch failure can be traced back to its RefID.
+// Successful results are not logged.
+func logBatchSubrequestError(refID string, index int, result computeapi.ComputeWithUnitsResult, qm NominalQueryModel) {
+	_ = result.ComputeResult.AcceptFuncs(
+		func(computeapi.ComputeNodeResponse) error { return nil },
+		func(errorResult computeapi.ErrorResult) error {
+			log.DefaultLogger.Warn("Batch compute subrequest failed",
+				"refId", refID,
+				"index", index,
+				"assetRid", qm.AssetRid,
+				"channel", qm.Channel,
+				"errorType", errorResult.ErrorType,
+				"code", errorResult.Code,
+			)
+			return nil
+		},
+		func(string) error { return nil },
+	)
+}
+
+// computeMissingResults runs computeMissingResult for each request, at most
+// maxConcurrentSingleComputes at a time. Each response lands at its request's
+// index, so one failed retry leaves the others untouched.
+func (e *NominalQueryExecution) computeMissingResults(ctx context.Context, bearerToken bearertoken.Token, requests []computeapi1.ComputeNodeRequest, models []NominalQueryModel) []backend.DataResponse {
+	responses := make([]backend.DataResponse, len(requests))
+	sem := make(chan struct{}, maxConcurrentSingleComputes)
+	var wg sync.WaitGroup
+	for i := range requests {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			responses[i] = e.computeMissingResult(ctx, bearerToken, requests[i], models[i])
+		}(i)
+	}
+	wg.Wait()
+	return responses
+}
+
+// computeMissingResult re-runs a request the batch response left without a
+// result as a single Compute call. Compute reports no unit, so the frames fall
+// back to the query's channel unit.
+func (e *NominalQueryExecution) computeMissingResult(ctx context.Context, bearerToken bearertoken.Token, request computeapi1.ComputeNodeRequest, qm NominalQueryModel) backend.DataResponse {
+	response, err := e.datasource.computeService.Compute(ctx, bearerToken, request)
+	if err != nil {
+		logErrorWithConjureFields("Single compute retry for missing batch result failed", err)
+		return backend.ErrDataResponse(backend.StatusInternal,
+			formatUserError("Missing result in batch response; single compute retry failed", err))
+	}
+	return e.transformBatchResult(computeapi.ComputeWithUnitsResult{
+		ComputeResult: computeapi.NewComputeNodeResultFromSuccess(response),
+	}, qm)
+}
+
+// handleDryRunQuery assembles the BatchComputeWithUnitsRequest the prepared
+// queries of refID would send, without calling the compute service, and
+// returns it in the Meta.Custom of an otherwise empty frame. A tagSelector or
+// allDataScopes query passes every query it expanded into, so the request
+// holds one subrequest per match.
+func (e *NominalQueryExecution) handleDryRunQuery(refID string, prepared ...preparedQuery) backend.DataResponse {
+	var batch queryBatch
+	for _, p := range prepared {
+		batch.add(p)
+	}
+
+	computeRequests := make([]computeapi1.ComputeNodeRequest, len(batch.models))
+	for i, qm := range batch.models {
+		computeRequests[i] = e.buildComputeRequest(qm, batch.queries[i].TimeRange, batch.queries[i].MaxDataPoints)
+	}
+
+	log.DefaultLogger.Debug("Dry run: skipping batch compute call", "refId", refID, "subrequests", len(computeRequests))
+
+	frame := data.NewFrame(refID)
+	setFrameCustomMeta(frame, "dryRun", true)
+	setFrameCustomMeta(frame, "batchComputeRequest", computeapi1.BatchComputeWithUnitsRequest{Requests: computeRequests})
+	return backend.DataResponse{Frames: data.Frames{frame}}
+}
+
+// slowQueryThreshold returns the configured slow-query threshold, falling back to
+// defaultSlowQueryThreshold when unset.
+func (e *NominalQueryExecution) slowQueryThreshold() time.Duration {
+	if e.config == nil || e.config.SlowQueryThresholdMs <= 0 {
+		return defaultSlowQueryThreshold
+	}
+	return time.Duration(e.config.SlowQueryThresholdMs) * time.Millisecond
+}
+
+// maxExpandedQueries returns the configured expansion cap, falling back to
+// defaultMaxExpandedQueries when unset.
+func (e *NominalQueryExecution) maxExpandedQueries() int {
+	if e.config == nil || e.config.MaxExpandedQueries <= 0 {
+		return defaultMaxExpandedQueries
+	}
+	return e.config.MaxExpandedQueries
+}
+
+// checkExpandedQueries returns an error response for every batchable query when
+// together they expand into more compute subrequests than maxExpandedQueries.
+// Nothing is executed in that case: a partial answer to a runaway selection
+// would be easy to mistake for the full one.
+func (e *NominalQueryExecution) checkExpandedQueries(prepared []preparedQuery) *backend.DataResponse {
+	var expanded queryBatch
+	for _, query := range prepared {
+		expanded.add(query)
+	}
+	limit := e.maxExpandedQueries()
+	if len(expanded.queries) <= limit {
+		return nil
+	}
+	log.DefaultLogger.Warn("Query expansion exceeds limit", "subrequests", len(expanded.queries), "queries", len(prepared), "limit", limit)
+	response := backend.ErrDataResponse(
+		backend.StatusBadRequest,
+		fmt.Sprintf("queries expand into %d compute subrequests, more than the limit of %d (maxExpandedQueries); narrow the variable selection or raise the limit in the datasource settings",
+			len(expanded.queries), limit),
+	)
+	return &response
+}
+
+// logSlowChunk warns when one batch compute chunk took longer than the slow-query
+// threshold. Failed chunks are included: a slow failure is still an SLO miss.
+func (e *NominalQueryExecution) logSlowChunk(chunkQueries []backend.DataQuery, elapsed time.Duration) {
+	threshold := e.slowQueryThreshold()
+	if elapsed <= threshold {
+		return
+	}
+	refIDs := make([]string, len(chunkQueries))
+	for i, q := range chunkQueries {
+		refIDs[i] = q.RefID
+	}
+	log.DefaultLogger.Warn("Slow batch compute chunk",
+		"refIds", refIDs,
+		"chunkSize", len(chunkQueries),
+		"elapsedMs", elapsed.Milliseconds(),
+		"thresholdMs", threshold.Milliseconds(),
+	)
 }
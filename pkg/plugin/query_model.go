@@ -1,11 +1,14 @@
 package plugin
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
@@ -19,27 +22,249 @@ type NominalQueryModel struct {
 	DataScopeName   string `json:"dataScopeName"`
 	ChannelDataType string `json:"channelDataType"`
 
+	// DataSourceRid addresses the channel directly through the RID of the data source
+	// that owns it, bypassing the asset/data-scope lookup. The compute API binds
+	// data-source channels by (data source RID, channel name), so Channel is still
+	// required alongside it; AssetRid and DataScopeName are ignored when set.
+	DataSourceRid string `json:"dataSourceRid,omitempty"`
+
 	// Aggregation functions for numeric channels (e.g. "MEAN", "MIN", "MAX").
 	// Empty/missing defaults to ["MEAN"]. Ignored for enum channels.
 	Aggregations         []string `json:"aggregations,omitempty"`
 	ExplicitAggregations bool     `json:"-"` // true when aggregations were set by the frontend (not defaulted)
 
+	// GroupByTags splits the channel by these tag keys; each group is returned
+	// as its own frame labelled with the group's tag values.
+	GroupByTags []string `json:"groupByTags,omitempty"`
+
+	// TagSelector selects every channel of the asset's data scope carrying all
+	// of these tags, in place of Channel. Each matched channel is computed
+	// filtered to these tags and returned as its own frame (see
+	// expandTagSelector).
+	TagSelector map[string]string `json:"tagSelector,omitempty"`
+
+	// AllDataScopes computes Channel in every dataset and connection scope of
+	// the asset, in place of DataScopeName. Each scope is returned as its own
+	// frame labelled with the scope name (see expandAllDataScopes).
+	AllDataScopes bool `json:"allDataScopes,omitempty"`
+
+	// IncludeChannelTags attaches the channel's single-valued tags as labels on
+	// the query's value fields. It costs one extra tag lookup per query, made
+	// after the compute call (see attachChannelTags), so it is opt-in.
+	IncludeChannelTags bool `json:"includeChannelTags,omitempty"`
+
+	// IncludeAssetMeta adds the asset's title and description to each frame's
+	// Meta.Custom under "asset", for context panels. The asset is fetched
+	// through the catalog's cache after the compute call (see attachAssetMeta).
+	IncludeAssetMeta bool `json:"includeAssetMeta,omitempty"`
+
+	// EnumAggregation picks each bucket's value for enum channels: "MODE" (default),
+	// "FIRST_POINT", or "LAST_POINT". Setting it also requests enum bucketing for a
+	// channel whose type is not known to be string.
+	EnumAggregation string `json:"enumAggregation,omitempty"`
+
 	// Query parameters
 	Buckets   int    `json:"buckets"`
 	QueryType string `json:"queryType"`
+	// BucketsTemplate is runtime-only; holds a string "buckets" value (e.g.
+	// "$resolution") until applyTemplateVariables resolves it into Buckets.
+	BucketsTemplate string `json:"-"`
+	// IntervalMs pins the bucket width in milliseconds: the time range is split
+	// into range/IntervalMs buckets regardless of Buckets and the panel's
+	// MaxDataPoints. Zero leaves bucketing to those. It may not exceed the time
+	// range, and a count above the datasource's bucket limit is clamped with a
+	// notice like any other.
+	IntervalMs int `json:"intervalMs,omitempty"`
+	// IntervalTemplate is runtime-only; holds a string "intervalMs" value (a
+	// millisecond count, a Go duration like "1m", an ISO 8601 duration like
+	// "PT1M", or a variable resolving to one) until applyTemplateVariables
+	// resolves it into IntervalMs.
+	IntervalTemplate string `json:"-"`
+	// IntervalBuckets is runtime-only; the bucket count IntervalMs resolves to
+	// over the query's time range in prepareQuery.
+	IntervalBuckets int `json:"-"`
+	// MaxBuckets and ClampedBuckets are runtime-only; set in prepareQuery to the
+	// datasource's bucket limit and, when the requested count exceeded it, that
+	// requested count (for bucketClampNotice).
+	MaxBuckets     int `json:"-"`
+	ClampedBuckets int `json:"-"`
+
+	// TimeShift moves the series later by a Go duration ("90m") or a calendar
+	// shift ("1d", "1w", "1M", "1y") so earlier data overlays the current range.
+	// Calendar shifts are resolved in TimeZone (IANA name, default UTC). The
+	// compute API cannot align buckets to TimeZone; see timeZoneAlignmentNotice.
+	TimeShift string `json:"timeShift,omitempty"`
+	TimeZone  string `json:"timeZone,omitempty"`
+	// TimeShiftDuration is runtime-only; TimeShift resolved against the query's
+	// time range in prepareQuery.
+	TimeShiftDuration time.Duration `json:"-"`
+
+	// IncludeStdDev adds a "stddev" field (per-bucket standard deviation) to
+	// bucketed numeric frames. Arrow queries request VARIANCE alongside MEAN
+	// for it; explicit Aggregations are left as chosen.
+	IncludeStdDev bool `json:"includeStdDev,omitempty"`
+
+	// StatFields emits the listed bucket statistics ("mean", "min", "max",
+	// "count", "stddev") as fields of one frame for bucketed numeric results.
+	// Empty keeps the single mean "value" field; when set, IncludeStdDev is
+	// ignored in favour of "stddev".
+	StatFields []string `json:"statFields,omitempty"`
+	// StatConfig chooses the server-side summarization of individual stat
+	// fields, e.g. {"max": "LAST_POINT"}. Keys must be in StatFields and be
+	// mean, min, or max; values are aggregations (MEAN, MIN, MAX, COUNT,
+	// VARIANCE, FIRST_POINT, LAST_POINT). Unset fields keep their own
+	// summary. The compute API offers no percentile summaries.
+	StatConfig map[string]string `json:"statConfig,omitempty"`
+
+	// FillPolicy fills null values in numeric series: "none" (default),
+	// "previous" (forward-fill), "zero", or "linear" (interpolated between the
+	// surrounding values; nulls at either end stay null).
+	FillPolicy string `json:"fillPolicy,omitempty"`
+
+	// GapValue inserts a sentinel point, at the bucket interval, for every
+	// bucket missing from a numeric series, so panels see an explicit value
+	// instead of no point. It is a number or "NaN" (JSON has no NaN literal).
+	// FillPolicy applies first and only to buckets returned as null; inserted
+	// sentinels are never filled over.
+	GapValue string `json:"gapValue,omitempty"`
+	// GapSentinel and GapInterval are runtime-only; the parsed GapValue and
+	// the bucket width gaps are detected against, set in prepareQuery.
+	GapSentinel float64       `json:"-"`
+	GapInterval time.Duration `json:"-"`
+
+	// DualResolution requests a bucketed overview and a capped raw detail series
+	// for the same numeric channel, returned as frames named "overview" and "detail".
+	DualResolution bool `json:"dualResolution,omitempty"`
+	// ValueScale and ValueOffset convert numeric values to value*scale + offset
+	// (e.g. raw ADC counts to engineering units). A zero ValueScale leaves
+	// values unchanged; an offset requires a non-zero scale.
+	ValueScale  float64 `json:"valueScale,omitempty"`
+	ValueOffset float64 `json:"valueOffset,omitempty"`
+
+	// ValuePrecision sets the width of numeric value fields: "float64"
+	// (default) or "float32", which halves their payload size. float32 keeps
+	// about 7 significant digits, so large or finely resolved values (e.g.
+	// counters, high-resolution sensors) are rounded.
+	ValuePrecision string `json:"valuePrecision,omitempty"`
+
+	// ValueType sets the type of numeric value fields: "float64" (default) or
+	// "int64", for counters and indices that should not render as "1.0" or
+	// lose digits above 2^53. Fractional values are rounded half away from
+	// zero (see int64Field).
+	ValueType string `json:"valueType,omitempty"`
+
+	// Reduce collapses the fetched series to one row holding a single value
+	// per field: "avg", "max", "min", "last", or "sum". Unlike the API's
+	// numeric point summaries it runs in the plugin, over the returned points.
+	Reduce string `json:"reduce,omitempty"`
+
+	// ReduceForAlerting returns each series as a single-value numeric frame
+	// (no time field) for threshold alerts, reduced with Reduce or, when it
+	// is empty, "last" (see reduceFramesForAlerting).
+	ReduceForAlerting bool `json:"reduceForAlerting,omitempty"`
+
+	// DenseArrowFrames decodes Arrow bucketed responses into dense numeric
+	// frames: each value column is bulk-copied into a non-nullable field
+	// rather than decoded into a *float64 per value. The frames are ordinary
+	// Go-backed frames, not views over the Arrow buffers. It is ignored, with
+	// the standard path used instead, when the query sets StatFields,
+	// IncludeStdDev, ValueScale, FillPolicy, GapValue, or Stride, or when the
+	// response has nulls or FIRST_POINT/LAST_POINT series (see
+	// extractArrowDenseSeries).
+	DenseArrowFrames bool `json:"denseArrowFrames,omitempty"`
+
+	// IncludeStats adds min, max, avg, and count of each numeric field to the
+	// frame's Meta.Stats, computed over the returned points (after reduce).
+	IncludeStats bool `json:"includeStats,omitempty"`
+
+	// Stride switches a numeric query to raw points (up to maxReturnedPoints)
+	// and keeps every Stride-th one, plus the last, instead of bucketing.
+	// Zero leaves the query bucketed.
+	Stride int `json:"stride,omitempty"`
+
+	// IncludeBucketEdges adds "bucketStart" and "bucketEnd" time fields to
+	// bucketed frames, for transforms that need explicit bucket extents.
+	IncludeBucketEdges bool `json:"includeBucketEdges,omitempty"`
+	// BucketInterval is runtime-only; the bucket width resolved from the
+	// query's time range and bucket count in prepareQuery.
+	BucketInterval time.Duration `json:"-"`
+
+	// GapThresholdMs is the minimum length, in milliseconds, of a range with no
+	// data for a gaps query to report it. Zero uses the bucket width.
+	GapThresholdMs int `json:"gapThresholdMs,omitempty"`
+	// GapThreshold is runtime-only; the resolved minimum gap length (see
+	// gapThreshold), set in prepareQuery for gaps queries.
+	GapThreshold time.Duration `json:"-"`
+
+	// ResolutionRole is runtime-only; set on the expanded batch entries of a
+	// DualResolution query to pick the overview or detail plan.
+	ResolutionRole string `json:"-"`
 
 	// Template variables support
 	TemplateVariables map[string]interface{} `json:"templateVariables,omitempty"`
 
-	// Legacy support
+	// DryRun returns the assembled batch compute request as frame metadata
+	// instead of executing it.
+	DryRun bool `json:"dryRun,omitempty"`
+
+	// DebugContext attaches the resolved compute-context variables (sensitive
+	// values redacted) to each result frame's Meta.Custom.
+	DebugContext bool `json:"debugContext,omitempty"`
+
+	// IncludeRefID records the query's RefID in each result frame's
+	// Meta.Custom, so frames can be traced back after panel transforms
+	// reorder or merge them.
+	IncludeRefID bool `json:"includeRefId,omitempty"`
+
+	// Legacy support. With an AssetRid and no Channel, QueryText is an
+	// arithmetic expression over the asset's channels (see channelExpression).
 	QueryText string  `json:"queryText"`
 	Constant  float64 `json:"constant"`
+	// NumPoints is how many evenly spaced points a constant query returns
+	// across the time range, for frontend rendering tests. Zero returns two,
+	// one at each end of the range.
+	NumPoints int `json:"numPoints,omitempty"`
+	// Expression is runtime-only; QueryText parsed in prepareQuery for
+	// expression queries.
+	Expression *channelExpression `json:"-"`
 
 	// ChannelUnit is runtime-only; populated by inferChannelMetadata at QueryData time.
 	// json:"-" prevents inferred values from persisting into saved dashboards.
 	ChannelUnit string `json:"-"`
 }
 
+// hasChannelQuery reports whether the model addresses a channel, either through
+// an asset or directly through a channel RID.
+func (qm NominalQueryModel) hasChannelQuery() bool {
+	return qm.Channel != "" && (qm.AssetRid != "" || qm.DataSourceRid != "")
+}
+
+// isExpressionQuery reports whether QueryText is a channel expression: an
+// asset-scoped query with QueryText but no single Channel.
+func (qm NominalQueryModel) isExpressionQuery() bool {
+	return qm.AssetRid != "" && qm.Channel == "" && strings.TrimSpace(qm.QueryText) != ""
+}
+
+// isBucketed reports whether the query is summarized into buckets, as opposed
+// to raw points (logs, strided or dual-resolution detail series) or gap ranges.
+func (qm NominalQueryModel) isBucketed() bool {
+	return qm.QueryType != queryTypeGaps && qm.ChannelDataType != ChannelDataTypeLog &&
+		qm.Stride == 0 && qm.ResolutionRole != ResolutionRoleDetail
+}
+
+// isEnumQuery reports whether the model is summarized as an enum series: a string
+// channel, or a channel of unknown type with an explicit EnumAggregation.
+// validateChannelDataType rejects EnumAggregation on a numeric channel.
+func (qm NominalQueryModel) isEnumQuery() bool {
+	switch qm.ChannelDataType {
+	case ChannelDataTypeString:
+		return true
+	case "":
+		return qm.EnumAggregation != ""
+	}
+	return false
+}
+
 // ChannelDataType values. These are produced by getChannelDataType (normalizing the
 // API's SeriesDataType) and consumed by the compute-request and query-execution layers.
 // An empty ChannelDataType (searched-but-not-found, or DataType nil) is treated as numeric.
@@ -49,14 +274,83 @@ const (
 	ChannelDataTypeLog     = "log"
 )
 
+// ResolutionRole values for DualResolution queries; also used as frame names.
+const (
+	ResolutionRoleOverview = "overview"
+	ResolutionRoleDetail   = "detail"
+)
+
 type preparedQueryKind int
 
 const (
 	preparedQueryConnectionTest preparedQueryKind = iota
 	preparedQueryLegacy
 	preparedQueryBatchable
+	preparedQueryChannelTable
+	// preparedQueryTagSelector is batchable once expandTagSelector resolves
+	// its channels.
+	preparedQueryTagSelector
+	// preparedQueryAllDataScopes is batchable once expandAllDataScopes
+	// resolves the asset's data scopes.
+	preparedQueryAllDataScopes
 )
 
+// queryTypeChannelTable lists an asset's channels as a table instead of
+// computing channel data.
+const queryTypeChannelTable = "channelTable"
+
+// queryTypeCount returns the number of samples per bucket instead of values,
+// for data-availability panels. It is shorthand for aggregations [COUNT].
+const queryTypeCount = "count"
+
+// queryTypeGaps returns the ranges in which a channel has no data, as a table
+// of gap start/end times (see buildGapsFrame), for data-quality panels.
+const queryTypeGaps = "gaps"
+
+// queryTypeStateTimeline returns an enum channel with consecutive identical
+// states merged into spans (see mergeStateRuns), for State Timeline panels.
+// Non-enum results are returned unchanged.
+const queryTypeStateTimeline = "stateTimeline"
+
+// nominalQueryModelJSON has NominalQueryModel's fields without its methods, so
+// UnmarshalJSON can decode into it without recursing.
+type nominalQueryModelJSON NominalQueryModel
+
+// UnmarshalJSON accepts "buckets" and "intervalMs" as either a number or a
+// string, so a template variable such as "$resolution" can drive them. String
+// values are kept in BucketsTemplate and IntervalTemplate and parsed by
+// applyTemplateVariables.
+func (qm *NominalQueryModel) UnmarshalJSON(raw []byte) error {
+	var decoded struct {
+		nominalQueryModelJSON
+		Buckets    json.RawMessage `json:"buckets"`
+		IntervalMs json.RawMessage `json:"intervalMs"`
+	}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return err
+	}
+	*qm = NominalQueryModel(decoded.nominalQueryModelJSON)
+
+	if interval := bytes.TrimSpace(decoded.IntervalMs); len(interval) > 0 && !bytes.Equal(interval, []byte("null")) {
+		target := any(&qm.IntervalMs)
+		if interval[0] == '"' {
+			target = &qm.IntervalTemplate
+		}
+		if err := json.Unmarshal(interval, target); err != nil {
+			return err
+		}
+	}
+
+	buckets := bytes.TrimSpace(decoded.Buckets)
+	if len(buckets) == 0 || bytes.Equal(buckets, []byte("null")) {
+		return nil
+	}
+	if buckets[0] == '"' {
+		return json.Unmarshal(buckets, &qm.BucketsTemplate)
+	}
+	return json.Unmarshal(buckets, &qm.Buckets)
+}
+
 type preparedQuery struct {
 	Query backend.DataQuery
 	Model NominalQueryModel
@@ -65,21 +359,47 @@ type preparedQuery struct {
 
 // prepareQuery turns one raw Grafana query into the runtime shape used by query execution.
 func (e *NominalQueryExecution) prepareQuery(ctx context.Context, q backend.DataQuery) (preparedQuery, *backend.DataResponse) {
+	if trimmed := bytes.TrimSpace(q.JSON); len(trimmed) == 0 || bytes.Equal(trimmed, []byte("null")) {
+		response := backend.ErrDataResponse(
+			backend.StatusBadRequest,
+			fmt.Sprintf("empty query (refId %s): the query has no JSON body; configure an asset and channel", q.RefID),
+		)
+		return preparedQuery{}, &response
+	}
+
 	var qm NominalQueryModel
 	if err := json.Unmarshal(q.JSON, &qm); err != nil {
 		response := backend.ErrDataResponse(
 			backend.StatusBadRequest,
-			fmt.Sprintf("json unmarshal: %v", err),
+			fmt.Sprintf("json unmarshal (refId %s): %v; query JSON: %s", q.RefID, err, queryJSONSnippet(q.JSON)),
 		)
 		return preparedQuery{}, &response
 	}
 
-	e.applyTemplateVariables(&qm)
+	if err := e.applyTemplateVariables(&qm); err != nil {
+		response := backend.ErrDataResponse(
+			backend.StatusBadRequest,
+			fmt.Sprintf("Query validation failed: %v", err),
+		)
+		return preparedQuery{}, &response
+	}
+	e.applyDefaultTimeShift(&qm)
 
 	if qm.QueryType == "connectionTest" {
 		return preparedQuery{Query: q, Model: qm, Kind: preparedQueryConnectionTest}, nil
 	}
 
+	if qm.QueryType == queryTypeChannelTable {
+		if strings.TrimSpace(qm.AssetRid) == "" {
+			response := backend.ErrDataResponse(
+				backend.StatusBadRequest,
+				"Query validation failed: assetRid is required for channelTable queries",
+			)
+			return preparedQuery{}, &response
+		}
+		return preparedQuery{Query: q, Model: qm, Kind: preparedQueryChannelTable}, nil
+	}
+
 	if err := e.validateQuery(qm); err != nil {
 		log.DefaultLogger.Error("Query validation failed", "error", err)
 		response := backend.ErrDataResponse(
@@ -89,26 +409,127 @@ func (e *NominalQueryExecution) prepareQuery(ctx context.Context, q backend.Data
 		return preparedQuery{}, &response
 	}
 
+	shift, err := resolveTimeShift(qm.TimeShift, qm.TimeZone, q.TimeRange.From)
+	if err != nil {
+		response := backend.ErrDataResponse(
+			backend.StatusBadRequest,
+			fmt.Sprintf("Query validation failed: %v", err),
+		)
+		return preparedQuery{}, &response
+	}
+	qm.TimeShiftDuration = shift
+	if qm.IntervalMs > 0 {
+		buckets, err := intervalBucketCount(q.TimeRange, qm.IntervalMs)
+		if err != nil {
+			response := backend.ErrDataResponse(
+				backend.StatusBadRequest,
+				fmt.Sprintf("Query validation failed: %v", err),
+			)
+			return preparedQuery{}, &response
+		}
+		qm.IntervalBuckets = buckets
+	}
+	// Checked before MaxBuckets is set, so effectiveBucketCount is unclamped.
+	if requested, limit := effectiveBucketCount(qm, q.MaxDataPoints), e.maxBuckets(); requested > limit {
+		log.DefaultLogger.Warn("Clamping bucket count to the backend maximum", "refId", q.RefID, "buckets", requested, "maxBuckets", limit)
+		qm.ClampedBuckets = requested
+	}
+	qm.MaxBuckets = e.maxBuckets()
+	if qm.QueryType == queryTypeGaps {
+		qm.GapThreshold = gapThreshold(qm, q.TimeRange, q.MaxDataPoints)
+	}
+	if qm.IncludeBucketEdges {
+		qm.BucketInterval = bucketInterval(q.TimeRange, effectiveBucketCount(qm, q.MaxDataPoints))
+	}
+	if qm.GapValue != "" {
+		// Validated above, so the parse cannot fail.
+		qm.GapSentinel, _ = strconv.ParseFloat(qm.GapValue, 64)
+		qm.GapInterval = bucketInterval(q.TimeRange, effectiveBucketCount(qm, q.MaxDataPoints))
+	}
+
+	if len(qm.TagSelector) > 0 {
+		// Channel metadata and aggregations are resolved per matched channel.
+		return preparedQuery{Query: q, Model: qm, Kind: preparedQueryTagSelector}, nil
+	}
+	if qm.AllDataScopes {
+		// Channel metadata and aggregations are resolved per data scope.
+		return preparedQuery{Query: q, Model: qm, Kind: preparedQueryAllDataScopes}, nil
+	}
+
 	e.inferChannelMetadata(ctx, &qm)
-	if prepErr := normalizeAggregations(&qm); prepErr != nil {
+	if prepErr := resolveQueryModel(&qm); prepErr != nil {
 		return preparedQuery{}, prepErr
 	}
 
-	if qm.AssetRid != "" && qm.Channel != "" {
+	if qm.isExpressionQuery() {
+		// validateQuery has already rejected unparseable expressions.
+		expr, _ := parseChannelExpression(qm.QueryText)
+		qm.Expression = &expr
+		return preparedQuery{Query: q, Model: qm, Kind: preparedQueryBatchable}, nil
+	}
+
+	if qm.hasChannelQuery() {
 		return preparedQuery{Query: q, Model: qm, Kind: preparedQueryBatchable}, nil
 	}
 
 	return preparedQuery{Query: q, Model: qm, Kind: preparedQueryLegacy}, nil
 }
 
+// maxQueryJSONSnippetBytes bounds how much of a malformed query body is echoed
+// back in its error message.
+const maxQueryJSONSnippetBytes = 200
+
+// queryJSONSnippet returns raw as a string, truncated to maxQueryJSONSnippetBytes
+// without splitting a multi-byte character.
+func queryJSONSnippet(raw []byte) string {
+	if len(raw) <= maxQueryJSONSnippetBytes {
+		return string(raw)
+	}
+	return strings.ToValidUTF8(string(raw[:maxQueryJSONSnippetBytes]), "") + "..."
+}
+
+// resolveQueryModel completes a model once its channel metadata is known: it
+// runs the checks that depend on the channel's data type, which validateQuery
+// makes before inference, and normalizes the aggregations.
+func resolveQueryModel(qm *NominalQueryModel) *backend.DataResponse {
+	if err := validateChannelDataType(*qm); err != nil {
+		response := backend.ErrDataResponse(
+			backend.StatusBadRequest,
+			fmt.Sprintf("Query validation failed: %v", err),
+		)
+		return &response
+	}
+	return normalizeAggregations(qm)
+}
+
+// validateChannelDataType returns an error when qm's options do not fit its
+// channel's data type.
+func validateChannelDataType(qm NominalQueryModel) error {
+	if qm.EnumAggregation != "" && qm.ChannelDataType == ChannelDataTypeNumeric {
+		return fmt.Errorf("enumAggregation requires a string channel, but %q is numeric", qm.Channel)
+	}
+	if qm.QueryType == queryTypeCount && (qm.isEnumQuery() || qm.ChannelDataType == ChannelDataTypeLog) {
+		return fmt.Errorf("count queries require a numeric channel, but %q is %s", qm.Channel, qm.ChannelDataType)
+	}
+	return nil
+}
+
 func normalizeAggregations(qm *NominalQueryModel) *backend.DataResponse {
+	if qm.QueryType == queryTypeCount {
+		qm.Aggregations = []string{AggCount}
+	}
 	qm.ExplicitAggregations = len(qm.Aggregations) > 0
-	if qm.ChannelDataType == ChannelDataTypeString || qm.ChannelDataType == ChannelDataTypeLog {
+	if qm.isEnumQuery() || qm.ChannelDataType == ChannelDataTypeLog {
 		return nil
 	}
 
 	if !qm.ExplicitAggregations {
 		qm.Aggregations = []string{AggMean}
+		if len(qm.StatFields) > 0 {
+			qm.Aggregations = qm.statAggregations()
+		} else if qm.IncludeStdDev {
+			qm.Aggregations = append(qm.Aggregations, AggVariance)
+		}
 		return nil
 	}
 
@@ -116,7 +537,7 @@ func normalizeAggregations(qm *NominalQueryModel) *backend.DataResponse {
 	if badAgg != "" {
 		response := backend.ErrDataResponse(
 			backend.StatusBadRequest,
-			fmt.Sprintf("unsupported aggregation %q; valid options are MEAN, MIN, MAX, COUNT, VARIANCE, FIRST_POINT, LAST_POINT", badAgg),
+			unsupportedAggregationMessage(badAgg),
 		)
 		return &response
 	}
@@ -124,6 +545,10 @@ func normalizeAggregations(qm *NominalQueryModel) *backend.DataResponse {
 	return nil
 }
 
+func unsupportedAggregationMessage(badAgg string) string {
+	return fmt.Sprintf("unsupported aggregation %q; valid options are MEAN, MIN, MAX, COUNT, VARIANCE, FIRST_POINT, LAST_POINT", badAgg)
+}
+
 // interpolateTemplateVariables replaces template variables in strings.
 // It supports both ${var} and $var syntax. The ${var} form is processed first
 // so that a bare $var replacement cannot accidentally corrupt a ${othervar}
@@ -155,6 +580,10 @@ func interpolateTemplateVariables(input string, variables map[string]interface{}
 	return result
 }
 
+// resolutionTemplateVariable is the conventional dashboard variable that sets
+// the bucket count of every query that does not set its own.
+const resolutionTemplateVariable = "__resolution"
+
 // applyTemplateVariables applies template variable interpolation to query fields.
 //
 // Defense-in-depth: Grafana's SDK resolves dashboard template variables before
@@ -164,26 +593,155 @@ func interpolateTemplateVariables(input string, variables map[string]interface{}
 // TemplateVariables field of the query model (populated by the frontend for
 // variable-panel queries and programmatic calls) are NOT resolved by the SDK,
 // so this server-side pass is still needed for those paths.
-func (e *NominalQueryExecution) applyTemplateVariables(qm *NominalQueryModel) {
+//
+// A string Buckets value is interpolated and parsed back to an int; it returns
+// an error when the result is not an integer. A query that sets no buckets of
+// its own takes them from the dashboard-wide resolutionTemplateVariable. A
+// string IntervalMs value is interpolated and parsed by parseIntervalMs.
+func (e *NominalQueryExecution) applyTemplateVariables(qm *NominalQueryModel) error {
+	if qm.IntervalTemplate != "" {
+		resolved := strings.TrimSpace(interpolateTemplateVariables(qm.IntervalTemplate, qm.TemplateVariables))
+		intervalMs, err := parseIntervalMs(resolved)
+		if err != nil {
+			return fmt.Errorf("intervalMs %q (from %q): %w", resolved, qm.IntervalTemplate, err)
+		}
+		qm.IntervalMs = intervalMs
+	}
+	if qm.BucketsTemplate != "" {
+		resolved := strings.TrimSpace(interpolateTemplateVariables(qm.BucketsTemplate, qm.TemplateVariables))
+		buckets, err := strconv.Atoi(resolved)
+		if err != nil {
+			return fmt.Errorf("buckets must be an integer, got %q (from %q)", resolved, qm.BucketsTemplate)
+		}
+		qm.Buckets = buckets
+	} else if value, ok := qm.TemplateVariables[resolutionTemplateVariable]; ok && qm.Buckets == 0 {
+		resolved := strings.TrimSpace(fmt.Sprint(value))
+		if number, isNumber := value.(float64); isNumber {
+			resolved = strconv.FormatFloat(number, 'f', -1, 64)
+		}
+		buckets, err := strconv.Atoi(resolved)
+		if err != nil {
+			return fmt.Errorf("%s must be an integer, got %q", resolutionTemplateVariable, resolved)
+		}
+		qm.Buckets = buckets
+	}
+
 	if qm.TemplateVariables == nil {
-		return
+		return nil
 	}
 
 	qm.AssetRid = interpolateTemplateVariables(qm.AssetRid, qm.TemplateVariables)
+	qm.DataSourceRid = interpolateTemplateVariables(qm.DataSourceRid, qm.TemplateVariables)
 	qm.Channel = interpolateTemplateVariables(qm.Channel, qm.TemplateVariables)
 	qm.DataScopeName = interpolateTemplateVariables(qm.DataScopeName, qm.TemplateVariables)
 	qm.QueryText = interpolateTemplateVariables(qm.QueryText, qm.TemplateVariables)
+	qm.TimeShift = interpolateTemplateVariables(qm.TimeShift, qm.TemplateVariables)
+	return nil
 }
 
 // validateQuery validates query parameters similar to pure-ts implementation
 func (e *NominalQueryExecution) validateQuery(qm NominalQueryModel) error {
 	// Check if we have either Nominal-specific fields or legacy fields
 	hasNominalQuery := qm.AssetRid != "" && qm.Channel != ""
+	hasDataSourceRidQuery := qm.DataSourceRid != "" && qm.Channel != ""
 	hasLegacyQuery := qm.QueryText != ""
 	hasConstantQuery := qm.Constant != 0
+	hasTagSelectorQuery := len(qm.TagSelector) > 0
 
-	if !hasNominalQuery && !hasLegacyQuery && !hasConstantQuery {
-		return fmt.Errorf("query must have either asset/channel parameters, query text, or constant value")
+	if !hasNominalQuery && !hasDataSourceRidQuery && !hasLegacyQuery && !hasConstantQuery && !hasTagSelectorQuery {
+		return fmt.Errorf("query must have either asset/channel parameters, dataSourceRid/channel parameters, query text, or constant value")
+	}
+
+	if err := validateFillPolicy(qm.FillPolicy); err != nil {
+		return err
+	}
+	if err := validateStatFields(qm.StatFields); err != nil {
+		return err
+	}
+	if err := validateStatConfig(qm.StatFields, qm.StatConfig); err != nil {
+		return err
+	}
+	if err := validateValueScale(qm.ValueScale, qm.ValueOffset); err != nil {
+		return err
+	}
+	if err := validateValuePrecision(qm.ValuePrecision); err != nil {
+		return err
+	}
+	if err := validateValueType(qm); err != nil {
+		return err
+	}
+	if qm.IntervalMs < 0 || int64(qm.IntervalMs) > maxDurationMs {
+		return fmt.Errorf("intervalMs must be between 0 and %d, got %d", maxDurationMs, qm.IntervalMs)
+	}
+	if qm.NumPoints < 0 || qm.NumPoints > maxReturnedPoints {
+		return fmt.Errorf("numPoints must be between 0 and %d, got %d", maxReturnedPoints, qm.NumPoints)
+	}
+	if qm.Stride < 0 {
+		return fmt.Errorf("stride must be >= 0 (0 disables it), got %d", qm.Stride)
+	}
+	if qm.Stride > 0 && qm.DualResolution {
+		return fmt.Errorf("stride cannot be combined with dualResolution")
+	}
+	if qm.QueryType == queryTypeCount && qm.Stride > 0 {
+		return fmt.Errorf("stride cannot be combined with a count query")
+	}
+	if err := validateEnumAggregation(qm.EnumAggregation); err != nil {
+		return err
+	}
+	if err := validateBucketEdges(qm); err != nil {
+		return err
+	}
+	if err := validateGapQuery(qm); err != nil {
+		return err
+	}
+	if err := validateReduce(qm); err != nil {
+		return err
+	}
+	if err := validateGapValue(qm); err != nil {
+		return err
+	}
+	if err := validateStateTimeline(qm); err != nil {
+		return err
+	}
+	if err := validateTagSelector(qm); err != nil {
+		return err
+	}
+	if err := validateAllDataScopes(qm); err != nil {
+		return err
+	}
+	if hasTagSelectorQuery {
+		if qm.Buckets < 0 {
+			return fmt.Errorf("buckets must be non-negative, got %d", qm.Buckets)
+		}
+		return nil
+	}
+
+	// A channel RID addresses the data source directly, so the asset-scoped
+	// checks below do not apply.
+	if hasDataSourceRidQuery {
+		if strings.TrimSpace(qm.DataSourceRid) == "" {
+			return fmt.Errorf("dataSourceRid cannot be empty")
+		}
+		if strings.TrimSpace(qm.Channel) == "" {
+			return fmt.Errorf("channel cannot be empty")
+		}
+		if qm.Buckets < 0 {
+			return fmt.Errorf("buckets must be non-negative, got %d", qm.Buckets)
+		}
+		return nil
+	}
+
+	if qm.isExpressionQuery() {
+		if strings.TrimSpace(qm.DataScopeName) == "" {
+			return fmt.Errorf("dataScopeName is required for queryText expressions")
+		}
+		if _, err := parseChannelExpression(qm.QueryText); err != nil {
+			return err
+		}
+		if qm.Buckets < 0 {
+			return fmt.Errorf("buckets must be non-negative, got %d", qm.Buckets)
+		}
+		return nil
 	}
 
 	// Validate Nominal query fields
@@ -196,21 +754,53 @@ func (e *NominalQueryExecution) validateQuery(qm NominalQueryModel) error {
 		}
 		// DataScopeName is required — the compute API needs it to locate the channel.
 		// The frontend filterQuery also enforces this; this is defense-in-depth.
-		if strings.TrimSpace(qm.DataScopeName) == "" {
+		if strings.TrimSpace(qm.DataScopeName) == "" && !qm.AllDataScopes {
 			return fmt.Errorf("dataScopeName is required for asset/channel queries")
 		}
 		// Validate bucket count
 		if qm.Buckets < 0 {
 			return fmt.Errorf("buckets must be non-negative, got %d", qm.Buckets)
 		}
-		if qm.Buckets > 10000 {
-			log.DefaultLogger.Warn("Large bucket count may impact performance", "buckets", qm.Buckets)
-		}
 	}
 
 	return nil
 }
 
+// validationErrors returns every static validation error for qm — the checks
+// prepareQuery applies before any API call — or an empty slice when the query
+// would pass preparation.
+func (e *NominalQueryExecution) validationErrors(qm NominalQueryModel) []string {
+	errs := make([]string, 0)
+	if err := e.applyTemplateVariables(&qm); err != nil {
+		return append(errs, err.Error())
+	}
+	e.applyDefaultTimeShift(&qm)
+	if qm.QueryType == "connectionTest" {
+		return errs
+	}
+	if qm.QueryType == queryTypeChannelTable {
+		if strings.TrimSpace(qm.AssetRid) == "" {
+			errs = append(errs, "assetRid is required for channelTable queries")
+		}
+		return errs
+	}
+	if err := e.validateQuery(qm); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if _, err := resolveTimeShift(qm.TimeShift, qm.TimeZone, time.Now()); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err := validateChannelDataType(qm); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if !qm.isEnumQuery() && qm.ChannelDataType != ChannelDataTypeLog && len(qm.Aggregations) > 0 {
+		if _, badAgg := validateAndDedup(qm.Aggregations); badAgg != "" {
+			errs = append(errs, unsupportedAggregationMessage(badAgg))
+		}
+	}
+	return errs
+}
+
 // inferChannelMetadata verifies (or backfills) channel metadata — both data type
 // and unit symbol — against the actual ChannelMetadata returned by SearchChannels.
 //
@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+	"github.com/nominal-io/nominal-api-go/io/nominal/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+// extractRangeValue turns a rangeValue response, one value held over a
+// range, into a step series: the value at the range start and again at its
+// end, so it renders as a flat step rather than a single sample. A summarized
+// range yields its average, and a range with no points yields null. Missing
+// bounds are left out; a nil range (no range matched) yields no points.
+func extractRangeValue(r *computeapi.Range) ([]time.Time, []*float64) {
+	if r == nil {
+		return nil, nil
+	}
+	var value *float64
+	if r.Value != nil {
+		_ = r.Value.AcceptFuncs(
+			func(v float64) error {
+				value = &v
+				return nil
+			},
+			func(agg computeapi.RangeAggregation) error {
+				value = &agg.Average
+				return nil
+			},
+			func(api.Empty) error { return nil },
+			func(string) error { return nil },
+		)
+	}
+
+	var timePoints []time.Time
+	var values []*float64
+	for _, bound := range []*api.Timestamp{r.Start, r.End} {
+		if bound == nil {
+			continue
+		}
+		timePoints = append(timePoints, time.Unix(int64(bound.Seconds), int64(bound.Nanos)))
+		if value == nil {
+			values = append(values, nil)
+			continue
+		}
+		v := *value
+		values = append(values, &v)
+	}
+	return timePoints, values
+}
+
+// setStepInterpolation asks time series panels to draw the field as steps
+// that hold each value until the next point.
+func setStepInterpolation(config *data.FieldConfig) {
+	if config.Custom == nil {
+		config.Custom = map[string]interface{}{}
+	}
+	config.Custom["lineInterpolation"] = "stepAfter"
+}
@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+
+	"github.com/nominal-io/nominal-api-go/io/nominal/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestTransformBatchResultRangeValueIsStepFrame(t *testing.T) {
+	start, end := testTimestamp(1704067200), testTimestamp(1704067200+600)
+	value := computeapi.NewRangeValueFromDouble(3.5)
+	result := computeapi.ComputeWithUnitsResult{
+		ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromRangeValue(&computeapi.Range{
+			Start: &start,
+			End:   &end,
+			Value: &value,
+		})),
+	}
+	exec := newTestQueryExecution(&Datasource{}, nil)
+	res := exec.transformBatchResult(result, NominalQueryModel{Channel: "gear"})
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+
+	frame := res.Frames[0]
+	if rows, _ := frame.RowLen(); rows != 2 {
+		t.Fatalf("rows = %d, want the range start and end", rows)
+	}
+	for i, want := range []time.Time{time.Unix(1704067200, 0), time.Unix(1704067200+600, 0)} {
+		if got := frame.Fields[0].At(i).(time.Time); !got.Equal(want) {
+			t.Errorf("time[%d] = %v, want %v", i, got, want)
+		}
+		if got := frame.Fields[1].At(i).(*float64); got == nil || *got != 3.5 {
+			t.Errorf("value[%d] = %v, want 3.5 held over the range", i, got)
+		}
+	}
+	config := frame.Fields[1].Config
+	if config == nil || config.Custom["lineInterpolation"] != "stepAfter" {
+		t.Errorf("value config = %+v, want a stepAfter interpolation hint", config)
+	}
+}
+
+func TestExtractRangeValue(t *testing.T) {
+	start := testTimestamp(1704067200)
+	aggregation := computeapi.NewRangeValueFromAggregation(computeapi.RangeAggregation{Average: 2, Min: 1, Max: 3})
+	noPoints := computeapi.NewRangeValueFromNoPointsInRange(api.Empty{})
+
+	times, values := extractRangeValue(&computeapi.Range{Start: &start, Value: &aggregation})
+	if len(times) != 1 || len(values) != 1 || values[0] == nil || *values[0] != 2 {
+		t.Errorf("open-ended aggregation = %v %v, want one point at the average", times, derefValues(values))
+	}
+	if _, values := extractRangeValue(&computeapi.Range{Start: &start, Value: &noPoints}); len(values) != 1 || values[0] != nil {
+		t.Errorf("no points in range = %v, want a null value", derefValues(values))
+	}
+	if times, _ := extractRangeValue(nil); times != nil {
+		t.Errorf("nil range = %v, want no points", times)
+	}
+}
@@ -0,0 +1,163 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// Reduce values collapse a numeric result to one row after it is fetched,
+// for single-stat panels. An empty Reduce returns the series unchanged.
+const (
+	ReduceAvg  = "avg"
+	ReduceMax  = "max"
+	ReduceMin  = "min"
+	ReduceLast = "last"
+	ReduceSum  = "sum"
+)
+
+// validateReduce returns an error for an unrecognised reducer or one set on a
+// query whose result is not a single numeric series.
+func validateReduce(qm NominalQueryModel) error {
+	switch qm.Reduce {
+	case "":
+		if !qm.ReduceForAlerting {
+			return nil
+		}
+	case ReduceAvg, ReduceMax, ReduceMin, ReduceLast, ReduceSum:
+	default:
+		return fmt.Errorf("unsupported reduce %q; valid options are avg, max, min, last, sum", qm.Reduce)
+	}
+	if qm.isEnumQuery() || qm.ChannelDataType == ChannelDataTypeLog || qm.QueryType == queryTypeGaps {
+		return fmt.Errorf("reduce requires a numeric channel")
+	}
+	if qm.DualResolution {
+		return fmt.Errorf("reduce cannot be combined with dualResolution")
+	}
+	return nil
+}
+
+// reduceFrames replaces every frame in frames with a one-row frame. Numeric
+// fields hold the reducer applied to their non-null values (null when there
+// are none); other fields, such as time, keep their last row. Empty frames
+// are left empty.
+//
+// The reduction runs over the returned points, so for a bucketed query avg
+// is the mean of the bucket values, not a count-weighted mean of raw points.
+func reduceFrames(frames data.Frames, reducer string) {
+	if reducer == "" {
+		return
+	}
+	for _, frame := range frames {
+		n, err := frame.RowLen()
+		if err != nil || n == 0 {
+			continue
+		}
+		for i, field := range frame.Fields {
+			var reduced *data.Field
+			if field.Type().Numeric() {
+				values := make([]*float64, n)
+				for row := range values {
+					values[row], _ = field.NullableFloatAt(row)
+				}
+				reduced = data.NewField(field.Name, field.Labels, []*float64{reduceValues(values, reducer)})
+			} else {
+				reduced = data.NewFieldFromFieldType(field.Type(), 1)
+				reduced.Name = field.Name
+				reduced.Labels = field.Labels
+				reduced.Set(0, field.CopyAt(n-1))
+			}
+			reduced.Config = field.Config
+			frame.Fields[i] = reduced
+		}
+	}
+}
+
+// alertingReducer is the reducer a ReduceForAlerting query applies.
+func alertingReducer(qm NominalQueryModel) string {
+	if qm.Reduce == "" {
+		return ReduceLast
+	}
+	return qm.Reduce
+}
+
+// reduceFramesForAlerting replaces every frame in frames with a numeric frame
+// (data.FrameTypeNumericMulti) holding the reducer applied to the frame's
+// value series: its "value" field, or else its first numeric field. The time
+// field and any other fields are dropped, so alerting threshold and math
+// expressions read exactly one number per series, keyed by its labels. A
+// frame without points yields a value field with no rows, which alerting
+// reports as no data.
+func reduceFramesForAlerting(frames data.Frames, reducer string) data.Frames {
+	reduced := make(data.Frames, 0, len(frames))
+	for _, frame := range frames {
+		var source *data.Field
+		for _, field := range frame.Fields {
+			if !field.Type().Numeric() {
+				continue
+			}
+			if source == nil || field.Name == "value" {
+				source = field
+			}
+		}
+		if source == nil {
+			continue
+		}
+
+		values := []*float64{}
+		if n := source.Len(); n > 0 {
+			series := make([]*float64, n)
+			for row := range series {
+				series[row], _ = source.NullableFloatAt(row)
+			}
+			values = append(values, reduceValues(series, reducer))
+		}
+		field := data.NewField("value", source.Labels, values)
+		field.Config = source.Config
+
+		out := data.NewFrame(frame.Name, field)
+		out.RefID = frame.RefID
+		meta := &data.FrameMeta{}
+		if frame.Meta != nil {
+			copied := *frame.Meta
+			meta = &copied
+		}
+		meta.Type = data.FrameTypeNumericMulti
+		meta.TypeVersion = data.FrameTypeVersion{0, 1}
+		out.Meta = meta
+		reduced = append(reduced, out)
+	}
+	return reduced
+}
+
+// reduceValues applies reducer to the non-nil values, or returns nil when
+// there are none.
+func reduceValues(values []*float64, reducer string) *float64 {
+	var result float64
+	count := 0
+	for _, v := range values {
+		if v == nil {
+			continue
+		}
+		switch {
+		case count == 0:
+			result = *v
+		case reducer == ReduceMax:
+			result = max(result, *v)
+		case reducer == ReduceMin:
+			result = min(result, *v)
+		case reducer == ReduceLast:
+			result = *v
+		default: // ReduceAvg, ReduceSum
+			result += *v
+		}
+		count++
+	}
+	if count == 0 {
+		return nil
+	}
+	if reducer == ReduceAvg {
+		result /= float64(count)
+	}
+	return &result
+}
@@ -0,0 +1,168 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestTransformBatchResultReducesSeries(t *testing.T) {
+	tests := []struct {
+		reducer string
+		want    float64
+	}{
+		{reducer: ReduceAvg, want: 4},
+		{reducer: ReduceMax, want: 7},
+		{reducer: ReduceMin, want: 1},
+		{reducer: ReduceLast, want: 4},
+		{reducer: ReduceSum, want: 12},
+	}
+	exec := newTestQueryExecution(&Datasource{}, nil)
+	for _, tt := range tests {
+		t.Run(tt.reducer, func(t *testing.T) {
+			qm := NominalQueryModel{Channel: "speed", ChannelUnit: "m/s", Reduce: tt.reducer}
+			res := exec.transformBatchResult(createMockComputeResult([]float64{1, 7, 4}), qm)
+			if res.Error != nil {
+				t.Fatalf("unexpected error: %v", res.Error)
+			}
+			frame := res.Frames[0]
+			if rows, _ := frame.RowLen(); rows != 1 {
+				t.Fatalf("rows = %d, want 1", rows)
+			}
+			if got := frame.Fields[0].At(0).(time.Time); !got.Equal(time.Unix(1704067200+120, 0)) {
+				t.Errorf("time = %v, want the last point's time", got)
+			}
+			valueField := frame.Fields[1]
+			if got, _ := valueField.At(0).(*float64); got == nil || *got != tt.want {
+				t.Errorf("value = %v, want %v", got, tt.want)
+			}
+			if valueField.Config == nil || valueField.Config.Unit == "" {
+				t.Errorf("value config = %+v, want the channel unit kept", valueField.Config)
+			}
+		})
+	}
+}
+
+func TestTransformBatchResultFramesSuitAlerting(t *testing.T) {
+	exec := newTestQueryExecution(&Datasource{}, nil)
+
+	t.Run("time series", func(t *testing.T) {
+		qm := NominalQueryModel{Channel: "speed"}
+		res := exec.transformBatchResult(createMockComputeResult([]float64{1, 7, 4}), qm)
+		if res.Error != nil {
+			t.Fatalf("unexpected error: %v", res.Error)
+		}
+		// Alerting reads a wide series: one non-null time field and numeric values.
+		frame := res.Frames[0]
+		if len(frame.Fields) != 2 {
+			t.Fatalf("fields = %d, want time and value", len(frame.Fields))
+		}
+		if got := frame.Fields[0].Type(); got != data.FieldTypeTime {
+			t.Errorf("time field type = %v, want %v", got, data.FieldTypeTime)
+		}
+		if got := frame.Fields[1].Type(); !got.Numeric() {
+			t.Errorf("value field type = %v, want numeric", got)
+		}
+	})
+
+	t.Run("reduced", func(t *testing.T) {
+		qm := NominalQueryModel{Channel: "speed", ChannelUnit: "m/s", Reduce: ReduceMax, ReduceForAlerting: true}
+		res := exec.transformBatchResult(createMockComputeResult([]float64{1, 7, 4}), qm)
+		if res.Error != nil {
+			t.Fatalf("unexpected error: %v", res.Error)
+		}
+		if len(res.Frames) != 1 {
+			t.Fatalf("frames = %d, want 1", len(res.Frames))
+		}
+		frame := res.Frames[0]
+		if frame.Meta == nil || frame.Meta.Type != data.FrameTypeNumericMulti {
+			t.Errorf("frame meta = %+v, want type %s", frame.Meta, data.FrameTypeNumericMulti)
+		}
+		if len(frame.Fields) != 1 || !frame.Fields[0].Type().Numeric() {
+			t.Fatalf("fields = %v, want a single numeric field", frame.Fields)
+		}
+		if rows, _ := frame.RowLen(); rows != 1 {
+			t.Fatalf("rows = %d, want 1", rows)
+		}
+		if got, _ := frame.Fields[0].NullableFloatAt(0); got == nil || *got != 7 {
+			t.Errorf("value = %v, want 7", got)
+		}
+	})
+}
+
+func TestReduceFramesForAlertingKeepsLabelsAndDefaultsToLast(t *testing.T) {
+	frame := data.NewFrame("speed",
+		data.NewField("time", nil, []time.Time{time.Unix(0, 0), time.Unix(60, 0)}),
+		data.NewField("value", data.Labels{"vehicle": "v1"}, floatPtrs(3.0, 5.0)),
+	)
+	empty := data.NewFrame("idle", data.NewField("time", nil, []time.Time{}), data.NewField("value", nil, []*float64{}))
+
+	reduced := reduceFramesForAlerting(data.Frames{frame, empty}, alertingReducer(NominalQueryModel{ReduceForAlerting: true}))
+	if len(reduced) != 2 {
+		t.Fatalf("frames = %d, want 2", len(reduced))
+	}
+	field := reduced[0].Fields[0]
+	if got, _ := field.NullableFloatAt(0); got == nil || *got != 5 {
+		t.Errorf("value = %v, want the last point, 5", got)
+	}
+	if field.Labels["vehicle"] != "v1" {
+		t.Errorf("labels = %v, want the series labels kept", field.Labels)
+	}
+	if rows, _ := reduced[1].RowLen(); rows != 0 {
+		t.Errorf("empty series rows = %d, want 0 (no data)", rows)
+	}
+}
+
+func TestReduceValuesSkipsNulls(t *testing.T) {
+	three, one, five := 3.0, 1.0, 5.0
+	values := []*float64{nil, &three, nil, &one, &five, nil}
+	want := map[string]float64{ReduceAvg: 3, ReduceMax: 5, ReduceMin: 1, ReduceLast: 5, ReduceSum: 9}
+	for reducer, w := range want {
+		if got := reduceValues(values, reducer); got == nil || *got != w {
+			t.Errorf("%s = %v, want %v", reducer, got, w)
+		}
+	}
+	if got := reduceValues([]*float64{nil, nil}, ReduceSum); got != nil {
+		t.Errorf("sum of nulls = %v, want nil", *got)
+	}
+}
+
+func TestReduceFramesLeavesEmptyFrames(t *testing.T) {
+	frame := data.NewFrame("speed", data.NewField("time", nil, []time.Time{}), data.NewField("value", nil, []*float64{}))
+	reduceFrames(data.Frames{frame}, ReduceAvg)
+	if rows, _ := frame.RowLen(); rows != 0 {
+		t.Errorf("rows = %d, want 0", rows)
+	}
+}
+
+func TestValidateReduce(t *testing.T) {
+	tests := []struct {
+		name      string
+		qm        NominalQueryModel
+		wantError string
+	}{
+		{name: "unset", qm: NominalQueryModel{}},
+		{name: "numeric", qm: NominalQueryModel{Reduce: ReduceSum}},
+		{name: "unknown", qm: NominalQueryModel{Reduce: "median"}, wantError: "unsupported reduce"},
+		{name: "enum", qm: NominalQueryModel{Reduce: ReduceMax, ChannelDataType: ChannelDataTypeString}, wantError: "numeric channel"},
+		{name: "dual resolution", qm: NominalQueryModel{Reduce: ReduceAvg, DualResolution: true}, wantError: "dualResolution"},
+		{name: "alerting", qm: NominalQueryModel{ReduceForAlerting: true}},
+		{name: "alerting enum", qm: NominalQueryModel{ReduceForAlerting: true, ChannelDataType: ChannelDataTypeString}, wantError: "numeric channel"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateReduce(tt.qm)
+			if tt.wantError == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+				t.Errorf("err = %v, want %q", err, tt.wantError)
+			}
+		})
+	}
+}
@@ -3,17 +3,28 @@ package plugin
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"runtime"
+	"slices"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/nominal-inc/nominal-ds/pkg/models"
 	"github.com/nominal-io/nominal-api-go/api/rids"
 	datasourceapi "github.com/nominal-io/nominal-api-go/datasource/api"
 	"github.com/nominal-io/nominal-api-go/io/nominal/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+	computeapi1 "github.com/nominal-io/nominal-api-go/scout/compute/api1"
+	runapi "github.com/nominal-io/nominal-api-go/scout/run/api"
+	unitsapi "github.com/nominal-io/nominal-api-go/scout/units/api"
 	"github.com/palantir/pkg/bearertoken"
 	"github.com/palantir/pkg/rid"
 )
@@ -130,9 +141,9 @@ func TestTemplateVariableCatalogChannelVariablesDedupesAndHandlesUnresolvedVaria
 	mockDS := &mockDatasourceService{
 		searchChannelsResponse: datasourceapi.SearchChannelsResponse{
 			Results: []datasourceapi.ChannelMetadata{
-				{Name: api.Channel("state")},
-				{Name: api.Channel("state")},
-				{Name: api.Channel("rpm")},
+				{Name: api.Channel("state"), DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "dataset1"))},
+				{Name: api.Channel("state"), DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "dataset1"))},
+				{Name: api.Channel("rpm"), DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "dataset1"))},
 			},
 		},
 	}
@@ -145,7 +156,7 @@ func TestTemplateVariableCatalogChannelVariablesDedupesAndHandlesUnresolvedVaria
 		},
 	}
 
-	values, err := templateCatalog.ChannelVariables(context.Background(), config, channelVariablesRequest{AssetRid: assetRid, DataScopeName: "scope-a"})
+	values, _, err := templateCatalog.ChannelVariables(context.Background(), config, channelVariablesRequest{AssetRid: assetRid, DataScopeName: "scope-a"})
 	if err != nil {
 		t.Fatalf("ChannelVariables returned error: %v", err)
 	}
@@ -159,7 +170,7 @@ func TestTemplateVariableCatalogChannelVariablesDedupesAndHandlesUnresolvedVaria
 		t.Fatalf("SearchChannels calls = %d, want 1", mockDS.searchChannelsCalls)
 	}
 
-	unresolved, err := templateCatalog.ChannelVariables(context.Background(), config, channelVariablesRequest{AssetRid: assetRid, DataScopeName: "$scope"})
+	unresolved, _, err := templateCatalog.ChannelVariables(context.Background(), config, channelVariablesRequest{AssetRid: assetRid, DataScopeName: "$scope"})
 	if err != nil {
 		t.Fatalf("unresolved ChannelVariables returned error: %v", err)
 	}
@@ -168,6 +179,57 @@ func TestTemplateVariableCatalogChannelVariablesDedupesAndHandlesUnresolvedVaria
 	}
 }
 
+func TestTemplateVariableCatalogChannelVariablesCaseInsensitiveDedup(t *testing.T) {
+	assetRid := "ri.scout.main.asset.1"
+	dataSourceRid := "ri.scout.main.data-source.dataset1"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid:   assetRid,
+			Title: "Asset",
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "scope-a", DataSource: AssetDataSource{Type: "dataset", Dataset: &dataSourceRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	dataSource := rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "dataset1"))
+	mockDS := &mockDatasourceService{
+		searchChannelsResponse: datasourceapi.SearchChannelsResponse{
+			Results: []datasourceapi.ChannelMetadata{
+				{Name: api.Channel("Temp"), DataSource: dataSource},
+				{Name: api.Channel("temp"), DataSource: dataSource},
+				{Name: api.Channel(" TEMP "), DataSource: dataSource},
+				{Name: api.Channel("rpm"), DataSource: dataSource},
+			},
+		},
+	}
+	templateCatalog := newTemplateVariableCatalog(newNominalCatalog(server.Client(), mockDS))
+	config := &models.PluginSettings{
+		BaseUrl: server.URL,
+		Secrets: &models.SecretPluginSettings{
+			ApiKey: "test-key",
+		},
+	}
+
+	exact, _, err := templateCatalog.ChannelVariables(context.Background(), config, channelVariablesRequest{AssetRid: assetRid, DataScopeName: "scope-a"})
+	if err != nil {
+		t.Fatalf("ChannelVariables returned error: %v", err)
+	}
+	if len(exact) != 4 {
+		t.Fatalf("exact-match values = %v, want all 4 names", exact)
+	}
+
+	values, _, err := templateCatalog.ChannelVariables(context.Background(), config, channelVariablesRequest{AssetRid: assetRid, DataScopeName: "scope-a", CaseInsensitive: true})
+	if err != nil {
+		t.Fatalf("ChannelVariables returned error: %v", err)
+	}
+	want := []metricFindValue{{Text: "Temp", Value: "Temp"}, {Text: "rpm", Value: "rpm"}}
+	if !reflect.DeepEqual(values, want) {
+		t.Fatalf("values = %+v, want %+v", values, want)
+	}
+}
+
 // ============================================================================
 // CallResource handler tests
 // ============================================================================
@@ -233,6 +295,74 @@ func TestHandleChannelsSearch(t *testing.T) {
 		}
 	})
 
+	t.Run("includeUnits backfills missing units via BatchComputeUnits", func(t *testing.T) {
+		dsRidParsed := rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))
+		mockDS := &mockDatasourceService{
+			searchChannelsResponse: datasourceapi.SearchChannelsResponse{
+				Results: []datasourceapi.ChannelMetadata{
+					{Name: api.Channel("speed"), DataSource: dsRidParsed},
+					{Name: api.Channel("altitude"), DataSource: dsRidParsed, Unit: &runapi.Unit{Symbol: "m"}},
+				},
+			},
+		}
+		mockCompute := &mockComputeService{
+			batchComputeUnitsFunc: func(requestArg computeapi1.BatchComputeUnitsRequest) (computeapi.BatchComputeUnitResult, error) {
+				results := make([]computeapi.ComputeUnitResult, len(requestArg.Requests))
+				for i := range results {
+					results[i] = computeapi.NewComputeUnitResultFromSingle(computeapi.NewUnitResultFromSuccess(unitsapi.UnitSymbol("m/s")))
+				}
+				return computeapi.BatchComputeUnitResult{Results: results}, nil
+			},
+		}
+		ds := newTestDatasource("https://api.test.com", &mockAuthService{}, mockDS)
+		ds.computeService = mockCompute
+
+		body, _ := json.Marshal(map[string]any{"dataSourceRids": []string{dsRid}, "searchText": "", "includeUnits": true})
+		req := &backend.CallResourceRequest{Path: "channels", Method: "POST", Body: body}
+		resp := callResourceAndCapture(t, ds, req)
+		if resp.Status != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+		}
+
+		var result channelsSearchResponse
+		if err := json.Unmarshal(resp.Body, &result); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		units := map[string]string{}
+		for _, ch := range result.Channels {
+			units[ch.Name] = ch.Unit
+		}
+		if units["speed"] != "m/s" || units["altitude"] != "m" {
+			t.Errorf("units = %v, want speed=m/s altitude=m", units)
+		}
+		if n := len(mockCompute.lastBatchUnitsRequest.Requests); n != 1 {
+			t.Errorf("unit lookups = %d, want 1 (only the channel without metadata unit)", n)
+		}
+	})
+
+	t.Run("units are not looked up without includeUnits", func(t *testing.T) {
+		mockDS := &mockDatasourceService{
+			searchChannelsResponse: datasourceapi.SearchChannelsResponse{
+				Results: []datasourceapi.ChannelMetadata{
+					{Name: api.Channel("speed"), DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))},
+				},
+			},
+		}
+		mockCompute := &mockComputeService{}
+		ds := newTestDatasource("https://api.test.com", &mockAuthService{}, mockDS)
+		ds.computeService = mockCompute
+
+		body, _ := json.Marshal(map[string]any{"dataSourceRids": []string{dsRid}, "searchText": ""})
+		req := &backend.CallResourceRequest{Path: "channels", Method: "POST", Body: body}
+		resp := callResourceAndCapture(t, ds, req)
+		if resp.Status != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+		}
+		if mockCompute.batchComputeUnitsCalls != 0 {
+			t.Errorf("BatchComputeUnits calls = %d, want 0", mockCompute.batchComputeUnitsCalls)
+		}
+	})
+
 	t.Run("rejects non-POST", func(t *testing.T) {
 		ds := newTestDatasource("https://api.test.com", &mockAuthService{}, &mockDatasourceService{})
 		req := &backend.CallResourceRequest{Path: "channels", Method: "GET"}
@@ -250,6 +380,98 @@ func TestHandleChannelsSearch(t *testing.T) {
 		if resp.Status != http.StatusBadRequest {
 			t.Errorf("status = %d, want 400; body = %s", resp.Status, string(resp.Body))
 		}
+		if !strings.Contains(string(resp.Body), "not-a-rid") {
+			t.Errorf("body = %s, want rejected RID named", string(resp.Body))
+		}
+	})
+
+	t.Run("trims whitespace around data source RIDs", func(t *testing.T) {
+		mockDS := &mockDatasourceService{}
+		ds := newTestDatasource("https://api.test.com", &mockAuthService{}, mockDS)
+		body, _ := json.Marshal(map[string]any{
+			"dataSourceRids": []string{"  " + dsRid, "ri.scout.main.data-source.ds2\n", "   "},
+			"searchText":     "x",
+		})
+		req := &backend.CallResourceRequest{Path: "channels", Method: "POST", Body: body}
+		resp := callResourceAndCapture(t, ds, req)
+		if resp.Status != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+		}
+		got := []string{}
+		for _, source := range mockDS.searchChannelsRequest.DataSources {
+			got = append(got, source.String())
+		}
+		want := []string{dsRid, "ri.scout.main.data-source.ds2"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("searched data sources = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("resolves data sources from assetRid", func(t *testing.T) {
+		assetRid := "ri.scout.main.asset.abc123"
+		ds2Rid := "ri.scout.main.data-source.ds2"
+		server := newTestAssetServer(t, map[string]SingleAssetResponse{
+			assetRid: {
+				Rid: assetRid,
+				DataScopes: []AssetDataScope{
+					{DataScopeName: "scope1", DataSource: AssetDataSource{Type: "dataset", Dataset: &dsRid}},
+					{DataScopeName: "scope2", DataSource: AssetDataSource{Type: "dataset", Dataset: &ds2Rid}},
+				},
+			},
+		}, nil)
+		defer server.Close()
+
+		mockDS := &mockDatasourceService{
+			searchChannelsResponse: datasourceapi.SearchChannelsResponse{
+				Results: []datasourceapi.ChannelMetadata{
+					{Name: api.Channel("temperature"), DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))},
+				},
+			},
+		}
+		ds := newTestDatasource(server.URL, &mockAuthService{}, mockDS)
+
+		body, _ := json.Marshal(map[string]any{"assetRid": assetRid, "dataScopeName": "scope1", "searchText": "temp"})
+		req := &backend.CallResourceRequest{Path: "channels", Method: "POST", Body: body}
+		resp := callResourceAndCapture(t, ds, req)
+		if resp.Status != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+		}
+
+		var result channelsSearchResponse
+		if err := json.Unmarshal(resp.Body, &result); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if len(result.Channels) != 1 || result.Channels[0].Name != "temperature" {
+			t.Errorf("channels = %+v, want temperature", result.Channels)
+		}
+		got := []string{}
+		for _, source := range mockDS.searchChannelsRequest.DataSources {
+			got = append(got, source.String())
+		}
+		if want := []string{dsRid}; !reflect.DeepEqual(got, want) {
+			t.Errorf("searched data sources = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("unknown assetRid returns no channels", func(t *testing.T) {
+		server := newTestAssetServer(t, map[string]SingleAssetResponse{}, nil)
+		defer server.Close()
+
+		mockDS := &mockDatasourceService{}
+		ds := newTestDatasource(server.URL, &mockAuthService{}, mockDS)
+
+		body, _ := json.Marshal(map[string]any{"assetRid": "ri.scout.main.asset.missing"})
+		req := &backend.CallResourceRequest{Path: "channels", Method: "POST", Body: body}
+		resp := callResourceAndCapture(t, ds, req)
+		if resp.Status != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+		}
+		if string(resp.Body) != `{"channels":[]}` {
+			t.Errorf("body = %q, want %q", string(resp.Body), `{"channels":[]}`)
+		}
+		if mockDS.searchChannelsRequest.DataSources != nil {
+			t.Errorf("SearchChannels called with %v, want no call", mockDS.searchChannelsRequest.DataSources)
+		}
 	})
 }
 
@@ -376,6 +598,104 @@ func TestHandleAssetsVariable(t *testing.T) {
 			t.Errorf("expected 0 results, got %d", len(result))
 		}
 	})
+
+	t.Run("forwards labels and includeArchived filters in the search body", func(t *testing.T) {
+		var reqBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewDecoder(r.Body).Decode(&reqBody)
+			json.NewEncoder(w).Encode(AssetResponse{})
+		}))
+		defer server.Close()
+
+		ds := newTestDatasource(server.URL, &mockAuthService{}, &mockDatasourceService{})
+
+		body, _ := json.Marshal(map[string]any{"searchText": "rig", "labels": []string{"flight", "prod"}, "includeArchived": true})
+		req := &backend.CallResourceRequest{Path: "assets", Method: "POST", Body: body}
+		resp := callResourceAndCapture(t, ds, req)
+		if resp.Status != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+		}
+
+		statuses, _ := json.Marshal(reqBody["archivedStatuses"])
+		if string(statuses) != `["NOT_ARCHIVED","ARCHIVED"]` {
+			t.Errorf("archivedStatuses = %s, want both statuses", statuses)
+		}
+		query, _ := json.Marshal(reqBody["query"])
+		want := `{"and":[{"searchText":"rig","type":"searchText"},{"labels":{"labels":["flight","prod"],"operator":"AND"},"type":"labels"}],"type":"and"}`
+		if string(query) != want {
+			t.Errorf("query = %s, want %s", query, want)
+		}
+	})
+
+	t.Run("omits filters when none are requested", func(t *testing.T) {
+		var reqBody map[string]any
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			json.NewDecoder(r.Body).Decode(&reqBody)
+			json.NewEncoder(w).Encode(AssetResponse{})
+		}))
+		defer server.Close()
+
+		ds := newTestDatasource(server.URL, &mockAuthService{}, &mockDatasourceService{})
+
+		req := &backend.CallResourceRequest{Path: "assets", Method: "POST", Body: []byte(`{"searchText":"rig"}`)}
+		resp := callResourceAndCapture(t, ds, req)
+		if resp.Status != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+		}
+		if _, ok := reqBody["archivedStatuses"]; ok {
+			t.Errorf("archivedStatuses present without includeArchived: %v", reqBody)
+		}
+		query, _ := json.Marshal(reqBody["query"])
+		if string(query) != `{"searchText":"rig","type":"searchText"}` {
+			t.Errorf("query = %s, want plain searchText query", query)
+		}
+	})
+}
+
+func TestHandleRecentAssets(t *testing.T) {
+	var reqBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewDecoder(r.Body).Decode(&reqBody)
+		dataset := []AssetDataScope{{DataScopeName: "ds", DataSource: AssetDataSource{Type: "dataset"}}}
+		json.NewEncoder(w).Encode(AssetResponse{Results: []AssetSearchResult{
+			{Rid: "ri.scout.main.asset.3", Title: "Newest", DataScopes: dataset},
+			{Rid: "ri.scout.main.asset.video", Title: "Video Only", DataScopes: []AssetDataScope{{DataScopeName: "v", DataSource: AssetDataSource{Type: "video"}}}},
+			{Rid: "ri.scout.main.asset.2", Title: "Middle", DataScopes: dataset},
+			{Rid: "ri.scout.main.asset.1", Title: "Oldest", DataScopes: dataset},
+		}})
+	}))
+	defer server.Close()
+
+	ds := newTestDatasource(server.URL, &mockAuthService{}, &mockDatasourceService{})
+
+	req := &backend.CallResourceRequest{Path: "recentassets", Method: "POST", Body: []byte(`{"maxResults": 2}`)}
+	resp := callResourceAndCapture(t, ds, req)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+	}
+
+	var result []metricFindValue
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	want := []metricFindValue{
+		{Text: "Newest", Value: "ri.scout.main.asset.3"},
+		{Text: "Middle", Value: "ri.scout.main.asset.2"},
+	}
+	if len(result) != len(want) || result[0] != want[0] || result[1] != want[1] {
+		t.Errorf("result = %+v, want %+v", result, want)
+	}
+
+	sort, _ := reqBody["sort"].(map[string]any)
+	if sort["field"] != "CREATED_AT" || sort["isDescending"] != true {
+		t.Errorf("sort = %v, want CREATED_AT descending", sort)
+	}
+	if reqBody["pageSize"] != float64(2) {
+		t.Errorf("pageSize = %v, want 2", reqBody["pageSize"])
+	}
 }
 
 func TestHandleAssetsVariablePagination(t *testing.T) {
@@ -666,6 +986,37 @@ func TestHandleChannelVariables(t *testing.T) {
 		}
 	})
 
+	t.Run("excludes channels from data sources outside the asset", func(t *testing.T) {
+		server := newTestAssetServer(t, makeAssetWithDS(), nil)
+		defer server.Close()
+
+		mockDS := &mockDatasourceService{
+			searchChannelsResponse: datasourceapi.SearchChannelsResponse{
+				Results: []datasourceapi.ChannelMetadata{
+					{Name: api.Channel("temperature"), DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))},
+					{Name: api.Channel("foreign"), DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "other"))},
+				},
+			},
+		}
+
+		ds := newTestDatasource(server.URL, &mockAuthService{}, mockDS)
+
+		body, _ := json.Marshal(map[string]string{"assetRid": assetRid})
+		req := &backend.CallResourceRequest{Path: "channelvariables", Method: "POST", Body: body}
+		resp := callResourceAndCapture(t, ds, req)
+		if resp.Status != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+		}
+
+		var result []map[string]string
+		if err := json.Unmarshal(resp.Body, &result); err != nil {
+			t.Fatalf("failed to parse response: %v", err)
+		}
+		if len(result) != 1 || result[0]["text"] != "temperature" {
+			t.Errorf("expected only temperature, got %v", result)
+		}
+	})
+
 	t.Run("filters by dataScopeName", func(t *testing.T) {
 		twoScopeAsset := map[string]SingleAssetResponse{
 			assetRid: {
@@ -1044,3 +1395,528 @@ func TestHandleChannelVariables(t *testing.T) {
 		}
 	})
 }
+
+func TestChannelVariablesCoalescesConcurrentLookups(t *testing.T) {
+	assetRid := "ri.scout.main.asset.ch123"
+	datasetRid := "ri.scout.main.data-source.ds1"
+	var assetFetches int
+	server := newCountingAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid: assetRid,
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "scope1", DataSource: AssetDataSource{Type: "dataset", Dataset: &datasetRid}},
+			},
+		},
+	}, &assetFetches)
+	defer server.Close()
+
+	var searchCalls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mockDS := &mockDatasourceService{
+		searchChannelsFunc: func(ctx context.Context, authHeader bearertoken.Token, req datasourceapi.SearchChannelsRequest) (datasourceapi.SearchChannelsResponse, error) {
+			if searchCalls.Add(1) == 1 {
+				close(started)
+			}
+			<-release
+			return datasourceapi.SearchChannelsResponse{
+				Results: []datasourceapi.ChannelMetadata{
+					{Name: api.Channel("temperature"), DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))},
+				},
+			}, nil
+		},
+	}
+	ds := newTestDatasource(server.URL, &mockAuthService{}, mockDS)
+	body, _ := json.Marshal(map[string]string{"assetRid": assetRid, "dataScopeName": "scope1"})
+
+	const callers = 5
+	statuses := make(chan *backend.CallResourceResponse, callers)
+	call := func() {
+		sender := backend.CallResourceResponseSenderFunc(func(resp *backend.CallResourceResponse) error {
+			statuses <- resp
+			return nil
+		})
+		if err := ds.CallResource(context.Background(), &backend.CallResourceRequest{Path: "channelvariables", Method: "POST", Body: body}, sender); err != nil {
+			t.Errorf("CallResource returned error: %v", err)
+		}
+	}
+	go call()
+	<-started
+	for i := 1; i < callers; i++ {
+		go call()
+	}
+
+	// Hold the upstream call until every caller has joined it.
+	waitForChannelVariablesWaiters(t, ds.templateCatalog(), callers)
+	close(release)
+
+	for i := 0; i < callers; i++ {
+		resp := <-statuses
+		if resp.Status != http.StatusOK || !strings.Contains(string(resp.Body), "temperature") {
+			t.Errorf("response %d = %d %s, want the shared channel list", i, resp.Status, string(resp.Body))
+		}
+	}
+	if got := searchCalls.Load(); got != 1 {
+		t.Errorf("SearchChannels calls = %d, want 1", got)
+	}
+	if assetFetches != 1 {
+		t.Errorf("asset fetches = %d, want 1", assetFetches)
+	}
+}
+
+func TestChannelVariablesCancelledCallerDoesNotFailWaiters(t *testing.T) {
+	assetRid := "ri.scout.main.asset.ch123"
+	datasetRid := "ri.scout.main.data-source.ds1"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid: assetRid,
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "scope1", DataSource: AssetDataSource{Type: "dataset", Dataset: &datasetRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	var searchCalls atomic.Int32
+	started := make(chan struct{})
+	release := make(chan struct{})
+	mockDS := &mockDatasourceService{
+		searchChannelsFunc: func(ctx context.Context, authHeader bearertoken.Token, req datasourceapi.SearchChannelsRequest) (datasourceapi.SearchChannelsResponse, error) {
+			if searchCalls.Add(1) == 1 {
+				close(started)
+			}
+			select {
+			case <-release:
+			case <-ctx.Done():
+				return datasourceapi.SearchChannelsResponse{}, ctx.Err()
+			}
+			return datasourceapi.SearchChannelsResponse{
+				Results: []datasourceapi.ChannelMetadata{
+					{Name: api.Channel("temperature"), DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))},
+				},
+			}, nil
+		},
+	}
+	catalog := newTemplateVariableCatalog(newNominalCatalog(server.Client(), mockDS))
+	config := &models.PluginSettings{
+		BaseUrl: server.URL,
+		Secrets: &models.SecretPluginSettings{ApiKey: "test-key"},
+	}
+	req := channelVariablesRequest{AssetRid: assetRid, DataScopeName: "scope1"}
+
+	firstCtx, cancelFirst := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, _, err := catalog.ChannelVariables(firstCtx, config, req)
+		firstErr <- err
+	}()
+	<-started
+
+	type lookup struct {
+		values []metricFindValue
+		err    error
+	}
+	second := make(chan lookup, 1)
+	go func() {
+		values, _, err := catalog.ChannelVariables(context.Background(), config, req)
+		second <- lookup{values, err}
+	}()
+
+	// The first caller leaves once the second has joined the shared lookup,
+	// while it is still running.
+	waitForChannelVariablesWaiters(t, catalog, 2)
+	cancelFirst()
+	if err := <-firstErr; !errors.Is(err, context.Canceled) {
+		t.Fatalf("cancelled caller error = %v, want context.Canceled", err)
+	}
+	close(release)
+
+	got := <-second
+	if got.err != nil {
+		t.Fatalf("waiting caller error = %v, want the shared result", got.err)
+	}
+	if len(got.values) != 1 || got.values[0].Value != "temperature" {
+		t.Errorf("waiting caller values = %v, want [temperature]", got.values)
+	}
+	if n := searchCalls.Load(); n != 1 {
+		t.Errorf("SearchChannels calls = %d, want 1", n)
+	}
+}
+
+// waitForChannelVariablesWaiters blocks until the catalog's single in-flight
+// ChannelVariables lookup has been joined by n callers.
+func waitForChannelVariablesWaiters(t *testing.T, catalog *TemplateVariableCatalog, n int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		catalog.channelVariablesMu.Lock()
+		waiters := 0
+		for _, call := range catalog.channelVariablesCalls {
+			waiters += call.waiters
+		}
+		catalog.channelVariablesMu.Unlock()
+		if waiters >= n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("in-flight lookup has %d callers, want %d", waiters, n)
+		}
+		runtime.Gosched()
+	}
+}
+
+func TestVariableEndpointsEmptyResultStatus(t *testing.T) {
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{}, nil)
+	defer server.Close()
+
+	requests := []struct {
+		name string
+		path string
+		body map[string]any
+		// budgeted requests answer 200 in the { channels, partial } shape.
+		budgeted bool
+	}{
+		{name: "datascopes unresolved variable", path: "datascopes", body: map[string]any{"assetRid": "$asset"}},
+		{name: "datascopes asset not found", path: "datascopes", body: map[string]any{"assetRid": "ri.scout.main.asset.nonexistent"}},
+		{name: "channel variables unresolved variable", path: "channelvariables", body: map[string]any{"assetRid": "ri.scout.main.asset.1", "dataScopeName": "$scope"}},
+		{name: "channel variables asset not found", path: "channelvariables", body: map[string]any{"assetRid": "ri.scout.main.asset.nonexistent"}},
+		{name: "budgeted channel variables unresolved variable", path: "channelvariables", body: map[string]any{"assetRid": "ri.scout.main.asset.1", "dataScopeName": "$scope", "timeBudgetMs": 100}, budgeted: true},
+		{name: "budgeted channel variables asset not found", path: "channelvariables", body: map[string]any{"assetRid": "ri.scout.main.asset.nonexistent", "timeBudgetMs": 100}, budgeted: true},
+	}
+	modes := []struct {
+		name       string
+		settings   string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "default", settings: `{"baseUrl": "` + server.URL + `"}`, wantStatus: http.StatusOK, wantBody: "[]"},
+		{name: "no content", settings: `{"baseUrl": "` + server.URL + `", "emptyVariableNoContent": true}`, wantStatus: http.StatusNoContent},
+	}
+	for _, mode := range modes {
+		for _, r := range requests {
+			t.Run(mode.name+"/"+r.name, func(t *testing.T) {
+				ds := newTestDatasource(server.URL, &mockAuthService{}, &mockDatasourceService{})
+				ds.settings.JSONData = []byte(mode.settings)
+
+				body, _ := json.Marshal(r.body)
+				resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: r.path, Method: "POST", Body: body})
+				if resp.Status != mode.wantStatus {
+					t.Fatalf("status = %d, want %d; body = %s", resp.Status, mode.wantStatus, string(resp.Body))
+				}
+				wantBody := mode.wantBody
+				if r.budgeted && mode.wantStatus == http.StatusOK {
+					wantBody = `{"channels":[],"partial":false}`
+				}
+				if string(resp.Body) != wantBody {
+					t.Errorf("body = %q, want %q", string(resp.Body), wantBody)
+				}
+			})
+		}
+	}
+}
+
+// --- handleChannelsExist tests ---
+
+func TestHandleChannelsExist(t *testing.T) {
+	assetRid := "ri.scout.main.asset.ch123"
+	datasetRid := "ri.scout.main.data-source.ds1"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid:   assetRid,
+			Title: "Test Asset",
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "scope1", DataSource: AssetDataSource{Type: "dataset", Dataset: &datasetRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	dataSource := rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))
+	mockDS := &mockDatasourceService{
+		searchChannelsResponse: datasourceapi.SearchChannelsResponse{
+			Results: []datasourceapi.ChannelMetadata{
+				{Name: api.Channel("temperature"), DataSource: dataSource},
+				{Name: api.Channel("pressure"), DataSource: dataSource},
+			},
+		},
+	}
+	ds := newTestDatasource(server.URL, &mockAuthService{}, mockDS)
+
+	body, _ := json.Marshal(map[string]any{
+		"assetRid":      assetRid,
+		"dataScopeName": "scope1",
+		"channels":      []string{"temperature", "Pressure", "missing"},
+	})
+	resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "channels/exists", Method: "POST", Body: body})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+	}
+
+	var result map[string]bool
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	want := map[string]bool{"temperature": true, "Pressure": false, "missing": false}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %v, want %v", result, want)
+	}
+	if mockDS.searchChannelsCalls != 3 {
+		t.Errorf("SearchChannels calls = %d, want one per channel", mockDS.searchChannelsCalls)
+	}
+
+	t.Run("requires assetRid", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]any{"channels": []string{"temperature"}})
+		resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "channels/exists", Method: "POST", Body: body})
+		if resp.Status != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", resp.Status)
+		}
+	})
+}
+
+func TestChannelsExistLooksUpEachNameExactly(t *testing.T) {
+	assetRid := "ri.scout.main.asset.ch123"
+	datasetRid := "ri.scout.main.data-source.ds1"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid:   assetRid,
+			Title: "Test Asset",
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "scope1", DataSource: AssetDataSource{Type: "dataset", Dataset: &datasetRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	dataSource := rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))
+	otherSource := rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "other"))
+	// Keyed by the lowercased name, as the search matches case-insensitively.
+	byName := map[string][]datasourceapi.ChannelMetadata{
+		"wheel_speed": {{Name: api.Channel("wheel_speed"), DataSource: dataSource}},
+		"volts":       {{Name: api.Channel("VOLTS"), DataSource: dataSource}},
+		"q":           {{Name: api.Channel("q"), DataSource: otherSource}},
+	}
+	var mu sync.Mutex
+	var searched []string
+	mockDS := &mockDatasourceService{
+		searchChannelsFunc: func(_ context.Context, _ bearertoken.Token, req datasourceapi.SearchChannelsRequest) (datasourceapi.SearchChannelsResponse, error) {
+			if len(req.ExactMatch) != 1 {
+				t.Errorf("search ExactMatch = %v, want one name per search", req.ExactMatch)
+				return datasourceapi.SearchChannelsResponse{}, nil
+			}
+			mu.Lock()
+			searched = append(searched, req.ExactMatch[0])
+			mu.Unlock()
+			return datasourceapi.SearchChannelsResponse{Results: byName[strings.ToLower(req.ExactMatch[0])]}, nil
+		},
+	}
+	ds := newTestDatasource(server.URL, &mockAuthService{}, mockDS)
+
+	// The names share no substring, so no single narrowed search covers them.
+	channels := []string{"wheel_speed", "volts", "q", "xyz"}
+	body, _ := json.Marshal(map[string]any{
+		"assetRid":      assetRid,
+		"dataScopeName": "scope1",
+		"channels":      channels,
+	})
+	resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "channels/exists", Method: "POST", Body: body})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+	}
+	var result map[string]bool
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	// "volts" only matches case-insensitively and "q" is in another data source.
+	want := map[string]bool{"wheel_speed": true, "volts": false, "q": false, "xyz": false}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %v, want %v", result, want)
+	}
+	slices.Sort(searched)
+	if wantSearched := slices.Sorted(slices.Values(channels)); !reflect.DeepEqual(searched, wantSearched) {
+		t.Errorf("searched = %v, want one search per name %v", searched, wantSearched)
+	}
+}
+
+func TestChannelsExistRejectsTooManyChannels(t *testing.T) {
+	mockDS := &mockDatasourceService{}
+	ds := newTestDatasource("https://api.test.com", &mockAuthService{}, mockDS)
+
+	channels := make([]string, maxChannelsExistChannels+1)
+	for i := range channels {
+		channels[i] = fmt.Sprintf("ch%d", i)
+	}
+	body, _ := json.Marshal(map[string]any{"assetRid": "ri.scout.main.asset.1", "channels": channels})
+	resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "channels/exists", Method: "POST", Body: body})
+	if resp.Status != http.StatusBadRequest {
+		t.Errorf("status = %d, want 400; body = %s", resp.Status, string(resp.Body))
+	}
+	if mockDS.searchChannelsCalls != 0 {
+		t.Errorf("SearchChannels calls = %d, want 0", mockDS.searchChannelsCalls)
+	}
+}
+
+func TestHandleAssetDescribe(t *testing.T) {
+	assetRid := "ri.scout.main.asset.desc1"
+	datasetRid := "ri.scout.main.data-source.ds1"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid:         assetRid,
+			Title:       "Rover",
+			Description: "Test vehicle",
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "telemetry", DataSource: AssetDataSource{Type: "dataset", Dataset: &datasetRid}},
+				{DataScopeName: "video", DataSource: AssetDataSource{Type: "video"}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	dataSource := rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))
+	mockDS := &mockDatasourceService{
+		searchChannelsResponse: datasourceapi.SearchChannelsResponse{
+			Results: []datasourceapi.ChannelMetadata{
+				{Name: api.Channel("temperature"), DataSource: dataSource},
+				{Name: api.Channel("pressure"), DataSource: dataSource},
+			},
+		},
+	}
+	ds := newTestDatasource(server.URL, &mockAuthService{}, mockDS)
+
+	body, _ := json.Marshal(map[string]any{"assetRid": assetRid})
+	resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "assets/describe", Method: "POST", Body: body})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+	}
+
+	var result map[string]any
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	want := map[string]any{
+		"rid":         assetRid,
+		"title":       "Rover",
+		"description": "Test vehicle",
+		"dataScopes":  []any{"telemetry"},
+		"channels": []any{
+			map[string]any{"name": "temperature", "dataSource": dataSource.String(), "description": "Channel: temperature", "dataType": ""},
+			map[string]any{"name": "pressure", "dataSource": dataSource.String(), "description": "Channel: pressure", "dataType": ""},
+		},
+	}
+	if !reflect.DeepEqual(result, want) {
+		t.Errorf("result = %v, want %v", result, want)
+	}
+	if mockDS.searchChannelsCalls != 1 {
+		t.Errorf("SearchChannels calls = %d, want 1", mockDS.searchChannelsCalls)
+	}
+	if size := mockDS.searchChannelsRequest.PageSize; size == nil || *size != assetDescribeChannelSample {
+		t.Errorf("SearchChannels page size = %v, want %d", size, assetDescribeChannelSample)
+	}
+
+	t.Run("missing asset", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]any{"assetRid": "ri.scout.main.asset.missing"})
+		resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "assets/describe", Method: "POST", Body: body})
+		if resp.Status != http.StatusNotFound {
+			t.Errorf("status = %d, want 404", resp.Status)
+		}
+	})
+
+	t.Run("requires assetRid", func(t *testing.T) {
+		resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "assets/describe", Method: "POST", Body: []byte(`{}`)})
+		if resp.Status != http.StatusBadRequest {
+			t.Errorf("status = %d, want 400", resp.Status)
+		}
+	})
+}
+
+func TestChannelVariablesReturnsPartialListWithinTimeBudget(t *testing.T) {
+	assetRid := "ri.scout.main.asset.ch123"
+	datasetRid := "ri.scout.main.data-source.ds1"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid: assetRid,
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "scope1", DataSource: AssetDataSource{Type: "dataset", Dataset: &datasetRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	// Every page but the first is slow, and there is always another page.
+	pageToken := api.Token("next")
+	var pages atomic.Int32
+	mockDS := &mockDatasourceService{
+		searchChannelsFunc: func(ctx context.Context, authHeader bearertoken.Token, req datasourceapi.SearchChannelsRequest) (datasourceapi.SearchChannelsResponse, error) {
+			page := pages.Add(1)
+			if page > 1 {
+				select {
+				case <-time.After(5 * time.Second):
+				case <-ctx.Done():
+					return datasourceapi.SearchChannelsResponse{}, ctx.Err()
+				}
+			}
+			return datasourceapi.SearchChannelsResponse{
+				Results: []datasourceapi.ChannelMetadata{
+					{Name: api.Channel(fmt.Sprintf("ch-%d", page)), DataSource: rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))},
+				},
+				NextPageToken: &pageToken,
+			}, nil
+		},
+	}
+	ds := newTestDatasource(server.URL, &mockAuthService{}, mockDS)
+	body, _ := json.Marshal(map[string]any{"assetRid": assetRid, "dataScopeName": "scope1", "timeBudgetMs": 100})
+
+	start := time.Now()
+	resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "channelvariables", Method: "POST", Body: body})
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("elapsed = %v, want the request bounded by its time budget", elapsed)
+	}
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+	}
+
+	var result channelVariablesResponse
+	if err := json.Unmarshal(resp.Body, &result); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	if !result.Partial {
+		t.Errorf("partial = false, want true once the budget ran out")
+	}
+	if len(result.Channels) != 1 || result.Channels[0].Text != "ch-1" {
+		t.Errorf("channels = %+v, want the first page gathered before the budget ran out", result.Channels)
+	}
+}
+
+func TestVariableHandlersTreatAssetWithoutScopesAsEmpty(t *testing.T) {
+	assets := map[string]SingleAssetResponse{
+		"ri.scout.main.asset.nil-scopes":   {Rid: "ri.scout.main.asset.nil-scopes", Title: "No scopes"},
+		"ri.scout.main.asset.empty-scopes": {Rid: "ri.scout.main.asset.empty-scopes", Title: "Empty scopes", DataScopes: []AssetDataScope{}},
+	}
+	server := newTestAssetServer(t, assets, []AssetResponse{{
+		Results: []AssetSearchResult{
+			{Rid: "ri.scout.main.asset.nil-scopes", Title: "No scopes"},
+			{Rid: "ri.scout.main.asset.empty-scopes", Title: "Empty scopes", DataScopes: []AssetDataScope{}},
+		},
+	}})
+	defer server.Close()
+	mockDS := &mockDatasourceService{}
+	ds := newTestDatasource(server.URL, &mockAuthService{}, mockDS)
+
+	expectEmpty := func(t *testing.T, path string, body map[string]string) {
+		t.Helper()
+		encoded, _ := json.Marshal(body)
+		resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: path, Method: "POST", Body: encoded})
+		if resp.Status != http.StatusOK || strings.TrimSpace(string(resp.Body)) != "[]" {
+			t.Errorf("%s %v = %d %s, want 200 []", path, body, resp.Status, string(resp.Body))
+		}
+	}
+
+	expectEmpty(t, "assets", map[string]string{})
+	for rid := range assets {
+		expectEmpty(t, "datascopes", map[string]string{"assetRid": rid})
+		expectEmpty(t, "channelvariables", map[string]string{"assetRid": rid})
+		expectEmpty(t, "channelvariables", map[string]string{"assetRid": rid, "dataScopeName": "scope1"})
+	}
+	if mockDS.searchChannelsCalls != 0 {
+		t.Errorf("SearchChannels calls = %d, want none for assets without data scopes", mockDS.searchChannelsCalls)
+	}
+}
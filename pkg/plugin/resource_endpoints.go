@@ -3,12 +3,18 @@ package plugin
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
 	"github.com/nominal-io/nominal-api-go/api/rids"
 	datasourceapi "github.com/nominal-io/nominal-api-go/datasource/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+	computeapi1 "github.com/nominal-io/nominal-api-go/scout/compute/api1"
+	unitsapi "github.com/nominal-io/nominal-api-go/scout/units/api"
 	"github.com/palantir/pkg/bearertoken"
 	"github.com/palantir/pkg/rid"
 )
@@ -16,6 +22,12 @@ import (
 type channelsSearchRequest struct {
 	DataSourceRids []string `json:"dataSourceRids"`
 	SearchText     string   `json:"searchText"`
+	// AssetRid (with an optional DataScopeName) resolves the data sources to
+	// search when DataSourceRids is empty, as the channel variables endpoint does.
+	AssetRid      string `json:"assetRid,omitempty"`
+	DataScopeName string `json:"dataScopeName,omitempty"`
+	// IncludeUnits backfills units missing from channel metadata via BatchComputeUnits.
+	IncludeUnits bool `json:"includeUnits,omitempty"`
 }
 
 type channelSearchResult struct {
@@ -23,8 +35,13 @@ type channelSearchResult struct {
 	DataSource  string `json:"dataSource"`
 	Description string `json:"description"`
 	DataType    string `json:"dataType"`
+	Unit        string `json:"unit,omitempty"`
 }
 
+// maxChannelUnitLookups caps how many channels one search request may enrich
+// through BatchComputeUnits.
+const maxChannelUnitLookups = 100
+
 type channelsSearchResponse struct {
 	Channels []channelSearchResult `json:"channels"`
 }
@@ -51,20 +68,51 @@ func (h *NominalResourceHandler) handleChannelsSearch(ctx context.Context, req *
 
 	bearerToken := bearertoken.Token(config.Secrets.ApiKey)
 
-	// Convert string RIDs to proper datasource RID types
+	// Convert string RIDs to proper datasource RID types. Surrounding
+	// whitespace (e.g. from pasted or templated values) is not part of a RID.
 	var dataSourceRids []rids.DataSourceRid
+	var rejected []string
 	for _, ridStr := range searchRequest.DataSourceRids {
-		if parsedRid, err := rid.ParseRID(ridStr); err != nil {
+		ridStr = strings.TrimSpace(ridStr)
+		if ridStr == "" {
+			continue
+		}
+		parsedRid, err := rid.ParseRID(ridStr)
+		if err != nil {
 			log.DefaultLogger.Warn("Failed to parse data source RID", "rid", ridStr, "error", err)
+			rejected = append(rejected, ridStr)
 			continue
-		} else {
-			dataSourceRids = append(dataSourceRids, rids.DataSourceRid(parsedRid))
+		}
+		dataSourceRids = append(dataSourceRids, rids.DataSourceRid(parsedRid))
+	}
+
+	assetRid := strings.TrimSpace(searchRequest.AssetRid)
+	if len(dataSourceRids) == 0 && len(rejected) == 0 && assetRid != "" {
+		if hasUnresolvedTemplateVariable(assetRid, searchRequest.DataScopeName) {
+			log.DefaultLogger.Debug("Channels search has unresolved template variable", "assetRid", assetRid, "dataScopeName", searchRequest.DataScopeName)
+			return jsonMarshalResponse(sender, http.StatusOK, channelsSearchResponse{Channels: []channelSearchResult{}})
+		}
+		asset, err := d.catalog().FetchAssetByRid(ctx, config, assetRid)
+		if err != nil {
+			logErrorWithConjureFields("Failed to fetch asset", err, "assetRid", assetRid)
+			return jsonErrorResponse(sender, http.StatusInternalServerError, appendInstanceID("Failed to fetch asset", err))
+		}
+		if asset != nil {
+			dataSourceRids = d.catalog().DataSourceRidsForScope(asset, searchRequest.DataScopeName)
+		}
+		if len(dataSourceRids) == 0 {
+			log.DefaultLogger.Debug("Asset has no data sources to search", "assetRid", assetRid, "dataScopeName", searchRequest.DataScopeName)
+			return jsonMarshalResponse(sender, http.StatusOK, channelsSearchResponse{Channels: []channelSearchResult{}})
 		}
 	}
 
 	if len(dataSourceRids) == 0 {
-		log.DefaultLogger.Warn("No valid data source RIDs provided")
-		return jsonErrorResponse(sender, http.StatusBadRequest, "No valid data source RIDs provided")
+		log.DefaultLogger.Warn("No valid data source RIDs provided", "rejected", rejected)
+		msg := "No valid data source RIDs provided"
+		if len(rejected) > 0 {
+			msg += "; rejected: " + strings.Join(rejected, ", ")
+		}
+		return jsonErrorResponse(sender, http.StatusBadRequest, msg)
 	}
 
 	// Build the search request with correct field names
@@ -89,13 +137,74 @@ func (h *NominalResourceHandler) handleChannelsSearch(ctx context.Context, req *
 			DataSource:  channel.DataSource.String(),
 			Description: getChannelMetadataDescription(channel),
 			DataType:    getChannelDataType(channel),
+			Unit:        getChannelUnit(channel),
 		})
 	}
 
+	if searchRequest.IncludeUnits {
+		h.enrichChannelUnits(ctx, bearerToken, channels)
+	}
+
 	log.DefaultLogger.Debug("Channels search successful", "channelCount", len(channels))
 	return jsonMarshalResponse(sender, http.StatusOK, channelsSearchResponse{Channels: channels})
 }
 
+// enrichChannelUnits fills in Unit for numeric channels whose metadata carries none,
+// resolving up to maxChannelUnitLookups of them in one BatchComputeUnits call.
+// Enrichment is best-effort: on failure the channels are left as they were.
+func (h *NominalResourceHandler) enrichChannelUnits(ctx context.Context, bearerToken bearertoken.Token, channels []channelSearchResult) {
+	qe := newNominalQueryExecution(h.datasource, nil)
+	var indexes []int
+	var requests []computeapi1.ComputeUnitsRequest
+	for i, channel := range channels {
+		if channel.Unit != "" || (channel.DataType != ChannelDataTypeNumeric && channel.DataType != "") {
+			continue
+		}
+		if len(requests) == maxChannelUnitLookups {
+			log.DefaultLogger.Debug("Channel unit lookups capped", "cap", maxChannelUnitLookups)
+			break
+		}
+		indexes = append(indexes, i)
+		requests = append(requests, qe.buildChannelUnitsRequest(channel.DataSource, channel.Name))
+	}
+	if len(requests) == 0 {
+		return
+	}
+
+	result, err := h.datasource.computeService.BatchComputeUnits(ctx, bearerToken, computeapi1.BatchComputeUnitsRequest{Requests: requests})
+	if err != nil {
+		logErrorWithConjureFields("Channel unit lookup failed", err)
+		return
+	}
+	if len(result.Results) != len(requests) {
+		log.DefaultLogger.Warn("Channel unit lookup returned unexpected result count", "expected", len(requests), "got", len(result.Results))
+		return
+	}
+
+	for i, unitResult := range result.Results {
+		channels[indexes[i]].Unit = computeUnitSymbol(unitResult)
+	}
+}
+
+// computeUnitSymbol returns the unit symbol of a single-series unit result, or ""
+// when no unit is available.
+func computeUnitSymbol(result computeapi.ComputeUnitResult) string {
+	var symbol string
+	_ = result.AcceptFuncs(
+		func(single computeapi.UnitResult) error {
+			return single.AcceptFuncs(
+				func(s unitsapi.UnitSymbol) error { symbol = strings.TrimSpace(string(s)); return nil },
+				func([]computeapi.UnitComputationError) error { return nil },
+				func(string) error { return nil },
+			)
+		},
+		func(computeapi.CartesianUnitResult) error { return nil },
+		func(computeapi.Cartesian3dUnitResult) error { return nil },
+		func(string) error { return nil },
+	)
+	return symbol
+}
+
 // handleAssetsVariable handles the assets endpoint for Grafana template variables
 // Returns a list of assets in MetricFindValue format: { text: "Asset Name", value: "ri.scout..." }
 func (h *NominalResourceHandler) handleAssetsVariable(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
@@ -128,6 +237,35 @@ func (h *NominalResourceHandler) handleAssetsVariable(ctx context.Context, req *
 	return jsonMarshalResponse(sender, http.StatusOK, result)
 }
 
+// handleRecentAssets returns the newest queryable assets in MetricFindValue format,
+// for use as the query editor's default asset list.
+func (h *NominalResourceHandler) handleRecentAssets(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	d := h.datasource
+
+	if ok, err := requirePost(req, sender); !ok {
+		return err
+	}
+
+	var recentRequest recentAssetsRequest
+	if ok, err := decodeOptionalResourceJSON(req, sender, &recentRequest, "Failed to parse recent assets request body"); !ok {
+		return err
+	}
+
+	config, ok, err := loadResourceSettings(d.settings, sender, "Failed to load settings for recent assets")
+	if !ok {
+		return err
+	}
+
+	result, err := d.templateCatalog().RecentAssets(ctx, config, recentRequest)
+	if err != nil {
+		logErrorWithConjureFields("Failed to fetch recent assets", err)
+		return jsonErrorResponse(sender, http.StatusInternalServerError, appendInstanceID("Failed to fetch recent assets", err))
+	}
+
+	log.DefaultLogger.Debug("Recent assets request successful", "assetCount", len(result))
+	return jsonMarshalResponse(sender, http.StatusOK, result)
+}
+
 // handleDatascopesVariable handles the datascopes endpoint for Grafana template variables
 // Returns a list of datascopes for a given asset in MetricFindValue format: { text: "scope name", value: "scope name" }
 func (h *NominalResourceHandler) handleDatascopesVariable(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
@@ -154,7 +292,7 @@ func (h *NominalResourceHandler) handleDatascopesVariable(ctx context.Context, r
 	// settings are absent/invalid (the catalog re-checks only to skip the network call).
 	if hasUnresolvedTemplateVariable(searchRequest.AssetRid) {
 		log.DefaultLogger.Debug("Asset RID contains unresolved template variable", "assetRid", searchRequest.AssetRid)
-		return jsonBytesResponse(sender, http.StatusOK, []byte("[]"))
+		return h.emptyVariableResponse(sender, nil, []metricFindValue{})
 	}
 
 	config, ok, err := loadResourceSettings(d.settings, sender, "Failed to load settings for datascopes variable")
@@ -169,11 +307,21 @@ func (h *NominalResourceHandler) handleDatascopesVariable(ctx context.Context, r
 	}
 
 	log.DefaultLogger.Debug("Datascopes variable request successful", "datascopeCount", len(result))
+	if len(result) == 0 {
+		return h.emptyVariableResponse(sender, config, []metricFindValue{})
+	}
 	return jsonMarshalResponse(sender, http.StatusOK, result)
 }
 
 // handleChannelVariables handles the channelvariables endpoint for Grafana template variables
 // Returns a list of channel names for a given asset in MetricFindValue format: { text: "channel name", value: "channel name" }
+// When the request sets timeBudgetMs the response is instead
+// { channels: [...], partial: bool }, with partial set when the budget ran out
+// before every channel was listed. An empty, complete result follows
+// emptyVariableNoContent on both paths: 204 when it is set, otherwise 200 with
+// [] or, under a time budget, { channels: [], partial: false }. With
+// caseInsensitive set, names differing only in case or surrounding whitespace
+// are listed once, as first seen.
 func (h *NominalResourceHandler) handleChannelVariables(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
 	d := h.datasource
 
@@ -193,12 +341,18 @@ func (h *NominalResourceHandler) handleChannelVariables(ctx context.Context, req
 	if searchRequest.AssetRid == "" {
 		return jsonErrorResponse(sender, http.StatusBadRequest, "assetRid is required")
 	}
+	if searchRequest.TimeBudgetMs < 0 || int64(searchRequest.TimeBudgetMs) > maxDurationMs {
+		return jsonErrorResponse(sender, http.StatusBadRequest, fmt.Sprintf("timeBudgetMs must be between 0 and %d", maxDurationMs))
+	}
 
 	// Must run before loadResourceSettings so unresolved vars return [] even when
 	// settings are absent/invalid (the catalog re-checks only to skip the network call).
 	if hasUnresolvedTemplateVariable(searchRequest.AssetRid, searchRequest.DataScopeName) {
 		log.DefaultLogger.Debug("Request contains unresolved template variable", "assetRid", searchRequest.AssetRid, "dataScopeName", searchRequest.DataScopeName)
-		return jsonBytesResponse(sender, http.StatusOK, []byte("[]"))
+		if searchRequest.TimeBudgetMs > 0 {
+			return h.emptyVariableResponse(sender, nil, channelVariablesResponse{Channels: []metricFindValue{}})
+		}
+		return h.emptyVariableResponse(sender, nil, []metricFindValue{})
 	}
 
 	config, ok, err := loadResourceSettings(d.settings, sender, "Failed to load settings for channel variables")
@@ -206,7 +360,7 @@ func (h *NominalResourceHandler) handleChannelVariables(ctx context.Context, req
 		return err
 	}
 
-	result, err := d.templateCatalog().ChannelVariables(ctx, config, searchRequest)
+	result, partial, err := d.templateCatalog().ChannelVariables(ctx, config, searchRequest)
 	if err != nil {
 		var catalogErr *templateVariableCatalogError
 		if errors.As(err, &catalogErr) && catalogErr.kind == templateVariableAssetFetchError {
@@ -217,6 +371,297 @@ func (h *NominalResourceHandler) handleChannelVariables(ctx context.Context, req
 		return jsonErrorResponse(sender, http.StatusInternalServerError, appendInstanceID("Channels search failed", err))
 	}
 
-	log.DefaultLogger.Debug("Channel variables request successful", "channelCount", len(result))
+	log.DefaultLogger.Debug("Channel variables request successful", "channelCount", len(result), "partial", partial)
+	if searchRequest.TimeBudgetMs > 0 {
+		if len(result) == 0 && !partial {
+			return h.emptyVariableResponse(sender, config, channelVariablesResponse{Channels: []metricFindValue{}})
+		}
+		return jsonMarshalResponse(sender, http.StatusOK, channelVariablesResponse{Channels: result, Partial: partial})
+	}
+	if len(result) == 0 {
+		return h.emptyVariableResponse(sender, config, []metricFindValue{})
+	}
 	return jsonMarshalResponse(sender, http.StatusOK, result)
 }
+
+// emptyVariableResponse answers a template variable request with no values:
+// 200 with empty by default, or 204 when EmptyVariableNoContent is set. A nil
+// config is loaded here, and settings that fail to load keep the default, so
+// unresolved variables still succeed without valid settings.
+func (h *NominalResourceHandler) emptyVariableResponse(sender backend.CallResourceResponseSender, config *models.PluginSettings, empty any) error {
+	if config == nil {
+		config, _ = models.LoadPluginSettings(h.datasource.settings)
+	}
+	if config != nil && config.EmptyVariableNoContent {
+		return sender.Send(&backend.CallResourceResponse{Status: http.StatusNoContent})
+	}
+	return jsonMarshalResponse(sender, http.StatusOK, empty)
+}
+
+// handleChannelsExist handles the channels/exists endpoint. It accepts
+// { assetRid, dataScopeName, channels: [...] } and returns a map of each channel
+// name to whether it exists on the asset, so the frontend can check a
+// dashboard's channels in one request. Up to maxChannelsExistChannels names
+// can be checked per request.
+func (h *NominalResourceHandler) handleChannelsExist(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	d := h.datasource
+
+	if ok, err := requirePost(req, sender); !ok {
+		return err
+	}
+
+	var existsRequest channelsExistRequest
+	if ok, err := decodeOptionalResourceJSON(req, sender, &existsRequest, "Failed to parse channels exist request body"); !ok {
+		return err
+	}
+
+	if existsRequest.AssetRid == "" {
+		return jsonErrorResponse(sender, http.StatusBadRequest, "assetRid is required")
+	}
+	if len(existsRequest.Channels) > maxChannelsExistChannels {
+		return jsonErrorResponse(sender, http.StatusBadRequest, fmt.Sprintf("at most %d channels can be checked at once, got %d", maxChannelsExistChannels, len(existsRequest.Channels)))
+	}
+
+	config, ok, err := loadResourceSettings(d.settings, sender, "Failed to load settings for channels exist")
+	if !ok {
+		return err
+	}
+
+	result, err := d.templateCatalog().ChannelsExist(ctx, config, existsRequest)
+	if err != nil {
+		var catalogErr *templateVariableCatalogError
+		if errors.As(err, &catalogErr) && catalogErr.kind == templateVariableAssetFetchError {
+			logErrorWithConjureFields("Failed to fetch asset", err, "assetRid", existsRequest.AssetRid)
+			return jsonErrorResponse(sender, http.StatusInternalServerError, appendInstanceID("Failed to fetch asset", err))
+		}
+		logErrorWithConjureFields("Channels search API call failed", err)
+		return jsonErrorResponse(sender, http.StatusInternalServerError, appendInstanceID("Channels search failed", err))
+	}
+
+	log.DefaultLogger.Debug("Channels exist request successful", "channelCount", len(result))
+	return jsonMarshalResponse(sender, http.StatusOK, result)
+}
+
+// handleAssetDescribe handles the assets/describe endpoint. It accepts
+// { assetRid } and returns the asset's title, description, data scopes, and a
+// channel preview, so the editor's landing view needs one request.
+func (h *NominalResourceHandler) handleAssetDescribe(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	d := h.datasource
+
+	if ok, err := requirePost(req, sender); !ok {
+		return err
+	}
+
+	var describeRequest assetDescribeRequest
+	if ok, err := decodeOptionalResourceJSON(req, sender, &describeRequest, "Failed to parse asset describe request body"); !ok {
+		return err
+	}
+
+	if describeRequest.AssetRid == "" {
+		return jsonErrorResponse(sender, http.StatusBadRequest, "assetRid is required")
+	}
+
+	config, ok, err := loadResourceSettings(d.settings, sender, "Failed to load settings for asset describe")
+	if !ok {
+		return err
+	}
+
+	result, err := d.templateCatalog().DescribeAsset(ctx, config, describeRequest)
+	if err != nil {
+		var catalogErr *templateVariableCatalogError
+		if errors.As(err, &catalogErr) && catalogErr.kind == templateVariableAssetFetchError {
+			logErrorWithConjureFields("Failed to fetch asset", err, "assetRid", describeRequest.AssetRid)
+			return jsonErrorResponse(sender, http.StatusInternalServerError, appendInstanceID("Failed to fetch asset", err))
+		}
+		logErrorWithConjureFields("Channels search API call failed", err)
+		return jsonErrorResponse(sender, http.StatusInternalServerError, appendInstanceID("Channels search failed", err))
+	}
+	if result == nil {
+		return jsonErrorResponse(sender, http.StatusNotFound, "Asset not found")
+	}
+
+	log.DefaultLogger.Debug("Asset describe request successful", "dataScopeCount", len(result.DataScopes), "channelCount", len(result.Channels))
+	return jsonMarshalResponse(sender, http.StatusOK, result)
+}
+
+// effectiveConfigResponse is the config/effective payload: the resolved
+// settings a support ticket needs, with the API key reduced to whether one is set.
+type effectiveConfigResponse struct {
+	BaseURL             string                    `json:"baseUrl"`
+	BaseURLSource       string                    `json:"baseUrlSource"`
+	FallbackBaseURL     string                    `json:"fallbackBaseUrl,omitempty"`
+	ProxyPathPrefix     string                    `json:"proxyPathPrefix,omitempty"`
+	ProxyAllowedHeaders []string                  `json:"proxyAllowedHeaders,omitempty"`
+	UIBaseURL           string                    `json:"uiBaseUrl,omitempty"`
+	UIChannelPath       string                    `json:"uiChannelPath,omitempty"`
+	AuthHeaderName      string                    `json:"authHeaderName"`
+	AuthHeaderScheme    string                    `json:"authHeaderScheme,omitempty"`
+	APIKeySet           bool                      `json:"apiKeySet"`
+	Timeouts            effectiveConfigTimeouts   `json:"timeouts"`
+	Features            effectiveConfigFeatures   `json:"features"`
+	ConnectionPool      effectiveConnectionLimits `json:"connectionPool"`
+	DialLocalAddr       string                    `json:"dialLocalAddr,omitempty"`
+	ForceIPv4           bool                      `json:"forceIPv4"`
+	MaxExpandedQueries  int                       `json:"maxExpandedQueries"`
+	MaxBuckets          int                       `json:"maxBuckets"`
+	MaxAssetSearchPages int                       `json:"maxAssetSearchPages"`
+	DefaultTimeShift    string                    `json:"defaultTimeShift,omitempty"`
+}
+
+type effectiveConfigTimeouts struct {
+	ResourceRequestMs    int64 `json:"resourceRequestMs"`
+	HealthCheckMs        int64 `json:"healthCheckMs"`
+	SlowQueryThresholdMs int64 `json:"slowQueryThresholdMs"`
+	AssetCacheTTLMs      int64 `json:"assetCacheTtlMs"`
+	ClockSkewThresholdMs int64 `json:"clockSkewThresholdMs"`
+}
+
+type effectiveConfigFeatures struct {
+	DeepHealthCheck          bool `json:"deepHealthCheck"`
+	AssetAccessCheck         bool `json:"assetAccessCheck"`
+	DataScopeCheck           bool `json:"dataScopeCheck"`
+	RetryMissingBatchResults bool `json:"retryMissingBatchResults"`
+	EmptyVariableNoContent   bool `json:"emptyVariableNoContent"`
+	DebugEndpoints           bool `json:"debugEndpoints"`
+}
+
+// effectiveConnectionLimits reports the configured pool limits; zero means the
+// Grafana SDK default.
+type effectiveConnectionLimits struct {
+	MaxIdleConns           int  `json:"maxIdleConns"`
+	MaxIdleConnsPerHost    int  `json:"maxIdleConnsPerHost"`
+	MaxConnsPerHost        int  `json:"maxConnsPerHost"`
+	IdleConnTimeoutSeconds int  `json:"idleConnTimeoutSeconds"`
+	DisableKeepAlives      bool `json:"disableKeepAlives"`
+}
+
+// handleEffectiveConfig handles the config/effective endpoint, returning the
+// datasource's resolved configuration for support tickets. The API key is
+// never included; only whether one is configured.
+func (h *NominalResourceHandler) handleEffectiveConfig(req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	d := h.datasource
+
+	if ok, err := requireGet(req, sender); !ok {
+		return err
+	}
+
+	config, ok, err := loadResourceSettings(d.settings, sender, "Failed to load settings for effective config")
+	if !ok {
+		return err
+	}
+
+	baseURL, source := config.BaseUrl, "baseUrl"
+	switch {
+	case baseURL != "":
+	case config.Path != "":
+		baseURL, source = config.Path, "path"
+	default:
+		baseURL, source = defaultAPIBaseURL, "default"
+	}
+	headerName, headerValue := authHeader(config, "")
+
+	return jsonMarshalResponse(sender, http.StatusOK, effectiveConfigResponse{
+		BaseURL:             redactedBaseURL(strings.TrimSuffix(baseURL, "/")),
+		BaseURLSource:       source,
+		FallbackBaseURL:     redactedBaseURL(strings.TrimSuffix(config.FallbackBaseUrl, "/")),
+		ProxyPathPrefix:     config.ProxyPathPrefix,
+		ProxyAllowedHeaders: config.ProxyAllowedHeaders,
+		UIBaseURL:           redactedBaseURL(config.UIBaseUrl),
+		UIChannelPath:       config.UIChannelPath,
+		AuthHeaderName:      headerName,
+		AuthHeaderScheme:    strings.TrimSpace(headerValue),
+		APIKeySet:           config.Secrets.ApiKey != "",
+		Timeouts: effectiveConfigTimeouts{
+			ResourceRequestMs:    resourceRequestTimeout.Milliseconds(),
+			HealthCheckMs:        healthCheckTimeout.Milliseconds(),
+			SlowQueryThresholdMs: newNominalQueryExecution(d, config).slowQueryThreshold().Milliseconds(),
+			AssetCacheTTLMs:      assetCacheTTL.Milliseconds(),
+			ClockSkewThresholdMs: clockSkewThreshold(config).Milliseconds(),
+		},
+		Features: effectiveConfigFeatures{
+			DeepHealthCheck:          config.DeepHealthCheck,
+			AssetAccessCheck:         config.AssetAccessCheck,
+			DataScopeCheck:           config.DataScopeCheck,
+			RetryMissingBatchResults: config.RetryMissingBatchResults,
+			EmptyVariableNoContent:   config.EmptyVariableNoContent,
+			DebugEndpoints:           config.DebugEndpoints,
+		},
+		ConnectionPool: effectiveConnectionLimits{
+			MaxIdleConns:           config.MaxIdleConns,
+			MaxIdleConnsPerHost:    config.MaxIdleConnsPerHost,
+			MaxConnsPerHost:        config.MaxConnsPerHost,
+			IdleConnTimeoutSeconds: config.IdleConnTimeoutSeconds,
+			DisableKeepAlives:      config.DisableKeepAlives,
+		},
+		DialLocalAddr:       config.DialLocalAddr,
+		ForceIPv4:           config.ForceIPv4,
+		MaxExpandedQueries:  newNominalQueryExecution(d, config).maxExpandedQueries(),
+		MaxBuckets:          newNominalQueryExecution(d, config).maxBuckets(),
+		MaxAssetSearchPages: maxAssetSearchPages(config),
+		DefaultTimeShift:    config.DefaultTimeShift,
+	})
+}
+
+type validateQueryResponse struct {
+	Valid  bool     `json:"valid"`
+	Errors []string `json:"errors"`
+}
+
+// handleValidateQuery runs the same static checks QueryData applies to a query
+// model — template interpolation, the datasource's default time shift, field
+// validation, and aggregation names — and reports the outcome without calling
+// the Nominal API.
+func (h *NominalResourceHandler) handleValidateQuery(req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	if ok, err := requirePost(req, sender); !ok {
+		return err
+	}
+
+	config, ok, err := loadResourceSettings(h.datasource.settings, sender, "Failed to load settings for validate")
+	if !ok {
+		return err
+	}
+
+	var qm NominalQueryModel
+	if ok, err := decodeResourceJSON(req.Body, sender, &qm, "Failed to parse validate request body"); !ok {
+		return err
+	}
+
+	errs := newNominalQueryExecution(h.datasource, config).validationErrors(qm)
+	log.DefaultLogger.Debug("Validate query request", "valid", len(errs) == 0, "errorCount", len(errs))
+	return jsonMarshalResponse(sender, http.StatusOK, validateQueryResponse{
+		Valid:  len(errs) == 0,
+		Errors: errs,
+	})
+}
+
+// handleDebugResolve handles the debug/resolve endpoint: it applies a query's
+// TemplateVariables and then the datasource's defaultTimeShift, as QueryData
+// does, and returns the resulting query, so the final assetRid, channel,
+// dataScopeName, and timeShift can be inspected. It is only served when
+// DebugEndpoints is set.
+func (h *NominalResourceHandler) handleDebugResolve(req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
+	if ok, err := requirePost(req, sender); !ok {
+		return err
+	}
+
+	config, ok, err := loadResourceSettings(h.datasource.settings, sender, "Failed to load settings for debug resolve")
+	if !ok {
+		return err
+	}
+	if !config.DebugEndpoints {
+		return jsonErrorResponse(sender, http.StatusForbidden, "debug endpoints are disabled; enable debugEndpoints in the data source settings")
+	}
+
+	var qm NominalQueryModel
+	if ok, err := decodeResourceJSON(req.Body, sender, &qm, "Failed to parse debug resolve request body"); !ok {
+		return err
+	}
+
+	execution := newNominalQueryExecution(h.datasource, config)
+	if err := execution.applyTemplateVariables(&qm); err != nil {
+		return jsonErrorResponse(sender, http.StatusBadRequest, err.Error())
+	}
+	execution.applyDefaultTimeShift(&qm)
+	log.DefaultLogger.Debug("Debug resolve request", "assetRid", qm.AssetRid, "channel", qm.Channel, "dataScopeName", qm.DataScopeName, "timeShift", qm.TimeShift)
+	return jsonMarshalResponse(sender, http.StatusOK, qm)
+}
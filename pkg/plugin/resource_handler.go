@@ -9,7 +9,6 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
-	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
@@ -17,14 +16,41 @@ import (
 	"github.com/palantir/pkg/bearertoken"
 )
 
-// proxyAllowedHeaders is the set of safe request headers forwarded to the
-// upstream Nominal API. Sensitive caller context like Cookie and
-// Authorization must never be relayed.
+// proxyAllowedHeaders is the default set of safe request headers forwarded
+// to the upstream Nominal API; ProxyAllowedHeaders extends it.
 var proxyAllowedHeaders = map[string]bool{
 	"Content-Type": true,
 	"Accept":       true,
 }
 
+// proxyBlockedHeaders are never forwarded, even when configured: sensitive
+// caller context must not be relayed, and the plugin injects its own
+// credentials and User-Agent.
+var proxyBlockedHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"User-Agent":          true,
+}
+
+// proxyForwardsHeader reports whether the proxy forwards the request header
+// key upstream under config.
+func proxyForwardsHeader(config *models.PluginSettings, key string) bool {
+	key = http.CanonicalHeaderKey(key)
+	if proxyBlockedHeaders[key] || key == http.CanonicalHeaderKey(config.AuthHeaderName) {
+		return false
+	}
+	if proxyAllowedHeaders[key] {
+		return true
+	}
+	for _, allowed := range config.ProxyAllowedHeaders {
+		if http.CanonicalHeaderKey(strings.TrimSpace(allowed)) == key {
+			return true
+		}
+	}
+	return false
+}
+
 type NominalResourceHandler struct {
 	datasource *Datasource
 }
@@ -46,10 +72,26 @@ func (h *NominalResourceHandler) Handle(ctx context.Context, req *backend.CallRe
 	case "assets":
 		log.DefaultLogger.Debug("Handling assets variable request")
 		return h.handleAssetsVariable(ctx, req, sender)
+	case "recentassets":
+		return h.handleRecentAssets(ctx, req, sender)
 	case "datascopes":
 		return h.handleDatascopesVariable(ctx, req, sender)
 	case "channelvariables":
 		return h.handleChannelVariables(ctx, req, sender)
+	case "channels/exists":
+		return h.handleChannelsExist(ctx, req, sender)
+	case "assets/describe":
+		return h.handleAssetDescribe(ctx, req, sender)
+	case "config/effective":
+		return h.handleEffectiveConfig(req, sender)
+	case "diagnostics":
+		return h.handleDiagnostics(req, sender)
+	case "validate":
+		return h.handleValidateQuery(req, sender)
+	case "debug/resolve":
+		return h.handleDebugResolve(req, sender)
+	case "export/csv/wide":
+		return h.handleWideCSVExport(ctx, req, sender)
 	}
 
 	if strings.HasPrefix(path, "nominal/") {
@@ -120,12 +162,19 @@ func requirePost(req *backend.CallResourceRequest, sender backend.CallResourceRe
 	return false, jsonErrorResponse(sender, http.StatusMethodNotAllowed, "Method not allowed. Use POST.")
 }
 
+func requireGet(req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) (bool, error) {
+	if req.Method == http.MethodGet {
+		return true, nil
+	}
+	return false, jsonErrorResponse(sender, http.StatusMethodNotAllowed, "Method not allowed. Use GET.")
+}
+
 // handleTestConnection handles the test connection endpoint.
 func (h *NominalResourceHandler) handleTestConnection(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender) error {
 	d := h.datasource
 
 	// Add timeout to prevent hanging
-	ctxWithTimeout, cancel := context.WithTimeout(ctx, 10*time.Second)
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, healthCheckTimeout)
 	defer cancel()
 
 	// Load settings to get API key and base URL
@@ -158,13 +207,54 @@ func (h *NominalResourceHandler) handleTestConnection(ctx context.Context, req *
 
 	// Connection successful
 	response := map[string]interface{}{
-		"status":  "success",
-		"message": "Successfully connected to Nominal API and retrieved user profile",
+		"status":       "success",
+		"message":      "Successfully connected to Nominal API and retrieved user profile",
+		"capabilities": d.probeConnectionCapabilities(ctxWithTimeout, config, bearerToken),
 	}
 	return jsonMarshalResponse(sender, http.StatusOK, response)
 }
 
+// withProxyPathPrefix prepends the configured proxy path prefix (ignoring
+// surrounding slashes) to targetPath.
+func withProxyPathPrefix(prefix, targetPath string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return targetPath
+	}
+	return prefix + "/" + strings.TrimPrefix(targetPath, "/")
+}
+
+// proxyTargetURL joins targetPath onto baseURL and checks that the result stays
+// on the configured API: a targetPath that is itself an absolute URL, a final
+// URL whose scheme or host differs from baseURL's, and a ".." segment (which
+// the server could resolve outside the base path) are all rejected.
+func proxyTargetURL(baseURL, pathPrefix, targetPath string) (*url.URL, error) {
+	if asURL, err := url.Parse(targetPath); err == nil && (asURL.Scheme != "" || asURL.Host != "") {
+		return nil, fmt.Errorf("absolute URLs are not allowed")
+	}
+	base, err := url.Parse(baseURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL")
+	}
+
+	target, err := url.Parse(strings.TrimSuffix(baseURL, "/") + "/" + withProxyPathPrefix(pathPrefix, targetPath))
+	if err != nil {
+		return nil, fmt.Errorf("invalid target URL: %v", err)
+	}
+	if target.Scheme != base.Scheme || target.Host != base.Host {
+		return nil, fmt.Errorf("target host %q does not match the configured API host", target.Host)
+	}
+	for _, segment := range strings.Split(target.Path, "/") {
+		if segment == ".." {
+			return nil, fmt.Errorf("path traversal is not allowed")
+		}
+	}
+	return target, nil
+}
+
 // handleNominalProxy handles proxying requests to Nominal API with secure API key injection.
+// The upstream request carries ctx, so a caller that disconnects or cancels
+// aborts the upstream call rather than leaving it running.
 func (h *NominalResourceHandler) handleNominalProxy(ctx context.Context, req *backend.CallResourceRequest, sender backend.CallResourceResponseSender, targetPath string) error {
 	d := h.datasource
 
@@ -180,19 +270,18 @@ func (h *NominalResourceHandler) handleNominalProxy(ctx context.Context, req *ba
 		return jsonErrorResponse(sender, http.StatusBadRequest, "Missing base URL or API key configuration")
 	}
 
-	// Construct the full target URL
-	baseURL = strings.TrimSuffix(baseURL, "/")
-	targetURL := baseURL + "/" + targetPath
-
-	log.DefaultLogger.Debug("Proxy request", "fromPath", req.Path, "targetPath", targetPath, "toURL", targetURL)
-
-	// Parse the target URL to ensure it's valid
-	parsedURL, err := url.Parse(targetURL)
+	parsedURL, err := proxyTargetURL(baseURL, config.ProxyPathPrefix, targetPath)
 	if err != nil {
-		return fmt.Errorf("invalid target URL: %v", err)
+		log.DefaultLogger.Warn("Rejected proxy request", "fromPath", req.Path, "error", err)
+		return jsonErrorResponse(sender, http.StatusBadRequest, fmt.Sprintf("Invalid proxy path: %v", err))
 	}
 
-	// Create the proxied request
+	log.DefaultLogger.Debug("Proxy request", "fromPath", req.Path, "targetPath", targetPath, "toURL", parsedURL.String())
+
+	// Create the proxied request. The SDK hands over the body already read
+	// into req.Body, so wrapping it in a bytes.Reader streams it upstream
+	// without another copy. It also lets net/http set Content-Length and
+	// GetBody, which the fallback transport needs to replay the body.
 	var body io.Reader
 	if req.Body != nil {
 		body = bytes.NewReader(req.Body)
@@ -208,9 +297,9 @@ func (h *NominalResourceHandler) handleNominalProxy(ctx context.Context, req *ba
 		proxyReq.Host = parsedURL.Host
 	}
 
-	// Forward only the small allowlist of headers the upstream needs.
+	// Forward only the allow-listed headers the upstream needs.
 	for key, values := range req.Headers {
-		if !proxyAllowedHeaders[http.CanonicalHeaderKey(key)] {
+		if !proxyForwardsHeader(config, key) {
 			continue
 		}
 		for _, value := range values {
@@ -219,7 +308,7 @@ func (h *NominalResourceHandler) handleNominalProxy(ctx context.Context, req *ba
 	}
 
 	// Use the datasource API key for all proxied upstream requests.
-	proxyReq.Header.Set("Authorization", "Bearer "+apiKey)
+	setAuthHeader(proxyReq, config, apiKey)
 
 	log.DefaultLogger.Debug("Using API key for proxy request")
 
@@ -231,11 +320,17 @@ func (h *NominalResourceHandler) handleNominalProxy(ctx context.Context, req *ba
 	// Make the request
 	resp, err := d.getResourceHTTPClient().Do(proxyReq)
 	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			log.DefaultLogger.Debug("Proxy request cancelled by caller", "targetPath", targetPath)
+			return fmt.Errorf("proxy request cancelled: %w", ctxErr)
+		}
 		return fmt.Errorf("proxy request failed: %v", err)
 	}
 	defer resp.Body.Close()
 
-	// Read response body
+	// Relay the body as raw bytes whatever its Content-Type: binary payloads
+	// (Arrow, file downloads) must reach the caller unchanged, so nothing here
+	// may decode, re-encode, or otherwise rewrite it.
 	responseBody, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return fmt.Errorf("failed to read response body: %v", err)
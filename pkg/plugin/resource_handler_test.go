@@ -1,12 +1,17 @@
 package plugin
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
 	authapi "github.com/nominal-io/nominal-api-go/authentication/api"
@@ -144,6 +149,32 @@ func TestCallResourceRouting(t *testing.T) {
 			method:       "GET",
 			expectStatus: http.StatusMethodNotAllowed,
 		},
+		{
+			name:           "routes /validate",
+			path:           "validate",
+			method:         "POST",
+			body:           []byte(`{}`),
+			expectStatus:   http.StatusOK,
+			expectContains: `"valid":false`,
+		},
+		{
+			name:         "routes /recentassets",
+			path:         "recentassets",
+			method:       "POST",
+			expectStatus: http.StatusOK,
+		},
+		{
+			name:         "GET /recentassets returns 405",
+			path:         "recentassets",
+			method:       "GET",
+			expectStatus: http.StatusMethodNotAllowed,
+		},
+		{
+			name:         "GET /validate returns 405",
+			path:         "validate",
+			method:       "GET",
+			expectStatus: http.StatusMethodNotAllowed,
+		},
 	}
 
 	for _, tt := range tests {
@@ -164,6 +195,208 @@ func TestCallResourceRouting(t *testing.T) {
 	}
 }
 
+func TestHandleValidateQuery(t *testing.T) {
+	// No API services are configured: validation must never reach the network.
+	ds := &Datasource{settings: backend.DataSourceInstanceSettings{JSONData: []byte(`{}`)}}
+
+	tests := []struct {
+		name       string
+		query      NominalQueryModel
+		wantValid  bool
+		wantErrors []string
+	}{
+		{
+			name:      "valid asset/channel query",
+			query:     NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temp", DataScopeName: "default", Buckets: 100},
+			wantValid: true,
+		},
+		{
+			name:      "valid dataSourceRid query",
+			query:     NominalQueryModel{DataSourceRid: "ri.nominal.dataset.1", Channel: "temp"},
+			wantValid: true,
+		},
+		{
+			name:      "template variables are resolved before validation",
+			query:     NominalQueryModel{AssetRid: "$asset", Channel: "temp", DataScopeName: "default", TemplateVariables: map[string]interface{}{"asset": "ri.nominal.asset.1"}},
+			wantValid: true,
+		},
+		{
+			name:      "valid channelTable query",
+			query:     NominalQueryModel{QueryType: queryTypeChannelTable, AssetRid: "ri.nominal.asset.1"},
+			wantValid: true,
+		},
+		{
+			name:       "channelTable query without asset",
+			query:      NominalQueryModel{QueryType: queryTypeChannelTable},
+			wantErrors: []string{"assetRid is required for channelTable queries"},
+		},
+		{
+			name:       "empty query",
+			query:      NominalQueryModel{},
+			wantErrors: []string{"query must have either asset/channel parameters, dataSourceRid/channel parameters, query text, or constant value"},
+		},
+		{
+			name:       "missing data scope",
+			query:      NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temp"},
+			wantErrors: []string{"dataScopeName is required for asset/channel queries"},
+		},
+		{
+			name:       "negative buckets",
+			query:      NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temp", DataScopeName: "default", Buckets: -1},
+			wantErrors: []string{"buckets must be non-negative, got -1"},
+		},
+		{
+			name:       "unsupported aggregation",
+			query:      NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temp", DataScopeName: "default", Aggregations: []string{"MEDIAN"}},
+			wantErrors: []string{`unsupported aggregation "MEDIAN"; valid options are MEAN, MIN, MAX, COUNT, VARIANCE, FIRST_POINT, LAST_POINT`},
+		},
+		{
+			name:  "multiple errors are all reported",
+			query: NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temp", Aggregations: []string{"MEDIAN"}},
+			wantErrors: []string{
+				"dataScopeName is required for asset/channel queries",
+				`unsupported aggregation "MEDIAN"; valid options are MEAN, MIN, MAX, COUNT, VARIANCE, FIRST_POINT, LAST_POINT`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{
+				Path:   "validate",
+				Method: http.MethodPost,
+				Body:   mustMarshal(tt.query),
+			})
+			if resp.Status != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+			}
+
+			var got validateQueryResponse
+			if err := json.Unmarshal(resp.Body, &got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got.Valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v (errors: %v)", got.Valid, tt.wantValid, got.Errors)
+			}
+			if len(got.Errors) != len(tt.wantErrors) {
+				t.Fatalf("errors = %v, want %v", got.Errors, tt.wantErrors)
+			}
+			for i, want := range tt.wantErrors {
+				if got.Errors[i] != want {
+					t.Errorf("errors[%d] = %q, want %q", i, got.Errors[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestHandleValidateQueryAppliesDatasourceSettings(t *testing.T) {
+	// Without a time shift the time zone is never loaded, so only the
+	// datasource's calendar defaultTimeShift makes this query invalid.
+	query := NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temp", DataScopeName: "default", TimeZone: "Nowhere/City"}
+	for _, tt := range []struct {
+		name      string
+		settings  string
+		wantValid bool
+	}{
+		{name: "no default time shift", settings: `{}`, wantValid: true},
+		{name: "calendar default time shift", settings: `{"defaultTimeShift": "1d"}`, wantValid: false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ds := &Datasource{settings: backend.DataSourceInstanceSettings{JSONData: []byte(tt.settings)}}
+			resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "validate", Method: http.MethodPost, Body: mustMarshal(query)})
+			if resp.Status != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+			}
+			var got validateQueryResponse
+			if err := json.Unmarshal(resp.Body, &got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got.Valid != tt.wantValid {
+				t.Errorf("valid = %v, want %v (errors: %v)", got.Valid, tt.wantValid, got.Errors)
+			}
+		})
+	}
+}
+
+func TestHandleDebugResolve(t *testing.T) {
+	body := []byte(`{
+		"assetRid": "$asset",
+		"channel": "${channel}",
+		"dataScopeName": "$scope",
+		"buckets": "$buckets",
+		"templateVariables": {"asset": "ri.nominal.asset.1", "channel": "speed", "scope": "default", "buckets": "250"}
+	}`)
+
+	t.Run("substitutes template variables", func(t *testing.T) {
+		ds := newTestDatasource("https://api.test.com", &mockAuthService{}, &mockDatasourceService{})
+		ds.settings.JSONData = []byte(`{"baseUrl": "https://api.test.com", "debugEndpoints": true}`)
+
+		resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "debug/resolve", Method: http.MethodPost, Body: body})
+		if resp.Status != http.StatusOK {
+			t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+		}
+		var got NominalQueryModel
+		if err := json.Unmarshal(resp.Body, &got); err != nil {
+			t.Fatalf("failed to decode response: %v", err)
+		}
+		if got.AssetRid != "ri.nominal.asset.1" || got.Channel != "speed" || got.DataScopeName != "default" || got.Buckets != 250 {
+			t.Errorf("resolved = %+v, want asset ri.nominal.asset.1, channel speed, scope default, 250 buckets", got)
+		}
+	})
+
+	t.Run("applies the default time shift", func(t *testing.T) {
+		ds := newTestDatasource("https://api.test.com", &mockAuthService{}, &mockDatasourceService{})
+		ds.settings.JSONData = []byte(`{"baseUrl": "https://api.test.com", "debugEndpoints": true, "defaultTimeShift": "1d"}`)
+
+		for _, tt := range []struct {
+			body string
+			want string
+		}{
+			{body: `{"channel": "speed"}`, want: "1d"},
+			{body: `{"channel": "speed", "timeShift": "$shift", "templateVariables": {"shift": "2h"}}`, want: "2h"},
+		} {
+			resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "debug/resolve", Method: http.MethodPost, Body: []byte(tt.body)})
+			if resp.Status != http.StatusOK {
+				t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+			}
+			var got NominalQueryModel
+			if err := json.Unmarshal(resp.Body, &got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got.TimeShift != tt.want {
+				t.Errorf("%s: timeShift = %q, want %q", tt.body, got.TimeShift, tt.want)
+			}
+		}
+	})
+
+	t.Run("bad buckets variable returns 400", func(t *testing.T) {
+		ds := newTestDatasource("https://api.test.com", &mockAuthService{}, &mockDatasourceService{})
+		ds.settings.JSONData = []byte(`{"baseUrl": "https://api.test.com", "debugEndpoints": true}`)
+
+		resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{
+			Path:   "debug/resolve",
+			Method: http.MethodPost,
+			Body:   []byte(`{"buckets": "$buckets", "templateVariables": {"buckets": "many"}}`),
+		})
+		if resp.Status != http.StatusBadRequest || !strings.Contains(string(resp.Body), "buckets must be an integer") {
+			t.Errorf("response = %d %s, want 400 buckets error", resp.Status, string(resp.Body))
+		}
+	})
+
+	t.Run("disabled without debugEndpoints", func(t *testing.T) {
+		ds := newTestDatasource("https://api.test.com", &mockAuthService{}, &mockDatasourceService{})
+
+		resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "debug/resolve", Method: http.MethodPost, Body: body})
+		if resp.Status != http.StatusForbidden {
+			t.Errorf("status = %d, want 403; body = %s", resp.Status, string(resp.Body))
+		}
+		if strings.Contains(string(resp.Body), "ri.nominal.asset.1") {
+			t.Errorf("body = %s, want no resolved query", string(resp.Body))
+		}
+	})
+}
+
 func TestCallResourceProxyPaths(t *testing.T) {
 	tests := []struct {
 		name           string
@@ -219,6 +452,57 @@ func TestCallResourceProxyPaths(t *testing.T) {
 	}
 }
 
+func TestProxyRejectsPathsEscapingTheAPIHost(t *testing.T) {
+	upstreamHit := false
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		upstreamHit = true
+		w.Write([]byte(`{}`))
+	}))
+	defer proxyServer.Close()
+	ds := newTestDatasource(proxyServer.URL, &mockAuthService{}, &mockDatasourceService{})
+
+	for _, path := range []string{
+		"scout/../../admin",
+		"nominal/scout/v1/../../../etc/passwd",
+		"%2e%2e/%2e%2e/admin",
+		"https://evil.example.com/steal",
+		"nominal/http://evil.example.com/steal",
+	} {
+		t.Run(path, func(t *testing.T) {
+			upstreamHit = false
+			resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: path, Method: "GET"})
+			if resp.Status != http.StatusBadRequest {
+				t.Errorf("status = %d, want 400; body = %s", resp.Status, resp.Body)
+			}
+			if upstreamHit {
+				t.Error("rejected request reached the upstream")
+			}
+		})
+	}
+}
+
+func TestCallResourceProxyPathPrefix(t *testing.T) {
+	var gotPath string
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok":true}`))
+	}))
+	defer proxyServer.Close()
+
+	ds := newTestDatasource(proxyServer.URL, &mockAuthService{}, &mockDatasourceService{})
+	ds.settings.JSONData = []byte(`{"baseUrl": "` + proxyServer.URL + `", "proxyPathPrefix": "/nominal-api/"}`)
+	req := &backend.CallResourceRequest{Path: "nominal/scout/v1/search-assets", Method: "POST", Body: []byte(`{}`)}
+
+	resp := callResourceAndCapture(t, ds, req)
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+	}
+	if want := "/nominal-api/scout/v1/search-assets"; gotPath != want {
+		t.Fatalf("upstream path = %q, want %q", gotPath, want)
+	}
+}
+
 func TestNominalProxySettingsLoadFailureUsesJSONResponse(t *testing.T) {
 	ds := newTestDatasource("https://api.test.com", &mockAuthService{}, &mockDatasourceService{})
 	ds.settings.JSONData = []byte(`{`)
@@ -312,3 +596,211 @@ func TestProxyHeaderFiltering(t *testing.T) {
 		t.Errorf("Authorization header = %q, want %q", authHeader, "Bearer test-api-key")
 	}
 }
+
+func TestProxyForwardsConfiguredAllowedHeaders(t *testing.T) {
+	var receivedHeaders http.Header
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedHeaders = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer proxyServer.Close()
+
+	ds := newTestDatasource(proxyServer.URL, &mockAuthService{}, &mockDatasourceService{})
+	ds.settings.JSONData = []byte(`{"baseUrl": "` + proxyServer.URL + `", "authHeaderName": "X-Api-Key", "proxyAllowedHeaders": ["x-request-id", "Cookie", "authorization", "X-Api-Key"]}`)
+
+	resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{
+		Path:   "scout/v1/some-endpoint",
+		Method: "POST",
+		Body:   []byte(`{}`),
+		Headers: map[string][]string{
+			"Accept":          {"application/json"},
+			"X-Request-Id":    {"req-1"},
+			"X-Custom-Header": {"should-be-stripped"},
+			"Cookie":          {"session=secret"},
+			"Authorization":   {"Bearer user-token"},
+			"X-Api-Key":       {"caller-key"},
+		},
+	})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("expected 200, got %d; body = %s", resp.Status, string(resp.Body))
+	}
+
+	if got := receivedHeaders.Get("X-Request-Id"); got != "req-1" {
+		t.Errorf("X-Request-Id = %q, want the configured header forwarded", got)
+	}
+	if got := receivedHeaders.Get("Accept"); got != "application/json" {
+		t.Errorf("Accept = %q, want the default header still forwarded", got)
+	}
+	if got := receivedHeaders.Get("X-Custom-Header"); got != "" {
+		t.Errorf("X-Custom-Header = %q, want headers outside the allow-list stripped", got)
+	}
+	if got := receivedHeaders.Get("Cookie"); got != "" {
+		t.Errorf("Cookie = %q, want it blocked even when configured", got)
+	}
+	if got := receivedHeaders.Get("Authorization"); got != "" {
+		t.Errorf("Authorization = %q, want the caller's credentials blocked", got)
+	}
+	if got := receivedHeaders.Get("X-Api-Key"); got != "test-api-key" {
+		t.Errorf("X-Api-Key = %q, want only the plugin's own key", got)
+	}
+}
+
+func TestTestConnectionReportsCapabilities(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"errorCode": "PERMISSION_DENIED", "errorName": "Forbidden"}`))
+	}))
+	defer server.Close()
+
+	ds := newTestDatasource(server.URL, &mockAuthService{}, &mockDatasourceService{})
+	ds.computeService = &mockComputeService{}
+	resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "test", Method: http.MethodPost})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+	}
+
+	var got struct {
+		Status       string         `json:"status"`
+		Capabilities map[string]any `json:"capabilities"`
+	}
+	if err := json.Unmarshal(resp.Body, &got); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	want := map[string]any{
+		"readProfile":     true,
+		"readOrgSettings": true,
+		"readAssets":      false, // the asset search is refused with 403
+		"compute":         true,
+	}
+	if got.Status != "success" || !reflect.DeepEqual(got.Capabilities, want) {
+		t.Errorf("response = %+v, want success with capabilities %v", got, want)
+	}
+}
+
+func TestProxyForwardsLargeBody(t *testing.T) {
+	body := bytes.Repeat([]byte("0123456789abcdef"), 1<<19) // 8 MiB
+	var received []byte
+	var contentLength int64
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentLength = r.ContentLength
+		received, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ok": true}`))
+	}))
+	defer proxyServer.Close()
+
+	ds := newTestDatasource(proxyServer.URL, &mockAuthService{}, &mockDatasourceService{})
+	resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "scout/v1/compute", Method: "POST", Body: body})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+	}
+	if !bytes.Equal(received, body) {
+		t.Errorf("upstream received %d bytes, want the %d-byte body unchanged", len(received), len(body))
+	}
+	// A known length means the body was sent from a replayable reader rather
+	// than chunked from a one-shot stream.
+	if contentLength != int64(len(body)) {
+		t.Errorf("Content-Length = %d, want %d", contentLength, len(body))
+	}
+}
+
+func TestProxyPassesBinaryResponseThroughUnchanged(t *testing.T) {
+	// Not valid UTF-8, and includes NUL and bytes a text decoder would replace.
+	body := []byte{0x00, 0xff, 0xfe, 0x80, 'A', 0xc3, 0x28, 0x0a, 0x0d, 0x00}
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(body)
+	}))
+	defer proxyServer.Close()
+
+	ds := newTestDatasource(proxyServer.URL, &mockAuthService{}, &mockDatasourceService{})
+	resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "scout/v1/files/download", Method: "GET"})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.Status)
+	}
+	if !bytes.Equal(resp.Body, body) {
+		t.Errorf("body = %x, want %x byte for byte", resp.Body, body)
+	}
+	if got := resp.Headers["Content-Type"]; len(got) != 1 || got[0] != "application/octet-stream" {
+		t.Errorf("Content-Type = %v, want application/octet-stream", got)
+	}
+}
+
+func TestProxyCancellationAbortsUpstream(t *testing.T) {
+	received := make(chan struct{})
+	aborted := make(chan struct{})
+	proxyServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices a dropped connection once the body is read.
+		_, _ = io.Copy(io.Discard, r.Body)
+		close(received)
+		select {
+		case <-r.Context().Done():
+			close(aborted)
+		case <-time.After(5 * time.Second):
+		}
+	}))
+	defer proxyServer.Close()
+
+	ds := newTestDatasource(proxyServer.URL, &mockAuthService{}, &mockDatasourceService{})
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-received
+		cancel()
+	}()
+
+	sender := backend.CallResourceResponseSenderFunc(func(*backend.CallResourceResponse) error { return nil })
+	err := ds.CallResource(ctx, &backend.CallResourceRequest{Path: "scout/v1/compute", Method: "POST", Body: []byte(`{}`)}, sender)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("CallResource err = %v, want context.Canceled", err)
+	}
+
+	select {
+	case <-aborted:
+	case <-time.After(5 * time.Second):
+		t.Fatal("upstream request was not aborted after cancellation")
+	}
+}
+
+func TestCallResourceEffectiveConfigRedactsAPIKey(t *testing.T) {
+	const apiKey = "secret-api-key-value"
+	ds := newTestDatasource("https://api.test.com/api/", &mockAuthService{}, &mockDatasourceService{})
+	ds.settings.JSONData = []byte(`{"baseUrl": "https://api.test.com/api/", "proxyPathPrefix": "gw", "authHeaderScheme": "Token", "assetAccessCheck": true, "slowQueryThresholdMs": 2500, "maxConnsPerHost": 8}`)
+	ds.settings.DecryptedSecureJSONData = map[string]string{"apiKey": apiKey}
+
+	resp := callResourceAndCapture(t, ds, &backend.CallResourceRequest{Path: "config/effective", Method: http.MethodGet})
+	if resp.Status != http.StatusOK {
+		t.Fatalf("status = %d, want 200; body = %s", resp.Status, string(resp.Body))
+	}
+	if strings.Contains(string(resp.Body), apiKey) {
+		t.Fatalf("effective config leaks the API key: %s", resp.Body)
+	}
+
+	var got effectiveConfigResponse
+	if err := json.Unmarshal(resp.Body, &got); err != nil {
+		t.Fatalf("failed to parse response: %v", err)
+	}
+	want := effectiveConfigResponse{
+		BaseURL:          "https://api.test.com/api",
+		BaseURLSource:    "baseUrl",
+		ProxyPathPrefix:  "gw",
+		AuthHeaderName:   "Authorization",
+		AuthHeaderScheme: "Token",
+		APIKeySet:        true,
+		Timeouts: effectiveConfigTimeouts{
+			ResourceRequestMs:    resourceRequestTimeout.Milliseconds(),
+			HealthCheckMs:        healthCheckTimeout.Milliseconds(),
+			SlowQueryThresholdMs: 2500,
+			AssetCacheTTLMs:      assetCacheTTL.Milliseconds(),
+		},
+		Features:            effectiveConfigFeatures{AssetAccessCheck: true},
+		ConnectionPool:      effectiveConnectionLimits{MaxConnsPerHost: 8},
+		MaxExpandedQueries:  defaultMaxExpandedQueries,
+		MaxBuckets:          defaultMaxBuckets,
+		MaxAssetSearchPages: defaultMaxAssetSearchPages,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("effective config = %+v\nwant %+v", got, want)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
 
 	"github.com/grafana/grafana-plugin-sdk-go/backend"
@@ -90,6 +91,8 @@ func (m *mockAuthService) ResetMyCoachmarkDismissal(ctx context.Context, authHea
 
 // mockDatasourceService implements datasourceservice.DataSourceServiceClient for testing
 type mockDatasourceService struct {
+	// mu guards the request and call-count fields for concurrent lookups.
+	mu                     sync.Mutex
 	searchChannelsResponse datasourceapi.SearchChannelsResponse
 	searchChannelsError    error
 	searchChannelsRequest  datasourceapi.SearchChannelsRequest
@@ -97,11 +100,21 @@ type mockDatasourceService struct {
 	// searchChannelsFunc, when non-nil, overrides searchChannelsResponse/searchChannelsError.
 	// This allows tests to return different responses on successive calls (e.g. pagination).
 	searchChannelsFunc func(ctx context.Context, authHeader bearertoken.Token, req datasourceapi.SearchChannelsRequest) (datasourceapi.SearchChannelsResponse, error)
+
+	availableTagsResponse datasourceapi.GetAvailableTagsForChannelResponse
+	availableTagsRequest  datasourceapi.GetAvailableTagsForChannelRequest
+	availableTagsCalls    int
+
+	searchFilteredChannelsResponse datasourceapi.SearchFilteredChannelsResponse
+	searchFilteredChannelsRequest  datasourceapi.SearchFilteredChannelsRequest
+	searchFilteredChannelsCalls    int
 }
 
 func (m *mockDatasourceService) SearchChannels(ctx context.Context, authHeader bearertoken.Token, queryArg datasourceapi.SearchChannelsRequest) (datasourceapi.SearchChannelsResponse, error) {
+	m.mu.Lock()
 	m.searchChannelsCalls++
 	m.searchChannelsRequest = queryArg
+	m.mu.Unlock()
 	if m.searchChannelsFunc != nil {
 		return m.searchChannelsFunc(ctx, authHeader, queryArg)
 	}
@@ -109,7 +122,9 @@ func (m *mockDatasourceService) SearchChannels(ctx context.Context, authHeader b
 }
 
 func (m *mockDatasourceService) SearchFilteredChannels(ctx context.Context, authHeader bearertoken.Token, queryArg datasourceapi.SearchFilteredChannelsRequest) (datasourceapi.SearchFilteredChannelsResponse, error) {
-	return datasourceapi.SearchFilteredChannelsResponse{}, nil
+	m.searchFilteredChannelsRequest = queryArg
+	m.searchFilteredChannelsCalls++
+	return m.searchFilteredChannelsResponse, nil
 }
 
 func (m *mockDatasourceService) SearchHierarchicalChannels(ctx context.Context, authHeader bearertoken.Token, queryArg datasourceapi.SearchHierarchicalChannelsRequest) (datasourceapi.SearchHierarchicalChannelsResponse, error) {
@@ -125,7 +140,11 @@ func (m *mockDatasourceService) BatchGetChannelPrefixTrees(ctx context.Context,
 }
 
 func (m *mockDatasourceService) GetAvailableTagsForChannel(ctx context.Context, authHeader bearertoken.Token, requestArg datasourceapi.GetAvailableTagsForChannelRequest) (datasourceapi.GetAvailableTagsForChannelResponse, error) {
-	return datasourceapi.GetAvailableTagsForChannelResponse{}, nil
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.availableTagsCalls++
+	m.availableTagsRequest = requestArg
+	return m.availableTagsResponse, nil
 }
 
 func (m *mockDatasourceService) GetDataScopeBounds(ctx context.Context, authHeader bearertoken.Token, requestArg datasourceapi.BatchGetDataScopeBoundsRequest) (datasourceapi.BatchGetDataScopeBoundsResponse, error) {
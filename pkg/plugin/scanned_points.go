@@ -0,0 +1,42 @@
+package plugin
+
+import (
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+// scannedPointsMetaKey is the Meta.Custom key holding the number of raw points
+// the compute node summarized into a result, for cost-awareness dashboards.
+//
+// The compute API reports no cost or scanned-data figure of its own; the
+// per-bucket point counts of bucketed numeric responses are the closest
+// measure it carries. Responses without them (raw points, enums, Arrow
+// responses that did not request COUNT) get no key.
+const scannedPointsMetaKey = "scannedPoints"
+
+// bucketedScannedPoints sums the point counts of a bucketed numeric response.
+func bucketedScannedPoints(bucketed computeapi.BucketedNumericPlot) *int64 {
+	var total int64
+	for _, bucket := range bucketed.Buckets {
+		total += int64(bucket.Count)
+	}
+	return &total
+}
+
+// aggSeriesScannedPoints sums the COUNT series of an Arrow bucketed response,
+// or returns nil when the query did not request COUNT.
+func aggSeriesScannedPoints(series []AggregationSeries) *int64 {
+	countName := aggSpecs[AggCount].Name
+	for _, s := range series {
+		if s.Name != countName {
+			continue
+		}
+		var total int64
+		for _, v := range s.Values {
+			if v != nil {
+				total += int64(*v)
+			}
+		}
+		return &total
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package plugin
+
+import (
+	"testing"
+
+	"github.com/nominal-io/nominal-api-go/io/nominal/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestTransformBatchResultAttachesScannedPoints(t *testing.T) {
+	bucketed := computeapi.BucketedNumericPlot{
+		Timestamps: []api.Timestamp{testTimestamp(1704067200), testTimestamp(1704067260), testTimestamp(1704067320)},
+		Buckets: []computeapi.NumericBucket{
+			{Mean: 1.5, Count: 12},
+			{Mean: 2.5, Count: 0},
+			{Mean: 3.5, Count: 7},
+		},
+	}
+	res := newTestQueryExecution(&Datasource{}, nil).transformBatchResult(computeapi.ComputeWithUnitsResult{
+		ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromBucketedNumeric(bucketed)),
+	}, NominalQueryModel{Channel: "speed"})
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if len(res.Frames) != 1 || res.Frames[0].Meta == nil {
+		t.Fatalf("frames = %v, want one frame with metadata", res.Frames)
+	}
+	custom, _ := res.Frames[0].Meta.Custom.(map[string]any)
+	if got := custom[scannedPointsMetaKey]; got != int64(19) {
+		t.Errorf("Meta.Custom[%q] = %v (%T), want 19", scannedPointsMetaKey, got, got)
+	}
+}
+
+func TestTransformBatchResultOmitsScannedPointsForRawPoints(t *testing.T) {
+	res := newTestQueryExecution(&Datasource{}, nil).transformBatchResult(createMockComputeResult([]float64{1, 2, 3}), NominalQueryModel{Channel: "speed"})
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	for _, frame := range res.Frames {
+		if frame.Meta == nil {
+			continue
+		}
+		if custom, ok := frame.Meta.Custom.(map[string]any); ok {
+			if _, found := custom[scannedPointsMetaKey]; found {
+				t.Errorf("raw point frame has %q metadata, want none", scannedPointsMetaKey)
+			}
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package plugin
+
+import (
+	"fmt"
+	"time"
+)
+
+// validateStateTimeline checks the options that do not apply to a
+// stateTimeline query: it reshapes one enum series, so there is no log
+// channel, second resolution, or bucket edges to merge.
+func validateStateTimeline(qm NominalQueryModel) error {
+	if qm.QueryType != queryTypeStateTimeline {
+		return nil
+	}
+	if qm.ChannelDataType == ChannelDataTypeLog {
+		return fmt.Errorf("stateTimeline queries require an enum channel")
+	}
+	if qm.DualResolution || qm.IncludeBucketEdges {
+		return fmt.Errorf("stateTimeline queries cannot be combined with dualResolution or includeBucketEdges")
+	}
+	return nil
+}
+
+// mergeStateRuns collapses each run of consecutive identical states into one
+// point at the run's start, so every row marks a state change and the State
+// Timeline panel draws each state as one span lasting until the next row.
+func mergeStateRuns(times []time.Time, states []string) ([]time.Time, []string) {
+	n := min(len(times), len(states))
+	mergedTimes := make([]time.Time, 0, n)
+	mergedStates := make([]string, 0, n)
+	for i := 0; i < n; i++ {
+		if i > 0 && states[i] == states[i-1] {
+			continue
+		}
+		mergedTimes = append(mergedTimes, times[i])
+		mergedStates = append(mergedStates, states[i])
+	}
+	return mergedTimes, mergedStates
+}
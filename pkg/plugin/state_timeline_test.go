@@ -0,0 +1,51 @@
+package plugin
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTransformBatchResultStateTimelineMergesRuns(t *testing.T) {
+	exec := newTestQueryExecution(&Datasource{}, nil)
+	qm := NominalQueryModel{Channel: "mode", ChannelDataType: ChannelDataTypeString, QueryType: queryTypeStateTimeline}
+	// idle idle active active active idle, one sample a minute.
+	res := exec.transformBatchResult(createMockEnumComputeResult([]string{"idle", "active"}, []int{0, 0, 1, 1, 1, 0}), qm)
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+
+	frame := res.Frames[0]
+	wantStates := []string{"idle", "active", "idle"}
+	wantTimes := []time.Time{time.Unix(1704067200, 0), time.Unix(1704067200+2*60, 0), time.Unix(1704067200+5*60, 0)}
+	if rows, _ := frame.RowLen(); rows != len(wantStates) {
+		t.Fatalf("rows = %d, want %d merged spans", rows, len(wantStates))
+	}
+	for i := range wantStates {
+		if got := frame.Fields[1].At(i).(string); got != wantStates[i] {
+			t.Errorf("state[%d] = %q, want %q", i, got, wantStates[i])
+		}
+		if got := frame.Fields[0].At(i).(time.Time); !got.Equal(wantTimes[i]) {
+			t.Errorf("time[%d] = %v, want the span start %v", i, got, wantTimes[i])
+		}
+	}
+
+	qm.QueryType = ""
+	if rows, _ := exec.transformBatchResult(createMockEnumComputeResult([]string{"idle", "active"}, []int{0, 0, 1, 1, 1, 0}), qm).Frames[0].RowLen(); rows != 6 {
+		t.Errorf("rows = %d without stateTimeline, want every sample", rows)
+	}
+}
+
+func TestValidateStateTimeline(t *testing.T) {
+	for name, qm := range map[string]NominalQueryModel{
+		"log channel":    {QueryType: queryTypeStateTimeline, ChannelDataType: ChannelDataTypeLog},
+		"dualResolution": {QueryType: queryTypeStateTimeline, DualResolution: true},
+		"bucket edges":   {QueryType: queryTypeStateTimeline, IncludeBucketEdges: true},
+	} {
+		if err := validateStateTimeline(qm); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+	if err := validateStateTimeline(NominalQueryModel{QueryType: queryTypeStateTimeline, ChannelDataType: ChannelDataTypeString}); err != nil {
+		t.Errorf("enum stateTimeline query: unexpected error %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+	"github.com/palantir/pkg/bearertoken"
+)
+
+// validateTagSelector checks a TagSelector query: it names an asset's data
+// scope and tags instead of a channel, so it cannot also name one, and its
+// channels are only known after the search, so per-channel tag lookups do not
+// apply.
+func validateTagSelector(qm NominalQueryModel) error {
+	if len(qm.TagSelector) == 0 {
+		return nil
+	}
+	if qm.Channel != "" || qm.DataSourceRid != "" || strings.TrimSpace(qm.QueryText) != "" {
+		return fmt.Errorf("tagSelector cannot be combined with channel, dataSourceRid, or queryText")
+	}
+	if strings.TrimSpace(qm.AssetRid) == "" || strings.TrimSpace(qm.DataScopeName) == "" {
+		return fmt.Errorf("tagSelector queries require assetRid and dataScopeName")
+	}
+	if qm.IncludeChannelTags {
+		return fmt.Errorf("tagSelector cannot be combined with includeChannelTags")
+	}
+	for key, value := range qm.TagSelector {
+		if strings.TrimSpace(key) == "" || strings.TrimSpace(value) == "" {
+			return fmt.Errorf("tagSelector keys and values cannot be empty")
+		}
+	}
+	return nil
+}
+
+// expandTagSelector resolves a TagSelector query into one batchable query per
+// matching channel, all sharing the query's RefID so each channel's frames are
+// returned together. A selector matching no channels answers with no frames.
+func (e *NominalQueryExecution) expandTagSelector(ctx context.Context, prepared preparedQuery) ([]preparedQuery, *backend.DataResponse) {
+	qm := prepared.Model
+	fail := func(message string, err error) *backend.DataResponse {
+		logErrorWithConjureFields(message, err, "refId", prepared.Query.RefID, "assetRid", qm.AssetRid)
+		response := backend.ErrDataResponse(backend.StatusInternal, appendInstanceID(message, err))
+		return &response
+	}
+
+	catalog := e.datasource.catalog()
+	asset, err := catalog.FetchAssetByRid(ctx, e.config, qm.AssetRid)
+	if err != nil {
+		return nil, fail("Failed to fetch asset for tagSelector", err)
+	}
+	var channelMetadata []channelMetadataCacheEntry
+	var channelNames []string
+	if asset != nil {
+		channels, truncated, err := catalog.SearchChannelsByTags(ctx, bearertoken.Token(e.config.Secrets.ApiKey), catalog.DataSourceRidsForScope(asset, qm.DataScopeName), qm.TagSelector)
+		if err != nil {
+			return nil, fail("Failed to search channels for tagSelector", err)
+		}
+		if truncated {
+			// Like checkExpandedQueries: a partial answer would be easy to
+			// mistake for the full one.
+			log.DefaultLogger.Warn("tagSelector matches too many channels", "refId", prepared.Query.RefID, "limit", maxTagSelectorChannels)
+			response := backend.ErrDataResponse(backend.StatusBadRequest,
+				fmt.Sprintf("tagSelector matches more than %d channels; narrow the selector", maxTagSelectorChannels))
+			return nil, &response
+		}
+		for _, channel := range channels {
+			channelNames = append(channelNames, string(channel.Name))
+			channelMetadata = append(channelMetadata, channelMetadataCacheEntry{
+				channelDataType: getChannelDataType(channel),
+				unit:            getChannelUnit(channel),
+			})
+		}
+	}
+	log.DefaultLogger.Debug("Resolved tagSelector", "refId", prepared.Query.RefID, "channels", len(channelNames))
+	if len(channelNames) == 0 {
+		return nil, &backend.DataResponse{}
+	}
+
+	expanded := make([]preparedQuery, len(channelNames))
+	for i, name := range channelNames {
+		channelModel := qm
+		channelModel.Channel = name
+		applyChannelMetadata(&channelModel, channelMetadata[i])
+		if prepErr := resolveQueryModel(&channelModel); prepErr != nil {
+			return nil, prepErr
+		}
+		expanded[i] = preparedQuery{Query: prepared.Query, Model: channelModel, Kind: preparedQueryBatchable}
+	}
+	return expanded, nil
+}
+
+// tagSelectorFilters restricts a tag-selected channel to the selector's tags,
+// so a channel whose series are split by tag returns only the matching one.
+func tagSelectorFilters(selector map[string]string) *computeapi.TagFilters {
+	if len(selector) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(selector))
+	for key := range selector {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	filters := make([]computeapi.TagFilters, len(keys))
+	for i, key := range keys {
+		filters[i] = computeapi.NewTagFiltersFromSingle(computeapi.TagFilter{
+			Key:      computeapi.NewStringConstantFromLiteral(key),
+			Values:   []computeapi.StringConstant{computeapi.NewStringConstantFromLiteral(selector[key])},
+			Operator: computeapi.New_TagFilterOperator(computeapi.TagFilterOperator_IN),
+		})
+	}
+	combined := computeapi.NewTagFiltersFromAnd(filters)
+	return &combined
+}
@@ -0,0 +1,216 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+	"github.com/nominal-io/nominal-api-go/api/rids"
+	datasourceapi "github.com/nominal-io/nominal-api-go/datasource/api"
+	"github.com/nominal-io/nominal-api-go/io/nominal/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+	"github.com/palantir/pkg/rid"
+)
+
+func TestTagSelectorReturnsFramePerMatchedChannel(t *testing.T) {
+	const assetRid = "ri.scout.main.asset.1"
+	datasetRid := "ri.scout.main.data-source.ds1"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid: assetRid,
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "default", DataSource: AssetDataSource{Type: "dataset", Dataset: &datasetRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	dsRid := rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))
+	mockDS := &mockDatasourceService{
+		searchFilteredChannelsResponse: datasourceapi.SearchFilteredChannelsResponse{
+			Results: []datasourceapi.ChannelMetadata{
+				{Name: api.Channel("pump.pressure"), DataSource: dsRid},
+				{Name: api.Channel("pump.temperature"), DataSource: dsRid},
+				{Name: api.Channel("pump.pressure"), DataSource: dsRid}, // duplicate
+			},
+		},
+	}
+	mockCompute := &mockComputeService{
+		batchComputeResponse: computeapi.BatchComputeWithUnitsResponse{
+			Results: []computeapi.ComputeWithUnitsResult{createMockArrowComputeResult([]float64{1, 2}), createMockArrowComputeResult([]float64{3, 4})},
+		},
+	}
+	config := &models.PluginSettings{BaseUrl: server.URL, Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+	qe := newTestQueryExecution(&Datasource{computeService: mockCompute, datasourceService: mockDS, resourceHTTPClient: &http.Client{}}, config)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      assetRid,
+			DataScopeName: "default",
+			TagSelector:   map[string]string{"subsystem": "hydraulics"},
+		}),
+		TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+	}})
+
+	res := resp.Responses["A"]
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if len(res.Frames) != 2 || res.Frames[0].Name != "pump.pressure" || res.Frames[1].Name != "pump.temperature" {
+		names := make([]string, len(res.Frames))
+		for i, frame := range res.Frames {
+			names[i] = frame.Name
+		}
+		t.Fatalf("frames = %v, want one per matched channel", names)
+	}
+
+	if got := mockDS.searchFilteredChannelsRequest.Tags[dsRid]; got[api.TagName("subsystem")] != api.TagValue("hydraulics") {
+		t.Errorf("search tags = %v, want the selector on the scope's data source", mockDS.searchFilteredChannelsRequest.Tags)
+	}
+	if got := len(mockCompute.lastBatchRequest.Requests); got != 2 {
+		t.Errorf("batch subrequests = %d, want one per matched channel", got)
+	}
+}
+
+func TestTagSelectorDryRunReturnsExpandedRequestWithoutComputing(t *testing.T) {
+	const assetRid = "ri.scout.main.asset.1"
+	datasetRid := "ri.scout.main.data-source.ds1"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid: assetRid,
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "default", DataSource: AssetDataSource{Type: "dataset", Dataset: &datasetRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	dsRid := rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))
+	mockDS := &mockDatasourceService{
+		searchFilteredChannelsResponse: datasourceapi.SearchFilteredChannelsResponse{
+			Results: []datasourceapi.ChannelMetadata{
+				{Name: api.Channel("pump.pressure"), DataSource: dsRid},
+				{Name: api.Channel("pump.temperature"), DataSource: dsRid},
+			},
+		},
+	}
+	mockCompute := &mockComputeService{}
+	config := &models.PluginSettings{BaseUrl: server.URL, Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+	qe := newTestQueryExecution(&Datasource{computeService: mockCompute, datasourceService: mockDS, resourceHTTPClient: &http.Client{}}, config)
+
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	resp := qe.Execute(context.Background(), []backend.DataQuery{{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      assetRid,
+			DataScopeName: "default",
+			TagSelector:   map[string]string{"subsystem": "hydraulics"},
+			DryRun:        true,
+		}),
+		TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+	}})
+
+	if mockCompute.batchComputeCalls != 0 {
+		t.Fatalf("batch compute calls = %d, want 0 for a dry run", mockCompute.batchComputeCalls)
+	}
+	if got := dryRunSubrequests(t, resp.Responses["A"]); got != 2 {
+		t.Errorf("dry-run subrequests = %d, want one per matched channel", got)
+	}
+}
+
+func TestTagSelectorRejectsTruncatedAndInvalidMatches(t *testing.T) {
+	const assetRid = "ri.scout.main.asset.1"
+	datasetRid := "ri.scout.main.data-source.ds1"
+	server := newTestAssetServer(t, map[string]SingleAssetResponse{
+		assetRid: {
+			Rid: assetRid,
+			DataScopes: []AssetDataScope{
+				{DataScopeName: "default", DataSource: AssetDataSource{Type: "dataset", Dataset: &datasetRid}},
+			},
+		},
+	}, nil)
+	defer server.Close()
+
+	dsRid := rids.DataSourceRid(rid.MustNew("scout", "main", "data-source", "ds1"))
+	tooMany := make([]datasourceapi.ChannelMetadata, maxTagSelectorChannels+1)
+	for i := range tooMany {
+		tooMany[i] = datasourceapi.ChannelMetadata{Name: api.Channel(fmt.Sprintf("ch%d", i)), DataSource: dsRid}
+	}
+	stringType := api.New_SeriesDataType(api.SeriesDataType_STRING)
+	tests := []struct {
+		name    string
+		matches []datasourceapi.ChannelMetadata
+		count   bool
+		want    string
+	}{
+		{name: "more channels than the limit", matches: tooMany, want: "tagSelector matches more than 200 channels"},
+		{
+			name:    "count on a string channel",
+			matches: []datasourceapi.ChannelMetadata{{Name: api.Channel("pump.state"), DataSource: dsRid, DataType: &stringType}},
+			count:   true,
+			want:    `count queries require a numeric channel, but "pump.state" is string`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockDS := &mockDatasourceService{
+				searchFilteredChannelsResponse: datasourceapi.SearchFilteredChannelsResponse{Results: tt.matches},
+			}
+			mockCompute := &mockComputeService{}
+			config := &models.PluginSettings{BaseUrl: server.URL, Secrets: &models.SecretPluginSettings{ApiKey: "test-key"}}
+			qe := newTestQueryExecution(&Datasource{computeService: mockCompute, datasourceService: mockDS, resourceHTTPClient: &http.Client{}}, config)
+
+			qm := NominalQueryModel{AssetRid: assetRid, DataScopeName: "default", TagSelector: map[string]string{"subsystem": "hydraulics"}}
+			if tt.count {
+				qm.QueryType = queryTypeCount
+			}
+			from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+			resp := qe.Execute(context.Background(), []backend.DataQuery{{
+				RefID:     "A",
+				JSON:      mustMarshal(qm),
+				TimeRange: backend.TimeRange{From: from, To: from.Add(time.Hour)},
+			}})
+
+			res := resp.Responses["A"]
+			if res.Error == nil || res.Status != backend.StatusBadRequest || !strings.Contains(res.Error.Error(), tt.want) {
+				t.Fatalf("response = %d %v, want 400 containing %q", res.Status, res.Error, tt.want)
+			}
+			if mockCompute.batchComputeCalls != 0 {
+				t.Errorf("batch compute calls = %d, want 0", mockCompute.batchComputeCalls)
+			}
+			if size := mockDS.searchFilteredChannelsRequest.ResultSize; size == nil || *size != maxTagSelectorChannels+1 {
+				t.Errorf("search result size = %v, want one past the limit", size)
+			}
+		})
+	}
+}
+
+func TestValidateTagSelector(t *testing.T) {
+	base := NominalQueryModel{AssetRid: "ri.scout.main.asset.1", DataScopeName: "default", TagSelector: map[string]string{"subsystem": "hydraulics"}}
+	if err := validateTagSelector(base); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	withChannel := base
+	withChannel.Channel = "speed"
+	noScope := base
+	noScope.DataScopeName = ""
+	emptyValue := base
+	emptyValue.TagSelector = map[string]string{"subsystem": ""}
+	for name, qm := range map[string]NominalQueryModel{
+		"with channel": withChannel,
+		"no scope":     noScope,
+		"empty value":  emptyValue,
+	} {
+		if err := validateTagSelector(qm); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
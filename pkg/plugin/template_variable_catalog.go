@@ -2,14 +2,38 @@ package plugin
 
 import (
 	"context"
+	"slices"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/grafana/grafana-plugin-sdk-go/backend/log"
 	"github.com/nominal-inc/nominal-ds/pkg/models"
 	"github.com/palantir/pkg/bearertoken"
 )
 
 type TemplateVariableCatalog struct {
 	nominal *NominalCatalog
+
+	// channelVariablesCalls holds the in-flight ChannelVariables lookups, so
+	// panels loading together share one upstream call per asset and scope.
+	channelVariablesMu    sync.Mutex
+	channelVariablesCalls map[channelVariablesRequest]*channelVariablesCall
+}
+
+// channelVariablesLookupTimeout bounds a shared ChannelVariables lookup, which
+// runs detached from any one caller's context.
+const channelVariablesLookupTimeout = 60 * time.Second
+
+// channelVariablesCall is one in-flight ChannelVariables lookup; done is
+// closed once result, partial, and err are set. waiters counts the callers
+// that have joined it, under channelVariablesMu.
+type channelVariablesCall struct {
+	done    chan struct{}
+	result  []metricFindValue
+	partial bool
+	err     error
+	waiters int
 }
 
 func newTemplateVariableCatalog(nominal *NominalCatalog) *TemplateVariableCatalog {
@@ -22,10 +46,22 @@ type metricFindValue struct {
 }
 
 type assetsVariableRequest struct {
-	SearchText string `json:"searchText"`
-	MaxResults int    `json:"maxResults"`
+	SearchText      string   `json:"searchText"`
+	MaxResults      int      `json:"maxResults"`
+	Labels          []string `json:"labels,omitempty"`
+	IncludeArchived bool     `json:"includeArchived,omitempty"`
+}
+
+type recentAssetsRequest struct {
+	MaxResults int `json:"maxResults"`
 }
 
+// Result bounds for the recentassets endpoint.
+const (
+	defaultRecentAssets = 10
+	maxRecentAssets     = 50
+)
+
 type datascopesVariableRequest struct {
 	AssetRid string `json:"assetRid"`
 }
@@ -33,8 +69,52 @@ type datascopesVariableRequest struct {
 type channelVariablesRequest struct {
 	AssetRid      string `json:"assetRid"`
 	DataScopeName string `json:"dataScopeName"`
+	// TimeBudgetMs, when positive, is a soft limit on the channel search:
+	// once spent, the channels gathered so far are returned as partial.
+	TimeBudgetMs int `json:"timeBudgetMs,omitempty"`
+	// CaseInsensitive dedups channel names ignoring case and surrounding
+	// whitespace, so "Temp" and "temp " list once. The first-seen name is
+	// kept, unchanged, as both text and value so queries still match it.
+	CaseInsensitive bool `json:"caseInsensitive,omitempty"`
 }
 
+// channelVariablesResponse is the channelvariables response for requests that
+// set TimeBudgetMs, so callers can tell a truncated listing from a full one.
+type channelVariablesResponse struct {
+	Channels []metricFindValue `json:"channels"`
+	Partial  bool              `json:"partial"`
+}
+
+// maxChannelsExistChannels caps the channels one channels/exists request can
+// check, each of which is its own channel search.
+const maxChannelsExistChannels = 100
+
+type channelsExistRequest struct {
+	AssetRid      string   `json:"assetRid"`
+	DataScopeName string   `json:"dataScopeName"`
+	Channels      []string `json:"channels"`
+}
+
+type assetDescribeRequest struct {
+	AssetRid string `json:"assetRid"`
+}
+
+// assetDescribeResponse is the assets/describe payload: what the editor shows
+// when an asset is picked, in one response.
+type assetDescribeResponse struct {
+	Rid         string `json:"rid"`
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	// DataScopes lists the asset's queryable data scope names.
+	DataScopes []string `json:"dataScopes"`
+	// Channels is a preview of up to assetDescribeChannelSample channels
+	// across those scopes, not the full listing.
+	Channels []channelSearchResult `json:"channels"`
+}
+
+// assetDescribeChannelSample caps the channel preview in assets/describe.
+const assetDescribeChannelSample = 20
+
 type templateVariableCatalogErrorKind int
 
 const (
@@ -69,7 +149,10 @@ func (c *TemplateVariableCatalog) Assets(ctx context.Context, config *models.Plu
 		req.MaxResults = 500
 	}
 
-	assetResponses, err := c.nominal.FetchAssetsForVariable(ctx, config, req.SearchText, req.MaxResults)
+	assetResponses, err := c.nominal.FetchAssetsForVariable(ctx, config, req.SearchText, assetSearchFilters{
+		Labels:          req.Labels,
+		IncludeArchived: req.IncludeArchived,
+	}, req.MaxResults)
 	if err != nil {
 		return nil, err
 	}
@@ -92,6 +175,35 @@ outer:
 	return result, nil
 }
 
+// RecentAssets returns the newest queryable assets, newest first, for the query
+// editor's default asset list. The API exposes neither per-user access history nor
+// an updated-at sort, so creation time is the recency signal.
+func (c *TemplateVariableCatalog) RecentAssets(ctx context.Context, config *models.PluginSettings, req recentAssetsRequest) ([]metricFindValue, error) {
+	if req.MaxResults <= 0 {
+		req.MaxResults = defaultRecentAssets
+	}
+	req.MaxResults = min(req.MaxResults, maxRecentAssets)
+
+	assetResponses, err := c.nominal.FetchAssetsForVariable(ctx, config, "", assetSearchFilters{NewestFirst: true}, req.MaxResults)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]metricFindValue, 0, req.MaxResults)
+	for _, resp := range assetResponses {
+		for _, asset := range resp.Results {
+			if !c.nominal.HasSupportedDataSource(asset) {
+				continue
+			}
+			result = append(result, metricFindValue{Text: asset.Title, Value: asset.Rid})
+			if len(result) >= req.MaxResults {
+				return result, nil
+			}
+		}
+	}
+	return result, nil
+}
+
 // assetForVariable fetches an asset by RID for a template-variable lookup,
 // wrapping any fetch failure as a templateVariableCatalogError. A nil asset
 // with a nil error means the asset was not found; callers treat that as an
@@ -129,42 +241,174 @@ func (c *TemplateVariableCatalog) Datascopes(ctx context.Context, config *models
 	return result, nil
 }
 
-func (c *TemplateVariableCatalog) ChannelVariables(ctx context.Context, config *models.PluginSettings, req channelVariablesRequest) ([]metricFindValue, error) {
+// ChannelVariables lists the channel names of an asset's data scope; partial
+// reports that req.TimeBudgetMs ran out before the listing was complete.
+// Concurrent identical lookups are coalesced: one upstream lookup runs, detached
+// from every caller's context and bounded by channelVariablesLookupTimeout, and
+// each caller waits for its result until the caller's own context ends. A
+// cancelled caller therefore never fails the others.
+func (c *TemplateVariableCatalog) ChannelVariables(ctx context.Context, config *models.PluginSettings, req channelVariablesRequest) ([]metricFindValue, bool, error) {
+	c.channelVariablesMu.Lock()
+	call, ok := c.channelVariablesCalls[req]
+	if !ok {
+		call = &channelVariablesCall{done: make(chan struct{})}
+		if c.channelVariablesCalls == nil {
+			c.channelVariablesCalls = make(map[channelVariablesRequest]*channelVariablesCall)
+		}
+		c.channelVariablesCalls[req] = call
+		go c.runChannelVariablesCall(context.WithoutCancel(ctx), config, req, call)
+	}
+	call.waiters++
+	c.channelVariablesMu.Unlock()
+
+	select {
+	case <-call.done:
+		return slices.Clone(call.result), call.partial, call.err
+	case <-ctx.Done():
+		return nil, false, &templateVariableCatalogError{kind: templateVariableChannelSearchError, err: ctx.Err()}
+	}
+}
+
+// runChannelVariablesCall performs the shared lookup for call, then removes it
+// from the in-flight set and releases its waiters.
+func (c *TemplateVariableCatalog) runChannelVariablesCall(ctx context.Context, config *models.PluginSettings, req channelVariablesRequest, call *channelVariablesCall) {
+	ctx, cancel := context.WithTimeout(ctx, channelVariablesLookupTimeout)
+	defer cancel()
+	defer func() {
+		c.channelVariablesMu.Lock()
+		delete(c.channelVariablesCalls, req)
+		waiters := call.waiters
+		c.channelVariablesMu.Unlock()
+		close(call.done)
+		if waiters > 1 {
+			log.DefaultLogger.Debug("Coalesced channel variable lookups", "assetRid", req.AssetRid, "dataScopeName", req.DataScopeName, "callers", waiters)
+		}
+	}()
+	call.result, call.partial, call.err = c.channelVariables(ctx, config, req)
+}
+
+func (c *TemplateVariableCatalog) channelVariables(ctx context.Context, config *models.PluginSettings, req channelVariablesRequest) ([]metricFindValue, bool, error) {
 	if hasUnresolvedTemplateVariable(req.AssetRid, req.DataScopeName) {
-		return []metricFindValue{}, nil
+		return []metricFindValue{}, false, nil
 	}
 
 	asset, err := c.assetForVariable(ctx, config, req.AssetRid)
 	if err != nil {
-		return nil, err
+		return nil, false, err
 	}
 	if asset == nil {
-		return []metricFindValue{}, nil
+		return []metricFindValue{}, false, nil
 	}
 
 	dataSourceRids := c.nominal.DataSourceRidsForScope(asset, req.DataScopeName)
 	if len(dataSourceRids) == 0 {
-		return []metricFindValue{}, nil
+		return []metricFindValue{}, false, nil
 	}
 
 	bearerToken := bearertoken.Token(config.Secrets.ApiKey)
-	allChannelResults, err := c.nominal.SearchChannelsForVariables(ctx, bearerToken, dataSourceRids)
+	budget := time.Duration(req.TimeBudgetMs) * time.Millisecond
+	allChannelResults, partial, err := c.nominal.SearchChannelsForVariablesWithin(ctx, bearerToken, dataSourceRids, budget)
 	if err != nil {
-		return nil, &templateVariableCatalogError{kind: templateVariableChannelSearchError, err: err}
+		return nil, false, &templateVariableCatalogError{kind: templateVariableChannelSearchError, err: err}
 	}
 
 	seen := make(map[string]bool)
 	result := make([]metricFindValue, 0)
 	for _, channel := range allChannelResults {
 		name := string(channel.Name)
-		if !seen[name] {
-			seen[name] = true
+		key := name
+		if req.CaseInsensitive {
+			key = strings.ToLower(strings.TrimSpace(name))
+		}
+		if !seen[key] {
+			seen[key] = true
 			result = append(result, metricFindValue{
 				Text:  name,
 				Value: name,
 			})
 		}
 	}
+	return result, partial, nil
+}
+
+// ChannelsExist reports, for each requested channel name, whether the asset has
+// a channel of that exact name in the data scope (every scope when
+// DataScopeName is empty). Each name is looked up on its own (see
+// NominalCatalog.ChannelsWithExactNames), so the asset's channels are never
+// listed. A missing asset or unresolved template variable reports every
+// channel absent.
+func (c *TemplateVariableCatalog) ChannelsExist(ctx context.Context, config *models.PluginSettings, req channelsExistRequest) (map[string]bool, error) {
+	result := make(map[string]bool, len(req.Channels))
+	for _, channel := range req.Channels {
+		result[channel] = false
+	}
+	if len(req.Channels) == 0 || hasUnresolvedTemplateVariable(req.AssetRid, req.DataScopeName) {
+		return result, nil
+	}
+
+	asset, err := c.assetForVariable(ctx, config, req.AssetRid)
+	if err != nil {
+		return nil, err
+	}
+	if asset == nil {
+		return result, nil
+	}
+
+	dataSourceRids := c.nominal.DataSourceRidsForScope(asset, req.DataScopeName)
+	if len(dataSourceRids) == 0 {
+		return result, nil
+	}
+
+	found, err := c.nominal.ChannelsWithExactNames(ctx, bearertoken.Token(config.Secrets.ApiKey), dataSourceRids, req.Channels)
+	if err != nil {
+		return nil, &templateVariableCatalogError{kind: templateVariableChannelSearchError, err: err}
+	}
+	return found, nil
+}
+
+// DescribeAsset returns the asset's title, description, queryable data scopes,
+// and a sample of its channels. It returns nil when the asset is not found or
+// the RID holds an unresolved template variable.
+func (c *TemplateVariableCatalog) DescribeAsset(ctx context.Context, config *models.PluginSettings, req assetDescribeRequest) (*assetDescribeResponse, error) {
+	if hasUnresolvedTemplateVariable(req.AssetRid) {
+		return nil, nil
+	}
+
+	asset, err := c.assetForVariable(ctx, config, req.AssetRid)
+	if err != nil {
+		return nil, err
+	}
+	if asset == nil {
+		return nil, nil
+	}
+
+	result := &assetDescribeResponse{
+		Rid:         asset.Rid,
+		Title:       asset.Title,
+		Description: asset.Description,
+		DataScopes:  make([]string, 0, len(asset.DataScopes)),
+		Channels:    make([]channelSearchResult, 0),
+	}
+	for _, scope := range asset.DataScopes {
+		if isSupportedDataSourceType(scope.DataSource.Type) {
+			result.DataScopes = append(result.DataScopes, scope.DataScopeName)
+		}
+	}
+
+	bearerToken := bearertoken.Token(config.Secrets.ApiKey)
+	channels, err := c.nominal.SampleChannels(ctx, bearerToken, c.nominal.DataSourceRidsForScope(asset, ""), assetDescribeChannelSample)
+	if err != nil {
+		return nil, &templateVariableCatalogError{kind: templateVariableChannelSearchError, err: err}
+	}
+	for _, channel := range channels {
+		result.Channels = append(result.Channels, channelSearchResult{
+			Name:        string(channel.Name),
+			DataSource:  channel.DataSource.String(),
+			Description: getChannelMetadataDescription(channel),
+			DataType:    getChannelDataType(channel),
+			Unit:        getChannelUnit(channel),
+		})
+	}
 	return result, nil
 }
 
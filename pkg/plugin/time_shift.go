@@ -0,0 +1,105 @@
+package plugin
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// calendarShiftPattern matches calendar time-shift expressions: a positive count
+// followed by d (days), w (weeks), M (months), or y (years). Lower-case m stays a
+// Go duration minute.
+var calendarShiftPattern = regexp.MustCompile(`^(\d+)([dwMy])$`)
+
+// resolveTimeShift converts a TimeShift expression into the fixed duration to shift
+// data by for a query whose range starts at from.
+//
+// Go durations ("90m", "1h30m") and ISO 8601 durations ("PT90M", "P1D"; see
+// parseISO8601Duration) are fixed. Calendar expressions ("1d", "1w", "1M",
+// "1y") step back by calendar units from from in timeZone (an IANA name; empty
+// means UTC), so "1d" across a DST change resolves to 23h or 25h. The duration is
+// resolved once at the start of the range and applied to the whole series.
+func resolveTimeShift(expr, timeZone string, from time.Time) (time.Duration, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return 0, nil
+	}
+
+	if m := calendarShiftPattern.FindStringSubmatch(expr); m != nil {
+		n, err := strconv.Atoi(m[1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid timeShift %q: %w", expr, err)
+		}
+		loc, err := loadTimeShiftLocation(timeZone)
+		if err != nil {
+			return 0, err
+		}
+		local := from.In(loc)
+		var earlier time.Time
+		switch m[2] {
+		case "d":
+			earlier = local.AddDate(0, 0, -n)
+		case "w":
+			earlier = local.AddDate(0, 0, -7*n)
+		case "M":
+			earlier = local.AddDate(0, -n, 0)
+		case "y":
+			earlier = local.AddDate(-n, 0, 0)
+		}
+		return local.Sub(earlier), nil
+	}
+
+	if isISO8601Duration(expr) {
+		d, err := parseISO8601Duration(expr)
+		if err != nil {
+			return 0, fmt.Errorf("invalid timeShift: %w", err)
+		}
+		return d, nil
+	}
+
+	d, err := time.ParseDuration(expr)
+	if err != nil {
+		return 0, fmt.Errorf("invalid timeShift %q: use a duration like \"90m\" or \"PT90M\", or a calendar shift like \"1d\", \"1w\", \"1M\", \"1y\"", expr)
+	}
+	if d < 0 {
+		return 0, fmt.Errorf("invalid timeShift %q: shift must not be negative", expr)
+	}
+	return d, nil
+}
+
+// applyDefaultTimeShift sets the datasource's DefaultTimeShift on a query that
+// leaves TimeShift empty. Gaps queries are skipped because they reject a time
+// shift.
+func (e *NominalQueryExecution) applyDefaultTimeShift(qm *NominalQueryModel) {
+	if e.config == nil || e.config.DefaultTimeShift == "" || strings.TrimSpace(qm.TimeShift) != "" || qm.QueryType == queryTypeGaps {
+		return
+	}
+	qm.TimeShift = e.config.DefaultTimeShift
+}
+
+func loadTimeShiftLocation(timeZone string) (*time.Location, error) {
+	if timeZone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timeZone %q: %w", timeZone, err)
+	}
+	return loc, nil
+}
+
+// timeZoneAlignmentNotice is the frame notice for a query that sets TimeZone
+// without a calendar TimeShift. The compute API has no time-zone parameter:
+// buckets split the query range evenly, so they cannot be aligned to local
+// days or hours and TimeZone only affects calendar shifts.
+func timeZoneAlignmentNotice(timeZone string) data.Notice {
+	return data.Notice{
+		Severity: data.NoticeSeverityInfo,
+		Text: fmt.Sprintf("timeZone %q only applies to calendar time shifts; Nominal does not support aligning buckets to a time zone, so bucket boundaries follow the query range.",
+			timeZone),
+	}
+}
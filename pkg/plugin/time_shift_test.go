@@ -0,0 +1,207 @@
+package plugin
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/grafana/grafana-plugin-sdk-go/backend"
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+)
+
+func TestResolveTimeShiftFixedDurations(t *testing.T) {
+	from := time.Date(2024, 3, 11, 4, 0, 0, 0, time.UTC)
+	tests := []struct {
+		expr string
+		want time.Duration
+	}{
+		{expr: "", want: 0},
+		{expr: "90m", want: 90 * time.Minute},
+		{expr: "1h30m", want: 90 * time.Minute},
+		{expr: " 24h ", want: 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		got, err := resolveTimeShift(tt.expr, "America/New_York", from)
+		if err != nil {
+			t.Fatalf("resolveTimeShift(%q) error: %v", tt.expr, err)
+		}
+		if got != tt.want {
+			t.Errorf("resolveTimeShift(%q) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestResolveTimeShiftCalendarUnits(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+	// Local midnight the day after the 2024-03-10 spring-forward change.
+	afterDST := time.Date(2024, 3, 11, 0, 0, 0, 0, newYork)
+
+	tests := []struct {
+		name     string
+		expr     string
+		timeZone string
+		from     time.Time
+		want     time.Duration
+	}{
+		{name: "day across DST is 23h", expr: "1d", timeZone: "America/New_York", from: afterDST, want: 23 * time.Hour},
+		{name: "day in UTC is 24h", expr: "1d", timeZone: "", from: afterDST, want: 24 * time.Hour},
+		{name: "week across DST", expr: "1w", timeZone: "America/New_York", from: afterDST, want: 7*24*time.Hour - time.Hour},
+		{name: "month", expr: "1M", timeZone: "", from: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), want: 29 * 24 * time.Hour},
+		{name: "year", expr: "1y", timeZone: "", from: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC), want: 366 * 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveTimeShift(tt.expr, tt.timeZone, tt.from)
+			if err != nil {
+				t.Fatalf("resolveTimeShift error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("resolveTimeShift(%q) = %v, want %v", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveTimeShiftRejectsInvalidInput(t *testing.T) {
+	from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for _, tc := range []struct{ expr, timeZone, wantErr string }{
+		{expr: "yesterday", wantErr: "invalid timeShift"},
+		{expr: "-1h", wantErr: "must not be negative"},
+		{expr: "1d", timeZone: "Mars/Olympus_Mons", wantErr: "invalid timeZone"},
+	} {
+		_, err := resolveTimeShift(tc.expr, tc.timeZone, from)
+		if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("resolveTimeShift(%q, %q) error = %v, want %q", tc.expr, tc.timeZone, err, tc.wantErr)
+		}
+	}
+}
+
+func TestTimeShiftIsAppliedToSeriesPlan(t *testing.T) {
+	qe := newTestQueryExecution(&Datasource{}, nil)
+	prepared, errResp := qe.prepareQuery(context.Background(), backend.DataQuery{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      "ri.nominal.asset.1",
+			Channel:       "temperature",
+			DataScopeName: "default",
+			TimeShift:     "1d",
+		}),
+		TimeRange: backend.TimeRange{
+			From: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC),
+			To:   time.Date(2024, 1, 2, 1, 0, 0, 0, time.UTC),
+		},
+	})
+	if errResp != nil {
+		t.Fatalf("prepareQuery error: %v", errResp.Error)
+	}
+
+	if prepared.Model.TimeShiftDuration != 24*time.Hour {
+		t.Fatalf("TimeShiftDuration = %v, want 24h", prepared.Model.TimeShiftDuration)
+	}
+	planJSON, err := json.Marshal(qe.buildSeriesPlan(prepared.Model, 0))
+	if err != nil {
+		t.Fatalf("marshal plan: %v", err)
+	}
+	if !strings.Contains(string(planJSON), `"seconds":86400`) {
+		t.Errorf("series plan does not carry the 1d shift: %s", planJSON)
+	}
+}
+
+func TestDefaultTimeShiftAppliesWhenQueryOmitsIt(t *testing.T) {
+	config := &models.PluginSettings{
+		Secrets:          &models.SecretPluginSettings{ApiKey: "test-key"},
+		DefaultTimeShift: "90m",
+	}
+	qe := newTestQueryExecution(&Datasource{}, config)
+	timeRange := backend.TimeRange{From: time.Unix(1704067200, 0), To: time.Unix(1704070800, 0)}
+	prepare := func(qm NominalQueryModel) NominalQueryModel {
+		t.Helper()
+		prepared, errResp := qe.prepareQuery(context.Background(), backend.DataQuery{RefID: "A", JSON: mustMarshal(qm), TimeRange: timeRange})
+		if errResp != nil {
+			t.Fatalf("prepareQuery error: %v", errResp.Error)
+		}
+		return prepared.Model
+	}
+	base := NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temperature", DataScopeName: "default"}
+
+	model := prepare(base)
+	if model.TimeShiftDuration != 90*time.Minute {
+		t.Fatalf("TimeShiftDuration = %v, want the 90m default", model.TimeShiftDuration)
+	}
+	requestJSON, err := json.Marshal(qe.buildComputeRequest(model, timeRange, 0))
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	if !strings.Contains(string(requestJSON), `"seconds":5400`) {
+		t.Errorf("compute request does not carry the default shift: %s", requestJSON)
+	}
+
+	explicit := base
+	explicit.TimeShift = "1h"
+	if got := prepare(explicit).TimeShiftDuration; got != time.Hour {
+		t.Errorf("TimeShiftDuration = %v, want the query's own 1h", got)
+	}
+
+	gaps := base
+	gaps.QueryType = queryTypeGaps
+	if got := prepare(gaps).TimeShiftDuration; got != 0 {
+		t.Errorf("gaps TimeShiftDuration = %v, want no default shift", got)
+	}
+}
+
+func TestPrepareQueryRejectsInvalidTimeShift(t *testing.T) {
+	qe := newTestQueryExecution(&Datasource{}, nil)
+	_, errResp := qe.prepareQuery(context.Background(), backend.DataQuery{
+		RefID: "A",
+		JSON: mustMarshal(NominalQueryModel{
+			AssetRid:      "ri.nominal.asset.1",
+			Channel:       "temperature",
+			DataScopeName: "default",
+			TimeShift:     "last tuesday",
+		}),
+	})
+	if errResp == nil || errResp.Status != backend.StatusBadRequest {
+		t.Fatalf("expected bad request for invalid timeShift, got %+v", errResp)
+	}
+}
+
+func TestTimeZoneWithoutShiftLeavesRequestUnchangedWithNotice(t *testing.T) {
+	qe := newTestQueryExecution(&Datasource{}, nil)
+	timeRange := backend.TimeRange{From: time.Unix(1704067200, 0), To: time.Unix(1704153600, 0)}
+	utc := NominalQueryModel{AssetRid: "ri.nominal.asset.1", Channel: "temperature", DataScopeName: "default", Buckets: 24}
+	local := utc
+	local.TimeZone = "America/New_York"
+
+	// The compute API has no time-zone parameter, so the zone cannot reach
+	// the request and bucket boundaries stay on the query range.
+	utcJSON, err := json.Marshal(qe.buildComputeRequest(utc, timeRange, 0))
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	localJSON, err := json.Marshal(qe.buildComputeRequest(local, timeRange, 0))
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	if string(utcJSON) != string(localJSON) {
+		t.Errorf("timeZone changed the compute request:\n%s\nvs\n%s", utcJSON, localJSON)
+	}
+
+	resp := qe.transformBatchResult(createMockComputeResult([]float64{1, 2}), local)
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	meta := resp.Frames[0].Meta
+	if meta == nil || len(meta.Notices) != 1 || !strings.Contains(meta.Notices[0].Text, "America/New_York") {
+		t.Errorf("expected a time-zone alignment notice, got %+v", meta)
+	}
+
+	local.TimeShift = "1d"
+	if meta := qe.transformBatchResult(createMockComputeResult([]float64{1, 2}), local).Frames[0].Meta; meta != nil && len(meta.Notices) > 0 {
+		t.Errorf("calendar time shift should not get the alignment notice, got %+v", meta.Notices)
+	}
+}
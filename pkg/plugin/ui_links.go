@@ -0,0 +1,66 @@
+package plugin
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// nominalUIChannelURL returns the Nominal web app URL for qm's asset channel:
+// channelPath under uiBaseURL with its {assetRid}, {channel}, and {dataScope}
+// placeholders filled in. The route is configured rather than assumed, since
+// the web app's is not part of the API. It returns "" when either is unset or
+// the query is not asset-scoped.
+func nominalUIChannelURL(uiBaseURL, channelPath string, qm NominalQueryModel) string {
+	uiBaseURL = strings.TrimSuffix(strings.TrimSpace(uiBaseURL), "/")
+	channelPath = strings.TrimSpace(channelPath)
+	if uiBaseURL == "" || channelPath == "" || qm.AssetRid == "" || qm.Channel == "" {
+		return ""
+	}
+	if !strings.HasPrefix(channelPath, "/") {
+		channelPath = "/" + channelPath
+	}
+	// Grafana variables like ${__from} are left unescaped for Grafana to
+	// interpolate; only the placeholders' values are escaped.
+	return uiBaseURL + strings.NewReplacer(
+		"{assetRid}", uiLinkEscape(qm.AssetRid),
+		"{channel}", uiLinkEscape(qm.Channel),
+		"{dataScope}", uiLinkEscape(qm.DataScopeName),
+	).Replace(channelPath)
+}
+
+// uiLinkEscape escapes value for either a path segment or a query parameter.
+func uiLinkEscape(value string) string {
+	return strings.ReplaceAll(url.QueryEscape(value), "+", "%20")
+}
+
+// attachNominalUILinks adds an "Open in Nominal" data link to every non-time
+// field of frames. Log frames are left alone.
+func (e *NominalQueryExecution) attachNominalUILinks(frames data.Frames, qm NominalQueryModel) {
+	if e.config == nil {
+		return
+	}
+	link := nominalUIChannelURL(e.config.UIBaseUrl, e.config.UIChannelPath, qm)
+	if link == "" {
+		return
+	}
+	for _, frame := range frames {
+		if frame.Meta != nil && frame.Meta.Type == data.FrameTypeLogLines {
+			continue
+		}
+		for _, field := range frame.Fields {
+			if fieldType := field.Type(); fieldType == data.FieldTypeTime || fieldType == data.FieldTypeNullableTime {
+				continue
+			}
+			if field.Config == nil {
+				field.Config = &data.FieldConfig{}
+			}
+			field.Config.Links = append(field.Config.Links, data.DataLink{
+				Title:       "Open in Nominal",
+				URL:         link,
+				TargetBlank: true,
+			})
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nominal-inc/nominal-ds/pkg/models"
+)
+
+func TestTransformBatchResultAddsNominalUILink(t *testing.T) {
+	config := &models.PluginSettings{
+		UIBaseUrl:     "https://app.nominal.test/",
+		UIChannelPath: "/assets/{assetRid}?channel={channel}&scope={dataScope}&start=${__from}&end=${__to}",
+		Secrets:       &models.SecretPluginSettings{ApiKey: "test-key"},
+	}
+	exec := newTestQueryExecution(&Datasource{}, config)
+	qm := NominalQueryModel{AssetRid: "ri.scout.main.asset.1", Channel: "engine temp", DataScopeName: "default"}
+
+	resp := exec.transformBatchResult(createMockComputeResult([]float64{1, 2}), qm)
+	if resp.Error != nil {
+		t.Fatalf("unexpected error: %v", resp.Error)
+	}
+	valueField, idx := resp.Frames[0].FieldByName("value")
+	if idx < 0 {
+		t.Fatal("expected a value field")
+	}
+	if len(valueField.Config.Links) != 1 {
+		t.Fatalf("links = %+v, want one", valueField.Config.Links)
+	}
+	link := valueField.Config.Links[0].URL
+	if !strings.HasPrefix(link, "https://app.nominal.test/assets/ri.scout.main.asset.1?") {
+		t.Errorf("link = %q, want the configured route under the UI base URL", link)
+	}
+	if !strings.Contains(link, "channel=engine%20temp&scope=default") {
+		t.Errorf("link = %q, want encoded channel", link)
+	}
+	if !strings.HasSuffix(link, "&start=${__from}&end=${__to}") {
+		t.Errorf("link = %q, want templated time range", link)
+	}
+	if timeField, _ := resp.Frames[0].FieldByName("time"); timeField.Config != nil && len(timeField.Config.Links) > 0 {
+		t.Errorf("time field has links %+v, want none", timeField.Config.Links)
+	}
+}
+
+func TestNominalUIChannelURLRequiresBaseURLRouteAndAsset(t *testing.T) {
+	const channelPath = "/assets/{assetRid}?channel={channel}"
+	qm := NominalQueryModel{AssetRid: "ri.scout.main.asset.1", Channel: "temp"}
+	if got := nominalUIChannelURL("", channelPath, qm); got != "" {
+		t.Errorf("no base URL: got %q, want empty", got)
+	}
+	if got := nominalUIChannelURL("https://app.nominal.test", "", qm); got != "" {
+		t.Errorf("no route: got %q, want empty", got)
+	}
+	if got := nominalUIChannelURL("https://app.nominal.test", channelPath, NominalQueryModel{DataSourceRid: "ri.ds.1", Channel: "temp"}); got != "" {
+		t.Errorf("dataSourceRid query: got %q, want empty", got)
+	}
+}
@@ -0,0 +1,63 @@
+package plugin
+
+import (
+	"fmt"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// ValuePrecision values select the float width of numeric value fields. An
+// empty ValuePrecision behaves like ValuePrecisionFloat64.
+const (
+	ValuePrecisionFloat32 = "float32"
+	ValuePrecisionFloat64 = "float64"
+)
+
+// validateValuePrecision returns an error for an unrecognised value precision.
+func validateValuePrecision(precision string) error {
+	switch precision {
+	case "", ValuePrecisionFloat32, ValuePrecisionFloat64:
+		return nil
+	}
+	return fmt.Errorf("unsupported valuePrecision %q; valid options are float32, float64", precision)
+}
+
+// applyValuePrecision narrows every float64 field in frames to float32 when
+// precision is ValuePrecisionFloat32. Time and string fields are unchanged.
+func applyValuePrecision(frames data.Frames, precision string) {
+	if precision != ValuePrecisionFloat32 {
+		return
+	}
+	for _, frame := range frames {
+		for i, field := range frame.Fields {
+			frame.Fields[i] = float32Field(field)
+		}
+	}
+}
+
+// float32Field returns a float32 copy of a float64 or nullable float64 field,
+// keeping its name, labels, and config, or field itself for any other type.
+func float32Field(field *data.Field) *data.Field {
+	var narrowed *data.Field
+	switch field.Type() {
+	case data.FieldTypeFloat64:
+		values := make([]float32, field.Len())
+		for i := range values {
+			values[i] = float32(field.At(i).(float64))
+		}
+		narrowed = data.NewField(field.Name, field.Labels, values)
+	case data.FieldTypeNullableFloat64:
+		values := make([]*float32, field.Len())
+		for i := range values {
+			if v := field.At(i).(*float64); v != nil {
+				value := float32(*v)
+				values[i] = &value
+			}
+		}
+		narrowed = data.NewField(field.Name, field.Labels, values)
+	default:
+		return field
+	}
+	narrowed.Config = field.Config
+	return narrowed
+}
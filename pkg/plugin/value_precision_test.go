@@ -0,0 +1,65 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestTransformBatchResultAppliesValuePrecision(t *testing.T) {
+	tests := []struct {
+		precision string
+		wantType  data.FieldType
+	}{
+		{precision: "", wantType: data.FieldTypeNullableFloat64},
+		{precision: ValuePrecisionFloat64, wantType: data.FieldTypeNullableFloat64},
+		{precision: ValuePrecisionFloat32, wantType: data.FieldTypeNullableFloat32},
+	}
+	exec := newTestQueryExecution(&Datasource{}, nil)
+	for _, tt := range tests {
+		t.Run("precision "+tt.precision, func(t *testing.T) {
+			qm := NominalQueryModel{Channel: "speed", ValuePrecision: tt.precision}
+			res := exec.transformBatchResult(createMockComputeResult([]float64{1.5, 2.25}), qm)
+			if res.Error != nil {
+				t.Fatalf("unexpected error: %v", res.Error)
+			}
+			frame := res.Frames[0]
+			if got := frame.Fields[0].Type(); got != data.FieldTypeTime {
+				t.Errorf("time field type = %v, want time", got)
+			}
+			valueField := frame.Fields[1]
+			if got := valueField.Type(); got != tt.wantType {
+				t.Fatalf("value field type = %v, want %v", got, tt.wantType)
+			}
+		})
+	}
+}
+
+func TestFloat32FieldKeepsNullsAndMetadata(t *testing.T) {
+	value := 3.5
+	field := data.NewField("value", data.Labels{"channel": "speed"}, []*float64{&value, nil})
+	field.Config = &data.FieldConfig{Unit: "m/s"}
+
+	narrowed := float32Field(field)
+	if narrowed.Name != "value" || narrowed.Labels["channel"] != "speed" || narrowed.Config.Unit != "m/s" {
+		t.Errorf("narrowed field = %+v, want name, labels, and config kept", narrowed)
+	}
+	if got := narrowed.At(0).(*float32); got == nil || *got != 3.5 {
+		t.Errorf("value[0] = %v, want 3.5", got)
+	}
+	if got := narrowed.At(1).(*float32); got != nil {
+		t.Errorf("value[1] = %v, want nil", *got)
+	}
+}
+
+func TestValidateValuePrecision(t *testing.T) {
+	for _, precision := range []string{"", ValuePrecisionFloat32, ValuePrecisionFloat64} {
+		if err := validateValuePrecision(precision); err != nil {
+			t.Errorf("validateValuePrecision(%q) = %v, want nil", precision, err)
+		}
+	}
+	if err := validateValuePrecision("float16"); err == nil || !strings.Contains(err.Error(), "valuePrecision") {
+		t.Errorf("validateValuePrecision(float16) = %v, want an error", err)
+	}
+}
@@ -0,0 +1,82 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+)
+
+// validateValueScale returns an error when ValueOffset is set without a
+// ValueScale: a zero scale would collapse every value to the offset. Set
+// valueScale to 1 to apply only an offset.
+func validateValueScale(scale, offset float64) error {
+	if offset != 0 && scale == 0 {
+		return fmt.Errorf("valueScale must be non-zero when valueOffset is set (use 1 for an offset only)")
+	}
+	return nil
+}
+
+// hasValueScale reports whether qm converts numeric values with
+// ValueScale/ValueOffset.
+func (qm NominalQueryModel) hasValueScale() bool {
+	return qm.ValueScale != 0 && (qm.ValueScale != 1 || qm.ValueOffset != 0)
+}
+
+// reversedExtremes pairs each minimum with its maximum, as both aggregations
+// and stat fields.
+var reversedExtremes = map[string]string{
+	AggMin:  AggMax,
+	AggMax:  AggMin,
+	StatMin: StatMax,
+	StatMax: StatMin,
+}
+
+// scaledSource is the aggregation or stat field whose raw values become name
+// once scaled. A negative ValueScale reverses order, so the scaled minimum is
+// read from the raw maximum and the scaled maximum from the raw minimum.
+func (qm NominalQueryModel) scaledSource(name string) string {
+	if qm.ValueScale < 0 {
+		if source, ok := reversedExtremes[name]; ok {
+			return source
+		}
+	}
+	return name
+}
+
+// scaleValues replaces each non-nil value with value*scale + offset. Scaled
+// entries get their own pointers so no shared value is mutated.
+func scaleValues(values []*float64, scale, offset float64) []*float64 {
+	for i, v := range values {
+		if v != nil {
+			scaled := *v*scale + offset
+			values[i] = &scaled
+		}
+	}
+	return values
+}
+
+// applyValueScale converts every numeric series in result from raw channel
+// values with value*scale + offset. Series in the channel unit get the full
+// conversion; spread statistics only scale (standard deviation by |scale|,
+// variance by scale²) and counts are left alone.
+func applyValueScale(result *TransformResult, scale, offset float64) {
+	result.NumericValues = scaleValues(result.NumericValues, scale, offset)
+	result.StdDevValues = scaleValues(result.StdDevValues, math.Abs(scale), 0)
+	for i := range result.StatSeries {
+		series := &result.StatSeries[i]
+		switch {
+		case series.Name == StatStdDev:
+			series.Values = scaleValues(series.Values, math.Abs(scale), 0)
+		case series.CarriesChannelUnit:
+			series.Values = scaleValues(series.Values, scale, offset)
+		}
+	}
+	for i := range result.AggSeries {
+		series := &result.AggSeries[i]
+		switch {
+		case series.Name == aggSpecs[AggVariance].Name:
+			series.Values = scaleValues(series.Values, scale*scale, 0)
+		case series.CarriesChannelUnit:
+			series.Values = scaleValues(series.Values, scale, offset)
+		}
+	}
+}
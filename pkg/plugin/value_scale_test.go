@@ -0,0 +1,107 @@
+package plugin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nominal-io/nominal-api-go/io/nominal/api"
+	computeapi "github.com/nominal-io/nominal-api-go/scout/compute/api"
+)
+
+func TestTransformBatchResultAppliesValueScale(t *testing.T) {
+	tests := []struct {
+		name          string
+		scale, offset float64
+		want          []float64
+	}{
+		{name: "unset leaves values", want: []float64{0, 100, 4095}},
+		{name: "scale only", scale: 0.5, want: []float64{0, 50, 2047.5}},
+		{name: "scale and offset", scale: 2, offset: -10, want: []float64{-10, 190, 8180}},
+		{name: "offset with unit scale", scale: 1, offset: 273.15, want: []float64{273.15, 373.15, 4368.15}},
+		{name: "negative scale", scale: -1, offset: 5, want: []float64{5, -95, -4090}},
+	}
+	exec := newTestQueryExecution(&Datasource{}, nil)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			qm := NominalQueryModel{Channel: "adc", ValueScale: tt.scale, ValueOffset: tt.offset}
+			res := exec.transformBatchResult(createMockComputeResult([]float64{0, 100, 4095}), qm)
+			if res.Error != nil {
+				t.Fatalf("unexpected error: %v", res.Error)
+			}
+			valueField := res.Frames[0].Fields[1]
+			for i, want := range tt.want {
+				if got, _ := valueField.At(i).(*float64); got == nil || *got != want {
+					t.Errorf("value[%d] = %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestValueScaleLeavesCountAndScalesSpread(t *testing.T) {
+	plot := computeapi.BucketedNumericPlot{
+		Timestamps: []api.Timestamp{testTimestamp(1704067200)},
+		Buckets:    []computeapi.NumericBucket{{Mean: 10, Min: 8, Max: 13, Count: 4, Variance: 4}},
+	}
+	result := computeapi.ComputeWithUnitsResult{
+		ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromBucketedNumeric(plot)),
+	}
+	qm := NominalQueryModel{
+		Channel:     "adc",
+		ChannelUnit: "V",
+		StatFields:  []string{StatMean, StatMin, StatMax, StatCount, StatStdDev},
+		ValueScale:  -3,
+		ValueOffset: 1,
+	}
+
+	res := newTestQueryExecution(&Datasource{}, nil).transformBatchResult(result, qm)
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	frame := res.Frames[0]
+	// The negative scale makes the raw max the scaled min. stddev is
+	// sqrt(4) = 2, scaled by |scale| with no offset.
+	for i, want := range []float64{-29, -38, -23, 4, 6} {
+		field := frame.Fields[i+1]
+		if got, _ := field.At(0).(*float64); got == nil || *got != want {
+			t.Errorf("%s = %v, want %v", field.Name, got, want)
+		}
+		if field.Config.Unit != "" {
+			t.Errorf("%s unit = %q, want none for scaled values", field.Name, field.Config.Unit)
+		}
+	}
+}
+
+func TestNegativeValueScaleReadsMinFromMaxColumn(t *testing.T) {
+	arrowPlot := computeapi.ArrowBucketedNumericPlot{ArrowBinary: createTestArrowMultiAgg(
+		[]int64{1704067200000000000},
+		map[string][]float64{"min": {8}, "max": {13}},
+	)}
+	result := computeapi.ComputeWithUnitsResult{
+		ComputeResult: computeapi.NewComputeNodeResultFromSuccess(computeapi.NewComputeNodeResponseFromArrowBucketedNumeric(arrowPlot)),
+	}
+	qm := NominalQueryModel{Channel: "adc", Aggregations: []string{AggMin}, ValueScale: -1}
+
+	res := newTestQueryExecution(&Datasource{}, nil).transformBatchResult(result, qm)
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+	if got, _ := res.Frames[0].Fields[1].At(0).(*float64); got == nil || *got != -13 {
+		t.Errorf("min = %v, want -13 (the negated raw max)", got)
+	}
+	if got := qm.scaledSource(AggMin); got != AggMax {
+		t.Errorf("scaledSource(MIN) = %q, want MAX so the request asks for the raw max", got)
+	}
+}
+
+func TestValidateValueScale(t *testing.T) {
+	if err := validateValueScale(0, 0); err != nil {
+		t.Errorf("unset scale/offset: unexpected error %v", err)
+	}
+	if err := validateValueScale(2, 1); err != nil {
+		t.Errorf("scale and offset: unexpected error %v", err)
+	}
+	if err := validateValueScale(0, 1); err == nil || !strings.Contains(err.Error(), "valueScale must be non-zero") {
+		t.Errorf("offset without scale: err = %v, want non-zero scale error", err)
+	}
+}
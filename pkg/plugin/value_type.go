@@ -0,0 +1,92 @@
+package plugin
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+// ValueType values select the type of numeric value fields. An empty
+// ValueType behaves like ValueTypeFloat64.
+const (
+	ValueTypeFloat64 = "float64"
+	ValueTypeInt64   = "int64"
+)
+
+// validateValueType returns an error for an unrecognised value type, or for
+// int64 on a query without numeric values or with a float32 precision.
+func validateValueType(qm NominalQueryModel) error {
+	switch qm.ValueType {
+	case "", ValueTypeFloat64:
+		return nil
+	case ValueTypeInt64:
+	default:
+		return fmt.Errorf("unsupported valueType %q; valid options are float64, int64", qm.ValueType)
+	}
+	if qm.isEnumQuery() || qm.ChannelDataType == ChannelDataTypeLog || qm.QueryType == queryTypeGaps {
+		return fmt.Errorf("valueType int64 requires a numeric channel")
+	}
+	if qm.ValuePrecision == ValuePrecisionFloat32 {
+		return fmt.Errorf("valueType int64 cannot be combined with valuePrecision float32")
+	}
+	return nil
+}
+
+// applyValueType converts every float64 field in frames to int64 when
+// valueType is ValueTypeInt64. Time and string fields are unchanged.
+func applyValueType(frames data.Frames, valueType string) {
+	if valueType != ValueTypeInt64 {
+		return
+	}
+	for _, frame := range frames {
+		for i, field := range frame.Fields {
+			frame.Fields[i] = int64Field(field)
+		}
+	}
+}
+
+// int64Field returns an int64 copy of a float64 or nullable float64 field,
+// keeping its name, labels, and config, or field itself for any other type.
+// Fractional values are rounded half away from zero and values beyond the
+// int64 range saturate at its bounds. NaN (e.g. a gapValue sentinel) becomes
+// null in a nullable field and 0 otherwise.
+func int64Field(field *data.Field) *data.Field {
+	var converted *data.Field
+	switch field.Type() {
+	case data.FieldTypeFloat64:
+		values := make([]int64, field.Len())
+		for i := range values {
+			values[i], _ = roundToInt64(field.At(i).(float64))
+		}
+		converted = data.NewField(field.Name, field.Labels, values)
+	case data.FieldTypeNullableFloat64:
+		values := make([]*int64, field.Len())
+		for i := range values {
+			if v := field.At(i).(*float64); v != nil {
+				if value, ok := roundToInt64(*v); ok {
+					values[i] = &value
+				}
+			}
+		}
+		converted = data.NewField(field.Name, field.Labels, values)
+	default:
+		return field
+	}
+	converted.Config = field.Config
+	return converted
+}
+
+// roundToInt64 rounds v half away from zero, saturating at the int64 bounds.
+// It reports false for NaN, which has no integer value.
+func roundToInt64(v float64) (int64, bool) {
+	switch {
+	case math.IsNaN(v):
+		return 0, false
+	case v >= math.MaxInt64:
+		return math.MaxInt64, true
+	case v <= math.MinInt64:
+		return math.MinInt64, true
+	}
+	return int64(math.Round(v)), true
+}
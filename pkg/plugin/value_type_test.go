@@ -0,0 +1,56 @@
+package plugin
+
+import (
+	"math"
+	"testing"
+
+	"github.com/grafana/grafana-plugin-sdk-go/data"
+)
+
+func TestTransformBatchResultAppliesInt64ValueType(t *testing.T) {
+	exec := newTestQueryExecution(&Datasource{}, nil)
+	qm := NominalQueryModel{Channel: "counter", ValueType: ValueTypeInt64}
+	res := exec.transformBatchResult(createMockComputeResult([]float64{41, 2.5, -2.5, 9007199254740993}), qm)
+	if res.Error != nil {
+		t.Fatalf("unexpected error: %v", res.Error)
+	}
+
+	valueField := res.Frames[0].Fields[1]
+	if got := valueField.Type(); got != data.FieldTypeNullableInt64 {
+		t.Fatalf("value field type = %v, want nullable int64", got)
+	}
+	// Halves round away from zero; the last value is not exactly representable
+	// as a float64 and comes back as its nearest float64.
+	for i, want := range []int64{41, 3, -3, 9007199254740992} {
+		if got := valueField.At(i).(*int64); got == nil || *got != want {
+			t.Errorf("value[%d] = %v, want %d", i, got, want)
+		}
+	}
+}
+
+func TestInt64FieldHandlesNaNAndRange(t *testing.T) {
+	nan, huge := math.NaN(), math.Inf(1)
+	field := data.NewField("value", data.Labels{"channel": "counter"}, []*float64{&nan, &huge, nil})
+	field.Config = &data.FieldConfig{Unit: "short"}
+
+	converted := int64Field(field)
+	if converted.Name != "value" || converted.Labels["channel"] != "counter" || converted.Config.Unit != "short" {
+		t.Errorf("converted field = %+v, want name, labels, and config kept", converted)
+	}
+	if got := converted.At(0).(*int64); got != nil {
+		t.Errorf("NaN = %d, want null", *got)
+	}
+	if got := converted.At(1).(*int64); got == nil || *got != math.MaxInt64 {
+		t.Errorf("+Inf = %v, want saturation at MaxInt64", got)
+	}
+	if got := converted.At(2).(*int64); got != nil {
+		t.Errorf("null = %d, want null", *got)
+	}
+
+	if err := validateValueType(NominalQueryModel{ValueType: "int32"}); err == nil {
+		t.Error("expected an error for an unsupported valueType")
+	}
+	if err := validateValueType(NominalQueryModel{ValueType: ValueTypeInt64, ChannelDataType: ChannelDataTypeString}); err == nil {
+		t.Error("expected an error for int64 on an enum channel")
+	}
+}